@@ -0,0 +1,5 @@
+// Package audit keeps an append-only log of the decisions and actions of flipper for post-incident reviews: every
+// health transition, every plan with the state it was based on, and every call to the provider with its result.
+//
+// The log is written as JSON lines with a stable schema, see Record. The file is rotated when it grows too large.
+package audit
@@ -0,0 +1,26 @@
+package audit
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/gzuidhof/flipper/event"
+)
+
+// Handler returns an event handler that writes a record of every audited event to the log. Failures to write are
+// logged, they do not stop the monitor.
+func Handler(log *Log, logger *slog.Logger) event.Handler {
+	return func(ctx context.Context, e event.Event) {
+		record, ok := NewRecord(e)
+		if !ok {
+			return
+		}
+		if err := log.Write(record); err != nil {
+			logger.ErrorContext(ctx, "Failed to write audit record.",
+				slog.String("type", string(record.Type)),
+				slog.String("group_id", record.GroupID),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+)
+
+// Log appends records to a file as JSON lines. When the file grows beyond its maximum size it is rotated: the file
+// is renamed with suffix ".1", the previous ".1" becomes ".2" and so on, and the oldest files are removed.
+type Log struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// Open opens the audit log that is configured, creating the file and its directory if they do not exist.
+func Open(cfg cfgmodel.AuditConfig) (*Log, error) {
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+
+	l := &Log{
+		path:     cfg.Path,
+		maxSize:  cfg.MaxSizeOrDefault(),
+		maxFiles: cfg.MaxFilesOrDefault(),
+	}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *Log) open() error {
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o640)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	l.file = file
+	l.size = info.Size()
+	return nil
+}
+
+// Write appends a record to the log. If the log can not be rotated, the record is still appended to the current
+// file and the error is returned.
+func (l *Log) Write(record Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit record: %w", err)
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return fmt.Errorf("audit log is closed")
+	}
+	// An empty file is never rotated, so a record that is larger than the maximum size is still written.
+	var rotateErr error
+	if l.size > 0 && l.size+int64(len(line)) > l.maxSize {
+		rotateErr = l.rotate()
+		if l.file == nil {
+			return rotateErr
+		}
+	}
+
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		return errors.Join(rotateErr, fmt.Errorf("failed to write audit record: %w", err))
+	}
+	return rotateErr
+}
+
+// rotate moves the current file aside and opens a new one. If the file can not be moved aside, the current file is
+// opened again so that records are not lost, the log is only closed if that fails too.
+func (l *Log) rotate() error {
+	err := l.file.Close()
+	l.file = nil
+	if err != nil {
+		err = fmt.Errorf("failed to close audit log: %w", err)
+	} else {
+		err = l.moveAside()
+	}
+	if err != nil {
+		if openErr := l.open(); openErr != nil {
+			return errors.Join(err, openErr)
+		}
+		return err
+	}
+	return l.open()
+}
+
+// moveAside renames the current file and the rotated files to the next suffix, removing the oldest.
+func (l *Log) moveAside() error {
+	if err := os.Remove(rotatedPath(l.path, l.maxFiles)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove old audit log: %w", err)
+	}
+	for i := l.maxFiles - 1; i >= 1; i-- {
+		err := os.Rename(rotatedPath(l.path, i), rotatedPath(l.path, i+1))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to rotate audit log: %w", err)
+		}
+	}
+	if err := os.Rename(l.path, rotatedPath(l.path, 1)); err != nil {
+		return fmt.Errorf("failed to rotate audit log: %w", err)
+	}
+	return nil
+}
+
+// Close closes the log, records can no longer be written.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// rotatedPath returns the path of the nth rotated file, 0 is the current file.
+func rotatedPath(path string, n int) string {
+	if n == 0 {
+		return path
+	}
+	return path + "." + strconv.Itoa(n)
+}
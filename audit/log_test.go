@@ -0,0 +1,147 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+	"github.com/gzuidhof/flipper/event"
+	"github.com/gzuidhof/flipper/resource"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLog(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+	l, err := Open(cfgmodel.AuditConfig{Enabled: true, Path: path, MaxFiles: 2})
+	require.NoError(t, err)
+	// Rotate after every record.
+	l.maxSize = 1
+
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	server := resource.Server{HetznerID: 1, ServerName: "lb-1", Location: "fsn1"}
+	for i := range 4 {
+		record, ok := NewRecord(event.ServerStateChanged{
+			Meta:     event.Meta{At: start.Add(time.Duration(i) * time.Minute), Group: event.Group{ID: "lb"}},
+			Server:   server,
+			Previous: resource.State{Status: resource.StatusUnknown},
+			Current:  resource.State{Status: resource.StatusUnhealthy},
+			CheckID:  "http__ipv4",
+		})
+		require.True(t, ok)
+		require.NoError(t, l.Write(record))
+	}
+	require.NoError(t, l.Close())
+	require.Error(t, l.Write(Record{}), "the log is closed")
+
+	var times []time.Time
+	err = Read(path, 2, Query{}, func(record Record) error {
+		times = append(times, record.Time)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []time.Time{
+		start.Add(1 * time.Minute), start.Add(2 * time.Minute), start.Add(3 * time.Minute),
+	}, times, "the oldest file is removed, the rest is read oldest first")
+	assert.NoFileExists(t, path+".3")
+
+	// Reopening appends to the existing file.
+	l, err = Open(cfgmodel.AuditConfig{Enabled: true, Path: path})
+	require.NoError(t, err)
+	record, _ := NewRecord(event.ConfigReloadFailed{Meta: event.Meta{At: start.Add(time.Hour)}, Error: "boom"})
+	require.NoError(t, l.Write(record))
+	require.NoError(t, l.Close())
+
+	var records []Record
+	require.NoError(t, Read(path, 0, Query{}, func(record Record) error {
+		records = append(records, record)
+		return nil
+	}))
+	require.Len(t, records, 2)
+	assert.Equal(t, event.KindServerStateChanged, records[0].Type)
+	assert.Equal(t, []Ref{{ID: "1", Name: "lb-1"}}, records[0].Servers)
+	assert.JSONEq(t,
+		`{"previous":{"id":"1","name":"lb-1","location":"fsn1","status":"unknown"},`+
+			`"current":{"id":"1","name":"lb-1","location":"fsn1","status":"unhealthy"},"check_id":"http__ipv4"}`,
+		string(records[0].Data),
+	)
+	assert.Equal(t, SchemaVersion, records[1].Version)
+	assert.Equal(t, event.KindConfigReloadFailed, records[1].Type)
+	assert.JSONEq(t, `{"error":"boom"}`, string(records[1].Data))
+}
+
+func TestLogRotateFailure(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l, err := Open(cfgmodel.AuditConfig{Enabled: true, Path: path, MaxFiles: 1})
+	require.NoError(t, err)
+	l.maxSize = 1
+
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	write := func(i int) error {
+		record, _ := NewRecord(event.SafetyOverridden{Meta: event.Meta{At: start.Add(time.Duration(i) * time.Minute)}})
+		return l.Write(record)
+	}
+	require.NoError(t, write(0))
+
+	// The oldest file can not be removed, so the log can not be rotated.
+	require.NoError(t, os.MkdirAll(filepath.Join(path+".1", "dir"), 0o750))
+	require.Error(t, write(1))
+	require.Error(t, write(2), "rotating is retried")
+
+	// The records are appended to the current file until rotating works again.
+	require.NoError(t, os.RemoveAll(path+".1"))
+	require.NoError(t, write(3))
+	require.NoError(t, l.Close())
+
+	var times []time.Time
+	require.NoError(t, Read(path, 1, Query{}, func(record Record) error {
+		times = append(times, record.Time)
+		return nil
+	}))
+	assert.Equal(t, []time.Time{
+		start, start.Add(1 * time.Minute), start.Add(2 * time.Minute), start.Add(3 * time.Minute),
+	}, times)
+}
+
+func TestQuery(t *testing.T) {
+	t.Parallel()
+
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	record := Record{
+		Time:        at,
+		GroupID:     "lb",
+		Servers:     []Ref{{ID: "1", Name: "lb-1"}},
+		FloatingIPs: []Ref{{ID: "11", Name: "public"}},
+	}
+
+	tests := []struct {
+		name  string
+		query Query
+		want  bool
+	}{
+		{"empty", Query{}, true},
+		{"group", Query{GroupID: "lb"}, true},
+		{"other group", Query{GroupID: "db"}, false},
+		{"server by id", Query{Server: "1"}, true},
+		{"server by name", Query{Server: "lb-1"}, true},
+		{"other server", Query{Server: "lb-2"}, false},
+		{"floating ip by name", Query{FloatingIP: "public"}, true},
+		{"other floating ip", Query{FloatingIP: "12"}, false},
+		{"since", Query{Since: at}, true},
+		{"after", Query{Since: at.Add(time.Second)}, false},
+		{"until", Query{Until: at}, false},
+		{"before", Query{Until: at.Add(time.Second)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.want, tt.query.Matches(record))
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// maxLineSize is the size of the largest record that can be read.
+const maxLineSize = 16 << 20
+
+// Query selects records from the log. Empty fields match every record.
+type Query struct {
+	GroupID string
+	// Server and FloatingIP match the ID or the name of a server or floating IP the record is about.
+	Server     string
+	FloatingIP string
+	// Since and Until limit the time of the records, Until is exclusive.
+	Since time.Time
+	Until time.Time
+}
+
+// Matches returns true if the record is selected by the query.
+func (q Query) Matches(record Record) bool {
+	switch {
+	case q.GroupID != "" && record.GroupID != q.GroupID:
+		return false
+	case q.Server != "" && !matchesRef(record.Servers, q.Server):
+		return false
+	case q.FloatingIP != "" && !matchesRef(record.FloatingIPs, q.FloatingIP):
+		return false
+	case !q.Since.IsZero() && record.Time.Before(q.Since):
+		return false
+	case !q.Until.IsZero() && !record.Time.Before(q.Until):
+		return false
+	}
+	return true
+}
+
+func matchesRef(refs []Ref, idOrName string) bool {
+	for _, ref := range refs {
+		if ref.ID == idOrName || ref.Name == idOrName {
+			return true
+		}
+	}
+	return false
+}
+
+// Read calls fn for every record in the log at path that matches the query, oldest first. The rotated files
+// are read as well, up to maxFiles of them. It stops at the first error that fn returns.
+func Read(path string, maxFiles int, q Query, fn func(Record) error) error {
+	for n := maxFiles; n >= 0; n-- {
+		err := readFile(rotatedPath(path, n), q, fn)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readFile(path string, q Query, fn func(Record) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err //nolint:wrapcheck // the caller checks for fs.ErrNotExist.
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return fmt.Errorf("failed to decode audit record at %s:%d: %w", path, line, err)
+		}
+		if !q.Matches(record) {
+			continue
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read audit log %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,508 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gzuidhof/flipper/consensus"
+	"github.com/gzuidhof/flipper/event"
+	"github.com/gzuidhof/flipper/maintenance"
+	"github.com/gzuidhof/flipper/plan"
+	"github.com/gzuidhof/flipper/resource"
+)
+
+// SchemaVersion is the version of the schema of the records. Fields may be added to the records without changing
+// it, it only changes if fields are removed or change their meaning.
+const SchemaVersion = 1
+
+// Record is a single entry in the audit log.
+type Record struct {
+	// Version is the schema version of the record.
+	Version int       `json:"v"`
+	Time    time.Time `json:"time"`
+	// Type is the kind of event the record is about, e.g. "server_state_changed" or "provider_call".
+	Type    event.Kind `json:"type"`
+	GroupID string     `json:"group_id,omitempty"`
+	PlanID  string     `json:"plan_id,omitempty"`
+
+	// Servers and FloatingIPs are the resources the record is about, they are used to query the log.
+	Servers     []Ref `json:"servers,omitempty"`
+	FloatingIPs []Ref `json:"floating_ips,omitempty"`
+
+	// Data are the details of the record, their fields depend on the type.
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// Ref refers to a server or floating IP.
+type Ref struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// HealthTransition are the details of a server_state_changed record.
+type HealthTransition struct {
+	Previous ServerState `json:"previous"`
+	Current  ServerState `json:"current"`
+	// CheckID is the ID of the check that caused the transition.
+	CheckID string `json:"check_id"`
+	// UnhealthyChecks are the errors of the checks that are unhealthy.
+	UnhealthyChecks []string `json:"unhealthy_checks,omitempty"`
+	// Verdict is what the flipper nodes agreed on, if consensus is enabled.
+	Verdict *consensus.Verdict `json:"verdict,omitempty"`
+	// Maintenance is the ID of the active maintenance window of the server, if any.
+	Maintenance string `json:"maintenance,omitempty"`
+}
+
+// PlanDetails are the details of plan_created and plan_blocked records.
+type PlanDetails struct {
+	Plan plan.Plan `json:"plan"`
+	// Reason is why the plan was blocked, empty if it was not.
+	Reason string `json:"reason,omitempty"`
+	// State is the state the plan is based on.
+	State State `json:"state"`
+}
+
+// WarningsDetails are the details of a plan_warnings_changed record.
+type WarningsDetails struct {
+	// Warnings are the current warnings, empty if the previous warnings were resolved.
+	Warnings []plan.Warning `json:"warnings"`
+}
+
+// ExecutionDetails are the details of a plan_executed record.
+type ExecutionDetails struct {
+	Report plan.ExecutionReport `json:"report"`
+	Failed bool                 `json:"failed"`
+}
+
+// DivergenceDetails are the details of a plan_diverged record.
+type DivergenceDetails struct {
+	Divergences []Divergence `json:"divergences"`
+	// PollError is the last error polling the provider, empty if it could be polled.
+	PollError string `json:"poll_error,omitempty"`
+}
+
+// Divergence is a floating IP that does not target the server a plan expected.
+type Divergence struct {
+	FloatingIP Ref `json:"floating_ip"`
+	Expected   Ref `json:"expected"`
+	// Actual is the server the floating IP targets according to the provider, nil if none or unknown.
+	Actual *Ref `json:"actual,omitempty"`
+}
+
+// ProviderCallDetails are the details of a provider_call record.
+type ProviderCallDetails struct {
+	Operation  string `json:"operation"`
+	Rollback   bool   `json:"rollback,omitempty"`
+	Attempt    int    `json:"attempt"`
+	DurationMS int64  `json:"duration_ms"`
+	// Error is the error the call failed with, empty if it succeeded.
+	Error string `json:"error,omitempty"`
+}
+
+// ResourcesChangedDetails are the details of a resources_changed record.
+type ResourcesChangedDetails struct {
+	AddedServers       []Ref `json:"added_servers,omitempty"`
+	RemovedServers     []Ref `json:"removed_servers,omitempty"`
+	AddedFloatingIPs   []Ref `json:"added_floating_ips,omitempty"`
+	RemovedFloatingIPs []Ref `json:"removed_floating_ips,omitempty"`
+}
+
+// MonitorStartedDetails are the details of a monitor_started record.
+type MonitorStartedDetails struct {
+	Version string `json:"version"`
+}
+
+// PendingDetails are the details of a plan_pending record.
+type PendingDetails struct {
+	Plan        plan.Plan `json:"plan"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	AutoApprove bool      `json:"auto_approve,omitempty"`
+	// Replaces is the ID of the pending plan the plan replaces, empty if none.
+	Replaces string `json:"replaces,omitempty"`
+}
+
+// DecisionDetails are the details of a plan_decided record.
+type DecisionDetails struct {
+	Decision string `json:"decision"`
+}
+
+// OverrideDetails are the details of a safety_overridden record.
+type OverrideDetails struct {
+	Until time.Time `json:"until"`
+}
+
+// DriftDetails are the details of a floating_ip_drifted record.
+type DriftDetails struct {
+	// From and To are the servers the floating IP targeted before and after the change, nil if unassigned.
+	From   *Ref   `json:"from,omitempty"`
+	To     *Ref   `json:"to,omitempty"`
+	Policy string `json:"policy"`
+	// Pin is the pin the policy created, nil if the change is reverted.
+	Pin *plan.Pin `json:"pin,omitempty"`
+	// AssignedAt and AssignedBy are when and by whom the floating IP was assigned according to the provider, if it
+	// can tell.
+	AssignedAt *time.Time `json:"assigned_at,omitempty"`
+	AssignedBy string     `json:"assigned_by,omitempty"`
+}
+
+// PinDetails are the details of floating_ip_pinned and floating_ip_unpinned records.
+type PinDetails struct {
+	// Pin is the new pin, nil if the floating IP was unpinned.
+	Pin *plan.Pin `json:"pin,omitempty"`
+	// Expired is true if the pin was removed because it expired.
+	Expired bool `json:"expired,omitempty"`
+}
+
+// VerdictDetails are the details of a verdict_differs record.
+type VerdictDetails struct {
+	// Local is the status this node sees.
+	Local   resource.Status   `json:"local"`
+	Verdict consensus.Verdict `json:"verdict"`
+}
+
+// MaintenanceDetails are the details of maintenance_started and maintenance_ended records.
+type MaintenanceDetails struct {
+	WindowID string           `json:"window_id"`
+	Mode     maintenance.Mode `json:"mode,omitempty"`
+	Reason   string           `json:"reason,omitempty"`
+	// Until is when the window ends, it is only set when it starts.
+	Until *time.Time `json:"until,omitempty"`
+}
+
+// LocationDetails are the details of a location_availability_changed record.
+type LocationDetails struct {
+	Location  string `json:"location"`
+	Available bool   `json:"available"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// LeadershipDetails are the details of a leadership_changed record.
+type LeadershipDetails struct {
+	Identity string `json:"identity"`
+	Leader   bool   `json:"leader"`
+	Holder   string `json:"holder,omitempty"`
+}
+
+// ReloadDetails are the details of config_reloaded and config_reload_failed records.
+type ReloadDetails struct {
+	Added                []string `json:"added,omitempty"`
+	Removed              []string `json:"removed,omitempty"`
+	Restarted            []string `json:"restarted,omitempty"`
+	Reconfigured         []string `json:"reconfigured,omitempty"`
+	NotificationsChanged bool     `json:"notifications_changed,omitempty"`
+	RequiresRestart      []string `json:"requires_restart,omitempty"`
+	// Error is why the config could not be reloaded, empty if it was.
+	Error string `json:"error,omitempty"`
+}
+
+// State is a snapshot of the state of a group.
+type State struct {
+	Servers     []ServerState     `json:"servers"`
+	FloatingIPs []FloatingIPState `json:"floating_ips"`
+}
+
+// ServerState is the health of a server.
+type ServerState struct {
+	Ref
+	Location string          `json:"location,omitempty"`
+	Status   resource.Status `json:"status"`
+	IPv4     resource.Status `json:"ipv4,omitempty"`
+	IPv6     resource.Status `json:"ipv6,omitempty"`
+	Flapping bool            `json:"flapping,omitempty"`
+}
+
+// FloatingIPState is the target of a floating IP.
+type FloatingIPState struct {
+	Ref
+	IP string `json:"ip"`
+	// TargetID is the ID of the server the floating IP is assigned to, empty if none.
+	TargetID string `json:"target_id,omitempty"`
+}
+
+func serverRef(server resource.Server) Ref {
+	return Ref{ID: server.ID(), Name: server.Name()}
+}
+
+func floatingIPRef(flip resource.FloatingIP) Ref {
+	return Ref{ID: flip.ID(), Name: flip.Name()}
+}
+
+func serverState(server resource.Server, state resource.State) ServerState {
+	return ServerState{
+		Ref:      serverRef(server),
+		Location: server.Location,
+		Status:   state.Status,
+		IPv4:     state.IPv4,
+		IPv6:     state.IPv6,
+		Flapping: state.Flapping,
+	}
+}
+
+// newState takes a snapshot of the state of a group.
+func newState(state plan.State) State {
+	snapshot := State{Servers: []ServerState{}, FloatingIPs: []FloatingIPState{}}
+	for _, server := range state.ServersAsSlice() {
+		snapshot.Servers = append(snapshot.Servers, serverState(server.Resource, server.State()))
+	}
+	for _, flip := range state.FloatingIPsAsSlice() {
+		snapshot.FloatingIPs = append(snapshot.FloatingIPs, FloatingIPState{
+			Ref:      floatingIPRef(flip),
+			IP:       flip.IP.String(),
+			TargetID: flip.CurrentTarget,
+		})
+	}
+	return snapshot
+}
+
+// stateServerRef returns the ref of a server of the state, with only the ID if it is not in the state.
+func stateServerRef(state plan.State, serverID string) Ref {
+	ref := Ref{ID: serverID}
+	if server, ok := state.Servers[serverID]; ok {
+		ref.Name = server.Resource.Name()
+	}
+	return ref
+}
+
+// optionalServerRef returns the ref of a server of the state, nil if the server ID is empty.
+func optionalServerRef(state plan.State, serverID string) *Ref {
+	if serverID == "" {
+		return nil
+	}
+	ref := stateServerRef(state, serverID)
+	return &ref
+}
+
+// serverRefs returns the refs of the servers.
+func serverRefs(servers []resource.Server) []Ref {
+	refs := make([]Ref, 0, len(servers))
+	for _, server := range servers {
+		refs = append(refs, serverRef(server))
+	}
+	return refs
+}
+
+// driftDetails returns the details of a floating_ip_drifted record, and the servers it is about.
+func driftDetails(e event.FloatingIPDrifted) (DriftDetails, []Ref) {
+	details := DriftDetails{
+		From:   optionalServerRef(e.State, e.Drift.From),
+		To:     optionalServerRef(e.State, e.Drift.To),
+		Policy: e.Policy,
+	}
+	if e.Pin.ServerID != "" {
+		details.Pin = &e.Pin
+	}
+	if e.Drift.Assignment != nil {
+		details.AssignedAt = &e.Drift.Assignment.At
+		details.AssignedBy = e.Drift.Assignment.By
+	}
+
+	var servers []Ref
+	for _, ref := range []*Ref{details.From, details.To} {
+		if ref != nil {
+			servers = append(servers, *ref)
+		}
+	}
+	return details, servers
+}
+
+// divergenceDetails returns the details of a plan_diverged record, and the servers and floating IPs it is about.
+func divergenceDetails(e event.PlanDiverged) (DivergenceDetails, []Ref, []Ref) {
+	details := DivergenceDetails{Divergences: []Divergence{}, PollError: e.PollError}
+	var servers, floatingIPs []Ref
+	seen := map[string]bool{}
+	addServer := func(ref Ref) {
+		if !seen[ref.ID] {
+			seen[ref.ID] = true
+			servers = append(servers, ref)
+		}
+	}
+	for _, divergence := range e.Divergences {
+		flip := Ref{ID: divergence.FloatingIPID, Name: e.State.FloatingIPs[divergence.FloatingIPID].Name()}
+		floatingIPs = append(floatingIPs, flip)
+
+		d := Divergence{FloatingIP: flip, Expected: stateServerRef(e.State, divergence.ExpectedServerID)}
+		addServer(d.Expected)
+		if divergence.ActualServerID != "" {
+			actual := stateServerRef(e.State, divergence.ActualServerID)
+			d.Actual = &actual
+			addServer(actual)
+		}
+		details.Divergences = append(details.Divergences, d)
+	}
+	return details, servers, floatingIPs
+}
+
+// planRefs returns the servers and floating IPs that the actions of a plan are about.
+func planRefs(state plan.State, actions []plan.ReassignFloatingIPAction) ([]Ref, []Ref) {
+	var servers, floatingIPs []Ref
+	seen := map[string]bool{}
+	for _, action := range actions {
+		flip := state.FloatingIPs[action.FloatingIPID]
+		floatingIPs = append(floatingIPs, Ref{ID: action.FloatingIPID, Name: flip.Name()})
+
+		// Both the server the floating IP moves away from and the one it moves to are involved.
+		for _, serverID := range []string{flip.CurrentTarget, action.ServerID} {
+			if serverID == "" || seen[serverID] {
+				continue
+			}
+			seen[serverID] = true
+			servers = append(servers, stateServerRef(state, serverID))
+		}
+	}
+	return servers, floatingIPs
+}
+
+// warningRefs returns the refs of the floating IPs that warnings are about, each floating IP once.
+func warningRefs(state plan.State, warnings []plan.Warning) []Ref {
+	var floatingIPs []Ref
+	seen := map[string]bool{}
+	for _, warning := range warnings {
+		if seen[warning.FloatingIPID] {
+			continue
+		}
+		seen[warning.FloatingIPID] = true
+		floatingIPs = append(floatingIPs, Ref{ID: warning.FloatingIPID, Name: state.FloatingIPs[warning.FloatingIPID].Name()})
+	}
+	return floatingIPs
+}
+
+// NewRecord creates the record of an event. It returns false for events that are not audited.
+func NewRecord(e event.Event) (Record, bool) {
+	meta := e.Metadata()
+	record := Record{
+		Version: SchemaVersion,
+		Time:    meta.At.UTC(),
+		Type:    e.Kind(),
+		GroupID: meta.Group.ID,
+	}
+
+	var details any
+	switch e := e.(type) {
+	case event.ServerStateChanged:
+		record.Servers = []Ref{serverRef(e.Server)}
+		transition := HealthTransition{
+			Previous: serverState(e.Server, e.Previous),
+			Current:  serverState(e.Server, e.Current),
+			CheckID:  e.CheckID,
+			Verdict:  e.Verdict,
+		}
+		if e.Maintenance != nil {
+			transition.Maintenance = e.Maintenance.ID
+		}
+		for _, result := range e.UnhealthyChecks {
+			if result.Error != nil {
+				transition.UnhealthyChecks = append(transition.UnhealthyChecks, result.Error.Error())
+			}
+		}
+		details = transition
+	case event.PlanCreated:
+		record.PlanID = e.Plan.ID.String()
+		record.Servers, record.FloatingIPs = planRefs(e.State, e.Plan.Actions)
+		details = PlanDetails{Plan: e.Plan, State: newState(e.State)}
+	case event.PlanWarningsChanged:
+		record.FloatingIPs = warningRefs(e.State, e.Warnings)
+		details = WarningsDetails{Warnings: append([]plan.Warning{}, e.Warnings...)}
+	case event.PlanBlocked:
+		record.PlanID = e.Plan.ID.String()
+		record.Servers, record.FloatingIPs = planRefs(e.State, e.Plan.Actions)
+		details = PlanDetails{Plan: e.Plan, Reason: e.Reason, State: newState(e.State)}
+	case event.PlanExecuted:
+		record.PlanID = e.Report.PlanID.String()
+		actions := make([]plan.ReassignFloatingIPAction, 0, len(e.Report.Results))
+		for _, result := range e.Report.Results {
+			actions = append(actions, result.Action)
+		}
+		record.Servers, record.FloatingIPs = planRefs(e.State, actions)
+		details = ExecutionDetails{Report: e.Report, Failed: e.Failed}
+	case event.PlanDiverged:
+		record.PlanID = e.PlanID.String()
+		details, record.Servers, record.FloatingIPs = divergenceDetails(e)
+	case event.ProviderCall:
+		record.PlanID = e.PlanID.String()
+		record.Servers = []Ref{serverRef(e.Server)}
+		record.FloatingIPs = []Ref{floatingIPRef(e.FloatingIP)}
+		details = ProviderCallDetails{
+			Operation:  e.Operation,
+			Rollback:   e.Rollback,
+			Attempt:    e.Attempt,
+			DurationMS: e.Duration.Milliseconds(),
+			Error:      e.Error,
+		}
+	case event.ResourcesChanged:
+		changes := ResourcesChangedDetails{}
+		for _, server := range e.Changeset.Servers.Added {
+			changes.AddedServers = append(changes.AddedServers, serverRef(server))
+		}
+		for _, server := range e.Changeset.Servers.Removed {
+			changes.RemovedServers = append(changes.RemovedServers, serverRef(server))
+		}
+		for _, flip := range e.Changeset.FloatingIPs.Added {
+			changes.AddedFloatingIPs = append(changes.AddedFloatingIPs, floatingIPRef(flip))
+		}
+		for _, flip := range e.Changeset.FloatingIPs.Removed {
+			changes.RemovedFloatingIPs = append(changes.RemovedFloatingIPs, floatingIPRef(flip))
+		}
+		record.Servers = append(changes.AddedServers, changes.RemovedServers...)
+		record.FloatingIPs = append(changes.AddedFloatingIPs, changes.RemovedFloatingIPs...)
+		details = changes
+	case event.MonitorStarted:
+		details = MonitorStartedDetails{Version: e.Version}
+	case event.PlanPending:
+		record.PlanID = e.Plan.ID.String()
+		record.Servers, record.FloatingIPs = planRefs(e.State, e.Plan.Actions)
+		details = PendingDetails{Plan: e.Plan, ExpiresAt: e.ExpiresAt, AutoApprove: e.AutoApprove, Replaces: e.Replaces}
+	case event.PlanDecided:
+		record.PlanID = e.Plan.ID.String()
+		record.Servers, record.FloatingIPs = planRefs(e.State, e.Plan.Actions)
+		details = DecisionDetails{Decision: e.Decision}
+	case event.SafetyOverridden:
+		details = OverrideDetails{Until: e.Until}
+	case event.FloatingIPDrifted:
+		record.FloatingIPs = []Ref{floatingIPRef(e.Drift.FloatingIP)}
+		details, record.Servers = driftDetails(e)
+	case event.FloatingIPPinned:
+		record.Servers = []Ref{serverRef(e.Server)}
+		record.FloatingIPs = []Ref{floatingIPRef(e.FloatingIP)}
+		details = PinDetails{Pin: &e.Pin}
+	case event.FloatingIPUnpinned:
+		record.FloatingIPs = []Ref{{ID: e.FloatingIPID, Name: e.FloatingIPName}}
+		details = PinDetails{Expired: e.Expired}
+	case event.VerdictDiffers:
+		record.Servers = []Ref{serverRef(e.Server)}
+		details = VerdictDetails{Local: e.Local, Verdict: e.Verdict}
+	case event.MaintenanceStarted:
+		record.Servers = serverRefs(e.Servers)
+		details = MaintenanceDetails{
+			WindowID: e.Window.ID, Mode: e.Window.ModeOrDefault(), Reason: e.Window.Reason, Until: &e.Until,
+		}
+	case event.MaintenanceEnded:
+		record.Servers = serverRefs(e.Servers)
+		details = MaintenanceDetails{WindowID: e.WindowID}
+	case event.LocationAvailabilityChanged:
+		details = LocationDetails{Location: e.Location, Available: e.Available, Reason: e.Reason}
+	case event.LeadershipChanged:
+		details = LeadershipDetails{Identity: e.Identity, Leader: e.Leader, Holder: e.Holder}
+	case event.ConfigReloaded:
+		details = ReloadDetails{
+			Added:                e.Added,
+			Removed:              e.Removed,
+			Restarted:            e.Restarted,
+			Reconfigured:         e.Reconfigured,
+			NotificationsChanged: e.NotificationsChanged,
+			RequiresRestart:      e.RequiresRestart,
+		}
+	case event.ConfigReloadFailed:
+		details = ReloadDetails{Error: e.Error}
+	default:
+		return Record{}, false
+	}
+
+	data, err := json.Marshal(details)
+	if err != nil {
+		// Should never happen, the details only hold types that can be marshaled.
+		data, _ = json.Marshal(map[string]string{"error": fmt.Sprintf("failed to marshal details: %s", err)})
+	}
+	record.Data = data
+	return record, true
+}
@@ -0,0 +1,140 @@
+package audit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gzuidhof/flipper/event"
+	"github.com/gzuidhof/flipper/plan"
+	"github.com/gzuidhof/flipper/resource"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRecordPlanDiverged(t *testing.T) {
+	t.Parallel()
+
+	state := plan.NewState(
+		[]resource.FloatingIP{{HetznerID: 11, FloatingIPName: "public"}},
+		[]*resource.WithStatus[resource.Server]{
+			resource.NewWithStatus(resource.Server{HetznerID: 1, ServerName: "lb-1"}, resource.State{}),
+			resource.NewWithStatus(resource.Server{HetznerID: 2, ServerName: "lb-2"}, resource.State{}),
+		},
+	)
+	planID := uuid.New()
+	record, ok := NewRecord(event.PlanDiverged{
+		Meta:   event.Meta{At: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), Group: event.Group{ID: "lb"}},
+		PlanID: planID,
+		Divergences: []plan.Divergence{
+			{FloatingIPID: "11", ExpectedServerID: "2", ActualServerID: "1"},
+			{FloatingIPID: "12", ExpectedServerID: "2"},
+		},
+		State: state,
+	})
+	require.True(t, ok)
+
+	assert.Equal(t, event.KindPlanDiverged, record.Type)
+	assert.Equal(t, planID.String(), record.PlanID)
+	assert.Equal(t, []Ref{{ID: "2", Name: "lb-2"}, {ID: "1", Name: "lb-1"}}, record.Servers)
+	assert.Equal(t, []Ref{{ID: "11", Name: "public"}, {ID: "12"}}, record.FloatingIPs)
+	assert.JSONEq(t, `{"divergences":[`+
+		`{"floating_ip":{"id":"11","name":"public"},"expected":{"id":"2","name":"lb-2"},`+
+		`"actual":{"id":"1","name":"lb-1"}},`+
+		`{"floating_ip":{"id":"12","name":""},"expected":{"id":"2","name":"lb-2"}}]}`,
+		string(record.Data),
+	)
+}
+
+func TestNewRecordRefs(t *testing.T) {
+	t.Parallel()
+
+	lb1 := resource.Server{HetznerID: 1, ServerName: "lb-1"}
+	lb2 := resource.Server{HetznerID: 2, ServerName: "lb-2"}
+	flip := resource.FloatingIP{HetznerID: 11, FloatingIPName: "public", CurrentTarget: "1"}
+	state := plan.NewState([]resource.FloatingIP{flip}, []*resource.WithStatus[resource.Server]{
+		resource.NewWithStatus(lb1, resource.State{}),
+		resource.NewWithStatus(lb2, resource.State{}),
+	})
+	p := plan.Plan{ID: uuid.New(), Actions: []plan.ReassignFloatingIPAction{{FloatingIPID: "11", ServerID: "2"}}}
+
+	tests := []struct {
+		name        string
+		event       event.Event
+		servers     []Ref
+		floatingIPs []Ref
+	}{
+		{
+			name:        "plan pending",
+			event:       event.PlanPending{Plan: p, State: state},
+			servers:     []Ref{{ID: "1", Name: "lb-1"}, {ID: "2", Name: "lb-2"}},
+			floatingIPs: []Ref{{ID: "11", Name: "public"}},
+		},
+		{
+			name:        "plan decided",
+			event:       event.PlanDecided{Plan: p, Decision: event.DecisionApproved, State: state},
+			servers:     []Ref{{ID: "1", Name: "lb-1"}, {ID: "2", Name: "lb-2"}},
+			floatingIPs: []Ref{{ID: "11", Name: "public"}},
+		},
+		{
+			name: "plan warnings changed",
+			event: event.PlanWarningsChanged{
+				Warnings: []plan.Warning{
+					{FloatingIPID: "11", Message: "pair split: no server is healthy for both IPv4 and IPv6"},
+					{FloatingIPID: "11", Message: "pin ignored: server lb-2 is not healthy"},
+				},
+				State: state,
+			},
+			floatingIPs: []Ref{{ID: "11", Name: "public"}},
+		},
+		{
+			name: "drifted",
+			event: event.FloatingIPDrifted{
+				Drift: resource.Drift{FloatingIP: flip, From: "2", To: "1"}, State: state,
+			},
+			servers:     []Ref{{ID: "2", Name: "lb-2"}, {ID: "1", Name: "lb-1"}},
+			floatingIPs: []Ref{{ID: "11", Name: "public"}},
+		},
+		{
+			name:        "pinned",
+			event:       event.FloatingIPPinned{FloatingIP: flip, Server: lb1, Pin: plan.Pin{ServerID: "1"}},
+			servers:     []Ref{{ID: "1", Name: "lb-1"}},
+			floatingIPs: []Ref{{ID: "11", Name: "public"}},
+		},
+		{
+			name:        "unpinned",
+			event:       event.FloatingIPUnpinned{FloatingIPID: "11", FloatingIPName: "public", Expired: true},
+			floatingIPs: []Ref{{ID: "11", Name: "public"}},
+		},
+		{
+			name:    "verdict differs",
+			event:   event.VerdictDiffers{Server: lb2},
+			servers: []Ref{{ID: "2", Name: "lb-2"}},
+		},
+		{
+			name:    "maintenance started",
+			event:   event.MaintenanceStarted{Servers: []resource.Server{lb1, lb2}},
+			servers: []Ref{{ID: "1", Name: "lb-1"}, {ID: "2", Name: "lb-2"}},
+		},
+		{
+			name:    "maintenance ended",
+			event:   event.MaintenanceEnded{WindowID: "updates", Servers: []resource.Server{lb1}},
+			servers: []Ref{{ID: "1", Name: "lb-1"}},
+		},
+		{
+			name:  "leadership changed",
+			event: event.LeadershipChanged{Identity: "a", Leader: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			record, ok := NewRecord(tt.event)
+			require.True(t, ok)
+			assert.Equal(t, tt.event.Kind(), record.Type)
+			assert.Equal(t, tt.servers, record.Servers)
+			assert.Equal(t, tt.floatingIPs, record.FloatingIPs)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package checker
+
+import (
+	"math"
+	"time"
+
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+)
+
+// FlapDamper detects resources that keep flipping between healthy and unhealthy, similar to BGP route flap
+// damping. Every flap adds a penalty which decays exponentially with the configured half life. When the penalty
+// exceeds the suppress threshold the resource is suppressed (held down) until the penalty decays below the reuse
+// threshold. Repeated flaps while suppressed grow the penalty and thus the hold-down time, up to a maximum.
+//
+// The FlapDamper is not safe for concurrent use.
+type FlapDamper struct {
+	cfg cfgmodel.FlapDampingConfig
+
+	penalty    float64
+	updatedAt  time.Time
+	suppressed bool
+}
+
+// NewFlapDamper creates a new flap damper from a config.
+func NewFlapDamper(cfg cfgmodel.FlapDampingConfig) *FlapDamper {
+	return &FlapDamper{cfg: cfg}
+}
+
+// maxPenalty is the penalty that takes exactly the max hold-down time to decay to the reuse threshold.
+func (d *FlapDamper) maxPenalty() float64 {
+	halfLives := float64(d.cfg.MaxHoldDownOrDefault()) / float64(d.cfg.HalfLifeOrDefault())
+	return d.cfg.ReuseThresholdOrDefault() * math.Pow(2, halfLives)
+}
+
+// decay the penalty up to the given time.
+func (d *FlapDamper) decay(now time.Time) {
+	if !d.updatedAt.IsZero() && !now.After(d.updatedAt) {
+		return
+	}
+	if !d.updatedAt.IsZero() {
+		halfLives := float64(now.Sub(d.updatedAt)) / float64(d.cfg.HalfLifeOrDefault())
+		d.penalty *= math.Pow(2, -halfLives)
+	}
+	d.updatedAt = now
+
+	if d.suppressed && d.penalty < d.cfg.ReuseThresholdOrDefault() {
+		d.suppressed = false
+	}
+}
+
+// Flap records a flap at the given time.
+func (d *FlapDamper) Flap(now time.Time) {
+	if !d.cfg.Enabled {
+		return
+	}
+	d.decay(now)
+
+	d.penalty = min(d.penalty+d.cfg.PenaltyOrDefault(), d.maxPenalty())
+	if d.penalty > d.cfg.SuppressThresholdOrDefault() {
+		d.suppressed = true
+	}
+}
+
+// Suppressed returns true if the resource is suppressed at the given time.
+func (d *FlapDamper) Suppressed(now time.Time) bool {
+	if !d.cfg.Enabled {
+		return false
+	}
+	d.decay(now)
+	return d.suppressed
+}
+
+// Penalty returns the current penalty at the given time.
+func (d *FlapDamper) Penalty(now time.Time) float64 {
+	d.decay(now)
+	return d.penalty
+}
+
+// HoldDownUntil returns the time until which the resource is expected to be suppressed, assuming no further flaps.
+// It returns the zero time if the resource is not suppressed.
+func (d *FlapDamper) HoldDownUntil(now time.Time) time.Time {
+	if !d.Suppressed(now) {
+		return time.Time{}
+	}
+
+	halfLives := math.Log2(d.penalty / d.cfg.ReuseThresholdOrDefault())
+	return now.Add(time.Duration(halfLives * float64(d.cfg.HalfLifeOrDefault())))
+}
+
+// Reset clears the penalty and lifts any suppression.
+func (d *FlapDamper) Reset() {
+	d.penalty = 0
+	d.suppressed = false
+	d.updatedAt = time.Time{}
+}
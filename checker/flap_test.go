@@ -0,0 +1,70 @@
+package checker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlapDamper(t *testing.T) {
+	t.Parallel()
+
+	cfg := cfgmodel.FlapDampingConfig{
+		Enabled:     true,
+		HalfLife:    10 * time.Minute,
+		MaxHoldDown: time.Hour,
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+		d := NewFlapDamper(cfgmodel.FlapDampingConfig{})
+		for i := 0; i < 10; i++ {
+			d.Flap(start)
+		}
+		assert.False(t, d.Suppressed(start))
+		assert.True(t, d.HoldDownUntil(start).IsZero())
+	})
+
+	t.Run("suppress_and_reuse", func(t *testing.T) {
+		t.Parallel()
+		d := NewFlapDamper(cfg)
+
+		d.Flap(start)
+		d.Flap(start.Add(time.Minute))
+		assert.False(t, d.Suppressed(start.Add(time.Minute)), "two flaps should not exceed the suppress threshold")
+
+		d.Flap(start.Add(2 * time.Minute))
+		now := start.Add(2 * time.Minute)
+		assert.True(t, d.Suppressed(now))
+
+		holdDown := d.HoldDownUntil(now)
+		assert.True(t, holdDown.After(now))
+		assert.True(t, d.Suppressed(holdDown.Add(-time.Second)))
+		assert.False(t, d.Suppressed(holdDown.Add(time.Second)))
+	})
+
+	t.Run("max_hold_down", func(t *testing.T) {
+		t.Parallel()
+		d := NewFlapDamper(cfg)
+		for i := 0; i < 100; i++ {
+			d.Flap(start)
+		}
+		assert.True(t, d.Suppressed(start))
+		assert.WithinDuration(t, start.Add(time.Hour), d.HoldDownUntil(start), time.Second)
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		t.Parallel()
+		d := NewFlapDamper(cfg)
+		for i := 0; i < 5; i++ {
+			d.Flap(start)
+		}
+		assert.True(t, d.Suppressed(start))
+		d.Reset()
+		assert.False(t, d.Suppressed(start))
+		assert.Zero(t, d.Penalty(start))
+	})
+}
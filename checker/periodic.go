@@ -3,10 +3,13 @@ package checker
 import (
 	"context"
 	"time"
+
+	"github.com/gzuidhof/flipper/clock"
 )
 
 type periodic[T Result] struct {
 	checker Check[T]
+	clock   clock.Clock
 }
 
 type periodicUpdate[T Result] struct {
@@ -19,27 +22,28 @@ type periodicUpdate[T Result] struct {
 }
 
 // newPeriodic creates a new periodic checker.
-func newPeriodic[T Result](checker Check[T]) *periodic[T] {
+func newPeriodic[T Result](checker Check[T], clk clock.Clock) *periodic[T] {
 	return &periodic[T]{
 		checker: checker,
+		clock:   clk,
 	}
 }
 
 // Check the health of the resource once.
 func (c *periodic[T]) Check(ctx context.Context) periodicUpdate[T] {
-	t := time.Now()
+	t := c.clock.Now()
 	result := c.checker.Check(ctx)
 	return periodicUpdate[T]{
 		Result:    result,
 		Timestamp: t,
-		Duration:  time.Since(t),
+		Duration:  c.clock.Now().Sub(t),
 	}
 }
 
 // Start the periodic checker. It will run the check every interval until the context is cancelled.
 // It will send updates to the update channel.
 func (c *periodic[T]) Start(ctx context.Context, updateChan chan<- periodicUpdate[T]) {
-	ticker := time.NewTicker(c.checker.Config().IntervalOrDefault())
+	ticker := c.clock.NewTicker(c.checker.Config().IntervalOrDefault())
 	defer ticker.Stop()
 	defer close(updateChan)
 
@@ -52,7 +56,7 @@ func (c *periodic[T]) Start(ctx context.Context, updateChan chan<- periodicUpdat
 		select {
 		case <-ctx.Done():
 			return
-		case <-ticker.C:
+		case <-ticker.C():
 			update = c.Check(ctx)
 			updateChan <- update
 		}
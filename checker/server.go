@@ -2,26 +2,50 @@ package checker
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"github.com/gzuidhof/flipper/check"
+	"github.com/gzuidhof/flipper/clock"
 	"github.com/gzuidhof/flipper/config/cfgmodel"
 	"github.com/gzuidhof/flipper/resource"
 )
 
+// Suffixes that are appended to the check IDs to distinguish the IPv4 and IPv6 variants of a check.
+const (
+	checkIDSuffixIPv4 = "__ipv4"
+	checkIDSuffixIPv6 = "__ipv6"
+)
+
 // Server checks the health of a server's public interfaces.
 type Server struct {
 	cfgs   []cfgmodel.HealthCheckConfig
 	server *resource.WithStatus[resource.Server]
 
 	multichecker *StatefulMulti[check.HTTPCheckResult]
+	damper       *FlapDamper
+	clock        clock.Clock
+
+	// resetFlappingChan is used to request the flap damping state to be reset.
+	resetFlappingChan chan struct{}
+
+	// lastResult is the most recent update of the checks, it is only used from the checker loop.
+	lastResult StatefulMultiUpdate[check.HTTPCheckResult]
 }
 
 // ServerCheckUpdate is an update to a server's health check.
 type ServerCheckUpdate struct {
 	PreviousServerState resource.State
 	ServerState         resource.State
-	ServerStateChanged  bool
+	// ServerStateChanged is true if the overall status of the server changed.
+	ServerStateChanged bool
+	// FamilyStateChanged is true if the status of the server changed for any of the IP families.
+	// This can happen without the overall status changing, e.g. when IPv4 fails while IPv6 was already failing.
+	FamilyStateChanged bool
+	// FlappingChanged is true if the server started or stopped flapping.
+	FlappingChanged bool
+	// FlappingReset is true if the update is the result of resetting the flap damping state, rather than a check.
+	FlappingReset bool
 
 	// The server that was checked.
 	Server *resource.Server
@@ -35,14 +59,57 @@ func (u ServerCheckUpdate) UnhealthyChecks() []check.HTTPCheckResult {
 	return u.Result.UnhealthyChecks()
 }
 
+// HTTPCheckFactory creates the HTTP check of an address of a server, from the config of the check with the ID of
+// the address' IP family.
+type HTTPCheckFactory func(cfg cfgmodel.HealthCheckConfig, target string) Check[check.HTTPCheckResult]
+
+// ServerOption configures a server checker.
+type ServerOption func(o *serverOptions)
+
+type serverOptions struct {
+	clock        clock.Clock
+	newHTTPCheck HTTPCheckFactory
+}
+
+// WithClock sets the clock that the checks run at and that the state of the server is timed with. It defaults to
+// the real clock.
+func WithClock(clk clock.Clock) ServerOption {
+	return func(o *serverOptions) {
+		o.clock = clk
+	}
+}
+
+// WithHTTPCheckFactory sets how the HTTP checks are created, they make real HTTP requests by default.
+func WithHTTPCheckFactory(factory HTTPCheckFactory) ServerOption {
+	return func(o *serverOptions) {
+		o.newHTTPCheck = factory
+	}
+}
+
 // NewServerChecker creates a new server checker.
 func NewServerChecker(
 	cfgs []cfgmodel.HealthCheckConfig,
+	flapDampingCfg cfgmodel.FlapDampingConfig,
 	serverWithStatus *resource.WithStatus[resource.Server],
+	opts ...ServerOption,
 ) *Server {
+	o := serverOptions{
+		clock: clock.Real{},
+		newHTTPCheck: func(cfg cfgmodel.HealthCheckConfig, target string) Check[check.HTTPCheckResult] {
+			return check.NewHTTPCheck(cfg, target)
+		},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	checker := &Server{
 		cfgs:   cfgs,
 		server: serverWithStatus,
+		damper: NewFlapDamper(flapDampingCfg),
+		clock:  o.clock,
+
+		resetFlappingChan: make(chan struct{}, 1),
 	}
 
 	server := serverWithStatus.Resource
@@ -51,17 +118,17 @@ func NewServerChecker(
 	for _, c := range cfgs {
 		if server.PublicIPv4.IsValid() && c.IPVersion != "ipv6" {
 			ipv4c := c
-			ipv4c.ID += "__ipv4"
-			checks = append(checks, check.NewHTTPCheck(ipv4c, server.PublicIPv4.String()))
+			ipv4c.ID += checkIDSuffixIPv4
+			checks = append(checks, o.newHTTPCheck(ipv4c, server.PublicIPv4.String()))
 		}
 		if server.PublicIPv6.IsValid() && c.IPVersion != "ipv4" {
 			ipv6c := c
-			ipv6c.ID += "__ipv6"
-			checks = append(checks, check.NewHTTPCheck(ipv6c, server.PublicIPv6.String()))
+			ipv6c.ID += checkIDSuffixIPv6
+			checks = append(checks, o.newHTTPCheck(ipv6c, server.PublicIPv6.String()))
 		}
 	}
 
-	checker.multichecker = NewStatefulMultiCheck(checks)
+	checker.multichecker = NewStatefulMultiCheck(checks, o.clock)
 	return checker
 }
 
@@ -75,41 +142,142 @@ func (c *Server) Start(ctx context.Context, onUpdate chan<- ServerCheckUpdate) {
 	updateChan := make(chan StatefulMultiUpdate[check.HTTPCheckResult], 16)
 	defer close(updateChan)
 
-	lastState := resource.State{
-		Status: resource.StatusUnknown,
-	}
-
 	go c.multichecker.Start(ctx, updateChan)
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case recvUpdate := <-updateChan:
-			state := resource.State{
-				LastUpdated: time.Now(),
-				Status:      resource.StatusUnhealthy,
-			}
-			if recvUpdate.HealthState == StateHealthy {
-				state.Status = resource.StatusHealthy
-			} else if recvUpdate.HealthState == StateUnknown {
-				state.Status = resource.StatusUnknown
+		case <-c.resetFlappingChan:
+			if update, ok := c.resetFlapping(); ok {
+				onUpdate <- update
 			}
+		case recvUpdate := <-updateChan:
+			onUpdate <- c.apply(recvUpdate, c.clock.Now())
+		}
+	}
+}
 
-			update := ServerCheckUpdate{
-				ServerState:         state,
-				PreviousServerState: lastState,
-				ServerStateChanged:  state.Status != lastState.Status,
-				Server:              &c.server.Resource,
-				Result:              recvUpdate,
-			}
+// apply changes the status of the server according to an update of its checks at the given time.
+func (c *Server) apply(recvUpdate StatefulMultiUpdate[check.HTTPCheckResult], now time.Time) ServerCheckUpdate {
+	// This is the unknown status, unless the checker continues from a stored state.
+	lastState := c.server.State()
+	state := resource.State{
+		LastUpdated: now,
+		Status:      statusFromCheckState(recvUpdate.HealthState),
+		IPv4:        familyStatus(recvUpdate, checkIDSuffixIPv4),
+		IPv6:        familyStatus(recvUpdate, checkIDSuffixIPv6),
+	}
 
-			if update.ServerStateChanged {
-				lastState = state
-				c.server.SetState(state)
-			}
+	// Every time the server goes down counts as a flap.
+	if lastState.Status == resource.StatusHealthy && state.Status == resource.StatusUnhealthy {
+		c.damper.Flap(now)
+	}
+	state.Flapping = c.damper.Suppressed(now)
+	state.HoldDownUntil = c.damper.HoldDownUntil(now)
 
-			onUpdate <- update
+	update := ServerCheckUpdate{
+		ServerState:         state,
+		PreviousServerState: lastState,
+		ServerStateChanged:  state.Status != lastState.Status,
+		FamilyStateChanged:  state.IPv4 != lastState.IPv4 || state.IPv6 != lastState.IPv6,
+		FlappingChanged:     state.Flapping != lastState.Flapping,
+		Server:              &c.server.Resource,
+		Result:              recvUpdate,
+	}
+
+	if update.ServerStateChanged || update.FamilyStateChanged || update.FlappingChanged {
+		if !update.ServerStateChanged {
+			// LastUpdated is the time the status last changed.
+			state.LastUpdated = lastState.LastUpdated
+			update.ServerState = state
 		}
+		c.server.SetState(state)
+	}
+	c.lastResult = recvUpdate
+	return update
+}
+
+// resetFlapping resets the flap damping state. It returns the update of the server, false if it was not flapping.
+func (c *Server) resetFlapping() (ServerCheckUpdate, bool) {
+	c.damper.Reset()
+	lastState := c.server.State()
+	if !lastState.Flapping {
+		return ServerCheckUpdate{}, false
+	}
+
+	state := lastState
+	state.Flapping = false
+	state.HoldDownUntil = time.Time{}
+	c.server.SetState(state)
+
+	return ServerCheckUpdate{
+		ServerState:         state,
+		PreviousServerState: lastState,
+		FlappingChanged:     true,
+		FlappingReset:       true,
+		Server:              &c.server.Resource,
+		Result:              c.lastResult,
+	}, true
+}
+
+// Replay applies the recorded result of a check to the server, as if the check with the given ID ran at the given
+// time. It returns false if the server has no check with the ID. It must not be called while the checker runs.
+func (c *Server) Replay(
+	checkID string,
+	result check.HTTPCheckResult,
+	now time.Time,
+	duration time.Duration,
+) (ServerCheckUpdate, bool) {
+	recvUpdate, ok := c.multichecker.replay(checkID, result, now.Add(-duration), duration)
+	if !ok {
+		return ServerCheckUpdate{}, false
+	}
+	return c.apply(recvUpdate, now), true
+}
+
+// ReplayResetFlapping resets the flap damping state of the server like ResetFlapping, and returns the update of the
+// server, false if it was not flapping. It must not be called while the checker runs.
+func (c *Server) ReplayResetFlapping() (ServerCheckUpdate, bool) {
+	return c.resetFlapping()
+}
+
+// Restore continues from the stored states of the checks of the server, by check ID, see StatefulMultiUpdate.
+// It must be called before the checker is started.
+func (c *Server) Restore(checks map[string]CheckState) {
+	c.multichecker.Restore(checks)
+}
+
+// ResetFlapping resets the flap damping state of the server, lifting any hold-down.
+// It is safe to call from any goroutine.
+func (c *Server) ResetFlapping() {
+	select {
+	case c.resetFlappingChan <- struct{}{}:
+	default: // A reset is already pending.
+	}
+}
+
+// statusFromCheckState converts the state of a check to a resource status.
+func statusFromCheckState(state State) resource.Status {
+	switch state {
+	case StateHealthy:
+		return resource.StatusHealthy
+	case StateUnknown:
+		return resource.StatusUnknown
+	case StateUnhealthy:
+		return resource.StatusUnhealthy
+	}
+	return resource.StatusUnhealthy
+}
+
+// familyStatus returns the status of the checks for a single IP family, identified by the check ID suffix.
+// It returns an empty status if there are no checks for the family, in which case the overall status applies.
+func familyStatus(update StatefulMultiUpdate[check.HTTPCheckResult], suffix string) resource.Status {
+	state, ok := update.HealthStateOf(func(id string) bool {
+		return strings.HasSuffix(id, suffix)
+	})
+	if !ok {
+		return ""
 	}
+	return statusFromCheckState(state)
 }
@@ -3,6 +3,8 @@ package checker
 import (
 	"context"
 	"time"
+
+	"github.com/gzuidhof/flipper/clock"
 )
 
 // State is the state of a health check.
@@ -55,11 +57,18 @@ type StatefulUpdate[ResultType Result] struct {
 	Fall uint64
 }
 
+// CheckState is the state of a health check that can be stored, and restored after a restart.
+type CheckState struct {
+	State State  `json:"state"`
+	Rise  uint64 `json:"rise"`
+	Fall  uint64 `json:"fall"`
+}
+
 // newStateful creates a new stateful health check.
-func newStateful[ResultType Result](checker Check[ResultType]) *Stateful[ResultType] {
+func newStateful[ResultType Result](checker Check[ResultType], clk clock.Clock) *Stateful[ResultType] {
 	cfg := checker.Config()
 	return &Stateful[ResultType]{
-		checker:       newPeriodic(checker),
+		checker:       newPeriodic(checker, clk),
 		riseThreshold: cfg.RiseOrDefault(),
 		fallThreshold: cfg.FallOrDefault(),
 		id:            cfg.ID,
@@ -67,6 +76,13 @@ func newStateful[ResultType Result](checker Check[ResultType]) *Stateful[ResultT
 	}
 }
 
+// restore continues from a stored state. It must be called before the check is started.
+func (c *Stateful[ResultType]) restore(state CheckState) {
+	c.currentState = state.State
+	c.riseCount = state.Rise
+	c.fallCount = state.Fall
+}
+
 // updateCheckerState updates the state of the health check based on the number of successive successes and failures.
 func (c *Stateful[ResultType]) updateCheckerState() State {
 	if c.riseCount >= c.riseThreshold {
@@ -89,6 +105,23 @@ func (c *Stateful[ResultType]) updateRiseAndFall(success bool) {
 	}
 }
 
+// apply updates the state of the health check with the result of a check.
+func (c *Stateful[ResultType]) apply(periodicUpdate periodicUpdate[ResultType]) StatefulUpdate[ResultType] {
+	c.updateRiseAndFall(periodicUpdate.Result.Healthy())
+	newState := c.updateCheckerState()
+
+	return StatefulUpdate[ResultType]{
+		ResultType: periodicUpdate.Result,
+		Timestamp:  periodicUpdate.Timestamp,
+		Duration:   periodicUpdate.Duration,
+		State:      newState,
+		ID:         c.id,
+
+		Rise: c.riseCount,
+		Fall: c.fallCount,
+	}
+}
+
 // Start the stateful health check. It will run the check every interval until the context is cancelled.
 // The index can be used to identify this check in a multi-check setup (which is the most common use case for this).
 // The caller is responsible for closing the update channel.
@@ -104,20 +137,7 @@ func (c *Stateful[ResultType]) Start(ctx context.Context, updateChan chan<- Stat
 		case <-ctx.Done():
 			return
 		case periodicUpdate := <-periodicUpdateChan:
-			c.updateRiseAndFall(periodicUpdate.Result.Healthy())
-			newState := c.updateCheckerState()
-
-			update := StatefulUpdate[ResultType]{
-				ResultType: periodicUpdate.Result,
-				Timestamp:  periodicUpdate.Timestamp,
-				Duration:   periodicUpdate.Duration,
-				State:      newState,
-				ID:         c.id,
-
-				Rise: c.riseCount,
-				Fall: c.fallCount,
-			}
-
+			update := c.apply(periodicUpdate)
 			if ctx.Err() != nil {
 				return
 			}
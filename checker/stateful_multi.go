@@ -3,6 +3,9 @@ package checker
 import (
 	"context"
 	"maps"
+	"time"
+
+	"github.com/gzuidhof/flipper/clock"
 )
 
 // StatefulMulti is a health checker that combines multiple stateful health checks.
@@ -45,13 +48,43 @@ func (u StatefulMultiUpdate[ResultType]) UnhealthyChecks() []ResultType {
 	return unhealthyChecks
 }
 
-// NewStatefulMultiCheck creates a new stateful multi check.
+// CheckStates returns the state of every check that reported, by check ID.
+func (u StatefulMultiUpdate[ResultType]) CheckStates() map[string]CheckState {
+	states := make(map[string]CheckState, len(u.updates))
+	for id, update := range u.updates {
+		states[id] = CheckState{State: update.State, Rise: update.Rise, Fall: update.Fall}
+	}
+	return states
+}
+
+// HealthStateOf returns the worst state of the checks for which the given function returns true.
+// The second return value is false if no checks matched.
+func (u StatefulMultiUpdate[ResultType]) HealthStateOf(match func(id string) bool) (State, bool) {
+	matched := false
+	worst := StateHealthy
+	for id, update := range u.updates {
+		if !match(id) {
+			continue
+		}
+		matched = true
+		if update.State == StateUnhealthy {
+			return StateUnhealthy, true
+		}
+		if update.State == StateUnknown {
+			worst = StateUnknown
+		}
+	}
+	return worst, matched
+}
+
+// NewStatefulMultiCheck creates a new stateful multi check. The checks run at the ticks of the clock.
 func NewStatefulMultiCheck[ResultType Result](
 	checks []Check[ResultType],
+	clk clock.Clock,
 ) *StatefulMulti[ResultType] {
 	statefulChecks := make([]*Stateful[ResultType], len(checks))
 	for i, check := range checks {
-		statefulChecks[i] = newStateful(check)
+		statefulChecks[i] = newStateful(check, clk)
 	}
 
 	return &StatefulMulti[ResultType]{
@@ -60,6 +93,24 @@ func NewStatefulMultiCheck[ResultType Result](
 	}
 }
 
+// Restore continues from the stored states of the checks, by check ID. Checks without a stored state start from
+// scratch. It must be called before the checks are started.
+func (c *StatefulMulti[ResultType]) Restore(states map[string]CheckState) {
+	for _, check := range c.checks {
+		state, ok := states[check.id]
+		if !ok {
+			continue
+		}
+		check.restore(state)
+		c.lastUpdates[check.id] = StatefulUpdate[ResultType]{
+			ID:    check.id,
+			State: state.State,
+			Rise:  state.Rise,
+			Fall:  state.Fall,
+		}
+	}
+}
+
 func (c *StatefulMulti[ResultType]) worstState() State {
 	worstState := StateHealthy
 	for _, update := range c.lastUpdates {
@@ -74,6 +125,37 @@ func (c *StatefulMulti[ResultType]) worstState() State {
 	return worstState
 }
 
+// apply updates the last state of a check and returns the update of the multi check.
+func (c *StatefulMulti[ResultType]) apply(recvUpdate StatefulUpdate[ResultType]) StatefulMultiUpdate[ResultType] {
+	c.lastUpdates[recvUpdate.ID] = recvUpdate
+
+	return StatefulMultiUpdate[ResultType]{
+		HealthState:   c.worstState(),
+		lastUpdatedID: recvUpdate.ID,
+		// We need to make a clone because we don't want to send the map by reference as we
+		// mutate it locally.
+		updates: maps.Clone(c.lastUpdates),
+	}
+}
+
+// replay applies the recorded result of the check with the given ID, as if the check ran. It returns false if
+// there is no check with the ID.
+func (c *StatefulMulti[ResultType]) replay(
+	id string,
+	result ResultType,
+	timestamp time.Time,
+	duration time.Duration,
+) (StatefulMultiUpdate[ResultType], bool) {
+	for _, check := range c.checks {
+		if check.id != id {
+			continue
+		}
+		update := check.apply(periodicUpdate[ResultType]{Result: result, Timestamp: timestamp, Duration: duration})
+		return c.apply(update), true
+	}
+	return StatefulMultiUpdate[ResultType]{}, false
+}
+
 // Start the stateful multi check. It will run the checks until the context is cancelled.
 // It will send updates to the update channel on every individual check update.
 func (c *StatefulMulti[ResultType]) Start(
@@ -98,17 +180,7 @@ func (c *StatefulMulti[ResultType]) Start(
 		case <-ctx.Done():
 			return
 		case recvUpdate := <-recvUpdateChan:
-			// Update the last state of the check.
-			c.lastUpdates[recvUpdate.ID] = recvUpdate
-
-			// Send the update.
-			update := StatefulMultiUpdate[ResultType]{
-				HealthState:   c.worstState(),
-				lastUpdatedID: recvUpdate.ID,
-				// We need to make a clone because we don't want to send the map by reference as we
-				// mutate it locally.
-				updates: maps.Clone(c.lastUpdates),
-			}
+			update := c.apply(recvUpdate)
 			if ctx.Err() != nil {
 				return
 			}
@@ -0,0 +1,82 @@
+// Package clock tells the time and waits for it. Code that depends on the time takes a Clock, so that it can run
+// with a virtual clock, e.g. to replay a trace or to simulate a scenario.
+package clock
+
+import (
+	"time"
+)
+
+// Clock tells the time and creates tickers and timers.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// NewTicker returns a ticker that ticks every period, like time.NewTicker.
+	NewTicker(period time.Duration) Ticker
+	// NewTimer returns a timer that fires once after the duration, like time.NewTimer.
+	NewTimer(d time.Duration) Timer
+	// After returns a channel that receives the time after the duration, like time.After.
+	After(d time.Duration) <-chan time.Time
+}
+
+// Ticker ticks at a period, see time.Ticker.
+type Ticker interface {
+	// C returns the channel the ticks are sent on.
+	C() <-chan time.Time
+	// Reset stops the ticker and changes its period.
+	Reset(period time.Duration)
+	// Stop stops the ticker, no more ticks are sent.
+	Stop()
+}
+
+// Timer fires once, see time.Timer.
+type Timer interface {
+	// C returns the channel the time is sent on when the timer fires.
+	C() <-chan time.Time
+	// Reset changes the timer to fire after the duration. It returns false if the timer had fired or was stopped.
+	Reset(d time.Duration) bool
+	// Stop prevents the timer from firing. It returns false if the timer had fired or was stopped.
+	Stop() bool
+}
+
+// Real is the clock of the system.
+type Real struct{}
+
+// Now returns the current time of the system.
+func (Real) Now() time.Time {
+	return time.Now()
+}
+
+// NewTicker returns a time.Ticker.
+//
+//nolint:ireturn // The clock returns interfaces, so that a virtual clock can be used instead.
+func (Real) NewTicker(period time.Duration) Ticker {
+	return realTicker{time.NewTicker(period)}
+}
+
+// NewTimer returns a time.Timer.
+//
+//nolint:ireturn // The clock returns interfaces, so that a virtual clock can be used instead.
+func (Real) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+// After calls time.After.
+func (Real) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+type realTicker struct {
+	*time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time {
+	return t.Ticker.C
+}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
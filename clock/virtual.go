@@ -0,0 +1,242 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Virtual is a clock that only moves when it is told to. Its tickers and timers fire when the clock passes their
+// time, in order. Like the tickers of the time package, a tick is dropped if the previous one was not received yet.
+// It is safe to use from multiple goroutines.
+type Virtual struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*waiter
+	// fired are the timers that fired, until their time is received.
+	fired []*waiter
+}
+
+// waiter is a ticker or timer of a virtual clock. Its fields are guarded by the mutex of the clock.
+type waiter struct {
+	clock *Virtual
+	c     chan time.Time
+	// at is the time the waiter fires next, period is the period of a ticker, zero for a timer.
+	at     time.Time
+	period time.Duration
+	active bool
+}
+
+// NewVirtual creates a virtual clock at the given time.
+func NewVirtual(now time.Time) *Virtual {
+	return &Virtual{now: now}
+}
+
+// Now returns the time the clock is at.
+func (v *Virtual) Now() time.Time {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.now
+}
+
+// Set moves the clock to the given time, firing the tickers and timers it passes. Setting it to an earlier time
+// only changes the time.
+func (v *Virtual) Set(now time.Time) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.advance(now)
+}
+
+// Advance moves the clock forward by the given duration, firing the tickers and timers it passes.
+func (v *Virtual) Advance(d time.Duration) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.advance(v.now.Add(d))
+}
+
+// Next returns the time at which the next ticker or timer fires, false if there is none.
+func (v *Virtual) Next() (time.Time, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	next := v.next()
+	if next == nil {
+		return time.Time{}, false
+	}
+	return next.at, true
+}
+
+// Pending returns the number of ticks and timers that fired but were not received yet.
+func (v *Virtual) Pending() int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	pending := 0
+	for _, w := range v.waiters {
+		pending += len(w.c)
+	}
+	fired := v.fired[:0]
+	for _, w := range v.fired {
+		if len(w.c) > 0 {
+			pending += len(w.c)
+			fired = append(fired, w)
+		}
+	}
+	v.fired = fired
+	return pending
+}
+
+// Waiters returns the number of tickers and timers that have yet to fire.
+func (v *Virtual) Waiters() int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return len(v.waiters)
+}
+
+// advance fires the waiters up to the given time, in order. The caller must hold the mutex.
+func (v *Virtual) advance(to time.Time) {
+	for {
+		next := v.next()
+		if next == nil || next.at.After(to) {
+			break
+		}
+		v.now = next.at
+		select {
+		case next.c <- next.at:
+		default: // The previous tick was not received yet.
+		}
+		if next.period > 0 {
+			next.at = next.at.Add(next.period)
+		} else {
+			v.remove(next)
+			v.fired = append(v.fired, next)
+		}
+	}
+	v.now = to
+}
+
+// next returns the waiter that fires next, nil if there is none. The caller must hold the mutex.
+func (v *Virtual) next() *waiter {
+	var next *waiter
+	for _, w := range v.waiters {
+		// Of the waiters that fire at the same time, the one that was scheduled first fires first.
+		if next == nil || w.at.Before(next.at) {
+			next = w
+		}
+	}
+	return next
+}
+
+// add schedules a waiter. The caller must hold the mutex.
+func (v *Virtual) add(w *waiter) {
+	w.active = true
+	v.waiters = append(v.waiters, w)
+}
+
+// remove unschedules a waiter, it returns false if it was not scheduled. The caller must hold the mutex.
+func (v *Virtual) remove(w *waiter) bool {
+	if !w.active {
+		return false
+	}
+	w.active = false
+	for i, other := range v.waiters {
+		if other == w {
+			v.waiters = append(v.waiters[:i], v.waiters[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// NewTicker returns a ticker that ticks every period of virtual time.
+//
+//nolint:ireturn // The clock returns interfaces, so that the real clock can be used instead.
+func (v *Virtual) NewTicker(period time.Duration) Ticker {
+	if period <= 0 {
+		panic("non-positive interval for virtual ticker")
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	w := &waiter{clock: v, c: make(chan time.Time, 1), at: v.now.Add(period), period: period}
+	v.add(w)
+	return virtualTicker{w}
+}
+
+// NewTimer returns a timer that fires after the duration of virtual time.
+//
+//nolint:ireturn // The clock returns interfaces, so that the real clock can be used instead.
+func (v *Virtual) NewTimer(d time.Duration) Timer {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	w := &waiter{clock: v, c: make(chan time.Time, 1), at: v.now.Add(d)}
+	v.add(w)
+	// A timer that is already due fires right away.
+	v.advance(v.now)
+	return virtualTimer{w}
+}
+
+// After returns a channel that receives the time after the duration of virtual time.
+func (v *Virtual) After(d time.Duration) <-chan time.Time {
+	return v.NewTimer(d).C()
+}
+
+type virtualTicker struct {
+	*waiter
+}
+
+func (t virtualTicker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t virtualTicker) Reset(period time.Duration) {
+	if period <= 0 {
+		panic("non-positive interval for virtual ticker")
+	}
+	v := t.clock
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.remove(t.waiter)
+	t.at = v.now.Add(period)
+	t.period = period
+	v.add(t.waiter)
+}
+
+func (t virtualTicker) Stop() {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	t.clock.remove(t.waiter)
+}
+
+type virtualTimer struct {
+	*waiter
+}
+
+func (t virtualTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t virtualTimer) Reset(d time.Duration) bool {
+	v := t.clock
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	active := v.remove(t.waiter)
+	t.at = v.now.Add(d)
+	v.add(t.waiter)
+	v.advance(v.now)
+	return active
+}
+
+func (t virtualTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	return t.clock.remove(t.waiter)
+}
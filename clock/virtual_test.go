@@ -0,0 +1,65 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVirtual(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	v := NewVirtual(start)
+	received := func(c <-chan time.Time) (time.Time, bool) {
+		select {
+		case at := <-c:
+			return at, true
+		default:
+			return time.Time{}, false
+		}
+	}
+
+	ticker := v.NewTicker(10 * time.Second)
+	timer := v.NewTimer(15 * time.Second)
+	after := v.After(time.Minute)
+	next, ok := v.Next()
+	assert.True(t, ok)
+	assert.Equal(t, start.Add(10*time.Second), next)
+
+	v.Advance(10 * time.Second)
+	at, ok := received(ticker.C())
+	assert.True(t, ok)
+	assert.Equal(t, start.Add(10*time.Second), at)
+	_, ok = received(timer.C())
+	assert.False(t, ok, "the timer did not fire yet")
+
+	v.Advance(25 * time.Second)
+	assert.Equal(t, 1, len(ticker.C()), "ticks that are not received are dropped")
+	assert.Equal(t, 2, v.Pending())
+	at, _ = received(ticker.C())
+	assert.Equal(t, start.Add(20*time.Second), at)
+	at, _ = received(timer.C())
+	assert.Equal(t, start.Add(15*time.Second), at)
+	assert.False(t, timer.Stop(), "the timer fired already")
+	assert.Equal(t, start.Add(35*time.Second), v.Now())
+
+	ticker.Reset(time.Hour)
+	assert.False(t, timer.Reset(-time.Second), "the timer fired already")
+	at, ok = received(timer.C())
+	assert.True(t, ok, "a timer that is due fires right away")
+	assert.Equal(t, start.Add(34*time.Second), at)
+
+	v.Set(start.Add(time.Minute))
+	at, ok = received(after)
+	assert.True(t, ok)
+	assert.Equal(t, start.Add(time.Minute), at)
+	_, ok = received(ticker.C())
+	assert.False(t, ok, "the ticker was reset")
+
+	ticker.Stop()
+	assert.Equal(t, 0, v.Waiters())
+	_, ok = v.Next()
+	assert.False(t, ok)
+}
@@ -2,6 +2,9 @@ package cmd
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
+	"os"
 
 	"github.com/gzuidhof/flipper/buildinfo"
 	"github.com/gzuidhof/flipper/entry"
@@ -31,6 +34,38 @@ func CLI(ctx context.Context, args []string) error {
 				Usage:  "Start monitoring the resources",
 				Action: func(ctx context.Context, c *cli.Command) error { return entry.Monitor(ctx, c.String("config")) },
 			},
+			{
+				Name:  "audit",
+				Usage: "Print the records of the audit log as JSON lines",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "group", Usage: "Only records of the group with this ID"},
+					&cli.StringFlag{Name: "server", Usage: "Only records about the server with this ID or name"},
+					&cli.StringFlag{Name: "floating-ip", Usage: "Only records about the floating IP with this ID or name"},
+					&cli.StringFlag{Name: "since", Usage: "Only records at or after this RFC 3339 time, or duration ago (e.g. 24h)"},
+					&cli.StringFlag{Name: "until", Usage: "Only records before this RFC 3339 time, or duration ago (e.g. 1h)"},
+				},
+				Action: func(ctx context.Context, c *cli.Command) error {
+					return entry.Audit(ctx, c.String("config"), entry.AuditOptions{
+						GroupID:    c.String("group"),
+						Server:     c.String("server"),
+						FloatingIP: c.String("floating-ip"),
+						Since:      c.String("since"),
+						Until:      c.String("until"),
+					}, os.Stdout)
+				},
+			},
+			{
+				Name:      "replay",
+				Usage:     "Replay a recorded trace and print the plans that are made as JSON lines",
+				ArgsUsage: "<trace file>",
+				Action: func(ctx context.Context, c *cli.Command) error {
+					if c.Args().Len() != 1 {
+						return fmt.Errorf("expected the path of a trace file")
+					}
+					logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+					return entry.Replay(ctx, c.Args().First(), os.Stdout, logger)
+				},
+			},
 		},
 	}
 
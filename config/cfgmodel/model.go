@@ -30,6 +30,9 @@ type GroupConfig struct {
 	// This defaults to 30 seconds.
 	PlanApplyTimeout time.Duration `koanf:"plan_apply_timeout"`
 
+	// Execution configures how plans are executed: concurrency, retries and what to do on partial failure.
+	Execution ExecutionConfig `koanf:"execution"`
+
 	// PlanApplyWithUnknownStatus is a flag that indicates that the group should apply plans
 	// even if the status of one or more servers is unknown.
 	PlanApplyWithUnkownStatus bool `koanf:"plan_apply_with_unknown_status"`
@@ -42,8 +45,52 @@ type GroupConfig struct {
 	// This is only used if the provider is "hetzner".
 	Hetzner HetznerProviderConfig `koanf:"hetzner"`
 
+	// Strategy determines how floating IPs are distributed over the healthy servers, either "spread" or "priority".
+	// "spread" (the default) distributes them evenly, "priority" targets the healthy server with the highest priority
+	// (active/passive).
+	Strategy string `koanf:"strategy"`
+
+	// LocationFallbacks lists, by home location of the floating IPs, the other locations in order of preference.
+	// They are used when there is no candidate server in the home location of a floating IP. Locations that are
+	// not listed come after the listed ones. E.g. {"fsn1": ["nbg1", "hel1"]}.
+	LocationFallbacks map[string][]string `koanf:"location_fallbacks"`
+
+	// HomeLocationOnly forbids targeting floating IPs at servers outside of their home location.
+	HomeLocationOnly bool `koanf:"home_location_only"`
+
 	// Checks is a list of health checks to perform on the servers.
 	Checks []HealthCheckConfig `koanf:"checks"`
+
+	// FlapDamping configures the damping of servers that keep flipping between healthy and unhealthy.
+	FlapDamping FlapDampingConfig `koanf:"flap_damping"`
+
+	// Failback configures when floating IPs move back to their preferred server after a failover.
+	Failback FailbackConfig `koanf:"failback"`
+
+	// Pairing configures which IPv4 and IPv6 floating IPs are kept together on the same server.
+	Pairing PairingConfig `koanf:"pairing"`
+
+	// Safety configures limits on how much flipper may change at once.
+	Safety SafetyConfig `koanf:"safety"`
+
+	// DependsOn lists the groups this group depends on. Locations in which those groups are not healthy enough are
+	// avoided by this group.
+	DependsOn []DependencyConfig `koanf:"depends_on"`
+
+	// Approval configures which plans need to be approved by a human before they are executed.
+	Approval ApprovalConfig `koanf:"approval"`
+
+	// Drift configures what happens when a floating IP is moved by someone other than flipper.
+	Drift DriftConfig `koanf:"drift"`
+
+	// FloatingIPOverrides overrides parts of the group configuration for specific floating IPs, by name.
+	FloatingIPOverrides []FloatingIPOverrideConfig `koanf:"floating_ip_overrides"`
+
+	// ServerOverrides overrides properties of specific servers, by name.
+	ServerOverrides []ServerOverrideConfig `koanf:"server_overrides"`
+
+	// Maintenance lists the maintenance windows of the group. More can be added through the admin API.
+	Maintenance []MaintenanceWindowConfig `koanf:"maintenance"`
 }
 
 // Validate validates the group config.
@@ -57,15 +104,61 @@ func (c GroupConfig) Validate() error {
 		ids[check.ID] = struct{}{}
 	}
 
+	// Check that every floating IP is overridden at most once.
+	names := make(map[string]struct{})
+	for _, override := range c.FloatingIPOverrides {
+		if _, ok := names[override.Name]; ok {
+			return validation.NewError("duplicate_floating_ip_override", "duplicate floating IP override")
+		}
+		names[override.Name] = struct{}{}
+	}
+
+	// Check that every server is overridden at most once.
+	serverNames := make(map[string]struct{})
+	for _, override := range c.ServerOverrides {
+		if _, ok := serverNames[override.Name]; ok {
+			return validation.NewError("duplicate_server_override", "duplicate server override")
+		}
+		serverNames[override.Name] = struct{}{}
+	}
+
+	// Check that all maintenance window IDs are unique.
+	windowIDs := make(map[string]struct{})
+	for _, window := range c.Maintenance {
+		if _, ok := windowIDs[window.ID]; ok {
+			return validation.NewError("duplicate_maintenance_window_id", "duplicate maintenance window ID")
+		}
+		windowIDs[window.ID] = struct{}{}
+	}
+
 	return validation.ValidateStruct(&c,
 		validation.Field(&c.ID, validation.Required),
 		validation.Field(&c.DisplayName, validation.Required),
 		validation.Field(&c.Provider, validation.Required, validation.In("hetzner")),
 		validation.Field(&c.Hetzner, validation.When(c.Provider == "hetzner", validation.Required)),
+		validation.Field(&c.Strategy, validation.In(StrategySpread, StrategyPriority)),
+		validation.Field(&c.Execution),
 		validation.Field(&c.Checks),
+		validation.Field(&c.FlapDamping),
+		validation.Field(&c.Failback),
+		validation.Field(&c.Safety),
+		validation.Field(&c.DependsOn),
+		validation.Field(&c.Approval),
+		validation.Field(&c.Drift),
+		validation.Field(&c.FloatingIPOverrides),
+		validation.Field(&c.ServerOverrides),
+		validation.Field(&c.Maintenance),
 	)
 }
 
+// Strategies to distribute floating IPs over servers.
+const (
+	// StrategySpread distributes floating IPs evenly over the healthy servers.
+	StrategySpread = "spread"
+	// StrategyPriority targets floating IPs at the healthy server with the highest priority.
+	StrategyPriority = "priority"
+)
+
 // PollIntervalOrDefault returns the poll interval or the default if not set.
 func (c GroupConfig) PollIntervalOrDefault() time.Duration {
 	if c.PollInterval == 0 {
@@ -111,6 +204,24 @@ type Config struct {
 	Service ServiceConfig `koanf:"service"`
 
 	Notifications NotificationsConfig `koanf:"notifications"`
+
+	// Store configures where state is kept across restarts.
+	Store StoreConfig `koanf:"store"`
+
+	// LeaderElection configures the election of a leader when multiple flipper instances run.
+	LeaderElection LeaderElectionConfig `koanf:"leader_election"`
+
+	// Consensus configures the exchange of health check results with other flipper nodes.
+	Consensus ConsensusConfig `koanf:"consensus"`
+
+	// Reload configures reloading the configuration while flipper runs.
+	Reload ReloadConfig `koanf:"reload"`
+
+	// Audit configures the audit log of decisions and actions.
+	Audit AuditConfig `koanf:"audit"`
+
+	// Trace configures the recording of the inputs of the monitor, for replaying them.
+	Trace TraceConfig `koanf:"trace"`
 }
 
 // Validate validates the config.
@@ -123,11 +234,24 @@ func (c Config) Validate() error {
 		}
 		ids[group.ID] = struct{}{}
 	}
+	if err := validateDependencies(c.Groups); err != nil {
+		return err
+	}
+	if c.Consensus.Enabled && !c.Server.Enabled {
+		// The peers fetch the view of this node from the built-in server.
+		return validation.NewError("consensus_requires_server", "consensus requires the server to be enabled")
+	}
 	return validation.ValidateStruct(&c,
 		validation.Field(&c.Version, validation.Required, validation.In(1)),
 		validation.Field(&c.Groups),
 		validation.Field(&c.Server),
 		validation.Field(&c.Telemetry),
 		validation.Field(&c.Heartbeat),
+		validation.Field(&c.Store),
+		validation.Field(&c.LeaderElection),
+		validation.Field(&c.Consensus),
+		validation.Field(&c.Reload),
+		validation.Field(&c.Audit),
+		validation.Field(&c.Trace),
 	)
 }
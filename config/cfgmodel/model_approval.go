@@ -0,0 +1,50 @@
+package cfgmodel
+
+import (
+	"errors"
+	"net/url"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// ApprovalConfig configures which plans need to be approved by a human before they are executed.
+type ApprovalConfig struct {
+	// Required requires approval for moving any floating IP of the group. It can be overridden per floating IP.
+	Required bool `koanf:"required"`
+
+	// Timeout is how long a plan waits for approval. Defaults to 30 minutes.
+	Timeout time.Duration `koanf:"timeout"`
+
+	// AutoApprove approves a plan that is still pending after the timeout, instead of dropping it.
+	AutoApprove bool `koanf:"auto_approve"`
+
+	// LinkURL is the URL the built-in server of flipper is reachable at, e.g. "https://flipper.example.com".
+	// If set, the notifications of pending plans contain links to approve or reject them.
+	LinkURL string `koanf:"link_url"`
+}
+
+// TimeoutOrDefault returns the approval timeout or the default if not set.
+func (c ApprovalConfig) TimeoutOrDefault() time.Duration {
+	if c.Timeout == 0 {
+		return 30 * time.Minute
+	}
+	return c.Timeout
+}
+
+// Validate validates the approval config.
+func (c ApprovalConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Timeout, validation.Min(time.Duration(0))),
+		validation.Field(&c.LinkURL, validation.By(func(value any) error {
+			linkURL, _ := value.(string)
+			if linkURL == "" {
+				return nil
+			}
+			if u, err := url.Parse(linkURL); err != nil || u.Scheme == "" || u.Host == "" {
+				return errors.New("must be an absolute URL")
+			}
+			return nil
+		})),
+	)
+}
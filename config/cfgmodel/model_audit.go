@@ -0,0 +1,46 @@
+package cfgmodel
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// AuditConfig configures the audit log, an append-only record of every health transition, plan and provider call.
+type AuditConfig struct {
+	// Enabled is a flag that enables the audit log.
+	Enabled bool `koanf:"enabled"`
+
+	// Path is the file the audit log is written to, as JSON lines. Rotated files get a numeric suffix, e.g.
+	// "audit.jsonl.1" is the most recent rotated file.
+	Path string `koanf:"path"`
+
+	// MaxSizeMB is the size in megabytes at which the file is rotated. Defaults to 100.
+	MaxSizeMB int `koanf:"max_size_mb"`
+
+	// MaxFiles is the number of rotated files that are kept, older files are removed. Defaults to 5.
+	MaxFiles int `koanf:"max_files"`
+}
+
+// MaxSizeOrDefault returns the size in bytes at which the file is rotated, or the default if not set.
+func (c AuditConfig) MaxSizeOrDefault() int64 {
+	if c.MaxSizeMB == 0 {
+		return 100 << 20
+	}
+	return int64(c.MaxSizeMB) << 20
+}
+
+// MaxFilesOrDefault returns the number of rotated files that are kept, or the default if not set.
+func (c AuditConfig) MaxFilesOrDefault() int {
+	if c.MaxFiles == 0 {
+		return 5
+	}
+	return c.MaxFiles
+}
+
+// Validate validates the audit config.
+func (c AuditConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Path, validation.When(c.Enabled, validation.Required)),
+		validation.Field(&c.MaxSizeMB, validation.Min(0)),
+		validation.Field(&c.MaxFiles, validation.Min(0)),
+	)
+}
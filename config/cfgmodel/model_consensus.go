@@ -0,0 +1,131 @@
+package cfgmodel
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// ConsensusConfig configures the exchange of health check results between flipper nodes that check the same
+// servers from different vantage points. A server only counts as unhealthy when a quorum of them agrees, so that
+// a node with a broken network path does not move floating IPs away from healthy servers.
+type ConsensusConfig struct {
+	Enabled bool `koanf:"enabled"`
+
+	// NodeID identifies this node to its peers. Defaults to the hostname.
+	NodeID string `koanf:"node_id"`
+
+	// Peers are the other nodes, their views are fetched from their built-in servers.
+	Peers []ConsensusPeerConfig `koanf:"peers"`
+
+	// Quorum is the number of nodes (including this one) that have to see a server as unhealthy for it to count
+	// as unhealthy, or as healthy. When fewer nodes have a recent view, the status they last agreed on is kept.
+	// Defaults to a majority.
+	Quorum int `koanf:"quorum"`
+
+	// PollInterval is the interval at which the views of the peers are fetched. Defaults to 5 seconds.
+	PollInterval time.Duration `koanf:"poll_interval"`
+
+	// PollTimeout is the maximum time to wait for a peer to respond. Defaults to 3 seconds.
+	PollTimeout time.Duration `koanf:"poll_timeout"`
+
+	// MaxAge is how old the view of a peer may be to be taken into account. Defaults to 30 seconds.
+	MaxAge time.Duration `koanf:"max_age"`
+}
+
+// ConsensusPeerConfig is another flipper node.
+type ConsensusPeerConfig struct {
+	// ID is the node ID of the peer.
+	ID string `koanf:"id"`
+
+	// URL is the URL of the built-in server of the peer, e.g. "http://10.0.0.2:8080".
+	URL string `koanf:"url"`
+
+	// Token is the admin token of the built-in server of the peer, if it has one.
+	Token string `koanf:"token"`
+}
+
+// NodeIDOrDefault returns the node ID or the hostname if not set.
+func (c ConsensusConfig) NodeIDOrDefault() string {
+	if c.NodeID != "" {
+		return c.NodeID
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "flipper"
+	}
+	return hostname
+}
+
+// QuorumOrDefault returns the quorum or a majority of the nodes if not set.
+func (c ConsensusConfig) QuorumOrDefault() int {
+	if c.Quorum == 0 {
+		return (len(c.Peers)+1)/2 + 1
+	}
+	return c.Quorum
+}
+
+// PollIntervalOrDefault returns the poll interval or the default if not set.
+func (c ConsensusConfig) PollIntervalOrDefault() time.Duration {
+	if c.PollInterval == 0 {
+		return 5 * time.Second
+	}
+	return c.PollInterval
+}
+
+// PollTimeoutOrDefault returns the poll timeout or the default if not set.
+func (c ConsensusConfig) PollTimeoutOrDefault() time.Duration {
+	if c.PollTimeout == 0 {
+		return 3 * time.Second
+	}
+	return c.PollTimeout
+}
+
+// MaxAgeOrDefault returns the max age or the default if not set.
+func (c ConsensusConfig) MaxAgeOrDefault() time.Duration {
+	if c.MaxAge == 0 {
+		return 30 * time.Second
+	}
+	return c.MaxAge
+}
+
+// Validate validates the consensus config.
+func (c ConsensusConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	ids := map[string]struct{}{c.NodeIDOrDefault(): {}}
+	for _, peer := range c.Peers {
+		if _, ok := ids[peer.ID]; ok {
+			return fmt.Errorf("duplicate node ID: %s", peer.ID)
+		}
+		ids[peer.ID] = struct{}{}
+	}
+
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Peers, validation.Required),
+		validation.Field(&c.Quorum, validation.Min(0), validation.Max(len(c.Peers)+1)),
+		validation.Field(&c.PollInterval, validation.Min(time.Duration(0))),
+		validation.Field(&c.PollTimeout, validation.Min(time.Duration(0))),
+		validation.Field(&c.MaxAge, validation.Min(time.Duration(0))),
+	)
+}
+
+// Validate validates the consensus peer config.
+func (c ConsensusPeerConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.ID, validation.Required),
+		validation.Field(&c.URL, validation.Required, validation.By(func(value any) error {
+			peerURL, _ := value.(string)
+			if u, err := url.Parse(peerURL); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+				return errors.New("must be an absolute http(s) URL")
+			}
+			return nil
+		})),
+	)
+}
@@ -0,0 +1,87 @@
+package cfgmodel
+
+import (
+	"slices"
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// DependencyConfig declares that a group depends on another group: locations in which the other group is not
+// healthy enough are avoided by this group, e.g. frontend load balancers move out of a location in which the
+// API load balancers are down.
+type DependencyConfig struct {
+	// Group is the ID of the group this group depends on.
+	Group string `koanf:"group"`
+
+	// MinHealthyFraction is the minimum fraction of the servers of the other group in a location (with a known
+	// status) that must be healthy for the location to be available. Zero means that one healthy server is enough.
+	MinHealthyFraction float64 `koanf:"min_healthy_fraction"`
+}
+
+// Validate validates the dependency config.
+func (c DependencyConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Group, validation.Required),
+		validation.Field(&c.MinHealthyFraction, validation.Min(0.0), validation.Max(1.0)),
+	)
+}
+
+// validateDependencies checks that the groups depend on existing groups other than themselves, without cycles.
+func validateDependencies(groups []GroupConfig) error {
+	dependsOn := make(map[string][]string, len(groups))
+	for _, group := range groups {
+		dependsOn[group.ID] = nil
+	}
+	for _, group := range groups {
+		for _, dependency := range group.DependsOn {
+			if _, ok := dependsOn[dependency.Group]; !ok {
+				return validation.NewError("unknown_dependency",
+					"group "+group.ID+" depends on unknown group "+dependency.Group)
+			}
+			dependsOn[group.ID] = append(dependsOn[group.ID], dependency.Group)
+		}
+	}
+
+	// Depth-first search, a group that is visited again while it is on the path is part of a cycle.
+	const (
+		onPath = iota + 1
+		done
+	)
+	visited := make(map[string]int, len(groups))
+	var path []string
+	var visit func(id string) error
+	visit = func(id string) error {
+		switch visited[id] {
+		case onPath:
+			start := 0
+			for i, p := range path {
+				if p == id {
+					start = i
+				}
+			}
+			cycle := slices.Concat(path[start:], []string{id})
+			return validation.NewError("dependency_cycle", "dependency cycle: "+strings.Join(cycle, " -> "))
+		case done:
+			return nil
+		}
+
+		visited[id] = onPath
+		path = append(path, id)
+		for _, dependency := range dependsOn[id] {
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		visited[id] = done
+		return nil
+	}
+
+	for _, group := range groups {
+		if err := visit(group.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package cfgmodel
+
+import (
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// What to do when a floating IP was moved by someone other than flipper, e.g. by hand in the web interface.
+const (
+	// DriftPolicyRevert notifies about the change, and lets the next plan move the floating IP back if needed.
+	DriftPolicyRevert = "revert"
+	// DriftPolicyAdopt notifies about the change, and pins the floating IP to its new server for a while.
+	DriftPolicyAdopt = "adopt"
+	// DriftPolicyAlert only notifies about the change, the floating IP is pinned to its new server for a longer
+	// while, so that someone can look into it.
+	DriftPolicyAlert = "alert"
+)
+
+// DriftConfig configures how changes to floating IPs that were not made by flipper are handled.
+type DriftConfig struct {
+	// Policy is one of "revert", "adopt" or "alert". Defaults to "revert".
+	//
+	// Adopted and alerted changes pin the floating IP to its new server. A pin is ignored while that server is
+	// unhealthy, and it is removed when the floating IP is moved again.
+	Policy string `koanf:"policy"`
+
+	// AdoptDuration is how long an adopted change is pinned. Defaults to 1 hour.
+	AdoptDuration time.Duration `koanf:"adopt_duration"`
+	// AlertDuration is how long an alerted change is pinned. Defaults to 24 hours.
+	AlertDuration time.Duration `koanf:"alert_duration"`
+}
+
+// PolicyOrDefault returns the drift policy or the default if not set.
+func (c DriftConfig) PolicyOrDefault() string {
+	if c.Policy == "" {
+		return DriftPolicyRevert
+	}
+	return c.Policy
+}
+
+// AdoptDurationOrDefault returns the adopt duration or the default if not set.
+func (c DriftConfig) AdoptDurationOrDefault() time.Duration {
+	if c.AdoptDuration == 0 {
+		return time.Hour
+	}
+	return c.AdoptDuration
+}
+
+// AlertDurationOrDefault returns the alert duration or the default if not set.
+func (c DriftConfig) AlertDurationOrDefault() time.Duration {
+	if c.AlertDuration == 0 {
+		return 24 * time.Hour
+	}
+	return c.AlertDuration
+}
+
+// Validate validates the drift config.
+func (c DriftConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Policy, validation.In(DriftPolicyRevert, DriftPolicyAdopt, DriftPolicyAlert)),
+		validation.Field(&c.AdoptDuration, validation.Min(time.Duration(0))),
+		validation.Field(&c.AlertDuration, validation.Min(time.Duration(0))),
+	)
+}
@@ -0,0 +1,130 @@
+package cfgmodel
+
+import (
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// What to do when some of the actions of a plan fail.
+const (
+	// OnFailureContinue executes the remaining actions, the completed moves are kept.
+	OnFailureContinue = "continue"
+	// OnFailureAbort stops starting new actions, the completed moves are kept.
+	OnFailureAbort = "abort"
+	// OnFailureRollback stops starting new actions and moves the floating IPs that were already moved back to
+	// their previous server.
+	OnFailureRollback = "rollback"
+)
+
+// ExecutionConfig configures how plans are executed.
+type ExecutionConfig struct {
+	// Concurrency is the maximum number of floating IPs that are reassigned at the same time. Defaults to 4.
+	Concurrency int `koanf:"concurrency"`
+
+	// MaxAttempts is the maximum number of attempts per reassignment. Defaults to 3.
+	MaxAttempts int `koanf:"max_attempts"`
+
+	// RetryBackoff is the time to wait before the first retry, it doubles for every next retry.
+	// Defaults to 1 second.
+	RetryBackoff time.Duration `koanf:"retry_backoff"`
+
+	// OnFailure is what to do when a reassignment fails after all attempts, one of "continue", "abort" or
+	// "rollback". Defaults to "abort".
+	OnFailure string `koanf:"on_failure"`
+
+	// Verification configures the check that the provider reflects the executed plan.
+	Verification VerificationConfig `koanf:"verification"`
+}
+
+// VerificationConfig configures the verification after a plan was executed: the provider is polled (with backoff)
+// until every reassignment is reflected, or the timeout passes and the plan is reported as diverged.
+type VerificationConfig struct {
+	// Disabled turns off the verification.
+	Disabled bool `koanf:"disabled"`
+
+	// Timeout is how long to keep polling before the plan is reported as diverged. Defaults to 1 minute.
+	Timeout time.Duration `koanf:"timeout"`
+
+	// InitialBackoff is the time between the first polls, it doubles up to MaxBackoff. Defaults to 1 second.
+	InitialBackoff time.Duration `koanf:"initial_backoff"`
+
+	// MaxBackoff is the maximum time between polls. Defaults to 10 seconds.
+	MaxBackoff time.Duration `koanf:"max_backoff"`
+}
+
+// TimeoutOrDefault returns the timeout or the default if not set.
+func (c VerificationConfig) TimeoutOrDefault() time.Duration {
+	if c.Timeout == 0 {
+		return time.Minute
+	}
+	return c.Timeout
+}
+
+// InitialBackoffOrDefault returns the initial backoff or the default if not set.
+func (c VerificationConfig) InitialBackoffOrDefault() time.Duration {
+	if c.InitialBackoff == 0 {
+		return time.Second
+	}
+	return c.InitialBackoff
+}
+
+// MaxBackoffOrDefault returns the maximum backoff or the default if not set.
+func (c VerificationConfig) MaxBackoffOrDefault() time.Duration {
+	if c.MaxBackoff == 0 {
+		return 10 * time.Second
+	}
+	return c.MaxBackoff
+}
+
+// Validate validates the verification config.
+func (c VerificationConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Timeout, validation.Min(time.Duration(0))),
+		validation.Field(&c.InitialBackoff, validation.Min(time.Duration(0))),
+		validation.Field(&c.MaxBackoff, validation.Min(time.Duration(0))),
+	)
+}
+
+// ConcurrencyOrDefault returns the concurrency or the default if not set.
+func (c ExecutionConfig) ConcurrencyOrDefault() int {
+	if c.Concurrency == 0 {
+		return 4
+	}
+	return c.Concurrency
+}
+
+// MaxAttemptsOrDefault returns the maximum number of attempts or the default if not set.
+func (c ExecutionConfig) MaxAttemptsOrDefault() int {
+	if c.MaxAttempts == 0 {
+		return 3
+	}
+	return c.MaxAttempts
+}
+
+// RetryBackoffOrDefault returns the retry backoff or the default if not set.
+func (c ExecutionConfig) RetryBackoffOrDefault() time.Duration {
+	if c.RetryBackoff == 0 {
+		return time.Second
+	}
+	return c.RetryBackoff
+}
+
+// OnFailureOrDefault returns the failure policy or the default if not set.
+func (c ExecutionConfig) OnFailureOrDefault() string {
+	if c.OnFailure == "" {
+		return OnFailureAbort
+	}
+	return c.OnFailure
+}
+
+// Validate validates the execution config.
+func (c ExecutionConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Concurrency, validation.Min(0)),
+		validation.Field(&c.MaxAttempts, validation.Min(0)),
+		validation.Field(&c.RetryBackoff, validation.Min(time.Duration(0))),
+		validation.Field(&c.OnFailure, validation.In(OnFailureContinue, OnFailureAbort, OnFailureRollback)),
+		validation.Field(&c.Verification),
+	)
+}
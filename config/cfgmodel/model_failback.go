@@ -0,0 +1,45 @@
+package cfgmodel
+
+import (
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// Failback policies.
+const (
+	// FailbackPolicyImmediate moves floating IPs back as soon as the original server is healthy again.
+	FailbackPolicyImmediate = "immediate"
+	// FailbackPolicyDelayed moves floating IPs back once the original server has been healthy for a while.
+	FailbackPolicyDelayed = "delayed"
+	// FailbackPolicyManual never moves floating IPs back automatically, only when requested by hand.
+	FailbackPolicyManual = "manual"
+)
+
+// FailbackConfig configures when floating IPs are moved back to their preferred server after a failover.
+// Failover away from unhealthy servers always happens immediately, this only concerns moves between healthy servers.
+type FailbackConfig struct {
+	// Policy is the failback policy, one of "immediate", "delayed" or "manual".
+	// If empty, the policy is inherited (from the group), and for the group it defaults to "immediate".
+	Policy string `koanf:"policy"`
+
+	// Delay is the time a server must be healthy before floating IPs fail back to it.
+	// Only used with the "delayed" policy. Defaults to 15 minutes.
+	Delay time.Duration `koanf:"delay"`
+}
+
+// DelayOrDefault returns the failback delay or the default if not set.
+func (c FailbackConfig) DelayOrDefault() time.Duration {
+	if c.Delay == 0 {
+		return 15 * time.Minute
+	}
+	return c.Delay
+}
+
+// Validate validates the failback config.
+func (c FailbackConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Policy, validation.In(FailbackPolicyImmediate, FailbackPolicyDelayed, FailbackPolicyManual)),
+		validation.Field(&c.Delay, validation.Min(time.Duration(0))),
+	)
+}
@@ -0,0 +1,93 @@
+package cfgmodel
+
+import (
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// FlapDampingConfig configures the detection and damping of servers that keep flipping between healthy and
+// unhealthy. It works similar to BGP route flap damping: every time a server becomes unhealthy it receives a
+// penalty, which decays exponentially over time. When the penalty exceeds the suppress threshold the server is
+// considered flapping and will not be targeted until the penalty decays below the reuse threshold.
+//
+// The thresholds are penalty values, not a number of flaps within a window: with the defaults, three flaps less than
+// about 10 minutes apart suppress a server.
+type FlapDampingConfig struct {
+	// Enabled is a flag that enables flap damping.
+	Enabled bool `koanf:"enabled"`
+
+	// Penalty is the penalty added every time the server becomes unhealthy. Defaults to 1000.
+	Penalty float64 `koanf:"penalty"`
+
+	// SuppressThreshold is the penalty above which a server is considered flapping, in the same unit as Penalty.
+	// Defaults to 2000.
+	SuppressThreshold float64 `koanf:"suppress_threshold"`
+
+	// ReuseThreshold is the penalty below which a flapping server can be targeted again, in the same unit as
+	// Penalty. Defaults to 750.
+	ReuseThreshold float64 `koanf:"reuse_threshold"`
+
+	// HalfLife is the time it takes for the penalty to be halved. Defaults to 15 minutes.
+	HalfLife time.Duration `koanf:"half_life"`
+
+	// MaxHoldDown is the maximum time a server can be held down for. Defaults to 1 hour.
+	MaxHoldDown time.Duration `koanf:"max_hold_down"`
+}
+
+// PenaltyOrDefault returns the penalty or the default if not set.
+func (c FlapDampingConfig) PenaltyOrDefault() float64 {
+	if c.Penalty == 0 {
+		return 1000
+	}
+	return c.Penalty
+}
+
+// SuppressThresholdOrDefault returns the suppress threshold or the default if not set.
+func (c FlapDampingConfig) SuppressThresholdOrDefault() float64 {
+	if c.SuppressThreshold == 0 {
+		return 2000
+	}
+	return c.SuppressThreshold
+}
+
+// ReuseThresholdOrDefault returns the reuse threshold or the default if not set.
+func (c FlapDampingConfig) ReuseThresholdOrDefault() float64 {
+	if c.ReuseThreshold == 0 {
+		return 750
+	}
+	return c.ReuseThreshold
+}
+
+// HalfLifeOrDefault returns the half life or the default if not set.
+func (c FlapDampingConfig) HalfLifeOrDefault() time.Duration {
+	if c.HalfLife == 0 {
+		return 15 * time.Minute
+	}
+	return c.HalfLife
+}
+
+// MaxHoldDownOrDefault returns the maximum hold down time or the default if not set.
+func (c FlapDampingConfig) MaxHoldDownOrDefault() time.Duration {
+	if c.MaxHoldDown == 0 {
+		return time.Hour
+	}
+	return c.MaxHoldDown
+}
+
+// Validate validates the flap damping config.
+func (c FlapDampingConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.ReuseThresholdOrDefault() >= c.SuppressThresholdOrDefault() {
+		return validation.NewError("invalid_thresholds", "reuse threshold must be lower than the suppress threshold")
+	}
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Penalty, validation.Min(0.0)),
+		validation.Field(&c.SuppressThreshold, validation.Min(0.0)),
+		validation.Field(&c.ReuseThreshold, validation.Min(0.0)),
+		validation.Field(&c.HalfLife, validation.Min(time.Duration(0))),
+		validation.Field(&c.MaxHoldDown, validation.Min(time.Duration(0))),
+	)
+}
@@ -0,0 +1,132 @@
+package cfgmodel
+
+import (
+	"errors"
+	"os"
+	"regexp"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// Backends that can hold the leader lease.
+const (
+	// LeaseBackendFile keeps the lease in a file, only instances on the same host (or sharing the file system)
+	// can take part in the election. It is mostly useful for testing.
+	LeaseBackendFile = "file"
+	// LeaseBackendHetzner keeps the lease in the labels of a Hetzner floating IP.
+	LeaseBackendHetzner = "hetzner"
+)
+
+// identityPattern matches identities that can be stored in Hetzner labels.
+//
+//nolint:gochecknoglobals // Static pattern.
+var identityPattern = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9._-]{0,61}[a-zA-Z0-9])?$`)
+
+// LeaderElectionConfig configures the election of a leader between flipper instances, so that more than one can
+// run for high availability. Only the leader executes plans, the others keep checking to be ready to take over.
+type LeaderElectionConfig struct {
+	Enabled bool `koanf:"enabled"`
+
+	// Backend is where the lease is kept, either "file" or "hetzner".
+	Backend string `koanf:"backend"`
+
+	// Identity identifies this instance, it must be unique among the instances. Defaults to the hostname.
+	Identity string `koanf:"identity"`
+
+	// LeaseDuration is how long the lease is valid after it was acquired or renewed. If the leader stops
+	// renewing it, another instance takes over after at most this time. Defaults to 15 seconds.
+	LeaseDuration time.Duration `koanf:"lease_duration"`
+
+	// RenewInterval is the interval at which the leader renews the lease, and the others try to acquire it.
+	// It must be shorter than the lease duration. Defaults to 5 seconds.
+	RenewInterval time.Duration `koanf:"renew_interval"`
+
+	// File configures the file backend.
+	File LeaseFileConfig `koanf:"file"`
+
+	// Hetzner configures the Hetzner backend.
+	Hetzner LeaseHetznerConfig `koanf:"hetzner"`
+}
+
+// LeaseFileConfig configures the file lease backend.
+type LeaseFileConfig struct {
+	// Path is the file the lease is kept in.
+	Path string `koanf:"path"`
+}
+
+// LeaseHetznerConfig configures the Hetzner lease backend.
+type LeaseHetznerConfig struct {
+	APIToken string `koanf:"api_token"`
+
+	// FloatingIPID is the ID of the floating IP whose labels hold the lease. Its labels are written as a whole, so
+	// it must not be selected by the floating IP label selector of any group.
+	FloatingIPID int64 `koanf:"floating_ip_id"`
+}
+
+// IdentityOrDefault returns the identity or the hostname if not set.
+func (c LeaderElectionConfig) IdentityOrDefault() string {
+	if c.Identity != "" {
+		return c.Identity
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "flipper"
+	}
+	return hostname
+}
+
+// LeaseDurationOrDefault returns the lease duration or the default if not set.
+func (c LeaderElectionConfig) LeaseDurationOrDefault() time.Duration {
+	if c.LeaseDuration == 0 {
+		return 15 * time.Second
+	}
+	return c.LeaseDuration
+}
+
+// RenewIntervalOrDefault returns the renew interval or the default if not set.
+func (c LeaderElectionConfig) RenewIntervalOrDefault() time.Duration {
+	if c.RenewInterval == 0 {
+		return 5 * time.Second
+	}
+	return c.RenewInterval
+}
+
+// Validate validates the leader election config.
+func (c LeaderElectionConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.RenewIntervalOrDefault() >= c.LeaseDurationOrDefault() {
+		return errors.New("renew_interval must be shorter than lease_duration")
+	}
+	identity := c.IdentityOrDefault()
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Backend, validation.Required, validation.In(LeaseBackendFile, LeaseBackendHetzner)),
+		validation.Field(&c.Identity, validation.By(func(any) error {
+			if !identityPattern.MatchString(identity) {
+				return errors.New("must be 1-63 letters, digits, '.', '_' or '-'")
+			}
+			return nil
+		})),
+		validation.Field(&c.LeaseDuration, validation.Min(time.Duration(0))),
+		validation.Field(&c.RenewInterval, validation.Min(time.Duration(0))),
+		validation.Field(&c.File, validation.Skip.When(c.Backend != LeaseBackendFile)),
+		validation.Field(&c.Hetzner, validation.Skip.When(c.Backend != LeaseBackendHetzner)),
+	)
+}
+
+// Validate validates the file lease config.
+func (c LeaseFileConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Path, validation.Required),
+	)
+}
+
+// Validate validates the Hetzner lease config.
+func (c LeaseHetznerConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.APIToken, validation.Required),
+		validation.Field(&c.FloatingIPID, validation.Required),
+	)
+}
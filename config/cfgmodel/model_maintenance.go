@@ -0,0 +1,96 @@
+package cfgmodel
+
+import (
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/gzuidhof/flipper/maintenance"
+)
+
+// MaintenanceWindowConfig configures a maintenance window, during which failover is suppressed for some servers or
+// they are drained of their floating IPs.
+type MaintenanceWindowConfig struct {
+	// ID identifies the window within the group, e.g. in notifications.
+	ID string `koanf:"id"`
+
+	// Mode is either "suppress" or "drain". Defaults to "suppress".
+	//
+	// "suppress" keeps the floating IPs on the servers of the window even if they become unhealthy, e.g. while they
+	// reboot for updates. "drain" moves the floating IPs away from them when the window starts, as long as there
+	// are other healthy servers, and lets them move back when it ends.
+	Mode string `koanf:"mode"`
+
+	// Notifications is either "annotate" or "mute". Defaults to "annotate".
+	// Notifications about the health of the servers of the window are marked as expected, or not sent at all.
+	Notifications string `koanf:"notifications"`
+
+	// Schedule is a cron schedule in UTC at which a recurring window starts, e.g. "0 3 * * sun".
+	// Duration is how long it lasts.
+	Schedule string        `koanf:"schedule"`
+	Duration time.Duration `koanf:"duration"`
+
+	// Start and End are the times of a one-off window, instead of a schedule, e.g. "2024-05-01T22:00:00Z".
+	Start time.Time `koanf:"start"`
+	End   time.Time `koanf:"end"`
+
+	// Servers are the names of the servers the window applies to. LabelSelector selects the servers by their labels
+	// instead, e.g. "role=lb,env!=staging". The window applies to all servers of the group if neither is set.
+	Servers       []string `koanf:"servers"`
+	LabelSelector string   `koanf:"label_selector"`
+
+	// Reason describes the maintenance, e.g. "weekly kernel updates".
+	Reason string `koanf:"reason"`
+}
+
+// Window returns the maintenance window of the config.
+func (c MaintenanceWindowConfig) Window() (maintenance.Window, error) {
+	window := maintenance.Window{
+		ID:            c.ID,
+		Mode:          maintenance.Mode(c.Mode),
+		Notifications: maintenance.Notifications(c.Notifications),
+		Duration:      c.Duration,
+		Start:         c.Start,
+		End:           c.End,
+		Servers:       c.Servers,
+		Reason:        c.Reason,
+	}
+	if c.Schedule != "" {
+		schedule, err := maintenance.ParseSchedule(c.Schedule)
+		if err != nil {
+			return maintenance.Window{}, err //nolint:wrapcheck // The error describes the schedule.
+		}
+		window.Schedule = schedule
+	}
+	if c.LabelSelector != "" {
+		selector, err := maintenance.ParseSelector(c.LabelSelector)
+		if err != nil {
+			return maintenance.Window{}, err //nolint:wrapcheck // The error describes the selector.
+		}
+		window.LabelSelector = selector
+	}
+	return window, nil
+}
+
+// Validate validates the maintenance window config.
+func (c MaintenanceWindowConfig) Validate() error {
+	err := validation.ValidateStruct(&c,
+		validation.Field(&c.ID, validation.Required),
+		validation.Field(&c.Mode, validation.In(string(maintenance.ModeSuppress), string(maintenance.ModeDrain))),
+		validation.Field(&c.Notifications,
+			validation.In(string(maintenance.NotificationsAnnotate), string(maintenance.NotificationsMute)),
+		),
+		validation.Field(&c.Schedule, validation.By(checkSchedule)),
+		validation.Field(&c.Duration, validation.Min(time.Duration(0))),
+		validation.Field(&c.Servers, validation.Each(validation.Required)),
+		validation.Field(&c.LabelSelector, validation.By(checkLabelSelector)),
+	)
+	if err != nil {
+		return err
+	}
+
+	window, err := c.Window()
+	if err != nil {
+		return err
+	}
+	return window.Validate() //nolint:wrapcheck // The error describes the window.
+}
@@ -0,0 +1,65 @@
+package cfgmodel
+
+import validation "github.com/go-ozzo/ozzo-validation/v4"
+
+// FloatingIPOverrideConfig overrides the group configuration for a single floating IP.
+type FloatingIPOverrideConfig struct {
+	// Name is the name of the floating IP this configuration applies to.
+	Name string `koanf:"name"`
+
+	// Failback overrides the failback policy of the group for this floating IP.
+	Failback FailbackConfig `koanf:"failback"`
+
+	// Priority lists server names from the highest to the lowest priority, for the priority strategy.
+	// It takes precedence over the priority of the servers themselves. Servers that are not listed come last.
+	Priority []string `koanf:"priority"`
+
+	// LocationFallbacks lists the locations to use, in order of preference, when there is no candidate server in
+	// the home location of the floating IP. It replaces the group's fallbacks for the home location.
+	LocationFallbacks []string `koanf:"location_fallbacks"`
+
+	// HomeLocationOnly overrides whether the floating IP may be targeted at servers outside of its home location.
+	// If not set it is inherited from the group.
+	HomeLocationOnly *bool `koanf:"home_location_only"`
+
+	// RequireApproval overrides whether moving the floating IP needs to be approved by a human.
+	// If not set it is inherited from the group.
+	RequireApproval *bool `koanf:"require_approval"`
+}
+
+// Validate validates the floating IP override config.
+func (c FloatingIPOverrideConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Name, validation.Required),
+		validation.Field(&c.Failback),
+		validation.Field(&c.Priority, validation.Each(validation.Required)),
+		validation.Field(&c.LocationFallbacks, validation.Each(validation.Required)),
+	)
+}
+
+// ServerOverrideConfig overrides the properties of a single server, instead of (or in addition to) labels.
+type ServerOverrideConfig struct {
+	// Name is the name of the server this configuration applies to.
+	Name string `koanf:"name"`
+
+	// Weight is the relative capacity of the server, floating IPs are distributed in proportion to it.
+	// This overrides the `weight` label. Zero means not overridden.
+	Weight int `koanf:"weight"`
+
+	// MaxFloatingIPs is the maximum number of floating IPs that may target the server.
+	// This overrides the `max_floating_ips` label. Zero means not overridden.
+	MaxFloatingIPs int `koanf:"max_floating_ips"`
+
+	// Priority of the server for the priority strategy, servers with a higher priority are preferred.
+	// This overrides the `priority` label. Zero means not overridden.
+	Priority int `koanf:"priority"`
+}
+
+// Validate validates the server override config.
+func (c ServerOverrideConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Name, validation.Required),
+		validation.Field(&c.Weight, validation.Min(0)),
+		validation.Field(&c.MaxFloatingIPs, validation.Min(0)),
+	)
+}
@@ -0,0 +1,14 @@
+package cfgmodel
+
+// PairingConfig configures how IPv4 and IPv6 floating IPs are paired up.
+// The floating IPs of a pair are always placed on the same server, so that a service is reachable over both IP
+// families on the same machine.
+type PairingConfig struct {
+	// Label is the name of the floating IP label that pairs floating IPs: an IPv4 and an IPv6 floating IP
+	// with the same value for this label form a pair. If empty, floating IPs are not paired by label.
+	Label string `koanf:"label"`
+
+	// ByName pairs floating IPs whose names only differ in their IP family suffix,
+	// e.g. "web-v4" and "web-v6", or "web-ipv4" and "web-ipv6".
+	ByName bool `koanf:"by_name"`
+}
@@ -1,6 +1,8 @@
 package cfgmodel
 
 import (
+	"time"
+
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 )
 
@@ -18,6 +20,9 @@ type HetznerProviderConfig struct {
 
 	FloatingIPs HetznerSelector `koanf:"floating_ips"`
 	Servers     HetznerSelector `koanf:"servers"`
+
+	// WatchActions configures watching the actions of the project between polls.
+	WatchActions HetznerWatchActionsConfig `koanf:"watch_actions"`
 }
 
 // Validate validates the Hetzner config.
@@ -27,6 +32,33 @@ func (c HetznerProviderConfig) Validate() error {
 		validation.Field(&c.ProjectID, validation.Required),
 		validation.Field(&c.FloatingIPs, validation.Required),
 		validation.Field(&c.Servers, validation.Required),
+		validation.Field(&c.WatchActions),
+	)
+}
+
+// HetznerWatchActionsConfig configures watching the actions of the floating IPs and servers of the project, e.g.
+// assigning a floating IP or creating a server. When a new action shows up, the resources are polled right away
+// instead of at the next poll interval.
+type HetznerWatchActionsConfig struct {
+	// Enabled enables watching the actions.
+	Enabled bool `koanf:"enabled"`
+
+	// Interval is the interval at which the most recent actions are fetched. Defaults to 5 seconds.
+	Interval time.Duration `koanf:"interval"`
+}
+
+// IntervalOrDefault returns the interval or the default if not set.
+func (c HetznerWatchActionsConfig) IntervalOrDefault() time.Duration {
+	if c.Interval == 0 {
+		return 5 * time.Second
+	}
+	return c.Interval
+}
+
+// Validate validates the watch actions config.
+func (c HetznerWatchActionsConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Interval, validation.Min(time.Duration(0))),
 	)
 }
 
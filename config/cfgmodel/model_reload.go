@@ -0,0 +1,32 @@
+package cfgmodel
+
+import (
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// ReloadConfig configures reloading the configuration while flipper runs. The configuration is always reloaded
+// when flipper receives a SIGHUP signal.
+type ReloadConfig struct {
+	// WatchFile enables reloading the configuration when the config file changes.
+	WatchFile bool `koanf:"watch_file"`
+
+	// WatchInterval is the interval at which the config file is checked for changes. Defaults to 10 seconds.
+	WatchInterval time.Duration `koanf:"watch_interval"`
+}
+
+// WatchIntervalOrDefault returns the watch interval or the default if not set.
+func (c ReloadConfig) WatchIntervalOrDefault() time.Duration {
+	if c.WatchInterval == 0 {
+		return 10 * time.Second
+	}
+	return c.WatchInterval
+}
+
+// Validate validates the reload config.
+func (c ReloadConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.WatchInterval, validation.Min(time.Duration(0))),
+	)
+}
@@ -0,0 +1,52 @@
+package cfgmodel
+
+import (
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// SafetyConfig configures limits that protect against moving too many floating IPs at once, for example when a
+// network partition makes it look like most servers are unhealthy. Plans that exceed the limits are blocked until
+// a human overrides the limits.
+type SafetyConfig struct {
+	// MaxUnhealthyFraction is the maximum fraction (between 0 and 1) of servers that may be unhealthy for a plan to
+	// be executed. If more servers are unhealthy, it is more likely that flipper itself is the problem.
+	// Zero disables this limit.
+	MaxUnhealthyFraction float64 `koanf:"max_unhealthy_fraction"`
+
+	// MaxMoves is the maximum number of floating IP reassignments within the window. Zero disables this limit.
+	MaxMoves int `koanf:"max_moves"`
+
+	// Window is the time window for MaxMoves. Defaults to 10 minutes.
+	Window time.Duration `koanf:"window"`
+
+	// OverrideDuration is how long a human override of the limits lasts. Defaults to 15 minutes.
+	OverrideDuration time.Duration `koanf:"override_duration"`
+}
+
+// WindowOrDefault returns the window or the default if not set.
+func (c SafetyConfig) WindowOrDefault() time.Duration {
+	if c.Window == 0 {
+		return 10 * time.Minute
+	}
+	return c.Window
+}
+
+// OverrideDurationOrDefault returns the override duration or the default if not set.
+func (c SafetyConfig) OverrideDurationOrDefault() time.Duration {
+	if c.OverrideDuration == 0 {
+		return 15 * time.Minute
+	}
+	return c.OverrideDuration
+}
+
+// Validate validates the safety config.
+func (c SafetyConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.MaxUnhealthyFraction, validation.Min(0.0), validation.Max(1.0)),
+		validation.Field(&c.MaxMoves, validation.Min(0)),
+		validation.Field(&c.Window, validation.Min(time.Duration(0))),
+		validation.Field(&c.OverrideDuration, validation.Min(time.Duration(0))),
+	)
+}
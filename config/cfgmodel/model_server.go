@@ -18,6 +18,10 @@ type ServerConfig struct {
 	// Timeout for graceful shutdown.
 	ShutdownTimeout time.Duration `koanf:"shutdown_timeout"`
 
+	// AdminToken is the bearer token required for the admin endpoints (e.g. resetting a flapping server).
+	// If empty, the admin endpoints do not require authentication, which is only safe when binding to localhost.
+	AdminToken string `koanf:"admin_token"`
+
 	// Assets is the path to serve assets from. If empty, the server will use the embedded files.
 	// Generally you will only need to specify this during development for quick iteration.
 	Assets string `koanf:"assets"`
@@ -0,0 +1,70 @@
+package cfgmodel
+
+import (
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// StoreTypeFile stores the state of every group as a JSON file in a directory.
+const StoreTypeFile = "file"
+
+// StoreConfig configures where flipper keeps its state, so that it can continue where it left off after a restart.
+type StoreConfig struct {
+	// Type is the type of store, currently only "file" is supported. If empty, no state is kept across restarts.
+	Type string `koanf:"type"`
+
+	// Path is the directory the file store writes to.
+	Path string `koanf:"path"`
+
+	// MaxStaleness is how old the stored state may be to be used after a restart. Older state is ignored, as if
+	// flipper started for the first time. Defaults to 5 minutes.
+	MaxStaleness time.Duration `koanf:"max_staleness"`
+
+	// CheckpointInterval is the interval at which the state is written while nothing changes, so that it does not
+	// go stale. Changes are written right away. Defaults to 30 seconds.
+	CheckpointInterval time.Duration `koanf:"checkpoint_interval"`
+
+	// MaxPlans is the number of executed plans that are kept per group. Defaults to 100.
+	MaxPlans int `koanf:"max_plans"`
+}
+
+// Enabled returns true if state is kept across restarts.
+func (c StoreConfig) Enabled() bool {
+	return c.Type != ""
+}
+
+// MaxStalenessOrDefault returns the max staleness or the default if not set.
+func (c StoreConfig) MaxStalenessOrDefault() time.Duration {
+	if c.MaxStaleness == 0 {
+		return 5 * time.Minute
+	}
+	return c.MaxStaleness
+}
+
+// CheckpointIntervalOrDefault returns the checkpoint interval or the default if not set.
+func (c StoreConfig) CheckpointIntervalOrDefault() time.Duration {
+	if c.CheckpointInterval == 0 {
+		return 30 * time.Second
+	}
+	return c.CheckpointInterval
+}
+
+// MaxPlansOrDefault returns the max number of plans or the default if not set.
+func (c StoreConfig) MaxPlansOrDefault() int {
+	if c.MaxPlans == 0 {
+		return 100
+	}
+	return c.MaxPlans
+}
+
+// Validate validates the store config.
+func (c StoreConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Type, validation.In(StoreTypeFile)),
+		validation.Field(&c.Path, validation.When(c.Type == StoreTypeFile, validation.Required)),
+		validation.Field(&c.MaxStaleness, validation.Min(time.Duration(0))),
+		validation.Field(&c.CheckpointInterval, validation.Min(time.Duration(0))),
+		validation.Field(&c.MaxPlans, validation.Min(0)),
+	)
+}
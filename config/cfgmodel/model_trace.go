@@ -0,0 +1,23 @@
+package cfgmodel
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// TraceConfig configures the recording of a trace: every poll of the resources and every health check result, so
+// that the decisions made from them can be replayed with `flipper replay`.
+type TraceConfig struct {
+	// Enabled is a flag that enables recording.
+	Enabled bool `koanf:"enabled"`
+
+	// Path is the file the trace is appended to, as JSON lines. It is not rotated, it is meant to be enabled while
+	// investigating.
+	Path string `koanf:"path"`
+}
+
+// Validate validates the trace config.
+func (c TraceConfig) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Path, validation.When(c.Enabled, validation.Required)),
+	)
+}
@@ -3,6 +3,8 @@ package cfgmodel
 import (
 	"errors"
 	"time"
+
+	"github.com/gzuidhof/flipper/maintenance"
 )
 
 func checkDuration(value interface{}) error {
@@ -21,3 +23,35 @@ func checkDuration(value interface{}) error {
 	}
 	return nil
 }
+
+func checkSchedule(value interface{}) error {
+	s, ok := value.(string)
+	if !ok {
+		return errors.New("must be a string")
+	}
+
+	if s == "" {
+		return nil
+	}
+
+	if _, err := maintenance.ParseSchedule(s); err != nil {
+		return errors.New("invalid schedule: " + err.Error())
+	}
+	return nil
+}
+
+func checkLabelSelector(value interface{}) error {
+	s, ok := value.(string)
+	if !ok {
+		return errors.New("must be a string")
+	}
+
+	if s == "" {
+		return nil
+	}
+
+	if _, err := maintenance.ParseSelector(s); err != nil {
+		return errors.New("invalid label selector: " + err.Error())
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package cfgmodel
 import (
 	"errors"
 	"testing"
+	"time"
 )
 
 func TestCheckDuration(t *testing.T) {
@@ -50,3 +51,104 @@ func TestCheckDuration(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateDependencies(t *testing.T) {
+	group := func(id string, dependsOn ...string) GroupConfig {
+		g := GroupConfig{ID: id}
+		for _, dependency := range dependsOn {
+			g.DependsOn = append(g.DependsOn, DependencyConfig{Group: dependency})
+		}
+		return g
+	}
+
+	tests := []struct {
+		name     string
+		groups   []GroupConfig
+		expected string
+	}{
+		{
+			name:   "valid",
+			groups: []GroupConfig{group("frontend", "api"), group("api", "db"), group("db"), group("other", "db")},
+		},
+		{
+			name:     "unknown",
+			groups:   []GroupConfig{group("frontend", "api")},
+			expected: "group frontend depends on unknown group api",
+		},
+		{
+			name:     "self",
+			groups:   []GroupConfig{group("frontend", "frontend")},
+			expected: "dependency cycle: frontend -> frontend",
+		},
+		{
+			name:     "cycle",
+			groups:   []GroupConfig{group("frontend", "api"), group("api", "db"), group("db", "api")},
+			expected: "dependency cycle: api -> db -> api",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateDependencies(test.groups)
+			if test.expected == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+			} else if err == nil || err.Error() != test.expected {
+				t.Errorf("Expected error: %v, got: %v", test.expected, err)
+			}
+		})
+	}
+}
+
+func TestLeaderElectionConfigValidate(t *testing.T) {
+	valid := LeaderElectionConfig{
+		Enabled:  true,
+		Backend:  LeaseBackendFile,
+		Identity: "flipper-1",
+		File:     LeaseFileConfig{Path: "/var/lib/flipper/lease.json"},
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(c *LeaderElectionConfig)
+		invalid bool
+	}{
+		{
+			name:   "valid",
+			modify: func(*LeaderElectionConfig) {},
+		},
+		{
+			name:   "disabled",
+			modify: func(c *LeaderElectionConfig) { *c = LeaderElectionConfig{} },
+		},
+		{
+			name:    "renew_not_shorter_than_lease",
+			modify:  func(c *LeaderElectionConfig) { c.RenewInterval = 15 * time.Second },
+			invalid: true,
+		},
+		{
+			name:    "invalid_identity",
+			modify:  func(c *LeaderElectionConfig) { c.Identity = "flipper 1" },
+			invalid: true,
+		},
+		{
+			name:    "hetzner_without_floating_ip",
+			modify:  func(c *LeaderElectionConfig) { c.Backend = LeaseBackendHetzner },
+			invalid: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := valid
+			test.modify(&cfg)
+			err := cfg.Validate()
+			if test.invalid && err == nil {
+				t.Errorf("Expected an error, got none")
+			} else if !test.invalid && err != nil {
+				t.Errorf("Expected no error, got: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+)
+
+// ReloadFunc is called with the reloaded configuration, or with the error if it could not be loaded. An invalid
+// configuration is never passed, so the current configuration should be kept when there is an error.
+type ReloadFunc func(ctx context.Context, cfg *cfgmodel.Config, err error)
+
+// Reloader reloads the configuration when flipper receives a SIGHUP signal, and optionally when the config file
+// changes. The file is polled for changes of its contents, which also works for files that are replaced rather
+// than written to, like mounted Kubernetes config maps.
+type Reloader struct {
+	configFilepath string
+	cfg            cfgmodel.ReloadConfig
+	logger         *slog.Logger
+
+	// lastSum is the checksum of the contents of the file as it was last loaded.
+	lastSum []byte
+}
+
+// NewReloader creates a new reloader for the configuration in the given file.
+func NewReloader(configFilepath string, cfg cfgmodel.ReloadConfig, logger *slog.Logger) *Reloader {
+	r := &Reloader{
+		configFilepath: configFilepath,
+		cfg:            cfg,
+		logger:         logger,
+	}
+	r.lastSum, _ = r.checksum()
+	return r
+}
+
+// Start reloading the configuration, calling onReload for every reload.
+// This function blocks until the context is cancelled.
+func (r *Reloader) Start(ctx context.Context, onReload ReloadFunc) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	// Without watching, the ticker channel stays nil and never fires.
+	var watch <-chan time.Time
+	if r.cfg.WatchFile && r.configFilepath != "" {
+		ticker := time.NewTicker(r.cfg.WatchIntervalOrDefault())
+		defer ticker.Stop()
+		watch = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			r.logger.InfoContext(ctx, "Received SIGHUP, reloading configuration.")
+			r.reload(ctx, onReload)
+		case <-watch:
+			sum, err := r.checksum()
+			if err != nil {
+				// The file may be in the middle of being replaced, we try again on the next tick.
+				r.logger.WarnContext(ctx, "Failed to read config file.", slog.String("error", err.Error()))
+				continue
+			}
+			if bytes.Equal(sum, r.lastSum) {
+				continue
+			}
+			r.logger.InfoContext(ctx, "Config file changed, reloading configuration.")
+			r.reload(ctx, onReload)
+		}
+	}
+}
+
+// reload loads the configuration and passes it to onReload.
+func (r *Reloader) reload(ctx context.Context, onReload ReloadFunc) {
+	// The checksum is taken before loading, so that a change while loading is picked up on the next tick.
+	sum, sumErr := r.checksum()
+	cfg, err := Init(r.configFilepath)
+	if sumErr == nil {
+		// An invalid file is not loaded again until it changes.
+		r.lastSum = sum
+	}
+	onReload(ctx, cfg, err)
+}
+
+// checksum returns the checksum of the contents of the config file.
+func (r *Reloader) checksum() ([]byte, error) {
+	if r.configFilepath == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(r.configFilepath)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // Only logged.
+	}
+	sum := sha256.Sum256(data)
+	return sum[:], nil
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloader(t *testing.T) {
+	t.Parallel()
+
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, file, "server:\n  shutdown_timeout: 10s\n")
+
+	type reload struct {
+		cfg *cfgmodel.Config
+		err error
+	}
+	reloads := make(chan reload, 4)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	r := NewReloader(file, cfgmodel.ReloadConfig{WatchFile: true, WatchInterval: 10 * time.Millisecond},
+		slog.New(slog.NewTextHandler(os.Stderr, nil)))
+	go r.Start(ctx, func(_ context.Context, cfg *cfgmodel.Config, err error) {
+		reloads <- reload{cfg: cfg, err: err}
+	})
+
+	select {
+	case <-reloads:
+		t.Fatal("reloaded without a change")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	writeConfig(t, file, "server:\n  shutdown_timeout: 20s\n")
+	select {
+	case got := <-reloads:
+		require.NoError(t, got.err)
+		assert.Equal(t, 20*time.Second, got.cfg.Server.ShutdownTimeout)
+	case <-time.After(time.Second):
+		t.Fatal("not reloaded after the file changed")
+	}
+
+	writeConfig(t, file, "server:\n  shutdown_timeout: invalid\n")
+	select {
+	case got := <-reloads:
+		assert.Error(t, got.err)
+		assert.Nil(t, got.cfg)
+	case <-time.After(time.Second):
+		t.Fatal("not reloaded after the file changed")
+	}
+
+	// The invalid file is not loaded again until it changes.
+	select {
+	case <-reloads:
+		t.Fatal("reloaded without a change")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// writeConfig replaces the config file at once, so that it is never read while half written.
+func writeConfig(t *testing.T, file, content string) {
+	t.Helper()
+
+	tmp := file + ".tmp"
+	require.NoError(t, os.WriteFile(tmp, []byte(content), 0o600))
+	require.NoError(t, os.Rename(tmp, file))
+}
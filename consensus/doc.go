@@ -0,0 +1,7 @@
+// Package consensus combines the health check results of several flipper nodes that check the same servers from
+// different vantage points.
+//
+// A single vantage point can not tell a server that is down from a broken network path of its own. Every node
+// shares its view (the status of every server it checks) with its peers, and a server only counts as unhealthy
+// when a quorum of the nodes agrees.
+package consensus
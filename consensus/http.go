@@ -0,0 +1,64 @@
+package consensus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+)
+
+// ViewPath is the path of the endpoint of the built-in server that returns the view of the node.
+const ViewPath = "/v1/consensus/view"
+
+// maxViewSize is the maximum size of a view response.
+const maxViewSize = 1 << 20
+
+// Fetcher fetches the view of a peer.
+type Fetcher interface {
+	Fetch(ctx context.Context) (View, error)
+}
+
+// HTTPFetcher fetches the view of a peer from its built-in server.
+type HTTPFetcher struct {
+	cfg    cfgmodel.ConsensusPeerConfig
+	client *http.Client
+}
+
+// NewHTTPFetcher creates a fetcher for the given peer.
+func NewHTTPFetcher(cfg cfgmodel.ConsensusPeerConfig, client *http.Client) *HTTPFetcher {
+	return &HTTPFetcher{cfg: cfg, client: client}
+}
+
+// Fetch fetches the view of the peer.
+func (f *HTTPFetcher) Fetch(ctx context.Context) (View, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(f.cfg.URL, "/")+ViewPath, nil)
+	if err != nil {
+		return View{}, fmt.Errorf("failed to create request: %w", err)
+	}
+	if f.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+f.cfg.Token)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return View{}, fmt.Errorf("failed to fetch view: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return View{}, fmt.Errorf("failed to fetch view: unexpected status %d", resp.StatusCode)
+	}
+
+	var view View
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxViewSize)).Decode(&view); err != nil {
+		return View{}, fmt.Errorf("failed to decode view: %w", err)
+	}
+	if view.NodeID != f.cfg.ID {
+		return View{}, fmt.Errorf("peer %s responded with the view of node %s", f.cfg.ID, view.NodeID)
+	}
+	return view, nil
+}
@@ -0,0 +1,299 @@
+package consensus
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+	"github.com/gzuidhof/flipper/resource"
+)
+
+// Peer is another node.
+type Peer struct {
+	ID      string
+	Fetcher Fetcher
+}
+
+// peerView is the last view that was fetched from a peer.
+type peerView struct {
+	view View
+	// receivedAt is when the view was fetched, the clocks of the nodes may differ so this is used for its age.
+	receivedAt time.Time
+}
+
+// Node shares the view of this node with its peers, and combines their views into verdicts.
+type Node struct {
+	cfg    cfgmodel.ConsensusConfig
+	id     string
+	logger *slog.Logger
+	peers  []Peer
+
+	// onChange is called from the node loop when the view of a peer changed.
+	onChange func(ctx context.Context)
+
+	mu sync.Mutex
+	// local is the view of this node, by group ID, then by server ID.
+	local map[string]map[string]resource.State
+	// peerViews are the last views of the peers, by node ID.
+	peerViews map[string]peerView
+	// agreed are the last statuses the nodes agreed on with a quorum, by group ID, then by server and IP family.
+	agreed map[string]map[agreedKey]resource.Status
+}
+
+// agreedKey identifies what the nodes agreed on: the status of a server, or with a family, its status over that
+// IP family.
+type agreedKey struct {
+	serverID string
+	family   resource.IPFamily
+}
+
+// NewNode creates a node that fetches the views of the configured peers over HTTP.
+func NewNode(cfg cfgmodel.ConsensusConfig, logger *slog.Logger) *Node {
+	client := &http.Client{Timeout: cfg.PollTimeoutOrDefault()}
+	peers := make([]Peer, 0, len(cfg.Peers))
+	for _, peer := range cfg.Peers {
+		peers = append(peers, Peer{ID: peer.ID, Fetcher: NewHTTPFetcher(peer, client)})
+	}
+	return NewNodeWithPeers(cfg, peers, logger)
+}
+
+// NewNodeWithPeers creates a node with the given peers, e.g. other nodes in the same process for testing.
+func NewNodeWithPeers(cfg cfgmodel.ConsensusConfig, peers []Peer, logger *slog.Logger) *Node {
+	id := cfg.NodeIDOrDefault()
+	return &Node{
+		cfg:       cfg,
+		id:        id,
+		logger:    logger.With(slog.String("node_id", id)),
+		peers:     peers,
+		onChange:  func(context.Context) {},
+		local:     make(map[string]map[string]resource.State),
+		peerViews: make(map[string]peerView),
+		agreed:    make(map[string]map[agreedKey]resource.Status),
+	}
+}
+
+// OnChange sets the function that is called when the view of a peer changed.
+// It must be called before the node is started.
+func (n *Node) OnChange(onChange func(ctx context.Context)) {
+	n.onChange = onChange
+}
+
+// ID returns the node ID of this node.
+func (n *Node) ID() string {
+	return n.id
+}
+
+// SetLocal sets the state of a server as seen by this node. Its status and the statuses of its IP families are
+// shared. It is safe to call from any goroutine.
+func (n *Node) SetLocal(groupID, serverID string, state resource.State) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.local[groupID] == nil {
+		n.local[groupID] = make(map[string]resource.State)
+	}
+	n.local[groupID][serverID] = state
+}
+
+// RemoveLocal removes a server from the view of this node.
+// It is safe to call from any goroutine.
+func (n *Node) RemoveLocal(groupID, serverID string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	delete(n.local[groupID], serverID)
+	for key := range n.agreed[groupID] {
+		if key.serverID == serverID {
+			delete(n.agreed[groupID], key)
+		}
+	}
+}
+
+// RemoveGroup removes all servers of a group from the view of this node, e.g. when the group is no longer
+// monitored. It is safe to call from any goroutine.
+func (n *Node) RemoveGroup(groupID string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	delete(n.local, groupID)
+	delete(n.agreed, groupID)
+}
+
+// View returns the view of this node.
+// It is safe to call from any goroutine.
+func (n *Node) View() View {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	view := View{
+		NodeID:   n.id,
+		At:       time.Now(),
+		Groups:   make(map[string]map[string]resource.Status, len(n.local)),
+		Families: make(map[string]map[string]map[resource.IPFamily]resource.Status),
+	}
+	for groupID, servers := range n.local {
+		view.Groups[groupID] = make(map[string]resource.Status, len(servers))
+		for serverID, state := range servers {
+			view.Groups[groupID][serverID] = state.Status
+			if families := familyStatuses(state); len(families) > 0 {
+				if view.Families[groupID] == nil {
+					view.Families[groupID] = make(map[string]map[resource.IPFamily]resource.Status)
+				}
+				view.Families[groupID][serverID] = families
+			}
+		}
+	}
+	return view
+}
+
+// familyStatuses returns the statuses of the IP families that are checked separately.
+func familyStatuses(state resource.State) map[resource.IPFamily]resource.Status {
+	families := make(map[resource.IPFamily]resource.Status)
+	if state.IPv4 != "" {
+		families[resource.IPFamilyIPv4] = state.IPv4
+	}
+	if state.IPv6 != "" {
+		families[resource.IPFamilyIPv6] = state.IPv6
+	}
+	return families
+}
+
+// Verdict returns the status of a server the nodes agree on at the given time, given its status according to
+// this node. Views of peers that are older than the max age are left out. Without a quorum, the last status the
+// nodes agreed on is kept, so that a node that lost its peers does not act on its own view.
+// It is safe to call from any goroutine.
+func (n *Node) Verdict(groupID, serverID string, local resource.Status, now time.Time) Verdict {
+	return n.verdict(groupID, agreedKey{serverID: serverID}, local, now, func(view View) resource.Status {
+		return view.Status(groupID, serverID)
+	})
+}
+
+// FamilyVerdict returns the status of a server over an IP family the nodes agree on, like Verdict. Peers that do
+// not check the family separately vote with the status of the server.
+// It is safe to call from any goroutine.
+func (n *Node) FamilyVerdict(
+	groupID, serverID string,
+	family resource.IPFamily,
+	local resource.Status,
+	now time.Time,
+) Verdict {
+	key := agreedKey{serverID: serverID, family: family}
+	return n.verdict(groupID, key, local, now, func(view View) resource.Status {
+		return view.FamilyStatus(groupID, serverID, family)
+	})
+}
+
+// verdict combines the vote of this node with the votes of the peers, as read from their views by status.
+func (n *Node) verdict(
+	groupID string,
+	key agreedKey,
+	local resource.Status,
+	now time.Time,
+	status func(view View) resource.Status,
+) Verdict {
+	votes := []Vote{{NodeID: n.id, Status: local, Self: true}}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for _, peer := range n.peers {
+		pv, ok := n.peerViews[peer.ID]
+		if !ok || now.Sub(pv.receivedAt) > n.cfg.MaxAgeOrDefault() {
+			continue
+		}
+		votes = append(votes, Vote{NodeID: peer.ID, Status: status(pv.view)})
+	}
+
+	verdict := Decide(votes, n.cfg.QuorumOrDefault())
+	if verdict.NoQuorum {
+		if agreed, ok := n.agreed[groupID][key]; ok {
+			verdict.Status = agreed
+		}
+		return verdict
+	}
+	if n.agreed[groupID] == nil {
+		n.agreed[groupID] = make(map[agreedKey]resource.Status)
+	}
+	n.agreed[groupID][key] = verdict.Status
+	return verdict
+}
+
+// Start fetches the views of the peers periodically until the context is cancelled.
+// This function blocks until the context is cancelled.
+func (n *Node) Start(ctx context.Context) {
+	ticker := time.NewTicker(n.cfg.PollIntervalOrDefault())
+	defer ticker.Stop()
+
+	for {
+		if n.poll(ctx, time.Now()) {
+			n.onChange(ctx)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll fetches the views of all peers. It returns true if any of them changed, or expired.
+func (n *Node) poll(ctx context.Context, now time.Time) bool {
+	type result struct {
+		peerID string
+		view   View
+		err    error
+	}
+	results := make(chan result, len(n.peers))
+	for _, peer := range n.peers {
+		peer := peer
+		go func() {
+			view, err := peer.Fetcher.Fetch(ctx)
+			results <- result{peerID: peer.ID, view: view, err: err}
+		}()
+	}
+
+	changed := false
+	for range n.peers {
+		r := <-results
+		n.mu.Lock()
+		previous, hadPrevious := n.peerViews[r.peerID]
+		fresh := hadPrevious && now.Sub(previous.receivedAt) <= n.cfg.MaxAgeOrDefault()
+		if r.err != nil {
+			n.logger.WarnContext(ctx, "Failed to fetch view of peer.",
+				slog.String("peer_id", r.peerID),
+				slog.String("error", r.err.Error()),
+			)
+			// The last view is used until it is too old, after that the peer no longer counts.
+			if hadPrevious && !fresh && !previous.receivedAt.IsZero() {
+				previous.receivedAt = time.Time{}
+				n.peerViews[r.peerID] = previous
+				changed = true
+			}
+		} else {
+			n.peerViews[r.peerID] = peerView{view: r.view, receivedAt: now}
+			if !fresh || !sameStatuses(previous.view, r.view) {
+				changed = true
+			}
+		}
+		n.mu.Unlock()
+	}
+	return changed
+}
+
+// sameStatuses returns true if the views have the same statuses for all servers and their IP families.
+func sameStatuses(a, b View) bool {
+	sameServers := func(x, y map[string]resource.Status) bool {
+		return maps.Equal(x, y)
+	}
+	sameFamilies := func(x, y map[string]map[resource.IPFamily]resource.Status) bool {
+		return maps.EqualFunc(x, y, func(f, g map[resource.IPFamily]resource.Status) bool {
+			return maps.Equal(f, g)
+		})
+	}
+	return maps.EqualFunc(a.Groups, b.Groups, sameServers) && maps.EqualFunc(a.Families, b.Families, sameFamilies)
+}
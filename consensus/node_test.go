@@ -0,0 +1,209 @@
+package consensus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+	"github.com/gzuidhof/flipper/resource"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// nodeFetcher fetches the view of a node in the same process.
+type nodeFetcher struct {
+	node *Node
+	err  error
+}
+
+func (f *nodeFetcher) Fetch(context.Context) (View, error) {
+	if f.err != nil {
+		return View{}, f.err
+	}
+	return f.node.View(), nil
+}
+
+func TestDecide(t *testing.T) {
+	t.Parallel()
+
+	votes := func(statuses ...resource.Status) []Vote {
+		v := make([]Vote, 0, len(statuses))
+		for i, status := range statuses {
+			v = append(v, Vote{Status: status, Self: i == 0})
+		}
+		return v
+	}
+
+	for _, tc := range []struct {
+		name   string
+		votes  []Vote
+		quorum int
+
+		expected resource.Status
+	}{
+		{
+			name:     "quorum_reached",
+			votes:    votes(resource.StatusUnhealthy, resource.StatusUnhealthy, resource.StatusHealthy),
+			quorum:   2,
+			expected: resource.StatusUnhealthy,
+		},
+		{
+			name:     "only_this_node",
+			votes:    votes(resource.StatusUnhealthy, resource.StatusHealthy, resource.StatusHealthy),
+			quorum:   2,
+			expected: resource.StatusHealthy,
+		},
+		{
+			name:     "peers_agree_without_this_node",
+			votes:    votes(resource.StatusHealthy, resource.StatusUnhealthy, resource.StatusUnhealthy),
+			quorum:   2,
+			expected: resource.StatusUnhealthy,
+		},
+		{
+			name:     "fewer_known_than_quorum",
+			votes:    votes(resource.StatusUnhealthy, resource.StatusUnknown, resource.StatusUnknown),
+			quorum:   2,
+			expected: resource.StatusUnknown,
+		},
+		{
+			name:     "quorum_healthy_with_unknown",
+			votes:    votes(resource.StatusUnhealthy, resource.StatusHealthy, resource.StatusUnknown),
+			quorum:   2,
+			expected: resource.StatusHealthy,
+		},
+		{
+			name:     "all_unknown",
+			votes:    votes(resource.StatusUnknown, resource.StatusUnknown),
+			quorum:   2,
+			expected: resource.StatusUnknown,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tc.expected, Decide(tc.votes, tc.quorum).Status)
+		})
+	}
+}
+
+func TestNodes(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cfg := func(id string) cfgmodel.ConsensusConfig {
+		// Three nodes, so the default quorum is two.
+		return cfgmodel.ConsensusConfig{NodeID: id, Peers: make([]cfgmodel.ConsensusPeerConfig, 2)}
+	}
+	a := NewNodeWithPeers(cfg("a"), nil, slog.Default())
+	b := NewNodeWithPeers(cfg("b"), nil, slog.Default())
+	c := NewNodeWithPeers(cfg("c"), nil, slog.Default())
+	cFetcher := &nodeFetcher{node: c}
+	a.peers = []Peer{{ID: "b", Fetcher: &nodeFetcher{node: b}}, {ID: "c", Fetcher: cFetcher}}
+
+	// Only a sees the server as unhealthy, e.g. because its own network path is broken.
+	a.SetLocal("lb", "1", resource.State{Status: resource.StatusUnhealthy})
+	b.SetLocal("lb", "1", resource.State{Status: resource.StatusHealthy})
+	c.SetLocal("lb", "1", resource.State{Status: resource.StatusHealthy})
+
+	now := time.Now()
+	assert.True(t, a.poll(ctx, now))
+	verdict := a.Verdict("lb", "1", resource.StatusUnhealthy, now)
+	assert.Equal(t, resource.StatusHealthy, verdict.Status)
+	assert.True(t, verdict.Overrides())
+	assert.Len(t, verdict.Votes, 3)
+	assert.False(t, a.poll(ctx, now), "nothing changed")
+
+	// b agrees, which makes a quorum.
+	b.SetLocal("lb", "1", resource.State{Status: resource.StatusUnhealthy})
+	assert.True(t, a.poll(ctx, now))
+	assert.Equal(t, resource.StatusUnhealthy, a.Verdict("lb", "1", resource.StatusUnhealthy, now).Status)
+
+	// c can no longer be reached, its view is used until it is too old.
+	cFetcher.err = errors.New("unreachable")
+	assert.False(t, a.poll(ctx, now.Add(10*time.Second)))
+	later := now.Add(time.Minute)
+	assert.True(t, a.poll(ctx, later))
+	assert.Len(t, a.Verdict("lb", "1", resource.StatusUnhealthy, later).Votes, 2)
+}
+
+func TestNodeWithoutQuorum(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cfg := cfgmodel.ConsensusConfig{NodeID: "a", Peers: make([]cfgmodel.ConsensusPeerConfig, 2)}
+	b := NewNodeWithPeers(cfgmodel.ConsensusConfig{NodeID: "b"}, nil, slog.Default())
+	bFetcher := &nodeFetcher{node: b}
+	a := NewNodeWithPeers(cfg, []Peer{
+		{ID: "b", Fetcher: bFetcher},
+		{ID: "c", Fetcher: &nodeFetcher{err: errors.New("unreachable")}},
+	}, slog.Default())
+
+	// Without any recent peer view there is no quorum, and the nodes never agreed.
+	now := time.Now()
+	verdict := a.Verdict("lb", "1", resource.StatusUnhealthy, now)
+	assert.True(t, verdict.NoQuorum)
+	assert.Equal(t, resource.StatusUnknown, verdict.Status)
+
+	b.SetLocal("lb", "1", resource.State{Status: resource.StatusHealthy})
+	assert.True(t, a.poll(ctx, now))
+	verdict = a.Verdict("lb", "1", resource.StatusHealthy, now)
+	assert.False(t, verdict.NoQuorum)
+	assert.Equal(t, resource.StatusHealthy, verdict.Status)
+
+	// b can no longer be reached either. This node alone can't make the server unhealthy, the agreed status is kept.
+	bFetcher.err = errors.New("unreachable")
+	later := now.Add(time.Minute)
+	a.poll(ctx, later)
+	verdict = a.Verdict("lb", "1", resource.StatusUnhealthy, later)
+	assert.True(t, verdict.NoQuorum)
+	assert.Equal(t, resource.StatusHealthy, verdict.Status)
+	assert.True(t, verdict.Overrides())
+}
+
+func TestHTTPFetcher(t *testing.T) {
+	t.Parallel()
+
+	node := NewNodeWithPeers(cfgmodel.ConsensusConfig{NodeID: "b"}, nil, slog.Default())
+	node.SetLocal("lb", "1", resource.State{Status: resource.StatusHealthy})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != ViewPath || r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(node.View())
+	}))
+	defer server.Close()
+
+	view, err := NewHTTPFetcher(
+		cfgmodel.ConsensusPeerConfig{ID: "b", URL: server.URL + "/", Token: "secret"}, server.Client(),
+	).Fetch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, resource.StatusHealthy, view.Status("lb", "1"))
+
+	_, err = NewHTTPFetcher(
+		cfgmodel.ConsensusPeerConfig{ID: "c", URL: server.URL, Token: "secret"}, server.Client(),
+	).Fetch(context.Background())
+	require.Error(t, err, "the view of another node is rejected")
+}
+
+func TestNodeViewFamilies(t *testing.T) {
+	t.Parallel()
+
+	node := NewNodeWithPeers(cfgmodel.ConsensusConfig{NodeID: "a"}, nil, slog.Default())
+	node.SetLocal("lb", "1", resource.State{
+		Status: resource.StatusUnhealthy, IPv4: resource.StatusHealthy, IPv6: resource.StatusUnhealthy,
+	})
+	node.SetLocal("lb", "2", resource.State{Status: resource.StatusHealthy})
+
+	view := node.View()
+	assert.Equal(t, resource.StatusHealthy, view.FamilyStatus("lb", "1", resource.IPFamilyIPv4))
+	assert.Equal(t, resource.StatusUnhealthy, view.FamilyStatus("lb", "1", resource.IPFamilyIPv6))
+	assert.Equal(t, resource.StatusHealthy, view.FamilyStatus("lb", "2", resource.IPFamilyIPv6),
+		"without separate checks the status of the server applies")
+	assert.NotContains(t, view.Families["lb"], "2")
+}
@@ -0,0 +1,113 @@
+package consensus
+
+import (
+	"time"
+
+	"github.com/gzuidhof/flipper/resource"
+)
+
+// View is what a node sees: the status of every server it checks.
+type View struct {
+	NodeID string `json:"node_id"`
+
+	// At is the time the view was taken.
+	At time.Time `json:"at"`
+
+	// Groups are the statuses of the servers by group ID, then by server ID.
+	Groups map[string]map[string]resource.Status `json:"groups"`
+
+	// Families are the statuses of the servers per IP family, by group ID, then by server ID, for the servers that
+	// are checked over IPv4 and IPv6 separately.
+	Families map[string]map[string]map[resource.IPFamily]resource.Status `json:"families,omitempty"`
+}
+
+// Status returns the status of a server in the view, unknown if the node does not check it.
+func (v View) Status(groupID, serverID string) resource.Status {
+	status, ok := v.Groups[groupID][serverID]
+	if !ok {
+		return resource.StatusUnknown
+	}
+	return status
+}
+
+// FamilyStatus returns the status of a server over an IP family in the view. It falls back to the status of the
+// server if the node does not check the family separately, e.g. because it runs an older version.
+func (v View) FamilyStatus(groupID, serverID string, family resource.IPFamily) resource.Status {
+	if status, ok := v.Families[groupID][serverID][family]; ok {
+		return status
+	}
+	return v.Status(groupID, serverID)
+}
+
+// Vote is the status of a server according to one node.
+type Vote struct {
+	NodeID string          `json:"node_id"`
+	Status resource.Status `json:"status"`
+	// Self is true for the vote of this node.
+	Self bool `json:"self,omitempty"`
+}
+
+// Verdict is the status of a server the nodes agreed on.
+type Verdict struct {
+	Status resource.Status `json:"status"`
+
+	// Votes are the votes of this node and the peers with a recent view, this node first.
+	Votes []Vote `json:"votes"`
+
+	// Required is the number of unhealthy votes that were required for the server to count as unhealthy.
+	Required int `json:"required"`
+
+	// NoQuorum is true if fewer than the required number of nodes know the status of the server. The nodes can't
+	// agree on a new status then, the status is the last one they agreed on, unknown if they never did.
+	NoQuorum bool `json:"no_quorum,omitempty"`
+}
+
+// Decide returns the verdict for the given votes. A server is unhealthy if at least quorum votes say so, and
+// healthy if at least quorum votes know its status but fewer say it is unhealthy. Otherwise there is no quorum, and
+// its status is unknown.
+func Decide(votes []Vote, quorum int) Verdict {
+	known, unhealthy := 0, 0
+	for _, vote := range votes {
+		switch vote.Status {
+		case resource.StatusHealthy:
+			known++
+		case resource.StatusUnhealthy:
+			known++
+			unhealthy++
+		case resource.StatusUnknown:
+		}
+	}
+
+	verdict := Verdict{Votes: votes, Required: quorum}
+	switch {
+	case known < quorum:
+		verdict.Status = resource.StatusUnknown
+		verdict.NoQuorum = true
+	case unhealthy >= quorum:
+		verdict.Status = resource.StatusUnhealthy
+	default:
+		verdict.Status = resource.StatusHealthy
+	}
+	return verdict
+}
+
+// Overrides returns true if the verdict differs from the vote of this node.
+func (v Verdict) Overrides() bool {
+	for _, vote := range v.Votes {
+		if vote.Self {
+			return vote.Status != v.Status
+		}
+	}
+	return false
+}
+
+// Unhealthy returns the number of votes that say the server is unhealthy.
+func (v Verdict) Unhealthy() int {
+	n := 0
+	for _, vote := range v.Votes {
+		if vote.Status == resource.StatusUnhealthy {
+			n++
+		}
+	}
+	return n
+}
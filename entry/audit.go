@@ -0,0 +1,66 @@
+package entry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/gzuidhof/flipper/audit"
+	"github.com/gzuidhof/flipper/config"
+)
+
+// AuditOptions selects the records that the audit command prints. Empty fields match every record.
+type AuditOptions struct {
+	GroupID    string
+	Server     string
+	FloatingIP string
+	// Since and Until are either RFC 3339 timestamps or durations relative to now, e.g. "24h".
+	Since string
+	Until string
+}
+
+// Audit prints the records of the audit log that match the options as JSON lines.
+func Audit(_ context.Context, configFilepath string, opts AuditOptions, out io.Writer) error {
+	cfg, err := config.Init(configFilepath)
+	if err != nil {
+		return fmt.Errorf("failed to initialize config: %w", err)
+	}
+	if cfg.Audit.Path == "" {
+		return fmt.Errorf("no audit log configured, set audit.path in the configuration")
+	}
+
+	now := time.Now()
+	query := audit.Query{GroupID: opts.GroupID, Server: opts.Server, FloatingIP: opts.FloatingIP}
+	if query.Since, err = parseTime(opts.Since, now); err != nil {
+		return fmt.Errorf("invalid since: %w", err)
+	}
+	if query.Until, err = parseTime(opts.Until, now); err != nil {
+		return fmt.Errorf("invalid until: %w", err)
+	}
+
+	encoder := json.NewEncoder(out)
+	err = audit.Read(cfg.Audit.Path, cfg.Audit.MaxFilesOrDefault(), query, func(record audit.Record) error {
+		return encoder.Encode(record)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return nil
+}
+
+// parseTime parses an RFC 3339 timestamp, or a duration that is subtracted from now. Empty is the zero time.
+func parseTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected an RFC 3339 time or a duration: %w", err)
+	}
+	return t, nil
+}
@@ -20,11 +20,13 @@ import (
 	"golang.org/x/sync/errgroup"
 )
 
-func setupServer(cfg cfgmodel.ServerConfig, logger *slog.Logger) (*server.Server, error) {
+func setupServer(cfg cfgmodel.ServerConfig, logger *slog.Logger, m *monitor.Monitor) (*server.Server, error) {
 	opts := []server.Option{
 		server.WithAddr(net.JoinHostPort(cfg.Host, fmt.Sprint(cfg.Port))),
 		server.WithShutdownTimeout(cfg.ShutdownTimeout),
 		server.WithLogger(logger),
+		server.WithMonitor(m),
+		server.WithAdminToken(cfg.AdminToken),
 	}
 	if cfg.Assets != "" {
 		logger.Info("Using assets from disk.")
@@ -76,8 +78,26 @@ func Monitor(ctx context.Context, configFilepath string) error {
 		return nil
 	})
 
+	reloader := config.NewReloader(configFilepath, cfg.Reload, logger)
+	grp.Go(func() error {
+		reloader.Start(ctx, func(ctx context.Context, newCfg *cfgmodel.Config, reloadErr error) {
+			if reloadErr == nil {
+				_, reloadErr = w.Reload(ctx, newCfg)
+			}
+			if reloadErr != nil {
+				w.ReloadFailed(ctx, reloadErr)
+			}
+		})
+		return nil
+	})
+
+	grp.Go(func() error {
+		w.ResyncOnSignal(ctx)
+		return nil
+	})
+
 	if cfg.Server.Enabled {
-		server, setupErr := setupServer(cfg.Server, logger)
+		server, setupErr := setupServer(cfg.Server, logger, w)
 		if setupErr != nil {
 			return fmt.Errorf("failed to create server: %w", setupErr)
 		}
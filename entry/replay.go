@@ -0,0 +1,35 @@
+package entry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/gzuidhof/flipper/monitor"
+)
+
+// Replay replays a recorded trace and prints the plans that were made as JSON lines. Only warnings and errors are
+// logged, to the logger.
+func Replay(ctx context.Context, tracePath string, out io.Writer, logger *slog.Logger) error {
+	file, err := os.Open(tracePath)
+	if err != nil {
+		return fmt.Errorf("failed to open trace: %w", err)
+	}
+	defer file.Close()
+
+	plans, err := monitor.Replay(ctx, file, logger)
+	if err != nil {
+		return fmt.Errorf("failed to replay trace: %w", err)
+	}
+
+	encoder := json.NewEncoder(out)
+	for _, replayed := range plans {
+		if err := encoder.Encode(replayed); err != nil {
+			return fmt.Errorf("failed to write plan: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package event
+
+import (
+	"context"
+	"sync"
+)
+
+// Handler handles the events it is subscribed to.
+type Handler func(ctx context.Context, e Event)
+
+// Bus delivers the events that are published on it to all of its subscribers. The events are delivered in the
+// order they are published, to every subscriber in the order they subscribed. Publish returns once all of the
+// subscribers handled the event, so subscribers should not block for long.
+// It is safe for concurrent use.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers []Handler
+}
+
+// NewBus creates a new bus without subscribers.
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe makes the handler receive all events that are published from now on.
+func (b *Bus) Subscribe(handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.handlers = append(b.handlers, handler)
+}
+
+// Publish delivers an event to all subscribers.
+func (b *Bus) Publish(ctx context.Context, e Event) {
+	b.mu.RLock()
+	handlers := b.handlers
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(ctx, e)
+	}
+}
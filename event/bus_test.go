@@ -0,0 +1,38 @@
+package event
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBus(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	bus := NewBus()
+	recorder := NewRecorder(2)
+
+	var order []string
+	bus.Subscribe(func(_ context.Context, e Event) {
+		order = append(order, "first:"+string(e.Kind()))
+	})
+	bus.Subscribe(func(_ context.Context, e Event) {
+		order = append(order, "second:"+string(e.Kind()))
+	})
+	bus.Subscribe(recorder.Handle)
+
+	bus.Publish(ctx, MonitorStarted{Meta: Meta{Group: Group{ID: "lb"}}})
+	bus.Publish(ctx, ConfigReloadFailed{Error: "one"})
+	bus.Publish(ctx, ConfigReloadFailed{Error: "two"})
+
+	assert.Equal(t, []string{
+		"first:monitor_started", "second:monitor_started",
+		"first:config_reload_failed", "second:config_reload_failed",
+		"first:config_reload_failed", "second:config_reload_failed",
+	}, order)
+	assert.Equal(t, []Event{ConfigReloadFailed{Error: "one"}, ConfigReloadFailed{Error: "two"}}, recorder.Events(),
+		"the recorder keeps the most recent events",
+	)
+}
@@ -0,0 +1,6 @@
+// Package event defines the events that happen while flipper monitors the groups, and a bus to publish them on.
+//
+// The monitor publishes typed events rather than formatted messages, so that every subscriber (notifications, the
+// audit log, the HTTP API) can use and format them in its own way. The states of the groups in events are not
+// copied: subscribers must only use them while handling the event, which is why they are left out of the JSON.
+package event
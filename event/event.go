@@ -0,0 +1,460 @@
+package event
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gzuidhof/flipper/check"
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+	"github.com/gzuidhof/flipper/consensus"
+	"github.com/gzuidhof/flipper/maintenance"
+	"github.com/gzuidhof/flipper/plan"
+	"github.com/gzuidhof/flipper/resource"
+)
+
+// Kind identifies the type of an event.
+type Kind string
+
+const (
+	// KindMonitorStarted is the kind of MonitorStarted events.
+	KindMonitorStarted Kind = "monitor_started"
+	// KindResourcesChanged is the kind of ResourcesChanged events.
+	KindResourcesChanged Kind = "resources_changed"
+	// KindServerStateChanged is the kind of ServerStateChanged events.
+	KindServerStateChanged Kind = "server_state_changed"
+	// KindPlanCreated is the kind of PlanCreated events.
+	KindPlanCreated Kind = "plan_created"
+	// KindPlanWarningsChanged is the kind of PlanWarningsChanged events.
+	KindPlanWarningsChanged Kind = "plan_warnings_changed"
+	// KindPlanBlocked is the kind of PlanBlocked events.
+	KindPlanBlocked Kind = "plan_blocked"
+	// KindPlanExecuting is the kind of PlanExecuting events.
+	KindPlanExecuting Kind = "plan_executing"
+	// KindPlanExecuted is the kind of PlanExecuted events.
+	KindPlanExecuted Kind = "plan_executed"
+	// KindProviderCall is the kind of ProviderCall events.
+	KindProviderCall Kind = "provider_call"
+	// KindPlanDiverged is the kind of PlanDiverged events.
+	KindPlanDiverged Kind = "plan_diverged"
+	// KindPlanPending is the kind of PlanPending events.
+	KindPlanPending Kind = "plan_pending"
+	// KindPlanDecided is the kind of PlanDecided events.
+	KindPlanDecided Kind = "plan_decided"
+	// KindSafetyOverridden is the kind of SafetyOverridden events.
+	KindSafetyOverridden Kind = "safety_overridden"
+	// KindFloatingIPDrifted is the kind of FloatingIPDrifted events.
+	KindFloatingIPDrifted Kind = "floating_ip_drifted"
+	// KindFloatingIPPinned is the kind of FloatingIPPinned events.
+	KindFloatingIPPinned Kind = "floating_ip_pinned"
+	// KindFloatingIPUnpinned is the kind of FloatingIPUnpinned events.
+	KindFloatingIPUnpinned Kind = "floating_ip_unpinned"
+	// KindVerdictDiffers is the kind of VerdictDiffers events.
+	KindVerdictDiffers Kind = "verdict_differs"
+	// KindMaintenanceStarted is the kind of MaintenanceStarted events.
+	KindMaintenanceStarted Kind = "maintenance_started"
+	// KindMaintenanceEnded is the kind of MaintenanceEnded events.
+	KindMaintenanceEnded Kind = "maintenance_ended"
+	// KindLocationAvailabilityChanged is the kind of LocationAvailabilityChanged events.
+	KindLocationAvailabilityChanged Kind = "location_availability_changed"
+	// KindLeadershipChanged is the kind of LeadershipChanged events.
+	KindLeadershipChanged Kind = "leadership_changed"
+	// KindConfigReloaded is the kind of ConfigReloaded events.
+	KindConfigReloaded Kind = "config_reloaded"
+	// KindConfigReloadFailed is the kind of ConfigReloadFailed events.
+	KindConfigReloadFailed Kind = "config_reload_failed"
+)
+
+// Event is something that happened in flipper.
+type Event interface {
+	// Kind returns the type of the event.
+	Kind() Kind
+	// Metadata returns the fields that all events have in common.
+	Metadata() Meta
+}
+
+// Group identifies the group an event happened in. It only holds the fields of the group config that are safe to
+// share, e.g. not the API token.
+type Group struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"display_name"`
+	Provider    string `json:"provider"`
+	ReadOnly    bool   `json:"read_only,omitempty"`
+}
+
+// NewGroup creates the group of an event from a group config.
+func NewGroup(cfg cfgmodel.GroupConfig) Group {
+	return Group{
+		ID:          cfg.ID,
+		DisplayName: cfg.DisplayName,
+		Provider:    cfg.Provider,
+		ReadOnly:    cfg.ReadOnly,
+	}
+}
+
+// Meta are the fields that all events have in common.
+type Meta struct {
+	At time.Time `json:"at"`
+	// Group is the group the event happened in, it is the zero group for events that are not about a group.
+	Group Group `json:"group"`
+}
+
+// NewMeta creates the metadata of an event that happens at the given time in the given group.
+func NewMeta(cfg cfgmodel.GroupConfig, at time.Time) Meta {
+	return Meta{At: at, Group: NewGroup(cfg)}
+}
+
+// Metadata returns the metadata itself, it is promoted to the events that embed it.
+func (m Meta) Metadata() Meta {
+	return m
+}
+
+// MonitorStarted is published when the monitor of a group starts.
+type MonitorStarted struct {
+	Meta
+	// Version is the version of flipper.
+	Version string `json:"version"`
+}
+
+// Kind returns KindMonitorStarted.
+func (MonitorStarted) Kind() Kind { return KindMonitorStarted }
+
+// ResourcesChanged is published when servers or floating IPs were added to or removed from a group while it was
+// watched. Changes of the resources themselves, and the resources found when the group starts, are not published.
+type ResourcesChanged struct {
+	Meta
+	Changeset resource.GroupChangeset `json:"changeset"`
+}
+
+// Kind returns KindResourcesChanged.
+func (ResourcesChanged) Kind() Kind { return KindResourcesChanged }
+
+// ServerStateChanged is published when the status of a server changed, overall or for an IP family, or when the
+// server started or stopped flapping.
+type ServerStateChanged struct {
+	Meta
+	Server   resource.Server `json:"server"`
+	Previous resource.State  `json:"previous"`
+	Current  resource.State  `json:"current"`
+
+	// StatusChanged, FamilyChanged and FlappingChanged tell what changed.
+	StatusChanged   bool `json:"status_changed,omitempty"`
+	FamilyChanged   bool `json:"family_changed,omitempty"`
+	FlappingChanged bool `json:"flapping_changed,omitempty"`
+
+	// CheckID is the ID of the check that caused the change, UnhealthyChecks are the results of the checks that
+	// are unhealthy.
+	CheckID         string                  `json:"check_id"`
+	UnhealthyChecks []check.HTTPCheckResult `json:"unhealthy_checks,omitempty"`
+
+	// Verdict is what the flipper nodes agree on about the server, nil if consensus is disabled.
+	Verdict *consensus.Verdict `json:"verdict,omitempty"`
+
+	// Maintenance is the active maintenance window of the server, nil if it is not in maintenance.
+	Maintenance *maintenance.Window `json:"maintenance,omitempty"`
+
+	// State is the state of the group after the change.
+	State plan.State `json:"-"`
+}
+
+// Kind returns KindServerStateChanged.
+func (ServerStateChanged) Kind() Kind { return KindServerStateChanged }
+
+// PlanCreated is published when a plan with actions was created. It may not be executed, e.g. because the group
+// is read-only or this instance is not the leader.
+type PlanCreated struct {
+	Meta
+	Plan plan.Plan `json:"plan"`
+
+	// State is the state the plan is based on.
+	State plan.State `json:"-"`
+}
+
+// Kind returns KindPlanCreated.
+func (PlanCreated) Kind() Kind { return KindPlanCreated }
+
+// PlanWarningsChanged is published when the warnings of a plan without actions differ from those of the previous
+// plan, e.g. because a floating IP can no longer be assigned. Warnings of plans with actions are part of PlanCreated.
+type PlanWarningsChanged struct {
+	Meta
+	// Warnings are the current warnings, empty if the previous warnings were resolved.
+	Warnings []plan.Warning `json:"warnings"`
+
+	// State is the state the plan is based on.
+	State plan.State `json:"-"`
+}
+
+// Kind returns KindPlanWarningsChanged.
+func (PlanWarningsChanged) Kind() Kind { return KindPlanWarningsChanged }
+
+// PlanBlocked is published when a plan was not executed because of the safety limits.
+type PlanBlocked struct {
+	Meta
+	Plan   plan.Plan `json:"plan"`
+	Reason string    `json:"reason"`
+
+	// State is the state the plan is based on.
+	State plan.State `json:"-"`
+}
+
+// Kind returns KindPlanBlocked.
+func (PlanBlocked) Kind() Kind { return KindPlanBlocked }
+
+// PlanExecuting is published right before a plan is executed.
+type PlanExecuting struct {
+	Meta
+	Plan plan.Plan `json:"plan"`
+
+	// State is the state the plan is based on.
+	State plan.State `json:"-"`
+}
+
+// Kind returns KindPlanExecuting.
+func (PlanExecuting) Kind() Kind { return KindPlanExecuting }
+
+// PlanExecuted is published when a plan was executed, whether it succeeded or failed.
+type PlanExecuted struct {
+	Meta
+	Report plan.ExecutionReport `json:"report"`
+	// Failed is true if any of the actions of the plan failed.
+	Failed bool `json:"failed"`
+
+	// State is the state the plan is based on.
+	State plan.State `json:"-"`
+}
+
+// Kind returns KindPlanExecuted.
+func (PlanExecuted) Kind() Kind { return KindPlanExecuted }
+
+// ProviderCall is published for every call to the provider that changes the resources, with its result.
+type ProviderCall struct {
+	Meta
+	// Operation is the call that was made, currently always "assign_floating_ip".
+	Operation  string              `json:"operation"`
+	PlanID     uuid.UUID           `json:"plan_id"`
+	FloatingIP resource.FloatingIP `json:"floating_ip"`
+	Server     resource.Server     `json:"server"`
+	// Rollback is true if the call moved a floating IP back after the plan failed.
+	Rollback bool `json:"rollback,omitempty"`
+	// Attempt is the number of the attempt, starting at 1.
+	Attempt  int           `json:"attempt"`
+	Duration time.Duration `json:"duration"`
+	// Error is the error the call failed with, empty if it succeeded.
+	Error string `json:"error,omitempty"`
+}
+
+// Kind returns KindProviderCall.
+func (ProviderCall) Kind() Kind { return KindProviderCall }
+
+// OperationAssignFloatingIP is the operation of provider calls that assign a floating IP to a server.
+const OperationAssignFloatingIP = "assign_floating_ip"
+
+// PlanDiverged is published when the provider does not reflect a plan after it was executed, within the
+// verification timeout.
+type PlanDiverged struct {
+	Meta
+	PlanID uuid.UUID `json:"plan_id"`
+	// Divergences are the floating IPs that do not target the server the plan expected, by floating IP ID. If the
+	// provider could not be polled, they are all the floating IPs the plan moved, without an actual server.
+	Divergences []plan.Divergence `json:"divergences"`
+	// PollError is the last error polling the provider, empty if it could be polled.
+	PollError string `json:"poll_error,omitempty"`
+
+	// State is the state the plan is based on.
+	State plan.State `json:"-"`
+}
+
+// Kind returns KindPlanDiverged.
+func (PlanDiverged) Kind() Kind { return KindPlanDiverged }
+
+// PlanPending is published when (part of) a plan waits for approval before it is executed.
+type PlanPending struct {
+	Meta
+	Plan plan.Plan `json:"plan"`
+	// ExpiresAt is when the plan is dropped, or approved if AutoApprove is set.
+	ExpiresAt   time.Time `json:"expires_at"`
+	AutoApprove bool      `json:"auto_approve,omitempty"`
+	// Replaces is the ID of the pending plan this plan replaces, empty if none.
+	Replaces string `json:"replaces,omitempty"`
+
+	// ApproveURL and RejectURL link to the pages to approve or reject the plan, empty if links are not configured.
+	// They carry the token of the plan, so they are only used for notifications.
+	ApproveURL string `json:"-"`
+	RejectURL  string `json:"-"`
+
+	// State is the state the plan is based on.
+	State plan.State `json:"-"`
+}
+
+// Kind returns KindPlanPending.
+func (PlanPending) Kind() Kind { return KindPlanPending }
+
+// Decisions on plans that wait for approval.
+const (
+	// DecisionApproved means the plan was approved, it is executed.
+	DecisionApproved = "approved"
+	// DecisionAutoApproved means the plan was approved automatically after the approval timeout.
+	DecisionAutoApproved = "auto_approved"
+	// DecisionRejected means the plan was rejected.
+	DecisionRejected = "rejected"
+	// DecisionExpired means the plan was dropped because it was not approved in time.
+	DecisionExpired = "expired"
+	// DecisionStale means the plan was dropped because the resources changed since it was proposed.
+	DecisionStale = "stale"
+	// DecisionUnneeded means the plan was dropped because its moves are no longer needed.
+	DecisionUnneeded = "unneeded"
+)
+
+// PlanDecided is published when a plan that waited for approval was approved, rejected or dropped.
+type PlanDecided struct {
+	Meta
+	Plan plan.Plan `json:"plan"`
+	// Decision is one of the Decision constants.
+	Decision string `json:"decision"`
+
+	// State is the state the plan is based on.
+	State plan.State `json:"-"`
+}
+
+// Kind returns KindPlanDecided.
+func (PlanDecided) Kind() Kind { return KindPlanDecided }
+
+// SafetyOverridden is published when the safety limits of a group were lifted by hand.
+type SafetyOverridden struct {
+	Meta
+	Until time.Time `json:"until"`
+}
+
+// Kind returns KindSafetyOverridden.
+func (SafetyOverridden) Kind() Kind { return KindSafetyOverridden }
+
+// FloatingIPDrifted is published when a floating IP got a different target without flipper moving it.
+type FloatingIPDrifted struct {
+	Meta
+	Drift resource.Drift `json:"drift"`
+	// Policy is the drift policy that was applied, Pin the pin it created, the zero pin if the change is reverted.
+	Policy string   `json:"policy"`
+	Pin    plan.Pin `json:"pin"`
+
+	// State is the state of the group when the change was detected.
+	State plan.State `json:"-"`
+}
+
+// Kind returns KindFloatingIPDrifted.
+func (FloatingIPDrifted) Kind() Kind { return KindFloatingIPDrifted }
+
+// FloatingIPPinned is published when a floating IP was pinned through the admin API.
+type FloatingIPPinned struct {
+	Meta
+	FloatingIP resource.FloatingIP `json:"floating_ip"`
+	Server     resource.Server     `json:"server"`
+	Pin        plan.Pin            `json:"pin"`
+
+	// State is the state of the group after the floating IP was pinned.
+	State plan.State `json:"-"`
+}
+
+// Kind returns KindFloatingIPPinned.
+func (FloatingIPPinned) Kind() Kind { return KindFloatingIPPinned }
+
+// FloatingIPUnpinned is published when the pin of a floating IP was removed through the admin API, or expired.
+type FloatingIPUnpinned struct {
+	Meta
+	// FloatingIPID is the ID of the floating IP, FloatingIPName its name if it is still in the group.
+	FloatingIPID   string `json:"floating_ip_id"`
+	FloatingIPName string `json:"floating_ip_name,omitempty"`
+	Expired        bool   `json:"expired,omitempty"`
+}
+
+// Kind returns KindFloatingIPUnpinned.
+func (FloatingIPUnpinned) Kind() Kind { return KindFloatingIPUnpinned }
+
+// VerdictDiffers is published when the status the flipper nodes agree on for a server differs from what this node
+// sees, or when it changes while it differs.
+type VerdictDiffers struct {
+	Meta
+	Server resource.Server `json:"server"`
+	// Local is the status this node sees.
+	Local   resource.Status   `json:"local"`
+	Verdict consensus.Verdict `json:"verdict"`
+}
+
+// Kind returns KindVerdictDiffers.
+func (VerdictDiffers) Kind() Kind { return KindVerdictDiffers }
+
+// MaintenanceStarted is published when a maintenance window started.
+type MaintenanceStarted struct {
+	Meta
+	Window maintenance.Window `json:"window"`
+	// Until is when the window ends.
+	Until time.Time `json:"until"`
+	// Servers are the servers the window applies to.
+	Servers []resource.Server `json:"servers"`
+}
+
+// Kind returns KindMaintenanceStarted.
+func (MaintenanceStarted) Kind() Kind { return KindMaintenanceStarted }
+
+// MaintenanceEnded is published when a maintenance window ended, or was removed while it was active.
+type MaintenanceEnded struct {
+	Meta
+	WindowID string `json:"window_id"`
+	// Servers are the servers the window applied to.
+	Servers []resource.Server `json:"servers"`
+}
+
+// Kind returns KindMaintenanceEnded.
+func (MaintenanceEnded) Kind() Kind { return KindMaintenanceEnded }
+
+// LocationAvailabilityChanged is published when a location became unavailable for a group because of a group it
+// depends on, or available again.
+type LocationAvailabilityChanged struct {
+	Meta
+	Location  string `json:"location"`
+	Available bool   `json:"available"`
+	// Reason is why the location is unavailable, empty if it is available.
+	Reason string `json:"reason,omitempty"`
+}
+
+// Kind returns KindLocationAvailabilityChanged.
+func (LocationAvailabilityChanged) Kind() Kind { return KindLocationAvailabilityChanged }
+
+// LeadershipChanged is published when this instance became or stopped being the leader. It is not about a group.
+type LeadershipChanged struct {
+	Meta
+	// Identity is the identity of this instance.
+	Identity string `json:"identity"`
+	Leader   bool   `json:"leader"`
+	// Holder is the identity of the instance that holds the lease if this instance is not the leader, empty if no
+	// instance holds it.
+	Holder string `json:"holder,omitempty"`
+}
+
+// Kind returns KindLeadershipChanged.
+func (LeadershipChanged) Kind() Kind { return KindLeadershipChanged }
+
+// ConfigReloaded is published when the configuration was reloaded. It is not about a group.
+type ConfigReloaded struct {
+	Meta
+	// Added, Removed, Restarted and Reconfigured are the IDs of the groups that changed.
+	Added        []string `json:"added,omitempty"`
+	Removed      []string `json:"removed,omitempty"`
+	Restarted    []string `json:"restarted,omitempty"`
+	Reconfigured []string `json:"reconfigured,omitempty"`
+	// NotificationsChanged is true if the notification targets changed.
+	NotificationsChanged bool `json:"notifications_changed,omitempty"`
+	// RequiresRestart lists the sections of the config that changed but only take effect after a restart.
+	RequiresRestart []string `json:"requires_restart,omitempty"`
+}
+
+// Kind returns KindConfigReloaded.
+func (ConfigReloaded) Kind() Kind { return KindConfigReloaded }
+
+// ConfigReloadFailed is published when the configuration could not be reloaded, the current one is kept. It is not
+// about a group.
+type ConfigReloadFailed struct {
+	Meta
+	Error string `json:"error"`
+}
+
+// Kind returns KindConfigReloadFailed.
+func (ConfigReloadFailed) Kind() Kind { return KindConfigReloadFailed }
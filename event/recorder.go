@@ -0,0 +1,39 @@
+package event
+
+import (
+	"context"
+	"sync"
+)
+
+// Recorder keeps the most recent events, e.g. to show them in the HTTP API.
+// It is safe for concurrent use.
+type Recorder struct {
+	mu     sync.Mutex
+	size   int
+	events []Event
+}
+
+// NewRecorder creates a new recorder that keeps the given number of most recent events.
+func NewRecorder(size int) *Recorder {
+	return &Recorder{size: size}
+}
+
+// Handle records an event, dropping the oldest event if the recorder is full. Subscribe it to a bus to record
+// the events published on it.
+func (r *Recorder) Handle(_ context.Context, e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = append(r.events, e)
+	if len(r.events) > r.size {
+		r.events = r.events[len(r.events)-r.size:]
+	}
+}
+
+// Events returns the recorded events, oldest first.
+func (r *Recorder) Events() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Event(nil), r.events...)
+}
@@ -4,8 +4,8 @@ import (
 	"context"
 	"log/slog"
 	"net/http"
-	"time"
 
+	"github.com/gzuidhof/flipper/clock"
 	"github.com/gzuidhof/flipper/config/cfgmodel"
 )
 
@@ -13,11 +13,26 @@ import (
 type Heartbeat struct {
 	cfg    cfgmodel.HeartbeatConfig
 	logger *slog.Logger
+	clock  clock.Clock
+}
+
+// Option configures a heartbeat.
+type Option func(h *Heartbeat)
+
+// WithClock sets the clock the interval is measured with, by default the wall clock.
+func WithClock(clk clock.Clock) Option {
+	return func(h *Heartbeat) {
+		h.clock = clk
+	}
 }
 
 // New creates a new heartbeat with the given configuration and logger.
-func New(cfg cfgmodel.HeartbeatConfig, logger *slog.Logger) *Heartbeat {
-	return &Heartbeat{cfg: cfg, logger: logger}
+func New(cfg cfgmodel.HeartbeatConfig, logger *slog.Logger, opts ...Option) *Heartbeat {
+	h := &Heartbeat{cfg: cfg, logger: logger, clock: clock.Real{}}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 func (h *Heartbeat) do(ctx context.Context) {
@@ -54,7 +69,7 @@ func (h *Heartbeat) Start(ctx context.Context) {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	ticker := time.NewTicker(h.cfg.Interval)
+	ticker := h.clock.NewTicker(h.cfg.Interval)
 	defer ticker.Stop()
 
 	// Send a heartbeat right away.
@@ -64,7 +79,7 @@ func (h *Heartbeat) Start(ctx context.Context) {
 		select {
 		case <-ctx.Done():
 			return
-		case <-ticker.C:
+		case <-ticker.C():
 			h.do(ctx)
 		}
 	}
@@ -0,0 +1,49 @@
+package heartbeat
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gzuidhof/flipper/clock"
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeartbeat(t *testing.T) {
+	t.Parallel()
+
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	clk := clock.NewVirtual(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	h := New(cfgmodel.HeartbeatConfig{URL: srv.URL, Interval: time.Minute, Timeout: time.Second}, slog.Default(),
+		WithClock(clk))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.Start(ctx)
+	}()
+
+	// The first heartbeat is sent right away, the next ones every interval of the virtual clock.
+	assert.Eventually(t, func() bool { return requests.Load() == 1 && clk.Waiters() == 1 },
+		time.Second, time.Millisecond)
+	for want := int32(2); want <= 3; want++ {
+		clk.Advance(time.Minute)
+		assert.Eventually(t, func() bool { return requests.Load() == want }, time.Second, time.Millisecond)
+	}
+
+	cancel()
+	<-done
+	assert.Equal(t, int32(3), requests.Load())
+}
@@ -0,0 +1,6 @@
+// Package leader elects a leader among flipper instances, so that multiple instances can run for high
+// availability without fighting over the floating IPs.
+//
+// The leader holds a lease that it renews periodically. The lease is kept in a backend that all instances share,
+// if the leader stops renewing it another instance acquires it once it expires.
+package leader
@@ -0,0 +1,144 @@
+package leader
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+)
+
+// Status tells whether this instance may act.
+type Status interface {
+	// IsLeader returns true if this instance is the leader.
+	IsLeader() bool
+}
+
+// Always is the status of an instance that runs on its own, it is always the leader.
+type Always struct{}
+
+// IsLeader always returns true.
+func (Always) IsLeader() bool {
+	return true
+}
+
+// Elector takes part in the election of a leader among flipper instances.
+type Elector struct {
+	cfg      cfgmodel.LeaderElectionConfig
+	backend  Backend
+	logger   *slog.Logger
+	identity string
+
+	// onChange is called from the elector loop when this instance becomes or stops being the leader.
+	onChange func(ctx context.Context, isLeader bool, lease Lease)
+
+	mu    sync.Mutex
+	lease Lease
+}
+
+var _ Status = (*Elector)(nil)
+
+// NewElector creates an elector that keeps the lease in the given backend.
+func NewElector(cfg cfgmodel.LeaderElectionConfig, backend Backend, logger *slog.Logger) *Elector {
+	identity := cfg.IdentityOrDefault()
+	return &Elector{
+		cfg:      cfg,
+		backend:  backend,
+		logger:   logger.With(slog.String("identity", identity)),
+		identity: identity,
+		onChange: func(context.Context, bool, Lease) {},
+	}
+}
+
+// OnChange sets the function that is called when this instance becomes or stops being the leader.
+// It must be called before the elector is started.
+func (e *Elector) OnChange(onChange func(ctx context.Context, isLeader bool, lease Lease)) {
+	e.onChange = onChange
+}
+
+// Identity returns the identity of this instance.
+func (e *Elector) Identity() string {
+	return e.identity
+}
+
+// IsLeader returns true if this instance holds the lease. The lease runs out by itself if it can not be renewed,
+// e.g. when the backend is not reachable, so that two instances never both think they are the leader.
+// It is safe to call from any goroutine.
+func (e *Elector) IsLeader() bool {
+	return e.Lease().HeldBy(e.identity, time.Now())
+}
+
+// Lease returns the lease as it was last seen.
+// It is safe to call from any goroutine.
+func (e *Elector) Lease() Lease {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.lease
+}
+
+// Start takes part in the election until the context is cancelled, then gives up the lease if it holds it.
+// This function blocks until the context is cancelled.
+func (e *Elector) Start(ctx context.Context) {
+	ticker := time.NewTicker(e.cfg.RenewIntervalOrDefault())
+	defer ticker.Stop()
+
+	wasLeader := false
+	for {
+		wasLeader = e.tick(ctx, time.Now(), wasLeader)
+
+		select {
+		case <-ctx.Done():
+			if wasLeader {
+				e.release()
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick tries to acquire or renew the lease, and reports a change of leadership. It returns whether this instance
+// is the leader.
+func (e *Elector) tick(ctx context.Context, now time.Time, wasLeader bool) bool {
+	lease, err := e.backend.Acquire(ctx, e.identity, now, e.cfg.LeaseDurationOrDefault())
+	if err != nil {
+		e.logger.ErrorContext(ctx, "Failed to acquire leader lease.", slog.String("error", err.Error()))
+		// The lease that was seen before stays valid until it expires.
+		lease = e.Lease()
+	} else {
+		e.mu.Lock()
+		e.lease = lease
+		e.mu.Unlock()
+	}
+
+	isLeader := lease.HeldBy(e.identity, now)
+	if isLeader == wasLeader {
+		return isLeader
+	}
+
+	if isLeader {
+		e.logger.InfoContext(ctx, "Became the leader.", slog.Time("until", lease.Until))
+	} else {
+		e.logger.WarnContext(ctx, "No longer the leader.", slog.String("leader", lease.Holder))
+	}
+	e.onChange(ctx, isLeader, lease)
+	return isLeader
+}
+
+// release gives up the lease, so that another instance can take over right away.
+func (e *Elector) release() {
+	// The context of the elector is cancelled already.
+	ctx, cancel := context.WithTimeout(context.Background(), e.cfg.RenewIntervalOrDefault())
+	defer cancel()
+
+	if err := e.backend.Release(ctx, e.identity); err != nil {
+		e.logger.ErrorContext(ctx, "Failed to release leader lease.", slog.String("error", err.Error()))
+		return
+	}
+	e.mu.Lock()
+	e.lease = Lease{}
+	e.mu.Unlock()
+	e.logger.InfoContext(ctx, "Released leader lease.")
+}
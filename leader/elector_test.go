@@ -0,0 +1,56 @@
+package leader
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestElector(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	backend := NewFile(filepath.Join(t.TempDir(), "lease.json"))
+	newElector := func(identity string) (*Elector, *[]bool) {
+		e := NewElector(cfgmodel.LeaderElectionConfig{Identity: identity, LeaseDuration: 15 * time.Second},
+			backend, slog.Default(),
+		)
+		changes := &[]bool{}
+		e.OnChange(func(_ context.Context, isLeader bool, _ Lease) {
+			*changes = append(*changes, isLeader)
+		})
+		return e, changes
+	}
+	a, aChanges := newElector("a")
+	b, bChanges := newElector("b")
+
+	now := time.Now()
+	assert.True(t, a.tick(ctx, now, false), "the free lease is acquired")
+	assert.False(t, b.tick(ctx, now.Add(time.Second), false), "the lease is held by a")
+	assert.Equal(t, "a", b.Lease().Holder)
+
+	// a renews its lease, so b can not take over.
+	assert.True(t, a.tick(ctx, now.Add(10*time.Second), true))
+	assert.False(t, b.tick(ctx, now.Add(20*time.Second), false))
+
+	// a stops renewing, b takes over once the lease expired.
+	assert.True(t, b.tick(ctx, now.Add(30*time.Second), false))
+	assert.False(t, a.tick(ctx, now.Add(31*time.Second), true))
+
+	// Releasing the lease lets a take over right away.
+	b.release()
+	assert.True(t, a.tick(ctx, now.Add(32*time.Second), false))
+
+	assert.Equal(t, []bool{true, false, true}, *aChanges)
+	assert.Equal(t, []bool{true}, *bChanges)
+
+	lease, err := backend.Acquire(ctx, "b", now.Add(33*time.Second), time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "a", lease.Holder)
+}
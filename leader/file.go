@@ -0,0 +1,126 @@
+package leader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Lock files older than this are left over from an instance that crashed while holding them.
+const staleLockAge = 10 * time.Second
+
+var _ Backend = (*File)(nil)
+
+// File keeps the lease in a file. Changes are guarded by a lock file, so it only works for instances that share
+// the file system, e.g. on the same host.
+type File struct {
+	path string
+}
+
+// NewFile creates a file lease backend.
+func NewFile(path string) *File {
+	return &File{path: path}
+}
+
+// lock creates the lock file, waiting for it to be removed if it exists. It returns a function that removes it.
+func (f *File) lock(ctx context.Context) (func(), error) {
+	lockPath := f.path + ".lock"
+	for {
+		lockFile, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			_ = lockFile.Close()
+			return func() { _ = os.Remove(lockPath) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("failed to create lock file: %w", err)
+		}
+
+		if info, statErr := os.Stat(lockPath); statErr == nil && time.Since(info.ModTime()) > staleLockAge {
+			_ = os.Remove(lockPath)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("failed to lock lease: %w", ctx.Err())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func (f *File) read() (Lease, error) {
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Lease{}, nil
+	}
+	if err != nil {
+		return Lease{}, fmt.Errorf("failed to read lease: %w", err)
+	}
+
+	var lease Lease
+	if err := json.Unmarshal(data, &lease); err != nil {
+		return Lease{}, fmt.Errorf("failed to decode lease: %w", err)
+	}
+	return lease, nil
+}
+
+func (f *File) write(lease Lease) error {
+	data, err := json.Marshal(lease)
+	if err != nil {
+		return fmt.Errorf("failed to encode lease: %w", err)
+	}
+
+	tmpPath := filepath.Join(filepath.Dir(f.path), "."+filepath.Base(f.path)+".tmp")
+	if err := os.WriteFile(tmpPath, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write lease: %w", err)
+	}
+	if err := os.Rename(tmpPath, f.path); err != nil {
+		return fmt.Errorf("failed to replace lease: %w", err)
+	}
+	return nil
+}
+
+// Acquire acquires the lease for the given identity if it is free or already held by it.
+func (f *File) Acquire(ctx context.Context, identity string, now time.Time, duration time.Duration) (Lease, error) {
+	unlock, err := f.lock(ctx)
+	if err != nil {
+		return Lease{}, err
+	}
+	defer unlock()
+
+	current, err := f.read()
+	if err != nil {
+		return Lease{}, err
+	}
+	next := current.next(identity, now, duration)
+	if next == current {
+		return current, nil
+	}
+	if err := f.write(next); err != nil {
+		return Lease{}, err
+	}
+	return next, nil
+}
+
+// Release gives up the lease if it is held by the given identity.
+func (f *File) Release(ctx context.Context, identity string) error {
+	unlock, err := f.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	current, err := f.read()
+	if err != nil {
+		return err
+	}
+	if current.Holder != identity {
+		return nil
+	}
+	return f.write(Lease{})
+}
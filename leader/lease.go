@@ -0,0 +1,44 @@
+package leader
+
+import (
+	"context"
+	"time"
+)
+
+// Lease is the right of an instance to lead until it expires.
+type Lease struct {
+	// Holder is the identity of the instance that holds the lease, empty if nobody does.
+	Holder string `json:"holder"`
+
+	// Until is when the lease expires.
+	Until time.Time `json:"until"`
+}
+
+// HeldBy returns true if the lease is held by the given identity at the given time.
+func (l Lease) HeldBy(identity string, now time.Time) bool {
+	return l.Holder == identity && now.Before(l.Until)
+}
+
+// Free returns true if nobody holds the lease at the given time.
+func (l Lease) Free(now time.Time) bool {
+	return l.Holder == "" || !now.Before(l.Until)
+}
+
+// next returns the lease after the given identity tried to acquire (or renew) it at the given time.
+func (l Lease) next(identity string, now time.Time, duration time.Duration) Lease {
+	if l.Holder != identity && !l.Free(now) {
+		return l
+	}
+	return Lease{Holder: identity, Until: now.Add(duration)}
+}
+
+// Backend keeps the lease where all instances can see it.
+type Backend interface {
+	// Acquire acquires the lease for the given identity if it is free or already held by it, extending it by the
+	// given duration. It returns the lease as it is after the attempt, which may be held by someone else.
+	Acquire(ctx context.Context, identity string, now time.Time, duration time.Duration) (Lease, error)
+
+	// Release gives up the lease if it is held by the given identity, so that another instance can take over
+	// right away.
+	Release(ctx context.Context, identity string) error
+}
@@ -0,0 +1,6 @@
+// Package maintenance implements maintenance windows: periods during which failover is suppressed for some servers,
+// or during which they are drained of their floating IPs.
+//
+// A window is either recurring, with a cron schedule and a duration, or a one-off time range. It applies to the
+// whole group, to the servers with the given names, or to the servers that match a label selector.
+package maintenance
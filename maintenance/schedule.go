@@ -0,0 +1,213 @@
+package maintenance
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// searchLimit is how far ahead the next time of a schedule is searched for. Schedules that do not fire within it
+// (e.g. on the 30th of February) are rejected when they are parsed.
+const searchLimit = 5 * 366 * 24 * time.Hour
+
+// Schedule is a cron schedule with five fields: minute, hour, day of the month, month and day of the week. Times are
+// in UTC.
+//
+// Every field is a comma separated list of values, ranges ("1-5") and steps ("*/15", "0-30/10"). Months and days
+// of the week may also be given by their first three letters ("jan", "mon"), and Sunday is both 0 and 7. Like in
+// cron, a day matches if either the day of the month or the day of the week matches when both are restricted.
+type Schedule struct {
+	expr string
+
+	minute, hour, dayOfMonth, month, dayOfWeek uint64
+	// anyDayOfMonth and anyDayOfWeek are true if the fields are "*", to combine the day fields like cron does.
+	anyDayOfMonth, anyDayOfWeek bool
+}
+
+// field describes one of the fields of a schedule.
+type field struct {
+	name     string
+	min, max int
+	names    []string
+}
+
+//nolint:gochecknoglobals // Fixed description of the fields.
+var fields = [5]field{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: []string{
+		"", "jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec",
+	}},
+	{name: "day of week", min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+// ParseSchedule parses a cron schedule, e.g. "0 3 * * sun" for every Sunday at 03:00 UTC.
+func ParseSchedule(expr string) (Schedule, error) {
+	parts := strings.Fields(expr)
+	if len(parts) != len(fields) {
+		return Schedule{}, fmt.Errorf("schedule %q must have %d fields, it has %d", expr, len(fields), len(parts))
+	}
+
+	var sets [5]uint64
+	for i, part := range parts {
+		set, err := fields[i].parse(strings.ToLower(part))
+		if err != nil {
+			return Schedule{}, fmt.Errorf("%s of schedule %q: %w", fields[i].name, expr, err)
+		}
+		sets[i] = set
+	}
+	// Sunday is both 0 and 7.
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1
+	}
+
+	s := Schedule{
+		expr:          strings.Join(parts, " "),
+		minute:        sets[0],
+		hour:          sets[1],
+		dayOfMonth:    sets[2],
+		month:         sets[3],
+		dayOfWeek:     sets[4],
+		anyDayOfMonth: parts[2] == "*",
+		anyDayOfWeek:  parts[4] == "*",
+	}
+	// Every day of the month occurs within the search limit, so the start of the search does not matter.
+	if s.Next(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)).IsZero() {
+		return Schedule{}, fmt.Errorf("schedule %q never fires", expr)
+	}
+	return s, nil
+}
+
+// parse parses a field of a schedule into a set of values, as a bit set.
+func (f field) parse(s string) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(s, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepPart)
+			}
+		}
+
+		from, to := f.min, f.max
+		switch lo, hi, isRange := strings.Cut(rangePart, "-"); {
+		case rangePart == "*":
+		case isRange:
+			var err error
+			if from, err = f.value(lo); err != nil {
+				return 0, err
+			}
+			if to, err = f.value(hi); err != nil {
+				return 0, err
+			}
+			if from > to {
+				return 0, fmt.Errorf("invalid range %q", rangePart)
+			}
+		default:
+			value, err := f.value(rangePart)
+			if err != nil {
+				return 0, err
+			}
+			// A single value with a step runs to the end of the field, like in cron.
+			from = value
+			if !hasStep {
+				to = value
+			}
+		}
+
+		for v := from; v <= to; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+// value parses a single value of a field, either a number or a name.
+func (f field) value(s string) (int, error) {
+	for i, name := range f.names {
+		if name != "" && s == name {
+			return i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("value %d out of range %d-%d", v, f.min, f.max)
+	}
+	return v, nil
+}
+
+// Next returns the first time after the given time at which the schedule fires, in UTC. It returns the zero time
+// for the zero schedule.
+func (s Schedule) Next(after time.Time) time.Time {
+	if s.expr == "" {
+		return time.Time{}
+	}
+
+	t := after.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(searchLimit)
+	for t.Before(limit) {
+		switch {
+		case !has(s.month, int(t.Month())):
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+		case !has(s.hour, t.Hour()):
+			t = t.Truncate(time.Hour).Add(time.Hour)
+		case !has(s.minute, t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// dayMatches returns true if the schedule fires on the day of the given time.
+func (s Schedule) dayMatches(t time.Time) bool {
+	dayOfMonth := has(s.dayOfMonth, t.Day())
+	dayOfWeek := has(s.dayOfWeek, int(t.Weekday()))
+	if s.anyDayOfMonth || s.anyDayOfWeek {
+		return dayOfMonth && dayOfWeek
+	}
+	return dayOfMonth || dayOfWeek
+}
+
+func has(set uint64, v int) bool {
+	return set&(1<<v) != 0
+}
+
+// IsZero returns true for the zero schedule, which never fires.
+func (s Schedule) IsZero() bool {
+	return s.expr == ""
+}
+
+// String returns the cron expression of the schedule.
+func (s Schedule) String() string {
+	return s.expr
+}
+
+// MarshalText returns the cron expression of the schedule.
+func (s Schedule) MarshalText() ([]byte, error) {
+	return []byte(s.expr), nil
+}
+
+// UnmarshalText parses a cron expression.
+func (s *Schedule) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		return errors.New("empty schedule")
+	}
+	parsed, err := ParseSchedule(string(text))
+	if err != nil {
+		return err
+	}
+	*s = parsed
+	return nil
+}
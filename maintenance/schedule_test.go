@@ -0,0 +1,63 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduleNext(t *testing.T) {
+	t.Parallel()
+
+	// A Monday.
+	after := time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC)
+
+	for _, tc := range []struct {
+		expr     string
+		expected time.Time
+	}{
+		{expr: "* * * * *", expected: time.Date(2024, 1, 1, 12, 31, 0, 0, time.UTC)},
+		{expr: "*/15 * * * *", expected: time.Date(2024, 1, 1, 12, 45, 0, 0, time.UTC)},
+		{expr: "0 3 * * *", expected: time.Date(2024, 1, 2, 3, 0, 0, 0, time.UTC)},
+		{expr: "0 3 * * sun", expected: time.Date(2024, 1, 7, 3, 0, 0, 0, time.UTC)},
+		{expr: "0 3 * * 7", expected: time.Date(2024, 1, 7, 3, 0, 0, 0, time.UTC)},
+		{expr: "0 22 * * mon-fri", expected: time.Date(2024, 1, 1, 22, 0, 0, 0, time.UTC)},
+		{expr: "30 1,13 * * *", expected: time.Date(2024, 1, 1, 13, 30, 0, 0, time.UTC)},
+		{expr: "0 0 1 */3 *", expected: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 29 feb *", expected: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// If both days are restricted, either matches.
+		{expr: "0 0 15 * sat", expected: time.Date(2024, 1, 6, 0, 0, 0, 0, time.UTC)},
+	} {
+		t.Run(tc.expr, func(t *testing.T) {
+			t.Parallel()
+
+			s, err := ParseSchedule(tc.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, s.Next(after))
+			assert.Equal(t, tc.expr, s.String())
+		})
+	}
+}
+
+func TestParseScheduleInvalid(t *testing.T) {
+	t.Parallel()
+
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"* * * foo *",
+		"0 0 30 feb *",
+	} {
+		_, err := ParseSchedule(expr)
+		assert.Error(t, err, expr)
+	}
+}
@@ -0,0 +1,207 @@
+package maintenance
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// operator is how a requirement of a label selector compares the value of a label.
+type operator int
+
+const (
+	opEquals operator = iota
+	opNotEquals
+	opExists
+	opNotExists
+	opIn
+	opNotIn
+)
+
+// requirement is a single comma separated term of a label selector.
+type requirement struct {
+	key    string
+	op     operator
+	values []string
+}
+
+// Selector selects servers by their labels, using the syntax of the label selectors of Hetzner Cloud (and
+// Kubernetes): a comma separated list of requirements that all have to match. The requirements are "key=value"
+// (or "key==value"), "key!=value", "key" (the label exists), "!key" (the label does not exist), "key in (a, b)"
+// and "key notin (a, b)".
+//
+// The zero selector matches all servers.
+type Selector struct {
+	expr         string
+	requirements []requirement
+}
+
+// ParseSelector parses a label selector, e.g. "role=lb,env in (prod, staging)".
+func ParseSelector(expr string) (Selector, error) {
+	terms, err := splitTerms(expr)
+	if err != nil {
+		return Selector{}, fmt.Errorf("label selector %q: %w", expr, err)
+	}
+
+	s := Selector{expr: strings.TrimSpace(expr)}
+	for _, term := range terms {
+		req, err := parseRequirement(term)
+		if err != nil {
+			return Selector{}, fmt.Errorf("label selector %q: %w", expr, err)
+		}
+		s.requirements = append(s.requirements, req)
+	}
+	return s, nil
+}
+
+// splitTerms splits a selector at the commas that are not within the parentheses of a set.
+func splitTerms(expr string) ([]string, error) {
+	var terms []string
+	depth, start := 0, 0
+	for i, r := range expr {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				terms = append(terms, strings.TrimSpace(expr[start:i]))
+				start = i + 1
+			}
+		}
+		if depth < 0 || depth > 1 {
+			return nil, errors.New("unbalanced parentheses")
+		}
+	}
+	if depth != 0 {
+		return nil, errors.New("unbalanced parentheses")
+	}
+	terms = append(terms, strings.TrimSpace(expr[start:]))
+	if len(terms) == 1 && terms[0] == "" {
+		return nil, errors.New("empty selector")
+	}
+	return terms, nil
+}
+
+// parseRequirement parses a single term of a selector.
+func parseRequirement(term string) (requirement, error) {
+	if term == "" {
+		return requirement{}, errors.New("empty requirement")
+	}
+
+	if key, ok := strings.CutPrefix(term, "!"); ok {
+		return newRequirement(strings.TrimSpace(key), opNotExists)
+	}
+	if key, value, ok := strings.Cut(term, "!="); ok {
+		return newRequirement(strings.TrimSpace(key), opNotEquals, strings.TrimSpace(value))
+	}
+	if key, value, ok := strings.Cut(term, "=="); ok {
+		return newRequirement(strings.TrimSpace(key), opEquals, strings.TrimSpace(value))
+	}
+	if key, value, ok := strings.Cut(term, "="); ok {
+		return newRequirement(strings.TrimSpace(key), opEquals, strings.TrimSpace(value))
+	}
+
+	key, rest, ok := strings.Cut(term, " ")
+	if !ok {
+		return newRequirement(key, opExists)
+	}
+	rest = strings.TrimSpace(rest)
+	op := opIn
+	set, isIn := strings.CutPrefix(rest, "in")
+	if !isIn {
+		op = opNotIn
+		if set, ok = strings.CutPrefix(rest, "notin"); !ok {
+			return requirement{}, fmt.Errorf("invalid requirement %q", term)
+		}
+	}
+	set = strings.TrimSpace(set)
+	if !strings.HasPrefix(set, "(") || !strings.HasSuffix(set, ")") {
+		return requirement{}, fmt.Errorf("the values of %q must be in parentheses", term)
+	}
+	var values []string
+	for _, value := range strings.Split(set[1:len(set)-1], ",") {
+		values = append(values, strings.TrimSpace(value))
+	}
+	return newRequirement(key, op, values...)
+}
+
+// newRequirement creates a requirement, checking that the key and values are valid label keys and values.
+func newRequirement(key string, op operator, values ...string) (requirement, error) {
+	if key == "" {
+		return requirement{}, errors.New("empty label key")
+	}
+	if !validLabel(key) {
+		return requirement{}, fmt.Errorf("invalid label key %q", key)
+	}
+	for _, value := range values {
+		if !validLabel(value) {
+			return requirement{}, fmt.Errorf("invalid label value %q", value)
+		}
+	}
+	return requirement{key: key, op: op, values: values}, nil
+}
+
+// validLabel returns true if the string only has characters that are allowed in label keys and values.
+func validLabel(s string) bool {
+	for _, r := range s {
+		isAlphanumeric := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+		if !isAlphanumeric && !strings.ContainsRune("-_./", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// Matches returns true if the labels match all requirements of the selector.
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, req := range s.requirements {
+		value, exists := labels[req.key]
+		var ok bool
+		switch req.op {
+		case opEquals:
+			ok = exists && value == req.values[0]
+		case opNotEquals:
+			ok = !exists || value != req.values[0]
+		case opExists:
+			ok = exists
+		case opNotExists:
+			ok = !exists
+		case opIn:
+			ok = exists && slices.Contains(req.values, value)
+		case opNotIn:
+			ok = !exists || !slices.Contains(req.values, value)
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// IsZero returns true for the zero selector, which matches all servers.
+func (s Selector) IsZero() bool {
+	return s.expr == ""
+}
+
+// String returns the expression of the selector.
+func (s Selector) String() string {
+	return s.expr
+}
+
+// MarshalText returns the expression of the selector.
+func (s Selector) MarshalText() ([]byte, error) {
+	return []byte(s.expr), nil
+}
+
+// UnmarshalText parses a label selector.
+func (s *Selector) UnmarshalText(text []byte) error {
+	parsed, err := ParseSelector(string(text))
+	if err != nil {
+		return err
+	}
+	*s = parsed
+	return nil
+}
@@ -0,0 +1,64 @@
+package maintenance
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectorMatches(t *testing.T) {
+	t.Parallel()
+
+	labels := map[string]string{"role": "lb", "env": "prod", "flipper/maintenance": ""}
+
+	for _, tc := range []struct {
+		expr     string
+		expected bool
+	}{
+		{expr: "role=lb", expected: true},
+		{expr: "role==lb", expected: true},
+		{expr: "role=api", expected: false},
+		{expr: "role!=api", expected: true},
+		{expr: "zone!=a", expected: true},
+		{expr: "env", expected: true},
+		{expr: "zone", expected: false},
+		{expr: "!zone", expected: true},
+		{expr: "!env", expected: false},
+		{expr: "flipper/maintenance", expected: true},
+		{expr: "env in (prod, staging)", expected: true},
+		{expr: "env in (staging)", expected: false},
+		{expr: "env notin (staging)", expected: true},
+		{expr: "zone notin (a)", expected: true},
+		{expr: "role=lb,env in (prod,staging)", expected: true},
+		{expr: "role=lb, env=staging", expected: false},
+	} {
+		t.Run(tc.expr, func(t *testing.T) {
+			t.Parallel()
+
+			s, err := ParseSelector(tc.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, s.Matches(labels))
+		})
+	}
+
+	assert.True(t, Selector{}.Matches(labels), "the zero selector matches everything")
+}
+
+func TestParseSelectorInvalid(t *testing.T) {
+	t.Parallel()
+
+	for _, expr := range []string{
+		"",
+		"role=lb,",
+		"=lb",
+		"role=l b",
+		"env in prod",
+		"env in (prod",
+		"env between (a, b)",
+		"ro$le",
+	} {
+		_, err := ParseSelector(expr)
+		assert.Error(t, err, expr)
+	}
+}
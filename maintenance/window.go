@@ -0,0 +1,238 @@
+package maintenance
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/gzuidhof/flipper/resource"
+)
+
+// Mode is what happens to the servers of a window while it is active.
+type Mode string
+
+const (
+	// ModeSuppress suppresses failover: the floating IPs stay on the servers of the window, even if they become
+	// unhealthy, e.g. while they are rebooted for updates.
+	ModeSuppress Mode = "suppress"
+	// ModeDrain moves the floating IPs away from the servers of the window and keeps them away, as long as there
+	// are other healthy servers.
+	ModeDrain Mode = "drain"
+)
+
+// Notifications is what happens to the notifications about the health of the servers of a window while it is
+// active.
+type Notifications string
+
+const (
+	// NotificationsAnnotate sends the notifications, marked as expected because of the window.
+	NotificationsAnnotate Notifications = "annotate"
+	// NotificationsMute does not send the notifications.
+	NotificationsMute Notifications = "mute"
+)
+
+// Window is a maintenance window. It is either recurring, with a schedule and a duration, or a one-off window from
+// its start to its end.
+type Window struct {
+	// ID identifies the window within its group.
+	ID string
+
+	// Mode is what happens to the servers of the window, ModeSuppress if empty.
+	Mode Mode
+	// Notifications is what happens to the notifications about the servers, NotificationsAnnotate if empty.
+	Notifications Notifications
+
+	// Schedule is when a recurring window starts, and Duration how long it lasts.
+	Schedule Schedule
+	Duration time.Duration
+
+	// Start and End are the times of a one-off window.
+	Start time.Time
+	End   time.Time
+
+	// Servers are the names of the servers the window applies to, LabelSelector selects them by their labels
+	// instead. The window applies to all servers of the group if neither is set.
+	Servers       []string
+	LabelSelector Selector
+
+	// Reason describes the maintenance, e.g. "kernel updates".
+	Reason string
+}
+
+// Validate checks that the window is either recurring or a one-off window, and that the servers are selected in at
+// most one way.
+func (w Window) Validate() error {
+	switch {
+	case w.ID == "":
+		return errors.New("the ID of a maintenance window is required")
+	case w.Mode != "" && w.Mode != ModeSuppress && w.Mode != ModeDrain:
+		return fmt.Errorf("invalid mode %q, it must be %q or %q", w.Mode, ModeSuppress, ModeDrain)
+	case w.Notifications != "" && w.Notifications != NotificationsAnnotate && w.Notifications != NotificationsMute:
+		return fmt.Errorf("invalid notifications %q, they must be %q or %q",
+			w.Notifications, NotificationsAnnotate, NotificationsMute)
+	case !w.Schedule.IsZero() && (!w.Start.IsZero() || !w.End.IsZero()):
+		return errors.New("a maintenance window has either a schedule or a start and end, not both")
+	case !w.Schedule.IsZero() && w.Duration <= 0:
+		return errors.New("a recurring maintenance window needs a positive duration")
+	case w.Schedule.IsZero() && (w.Start.IsZero() || w.End.IsZero()):
+		return errors.New("a maintenance window needs a schedule and duration, or a start and end")
+	case w.Schedule.IsZero() && !w.End.After(w.Start):
+		return errors.New("the end of a maintenance window must be after its start")
+	case len(w.Servers) > 0 && !w.LabelSelector.IsZero():
+		return errors.New("a maintenance window selects servers by name or by label selector, not both")
+	}
+	return nil
+}
+
+// ModeOrDefault returns the mode of the window, or ModeSuppress if not set.
+func (w Window) ModeOrDefault() Mode {
+	if w.Mode == "" {
+		return ModeSuppress
+	}
+	return w.Mode
+}
+
+// NotificationsOrDefault returns what happens to the notifications, or NotificationsAnnotate if not set.
+func (w Window) NotificationsOrDefault() Notifications {
+	if w.Notifications == "" {
+		return NotificationsAnnotate
+	}
+	return w.Notifications
+}
+
+// Active returns whether the window is active at the given time, and if so until when. A recurring window that
+// starts again before it ends is active until the end of the last occurrence that started.
+func (w Window) Active(now time.Time) (time.Time, bool) {
+	if w.Schedule.IsZero() {
+		return w.End, !now.Before(w.Start) && now.Before(w.End)
+	}
+
+	// The occurrences that are active at the given time started in the last duration.
+	start := w.Schedule.Next(now.Add(-w.Duration))
+	if start.IsZero() || start.After(now) {
+		return time.Time{}, false
+	}
+	end := start.Add(w.Duration)
+	for next := w.Schedule.Next(start); !next.IsZero() && !next.After(now); next = w.Schedule.Next(next) {
+		end = next.Add(w.Duration)
+	}
+	return end, true
+}
+
+// NextChange returns the next time after the given time at which the window starts or ends, or the zero time if
+// it never changes again.
+func (w Window) NextChange(now time.Time) time.Time {
+	if until, ok := w.Active(now); ok {
+		return until
+	}
+	if w.Schedule.IsZero() {
+		if now.Before(w.Start) {
+			return w.Start
+		}
+		return time.Time{}
+	}
+	return w.Schedule.Next(now)
+}
+
+// Expired returns true if a one-off window ended before the given time. Recurring windows never expire.
+func (w Window) Expired(now time.Time) bool {
+	return w.Schedule.IsZero() && !now.Before(w.End)
+}
+
+// Covers returns true if the window applies to the server.
+func (w Window) Covers(server resource.Server) bool {
+	if len(w.Servers) > 0 {
+		return slices.Contains(w.Servers, server.Name())
+	}
+	return w.LabelSelector.Matches(server.Labels)
+}
+
+// Equal returns true if the windows are the same.
+func (w Window) Equal(other Window) bool {
+	return w.ID == other.ID && w.Mode == other.Mode && w.Notifications == other.Notifications &&
+		w.Schedule.String() == other.Schedule.String() && w.Duration == other.Duration &&
+		w.Start.Equal(other.Start) && w.End.Equal(other.End) && slices.Equal(w.Servers, other.Servers) &&
+		w.LabelSelector.String() == other.LabelSelector.String() && w.Reason == other.Reason
+}
+
+// Describe describes the window, e.g. for notifications.
+func (w Window) Describe() string {
+	s := "maintenance window `" + w.ID + "`"
+	if w.Reason != "" {
+		s += " (" + w.Reason + ")"
+	}
+	return s
+}
+
+// windowJSON is the JSON representation of a window, with the duration as a string like "2h".
+type windowJSON struct {
+	ID            string        `json:"id"`
+	Mode          Mode          `json:"mode"`
+	Notifications Notifications `json:"notifications"`
+	Schedule      *Schedule     `json:"schedule,omitempty"`
+	Duration      string        `json:"duration,omitempty"`
+	Start         *time.Time    `json:"start,omitempty"`
+	End           *time.Time    `json:"end,omitempty"`
+	Servers       []string      `json:"servers,omitempty"`
+	LabelSelector *Selector     `json:"label_selector,omitempty"`
+	Reason        string        `json:"reason,omitempty"`
+}
+
+// MarshalJSON returns the JSON representation of the window.
+func (w Window) MarshalJSON() ([]byte, error) {
+	v := windowJSON{
+		ID:            w.ID,
+		Mode:          w.ModeOrDefault(),
+		Notifications: w.NotificationsOrDefault(),
+		Servers:       w.Servers,
+		Reason:        w.Reason,
+	}
+	if !w.Schedule.IsZero() {
+		v.Schedule = &w.Schedule
+		v.Duration = w.Duration.String()
+	} else {
+		v.Start, v.End = &w.Start, &w.End
+	}
+	if !w.LabelSelector.IsZero() {
+		v.LabelSelector = &w.LabelSelector
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON parses the JSON representation of the window.
+func (w *Window) UnmarshalJSON(data []byte) error {
+	var v windowJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err //nolint:wrapcheck // The error of the decoder describes the JSON.
+	}
+	window := Window{
+		ID:            v.ID,
+		Mode:          v.Mode,
+		Notifications: v.Notifications,
+		Servers:       v.Servers,
+		Reason:        v.Reason,
+	}
+	if v.Schedule != nil {
+		window.Schedule = *v.Schedule
+	}
+	if v.Duration != "" {
+		duration, err := time.ParseDuration(v.Duration)
+		if err != nil {
+			return fmt.Errorf("invalid duration: %w", err)
+		}
+		window.Duration = duration
+	}
+	if v.Start != nil {
+		window.Start = *v.Start
+	}
+	if v.End != nil {
+		window.End = *v.End
+	}
+	if v.LabelSelector != nil {
+		window.LabelSelector = *v.LabelSelector
+	}
+	*w = window
+	return nil
+}
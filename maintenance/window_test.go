@@ -0,0 +1,183 @@
+package maintenance
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/gzuidhof/flipper/resource"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWindowActive(t *testing.T) {
+	t.Parallel()
+
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 1, day, hour, minute, 0, 0, time.UTC)
+	}
+	// Every Sunday (the 7th) from 03:00 to 05:00.
+	weekly := Window{ID: "weekly", Schedule: mustParseSchedule(t, "0 3 * * sun"), Duration: 2 * time.Hour}
+	// Every 30 minutes for an hour, so the occurrences overlap.
+	overlapping := Window{ID: "overlapping", Schedule: mustParseSchedule(t, "*/30 * * * *"), Duration: time.Hour}
+	oneOff := Window{ID: "once", Start: at(2, 22, 0), End: at(3, 1, 0)}
+
+	for _, tc := range []struct {
+		name           string
+		window         Window
+		now            time.Time
+		expectedActive bool
+		expectedUntil  time.Time
+		expectedChange time.Time
+	}{
+		{name: "weekly before", window: weekly, now: at(7, 2, 59), expectedChange: at(7, 3, 0)},
+		{
+			name: "weekly start", window: weekly, now: at(7, 3, 0),
+			expectedActive: true, expectedUntil: at(7, 5, 0), expectedChange: at(7, 5, 0),
+		},
+		{
+			name: "weekly during", window: weekly, now: at(7, 4, 59),
+			expectedActive: true, expectedUntil: at(7, 5, 0), expectedChange: at(7, 5, 0),
+		},
+		{name: "weekly end", window: weekly, now: at(7, 5, 0), expectedChange: at(14, 3, 0)},
+		{
+			name: "overlapping", window: overlapping, now: at(1, 0, 45),
+			expectedActive: true, expectedUntil: at(1, 1, 30), expectedChange: at(1, 1, 30),
+		},
+		{name: "one-off before", window: oneOff, now: at(2, 21, 0), expectedChange: at(2, 22, 0)},
+		{
+			name: "one-off during", window: oneOff, now: at(2, 23, 0),
+			expectedActive: true, expectedUntil: at(3, 1, 0), expectedChange: at(3, 1, 0),
+		},
+		{name: "one-off after", window: oneOff, now: at(3, 1, 0)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			until, active := tc.window.Active(tc.now)
+			assert.Equal(t, tc.expectedActive, active)
+			if tc.expectedActive {
+				assert.Equal(t, tc.expectedUntil, until)
+			}
+			assert.Equal(t, tc.expectedChange, tc.window.NextChange(tc.now))
+		})
+	}
+
+	assert.False(t, weekly.Expired(at(30, 0, 0)), "recurring windows never expire")
+	assert.False(t, oneOff.Expired(at(2, 23, 0)))
+	assert.True(t, oneOff.Expired(at(3, 1, 0)))
+}
+
+func TestWindowValidate(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	schedule := mustParseSchedule(t, "0 3 * * *")
+	selector, err := ParseSelector("role=lb")
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name     string
+		window   Window
+		expected string
+	}{
+		{name: "recurring", window: Window{ID: "w", Schedule: schedule, Duration: time.Hour}},
+		{name: "one-off", window: Window{ID: "w", Start: start, End: start.Add(time.Hour), Mode: ModeDrain}},
+		{
+			name:     "no ID",
+			window:   Window{Schedule: schedule, Duration: time.Hour},
+			expected: "the ID of a maintenance window is required",
+		},
+		{
+			name:     "invalid mode",
+			window:   Window{ID: "w", Schedule: schedule, Duration: time.Hour, Mode: "pause"},
+			expected: `invalid mode "pause"`,
+		},
+		{
+			name:     "no duration",
+			window:   Window{ID: "w", Schedule: schedule},
+			expected: "a recurring maintenance window needs a positive duration",
+		},
+		{
+			name:     "schedule and start",
+			window:   Window{ID: "w", Schedule: schedule, Duration: time.Hour, Start: start},
+			expected: "either a schedule or a start and end",
+		},
+		{
+			name:     "end before start",
+			window:   Window{ID: "w", Start: start, End: start},
+			expected: "the end of a maintenance window must be after its start",
+		},
+		{
+			name:     "servers and selector",
+			window:   Window{ID: "w", Schedule: schedule, Duration: time.Hour, Servers: []string{"lb-1"}, LabelSelector: selector},
+			expected: "by name or by label selector, not both",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.window.Validate()
+			if tc.expected == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tc.expected)
+		})
+	}
+}
+
+func TestWindowCovers(t *testing.T) {
+	t.Parallel()
+
+	lb := resource.Server{ServerName: "lb-1", Labels: map[string]string{"role": "lb"}}
+	api := resource.Server{ServerName: "api-1", Labels: map[string]string{"role": "api"}}
+	selector, err := ParseSelector("role=lb")
+	require.NoError(t, err)
+
+	group := Window{}
+	assert.True(t, group.Covers(lb))
+	assert.True(t, group.Covers(api))
+
+	byName := Window{Servers: []string{"api-1"}}
+	assert.False(t, byName.Covers(lb))
+	assert.True(t, byName.Covers(api))
+
+	byLabel := Window{LabelSelector: selector}
+	assert.True(t, byLabel.Covers(lb))
+	assert.False(t, byLabel.Covers(api))
+}
+
+func TestWindowJSON(t *testing.T) {
+	t.Parallel()
+
+	selector, err := ParseSelector("role=lb")
+	require.NoError(t, err)
+	start := time.Date(2024, 1, 1, 22, 0, 0, 0, time.UTC)
+
+	for _, window := range []Window{
+		{
+			ID: "weekly", Mode: ModeDrain, Notifications: NotificationsMute, Schedule: mustParseSchedule(t, "0 3 * * sun"),
+			Duration: 90 * time.Minute, LabelSelector: selector, Reason: "updates",
+		},
+		{
+			ID: "once", Mode: ModeSuppress, Notifications: NotificationsAnnotate, Start: start, End: start.Add(time.Hour),
+			Servers: []string{"lb-1"},
+		},
+	} {
+		data, err := json.Marshal(window)
+		require.NoError(t, err)
+
+		var decoded Window
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		assert.True(t, window.Equal(decoded), "%s round-trips: %s", window.ID, data)
+	}
+}
+
+func mustParseSchedule(t *testing.T, expr string) Schedule {
+	t.Helper()
+
+	s, err := ParseSchedule(expr)
+	require.NoError(t, err)
+	return s
+}
@@ -0,0 +1,277 @@
+package monitor
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gzuidhof/flipper/event"
+	"github.com/gzuidhof/flipper/plan"
+)
+
+// PendingPlan is a plan that waits for a human to approve or reject it.
+type PendingPlan struct {
+	Plan plan.Plan `json:"plan"`
+
+	ProposedAt time.Time `json:"proposed_at"`
+	// ExpiresAt is when the plan is dropped, or approved if AutoApprove is set.
+	ExpiresAt   time.Time `json:"expires_at"`
+	AutoApprove bool      `json:"auto_approve"`
+
+	// action is the most recent action with the same moves, so that an approved plan is executed with the most
+	// recent state.
+	action HealthKeeperAction
+	// token authorizes approving or rejecting the plan through the links in the notification.
+	token string
+}
+
+// approvalDecision is a request to approve or reject the pending plan.
+type approvalDecision struct {
+	planID  uuid.UUID
+	approve bool
+	// token is the token of the plan, empty if the request is authorized otherwise (i.e. with the admin token).
+	token  string
+	result chan error
+}
+
+// requiresApproval returns true if moving the floating IP needs to be approved by a human.
+func (g *Group) requiresApproval(floatingIPName string) bool {
+	for _, override := range g.cfg.FloatingIPOverrides {
+		if override.Name == floatingIPName && override.RequireApproval != nil {
+			return *override.RequireApproval
+		}
+	}
+	return g.cfg.Approval.Required
+}
+
+// splitForApproval splits the plan of an action into the actions that can be executed right away and those that
+// need approval. Both plans keep the ID of the original plan.
+func (g *Group) splitForApproval(action HealthKeeperAction) (plan.Plan, plan.Plan) {
+	auto, needsApproval := action.Plan, action.Plan
+	auto.Actions, needsApproval.Actions = nil, nil
+	for _, a := range action.Plan.Actions {
+		if g.requiresApproval(action.State.FloatingIPs[a.FloatingIPID].Name()) {
+			needsApproval.Actions = append(needsApproval.Actions, a)
+			continue
+		}
+		auto.Actions = append(auto.Actions, a)
+	}
+	return auto, needsApproval
+}
+
+// propose puts a plan in the pending queue. If the same moves are already pending, only the state they are executed
+// with is updated. Otherwise the plan replaces the pending plan, as that was made for a state that changed since.
+func (g *Group) propose(ctx context.Context, logger *slog.Logger, action HealthKeeperAction, p plan.Plan) {
+	action.Plan = p
+
+	g.pendingMu.Lock()
+	previous := g.pending
+	if previous != nil && sameMoves(previous.Plan.Actions, p.Actions) {
+		previous.action = action
+		g.pendingMu.Unlock()
+		return
+	}
+
+	now := g.clock.Now()
+	pending := &PendingPlan{
+		Plan:        p,
+		ProposedAt:  now,
+		ExpiresAt:   now.Add(g.cfg.Approval.TimeoutOrDefault()),
+		AutoApprove: g.cfg.Approval.AutoApprove,
+		action:      action,
+		token:       newApprovalToken(),
+	}
+	g.pending = pending
+	g.pendingMu.Unlock()
+	g.resetPendingTimer(pending.ExpiresAt)
+
+	e := event.PlanPending{
+		Meta:        event.NewMeta(g.cfg, now),
+		Plan:        p,
+		ExpiresAt:   pending.ExpiresAt,
+		AutoApprove: pending.AutoApprove,
+		State:       action.State,
+	}
+	if previous != nil {
+		e.Replaces = previous.Plan.ID.String()
+	}
+	if linkURL := g.cfg.Approval.LinkURL; linkURL != "" {
+		base := fmt.Sprintf("%s/v1/groups/%s/plans/%s", linkURL, g.cfg.ID, p.ID)
+		e.ApproveURL = base + "/approve?token=" + pending.token
+		e.RejectURL = base + "/reject?token=" + pending.token
+	}
+
+	logger.WarnContext(ctx, "Plan needs approval.",
+		slog.Int("num_actions", len(p.Actions)),
+		slog.Time("expires_at", pending.ExpiresAt),
+	)
+	g.events.Publish(ctx, e)
+}
+
+// decide approves or rejects the pending plan. It returns the action to execute if the plan was approved.
+// Plans that were made for resources that changed since (i.e. with a sequence lower than the minimum sequence) can
+// not be approved, a new plan is proposed instead.
+func (g *Group) decide(ctx context.Context, decision approvalDecision, minSequence uint64) (HealthKeeperAction, error) {
+	g.pendingMu.Lock()
+	pending := g.pending
+	if pending == nil || pending.Plan.ID != decision.planID {
+		g.pendingMu.Unlock()
+		return HealthKeeperAction{}, fmt.Errorf("%w: %s", ErrPlanNotFound, decision.planID)
+	}
+	if decision.token != "" && subtle.ConstantTimeCompare([]byte(decision.token), []byte(pending.token)) != 1 {
+		g.pendingMu.Unlock()
+		return HealthKeeperAction{}, ErrInvalidApprovalToken
+	}
+	g.pending = nil
+	g.pendingMu.Unlock()
+	g.resetPendingTimer(time.Time{})
+
+	logger := g.logger.With(slog.String("plan_id", pending.Plan.ID.String()))
+	if !decision.approve {
+		logger.InfoContext(ctx, "Plan rejected.")
+		g.notifyDecision(ctx, pending, event.DecisionRejected)
+		return HealthKeeperAction{}, nil
+	}
+
+	if pending.action.ResourcesSequence < minSequence {
+		logger.WarnContext(ctx, "Approved plan is stale, dropping it.")
+		g.notifyDecision(ctx, pending, event.DecisionStale)
+		return HealthKeeperAction{}, fmt.Errorf("%w: %s", ErrPlanStale, decision.planID)
+	}
+
+	logger.InfoContext(ctx, "Plan approved.")
+	g.notifyDecision(ctx, pending, event.DecisionApproved)
+	return pending.action, nil
+}
+
+// expirePending drops the pending plan after its timeout, or approves it if auto-approve is enabled.
+// It returns the action to execute if the plan was approved.
+func (g *Group) expirePending(ctx context.Context, minSequence uint64) (HealthKeeperAction, bool) {
+	g.pendingMu.Lock()
+	pending := g.pending
+	g.pending = nil
+	g.pendingMu.Unlock()
+	g.resetPendingTimer(time.Time{})
+
+	if pending == nil {
+		return HealthKeeperAction{}, false
+	}
+
+	logger := g.logger.With(slog.String("plan_id", pending.Plan.ID.String()))
+	if !pending.AutoApprove || pending.action.ResourcesSequence < minSequence {
+		logger.WarnContext(ctx, "Plan expired without approval.")
+		g.notifyDecision(ctx, pending, event.DecisionExpired)
+		return HealthKeeperAction{}, false
+	}
+
+	logger.WarnContext(ctx, "Plan approved automatically after the approval timeout.")
+	g.notifyDecision(ctx, pending, event.DecisionAutoApproved)
+	return pending.action, true
+}
+
+// dropPending drops the pending plan, if any, because the moves are no longer needed.
+func (g *Group) dropPending(ctx context.Context) {
+	g.pendingMu.Lock()
+	pending := g.pending
+	g.pending = nil
+	g.pendingMu.Unlock()
+
+	if pending == nil {
+		return
+	}
+	g.resetPendingTimer(time.Time{})
+
+	g.logger.InfoContext(ctx, "Pending plan is no longer needed, dropping it.",
+		slog.String("plan_id", pending.Plan.ID.String()),
+	)
+	g.notifyDecision(ctx, pending, event.DecisionUnneeded)
+}
+
+// notifyDecision notifies what happened to a pending plan.
+func (g *Group) notifyDecision(ctx context.Context, pending *PendingPlan, decision string) {
+	g.events.Publish(ctx, event.PlanDecided{
+		Meta:     event.NewMeta(g.cfg, g.clock.Now()),
+		Plan:     pending.Plan,
+		Decision: decision,
+		State:    pending.action.State,
+	})
+}
+
+// resetPendingTimer sets the timer that fires when the pending plan expires, or stops it for the zero time.
+// It must only be called from the group loop.
+func (g *Group) resetPendingTimer(expiresAt time.Time) {
+	if g.pendingTimer != nil {
+		g.pendingTimer.Stop()
+		g.pendingTimer = nil
+	}
+	if !expiresAt.IsZero() {
+		g.pendingTimer = g.clock.NewTimer(expiresAt.Sub(g.clock.Now()))
+	}
+}
+
+// pendingExpired returns a channel that receives when the pending plan expires, nil if there is no pending plan.
+func (g *Group) pendingExpired() <-chan time.Time {
+	if g.pendingTimer == nil {
+		return nil
+	}
+	return g.pendingTimer.C()
+}
+
+// PendingPlan returns the plan that waits for approval, it returns false if there is none.
+func (g *Group) PendingPlan() (PendingPlan, bool) {
+	g.pendingMu.Lock()
+	defer g.pendingMu.Unlock()
+
+	if g.pending == nil {
+		return PendingPlan{}, false
+	}
+	return *g.pending, true
+}
+
+// Decide approves or rejects the pending plan with the given ID. The token is the token from the links in the
+// notification of the plan, it must be empty if the request is authorized otherwise (e.g. with the admin token).
+// It is safe to call from any goroutine, it blocks until the decision is handled.
+func (g *Group) Decide(ctx context.Context, planID uuid.UUID, approve bool, token string) error {
+	decision := approvalDecision{planID: planID, approve: approve, token: token, result: make(chan error, 1)}
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("failed to request decision: %w", ctx.Err())
+	case g.decisions <- decision:
+	}
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("failed to wait for decision: %w", ctx.Err())
+	case err := <-decision.result:
+		return err
+	}
+}
+
+// sameMoves returns true if both lists of actions move the same floating IPs to the same servers.
+func sameMoves(a, b []plan.ReassignFloatingIPAction) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	moves := make(map[string]string, len(a))
+	for _, action := range a {
+		moves[action.FloatingIPID] = action.ServerID
+	}
+	for _, action := range b {
+		if serverID, ok := moves[action.FloatingIPID]; !ok || serverID != action.ServerID {
+			return false
+		}
+	}
+	return true
+}
+
+// newApprovalToken returns a random token to authorize the approval links of a plan.
+func newApprovalToken() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,160 @@
+package monitor
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gzuidhof/flipper/clock"
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+	"github.com/gzuidhof/flipper/plan"
+	"github.com/gzuidhof/flipper/resource"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApproval(t *testing.T) {
+	t.Parallel()
+
+	yes, no := true, false
+	state := plan.NewState([]resource.FloatingIP{
+		{HetznerID: 11, FloatingIPName: "critical"},
+		{HetznerID: 12, FloatingIPName: "other"},
+		{HetznerID: 13, FloatingIPName: "excluded"},
+	}, []*resource.WithStatus[resource.Server]{
+		resource.NewWithStatus(resource.Server{HetznerID: 1, ServerName: "lb-1"}, resource.State{}),
+		resource.NewWithStatus(resource.Server{HetznerID: 2, ServerName: "lb-2"}, resource.State{}),
+	})
+	newAction := func(sequence uint64, serverID string) HealthKeeperAction {
+		return HealthKeeperAction{
+			ResourcesSequence: sequence,
+			State:             state,
+			Plan: plan.Plan{ID: uuid.New(), Actions: []plan.ReassignFloatingIPAction{
+				{FloatingIPID: "11", ServerID: serverID},
+				{FloatingIPID: "12", ServerID: serverID},
+				{FloatingIPID: "13", ServerID: serverID},
+			}},
+		}
+	}
+	newGroup := func(approval cfgmodel.ApprovalConfig) (*Group, *recordingNotifier) {
+		notifier := &recordingNotifier{}
+		return &Group{
+			cfg: cfgmodel.GroupConfig{
+				Approval: approval,
+				FloatingIPOverrides: []cfgmodel.FloatingIPOverrideConfig{
+					{Name: "critical", RequireApproval: &yes},
+					{Name: "excluded", RequireApproval: &no},
+				},
+			},
+			logger: slog.Default(),
+			events: notifyingBus(notifier),
+			clock:  clock.Real{},
+		}, notifier
+	}
+	ctx := context.Background()
+
+	t.Run("split", func(t *testing.T) {
+		t.Parallel()
+
+		g, _ := newGroup(cfgmodel.ApprovalConfig{})
+		auto, needsApproval := g.splitForApproval(newAction(1, "1"))
+		assert.Equal(t, []string{"12", "13"}, floatingIPIDs(auto.Actions))
+		assert.Equal(t, []string{"11"}, floatingIPIDs(needsApproval.Actions))
+
+		g, _ = newGroup(cfgmodel.ApprovalConfig{Required: true})
+		auto, needsApproval = g.splitForApproval(newAction(1, "1"))
+		assert.Equal(t, []string{"13"}, floatingIPIDs(auto.Actions))
+		assert.Equal(t, []string{"11", "12"}, floatingIPIDs(needsApproval.Actions))
+	})
+
+	t.Run("approve", func(t *testing.T) {
+		t.Parallel()
+
+		g, notifier := newGroup(cfgmodel.ApprovalConfig{LinkURL: "https://flipper.example.com"})
+		action := newAction(1, "1")
+		_, needsApproval := g.splitForApproval(action)
+		g.propose(ctx, g.logger, action, needsApproval)
+		assert.Len(t, notifier.messages, 1)
+		assert.Contains(t, notifier.messages[0], "https://flipper.example.com/v1/groups//plans/"+action.Plan.ID.String())
+
+		// The same moves keep the pending plan, but the most recent state is used to execute it.
+		g.propose(ctx, g.logger, newAction(2, "1"), needsApproval)
+		assert.Len(t, notifier.messages, 1)
+		pending, ok := g.PendingPlan()
+		assert.True(t, ok)
+		assert.Equal(t, action.Plan.ID, pending.Plan.ID)
+
+		_, err := g.decide(ctx, approvalDecision{planID: uuid.New(), approve: true}, 0)
+		assert.ErrorIs(t, err, ErrPlanNotFound)
+		_, err = g.decide(ctx, approvalDecision{planID: action.Plan.ID, approve: true, token: "wrong"}, 0)
+		assert.ErrorIs(t, err, ErrInvalidApprovalToken)
+
+		approved, err := g.decide(ctx,
+			approvalDecision{planID: action.Plan.ID, approve: true, token: pending.token}, 2,
+		)
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(2), approved.ResourcesSequence)
+		assert.Equal(t, []string{"11"}, floatingIPIDs(approved.Plan.Actions))
+		_, ok = g.PendingPlan()
+		assert.False(t, ok)
+	})
+
+	t.Run("replace_and_reject", func(t *testing.T) {
+		t.Parallel()
+
+		g, notifier := newGroup(cfgmodel.ApprovalConfig{})
+		first := newAction(1, "1")
+		_, needsApproval := g.splitForApproval(first)
+		g.propose(ctx, g.logger, first, needsApproval)
+
+		second := newAction(2, "2")
+		_, needsApproval = g.splitForApproval(second)
+		g.propose(ctx, g.logger, second, needsApproval)
+		assert.Len(t, notifier.messages, 2)
+		assert.Contains(t, notifier.messages[1], "It replaces plan `"+first.Plan.ID.String()+"`")
+
+		_, err := g.decide(ctx, approvalDecision{planID: first.Plan.ID, approve: true}, 0)
+		assert.ErrorIs(t, err, ErrPlanNotFound)
+
+		_, err = g.decide(ctx, approvalDecision{planID: second.Plan.ID, approve: false}, 0)
+		assert.NoError(t, err)
+		_, ok := g.PendingPlan()
+		assert.False(t, ok)
+	})
+
+	t.Run("stale", func(t *testing.T) {
+		t.Parallel()
+
+		g, _ := newGroup(cfgmodel.ApprovalConfig{})
+		action := newAction(1, "1")
+		_, needsApproval := g.splitForApproval(action)
+		g.propose(ctx, g.logger, action, needsApproval)
+
+		_, err := g.decide(ctx, approvalDecision{planID: action.Plan.ID, approve: true}, 2)
+		assert.ErrorIs(t, err, ErrPlanStale)
+	})
+
+	t.Run("expire", func(t *testing.T) {
+		t.Parallel()
+
+		for _, autoApprove := range []bool{false, true} {
+			g, _ := newGroup(cfgmodel.ApprovalConfig{AutoApprove: autoApprove})
+			action := newAction(1, "1")
+			_, needsApproval := g.splitForApproval(action)
+			g.propose(ctx, g.logger, action, needsApproval)
+			assert.NotNil(t, g.pendingExpired())
+
+			_, approved := g.expirePending(ctx, 0)
+			assert.Equal(t, autoApprove, approved)
+			assert.Nil(t, g.pendingExpired())
+		}
+	})
+}
+
+func floatingIPIDs(actions []plan.ReassignFloatingIPAction) []string {
+	ids := make([]string, 0, len(actions))
+	for _, action := range actions {
+		ids = append(ids, action.FloatingIPID)
+	}
+	return ids
+}
@@ -0,0 +1,90 @@
+package monitor
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/gzuidhof/flipper/consensus"
+	"github.com/gzuidhof/flipper/event"
+	"github.com/gzuidhof/flipper/plan"
+	"github.com/gzuidhof/flipper/resource"
+)
+
+// shareServerState shares the status of a server and its IP families as seen by this node with the other nodes.
+func (h *HealthKeeper) shareServerState(serverID string, state resource.State) {
+	if h.consensus == nil {
+		return
+	}
+	h.consensus.SetLocal(h.cfg.ID, serverID, state)
+}
+
+// unshareServer stops sharing the status of a server that was removed from the group.
+func (h *HealthKeeper) unshareServer(serverID string) {
+	if h.consensus == nil {
+		return
+	}
+	h.consensus.RemoveLocal(h.cfg.ID, serverID)
+}
+
+// verdict returns what the nodes agree on about a server, for the events about it. It is nil if there are no
+// other nodes.
+func (h *HealthKeeper) verdict(server *resource.WithStatus[resource.Server]) *consensus.Verdict {
+	if h.consensus == nil || server == nil {
+		return nil
+	}
+	verdict := h.consensus.Verdict(h.cfg.ID, server.Resource.ID(), server.Status(), h.clock.Now())
+	return &verdict
+}
+
+// consensusState returns the state with the statuses of the servers that the nodes agree on. Servers whose status
+// differs from what this node sees are replaced by a copy, the state of this node is not changed.
+func (h *HealthKeeper) consensusState(ctx context.Context) plan.State {
+	if h.consensus == nil {
+		return h.state
+	}
+
+	now := h.clock.Now()
+	state := h.state
+	state.Servers = make(map[string]*resource.WithStatus[resource.Server], len(h.state.Servers))
+	overridden := make(map[string]consensus.Verdict)
+	for serverID, server := range h.state.Servers {
+		local := server.State()
+		verdict := h.consensus.Verdict(h.cfg.ID, serverID, local.Status, now)
+		agreed := local
+		agreed.Status = verdict.Status
+		// The IP families that are checked separately get their own verdict, so that a server that is only
+		// unhealthy over one of them keeps the floating IPs of the other.
+		if local.IPv4 != "" {
+			agreed.IPv4 = h.consensus.FamilyVerdict(h.cfg.ID, serverID, resource.IPFamilyIPv4, local.IPv4, now).Status
+		}
+		if local.IPv6 != "" {
+			agreed.IPv6 = h.consensus.FamilyVerdict(h.cfg.ID, serverID, resource.IPFamilyIPv6, local.IPv6, now).Status
+		}
+		if agreed == local {
+			state.Servers[serverID] = server
+			continue
+		}
+		state.Servers[serverID] = resource.NewWithStatus(server.Resource, agreed)
+		if !verdict.Overrides() {
+			continue
+		}
+		overridden[serverID] = verdict
+
+		if previous, ok := h.overridden[serverID]; ok && previous.Status == verdict.Status {
+			continue
+		}
+		h.logger.WarnContext(ctx, "Status of server differs from what the other nodes see.",
+			slog.String("server_id", serverID),
+			slog.String("local_status", string(local.Status)),
+			slog.String("agreed_status", string(verdict.Status)),
+		)
+		h.events.Publish(ctx, event.VerdictDiffers{
+			Meta:    event.NewMeta(h.cfg, h.clock.Now()),
+			Server:  server.Resource,
+			Local:   local.Status,
+			Verdict: verdict,
+		})
+	}
+	h.overridden = overridden
+	return state
+}
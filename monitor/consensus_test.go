@@ -0,0 +1,129 @@
+package monitor
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+	"github.com/gzuidhof/flipper/consensus"
+	"github.com/gzuidhof/flipper/event"
+	"github.com/gzuidhof/flipper/plan"
+	"github.com/gzuidhof/flipper/provider/mock"
+	"github.com/gzuidhof/flipper/resource"
+	"github.com/stretchr/testify/assert"
+)
+
+// viewFetcher returns a fixed view.
+type viewFetcher struct {
+	view consensus.View
+}
+
+func (f viewFetcher) Fetch(context.Context) (consensus.View, error) {
+	return f.view, nil
+}
+
+func TestConsensusState(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	peerView := func(id string, status resource.Status) consensus.Peer {
+		return consensus.Peer{ID: id, Fetcher: viewFetcher{view: consensus.View{
+			NodeID: id,
+			Groups: map[string]map[string]resource.Status{"lb": {"1": status, "2": resource.StatusHealthy}},
+		}}}
+	}
+
+	node := consensus.NewNodeWithPeers(
+		cfgmodel.ConsensusConfig{NodeID: "a", Peers: make([]cfgmodel.ConsensusPeerConfig, 2)},
+		[]consensus.Peer{peerView("b", resource.StatusHealthy), peerView("c", resource.StatusHealthy)},
+		slog.Default(),
+	)
+	node.Start(canceledContext())
+
+	notifier := &recordingNotifier{}
+	h := NewHealthKeeper(cfgmodel.GroupConfig{ID: "lb"}, slog.Default(), mock.NewProvider(), notifyingBus(notifier))
+	h.consensus = node
+	h.state = plan.NewState(nil, []*resource.WithStatus[resource.Server]{
+		resource.NewWithStatus(resource.Server{HetznerID: 1, ServerName: "lb-1"},
+			resource.State{Status: resource.StatusUnhealthy, IPv4: resource.StatusUnhealthy}),
+		resource.NewWithStatus(resource.Server{HetznerID: 2, ServerName: "lb-2"},
+			resource.State{Status: resource.StatusHealthy}),
+	})
+
+	state := h.consensusState(ctx)
+	assert.Equal(t, resource.StatusHealthy, state.Servers["1"].State().StatusFor(resource.IPFamilyIPv4),
+		"the other nodes see the server as healthy",
+	)
+	assert.Equal(t, resource.StatusUnhealthy, h.state.Servers["1"].Status(), "the local state is not changed")
+	assert.Same(t, h.state.Servers["2"], state.Servers["2"])
+	assert.Len(t, notifier.messages, 1)
+
+	// The same verdict is not notified again.
+	h.consensusState(ctx)
+	assert.Len(t, notifier.messages, 1)
+}
+
+// canceledContext returns a context that is already cancelled, so that loops run once.
+func canceledContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}
+
+func TestConsensusStateFamilies(t *testing.T) {
+	t.Parallel()
+
+	// The other nodes can reach the server over IPv4, but not over IPv6.
+	ipv6Down := func(id string) consensus.Peer {
+		return consensus.Peer{ID: id, Fetcher: viewFetcher{view: consensus.View{
+			NodeID: id,
+			Groups: map[string]map[string]resource.Status{"lb": {"1": resource.StatusUnhealthy}},
+			Families: map[string]map[string]map[resource.IPFamily]resource.Status{"lb": {"1": {
+				resource.IPFamilyIPv4: resource.StatusHealthy,
+				resource.IPFamilyIPv6: resource.StatusUnhealthy,
+			}}},
+		}}}
+	}
+
+	for _, tc := range []struct {
+		name  string
+		local resource.State
+	}{
+		{
+			name: "agrees",
+			local: resource.State{
+				Status: resource.StatusUnhealthy, IPv4: resource.StatusHealthy, IPv6: resource.StatusUnhealthy,
+			},
+		},
+		{
+			name: "outvoted",
+			local: resource.State{
+				Status: resource.StatusHealthy, IPv4: resource.StatusHealthy, IPv6: resource.StatusHealthy,
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			node := consensus.NewNodeWithPeers(
+				cfgmodel.ConsensusConfig{NodeID: "a", Peers: make([]cfgmodel.ConsensusPeerConfig, 2)},
+				[]consensus.Peer{ipv6Down("b"), ipv6Down("c")},
+				slog.Default(),
+			)
+			node.Start(canceledContext())
+
+			h := NewHealthKeeper(cfgmodel.GroupConfig{ID: "lb"}, slog.Default(), mock.NewProvider(), event.NewBus())
+			h.consensus = node
+			h.state = plan.NewState(nil, []*resource.WithStatus[resource.Server]{
+				resource.NewWithStatus(resource.Server{HetznerID: 1, ServerName: "lb-1"}, tc.local),
+			})
+
+			agreed := h.consensusState(context.Background()).Servers["1"].State()
+			assert.Equal(t, resource.StatusUnhealthy, agreed.Status)
+			assert.Equal(t, resource.StatusHealthy, agreed.StatusFor(resource.IPFamilyIPv4),
+				"the floating IPs of the IPv4 family stay on the server")
+			assert.Equal(t, resource.StatusUnhealthy, agreed.StatusFor(resource.IPFamilyIPv6))
+		})
+	}
+}
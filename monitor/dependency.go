@@ -0,0 +1,180 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+	"github.com/gzuidhof/flipper/event"
+	"github.com/gzuidhof/flipper/resource"
+)
+
+// LocationHealth is the health of the servers of a group in a location.
+type LocationHealth struct {
+	Healthy   int
+	Unhealthy int
+	// Total includes the servers with an unknown status.
+	Total int
+}
+
+// dependency is a group that a group depends on.
+type dependency struct {
+	cfg    cfgmodel.DependencyConfig
+	source *HealthKeeper
+}
+
+// unavailableReason returns why the location is unavailable according to this dependency, empty if it is available.
+// Locations in which none of the servers of the other group have a known status are available.
+func (d dependency) unavailableReason(location string, health LocationHealth) string {
+	known := health.Healthy + health.Unhealthy
+	if known == 0 {
+		return ""
+	}
+	if d.cfg.MinHealthyFraction == 0 && health.Healthy > 0 {
+		return ""
+	}
+	if d.cfg.MinHealthyFraction > 0 && float64(health.Healthy)/float64(known) >= d.cfg.MinHealthyFraction {
+		return ""
+	}
+	return fmt.Sprintf("group %s has %d/%d healthy servers in %s", d.cfg.Group, health.Healthy, known, location)
+}
+
+// wireDependencies makes the healthkeeper of every group aware of the groups it depends on. The dependencies are
+// validated with the config, groups that can not be found are ignored. It replaces the previous wiring, so it is
+// called again when groups are added or removed.
+func wireDependencies(groups []*Group) {
+	byID := make(map[string]*Group, len(groups))
+	for _, group := range groups {
+		byID[group.ID()] = group
+	}
+
+	dependencies := make(map[*HealthKeeper][]dependency, len(groups))
+	dependents := make(map[*HealthKeeper][]*HealthKeeper, len(groups))
+	for _, group := range groups {
+		for _, dependencyCfg := range group.config().DependsOn {
+			if other, ok := byID[dependencyCfg.Group]; ok {
+				dependencies[group.healthkeeper] = append(dependencies[group.healthkeeper],
+					dependency{cfg: dependencyCfg, source: other.healthkeeper},
+				)
+				dependents[other.healthkeeper] = append(dependents[other.healthkeeper], group.healthkeeper)
+			}
+		}
+	}
+
+	for _, group := range groups {
+		h := group.healthkeeper
+		h.dependenciesMu.Lock()
+		h.dependencies = dependencies[h]
+		h.dependents = dependents[h]
+		h.dependenciesMu.Unlock()
+	}
+}
+
+// LocationHealth returns the health of the servers of the group by location.
+// It is safe to call from any goroutine.
+func (h *HealthKeeper) LocationHealth() map[string]LocationHealth {
+	h.locationHealthMu.Lock()
+	defer h.locationHealthMu.Unlock()
+
+	return maps.Clone(h.locationHealth)
+}
+
+// publishLocationHealth updates the health by location that is exposed to the groups depending on this group, and
+// asks them to create a new plan if it changed.
+func (h *HealthKeeper) publishLocationHealth() {
+	health := map[string]LocationHealth{}
+	for _, server := range h.state.Servers {
+		location := health[server.Resource.Location]
+		location.Total++
+		switch server.Status() {
+		case resource.StatusHealthy:
+			location.Healthy++
+		case resource.StatusUnhealthy:
+			location.Unhealthy++
+		case resource.StatusUnknown:
+		}
+		health[server.Resource.Location] = location
+	}
+
+	h.locationHealthMu.Lock()
+	changed := !maps.Equal(h.locationHealth, health)
+	h.locationHealth = health
+	h.locationHealthMu.Unlock()
+
+	if !changed {
+		return
+	}
+
+	h.dependenciesMu.Lock()
+	dependents := h.dependents
+	h.dependenciesMu.Unlock()
+	for _, dependent := range dependents {
+		dependent.RequestPlan()
+	}
+}
+
+// syncDependencies updates the unavailable locations of the state from the groups this group depends on, and
+// notifies when they change.
+func (h *HealthKeeper) syncDependencies(ctx context.Context) {
+	h.dependenciesMu.Lock()
+	dependencies := h.dependencies
+	h.dependenciesMu.Unlock()
+	if len(dependencies) == 0 && len(h.state.UnavailableLocations) == 0 {
+		return
+	}
+
+	unavailable := map[string]string{}
+	for _, dep := range dependencies {
+		for location, health := range dep.source.LocationHealth() {
+			if reason := dep.unavailableReason(location, health); reason != "" && unavailable[location] == "" {
+				unavailable[location] = reason
+			}
+		}
+	}
+
+	previous := h.state.UnavailableLocations
+	// The state is shared with the actions that were sent, so the map is replaced rather than changed.
+	h.state.UnavailableLocations = unavailable
+	if maps.Equal(previous, unavailable) {
+		return
+	}
+
+	for _, location := range sortedKeys(unavailable) {
+		if _, ok := previous[location]; ok {
+			continue
+		}
+		h.logger.WarnContext(ctx, "Location became unavailable because of a dependency.",
+			slog.String("location", location),
+			slog.String("reason", unavailable[location]),
+		)
+		h.events.Publish(ctx, event.LocationAvailabilityChanged{
+			Meta:     event.NewMeta(h.cfg, h.clock.Now()),
+			Location: location,
+			Reason:   unavailable[location],
+		})
+	}
+	for _, location := range sortedKeys(previous) {
+		if _, ok := unavailable[location]; ok {
+			continue
+		}
+		h.logger.InfoContext(ctx, "Location became available again.", slog.String("location", location))
+		h.events.Publish(ctx, event.LocationAvailabilityChanged{
+			Meta:      event.NewMeta(h.cfg, h.clock.Now()),
+			Location:  location,
+			Available: true,
+		})
+	}
+}
+
+// sortedKeys returns the keys of the map in order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,83 @@
+package monitor
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+	"github.com/gzuidhof/flipper/plan"
+	"github.com/gzuidhof/flipper/provider/mock"
+	"github.com/gzuidhof/flipper/resource"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncDependencies(t *testing.T) {
+	t.Parallel()
+
+	server := func(id int64, location string, status resource.Status) *resource.WithStatus[resource.Server] {
+		return resource.NewWithStatus(resource.Server{HetznerID: id, Location: location}, resource.State{Status: status})
+	}
+
+	for _, tc := range []struct {
+		name     string
+		fraction float64
+		servers  []*resource.WithStatus[resource.Server]
+
+		expectedUnavailable []string
+	}{
+		{
+			name: "all_healthy",
+			servers: []*resource.WithStatus[resource.Server]{
+				server(1, "fsn1", resource.StatusHealthy),
+				server(2, "nbg1", resource.StatusHealthy),
+			},
+		},
+		{
+			name: "none_healthy_in_location",
+			servers: []*resource.WithStatus[resource.Server]{
+				server(1, "fsn1", resource.StatusUnhealthy),
+				server(2, "nbg1", resource.StatusHealthy),
+			},
+			expectedUnavailable: []string{"fsn1"},
+		},
+		{
+			name: "unknown_is_available",
+			servers: []*resource.WithStatus[resource.Server]{
+				server(1, "fsn1", resource.StatusUnknown),
+			},
+		},
+		{
+			name:     "below_fraction",
+			fraction: 0.5,
+			servers: []*resource.WithStatus[resource.Server]{
+				server(1, "fsn1", resource.StatusHealthy),
+				server(2, "fsn1", resource.StatusUnhealthy),
+				server(3, "fsn1", resource.StatusUnhealthy),
+				server(4, "nbg1", resource.StatusHealthy),
+				server(5, "nbg1", resource.StatusUnhealthy),
+			},
+			expectedUnavailable: []string{"fsn1"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			api := NewGroup(cfgmodel.GroupConfig{ID: "api"}, mock.NewProvider(), slog.Default(), notifyingBus(&recordingNotifier{}))
+			notifier := &recordingNotifier{}
+			lb := NewGroup(cfgmodel.GroupConfig{
+				ID:        "lb",
+				DependsOn: []cfgmodel.DependencyConfig{{Group: "api", MinHealthyFraction: tc.fraction}},
+			}, mock.NewProvider(), slog.Default(), notifyingBus(notifier))
+			wireDependencies([]*Group{api, lb})
+
+			api.healthkeeper.state = plan.NewState(nil, tc.servers)
+			api.healthkeeper.publishLocationHealth()
+			assert.Len(t, lb.healthkeeper.planRequests, 1)
+
+			lb.healthkeeper.syncDependencies(context.Background())
+			assert.ElementsMatch(t, tc.expectedUnavailable, sortedKeys(lb.healthkeeper.state.UnavailableLocations))
+			assert.Len(t, notifier.messages, len(tc.expectedUnavailable))
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package monitor
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+	"github.com/gzuidhof/flipper/event"
+	"github.com/gzuidhof/flipper/plan"
+	"github.com/gzuidhof/flipper/resource"
+)
+
+const (
+	// lastAssignmentTimeout is the maximum time to wait for the provider to tell when a floating IP was assigned.
+	lastAssignmentTimeout = 10 * time.Second
+	// assignmentClockSkew is how much earlier than the previous poll an assignment may have started according to the
+	// provider, as its clock may differ from ours.
+	assignmentClockSkew = time.Minute
+)
+
+// driftReport is a drift that was detected and the outcome of its policy.
+type driftReport struct {
+	drift  resource.Drift
+	policy string
+	pin    plan.Pin
+}
+
+// expectedTarget is a set of servers a floating IP may be moved to by flipper itself, until a point in time.
+type expectedTarget struct {
+	serverIDs []string
+	until     time.Time
+}
+
+// expectTargets registers the targets the floating IPs of the actions may get while flipper executes them, so that
+// those changes are not reported as drift. Both the new and the previous target are expected, as a move may be
+// rolled back. It is safe to call from any goroutine.
+func (h *HealthKeeper) expectTargets(state plan.State, actions []plan.ReassignFloatingIPAction, until time.Time) {
+	h.expectedTargetsMu.Lock()
+	defer h.expectedTargetsMu.Unlock()
+
+	for _, action := range actions {
+		serverIDs := []string{action.ServerID}
+		if previous := state.FloatingIPs[action.FloatingIPID].CurrentTarget; previous != "" {
+			serverIDs = append(serverIDs, previous)
+		}
+		h.expectedTargets[action.FloatingIPID] = expectedTarget{serverIDs: serverIDs, until: until}
+	}
+}
+
+// isExpectedTarget returns true if flipper itself may have moved the floating IP to the server.
+func (h *HealthKeeper) isExpectedTarget(now time.Time, floatingIPID string, serverID string) bool {
+	h.expectedTargetsMu.Lock()
+	defer h.expectedTargetsMu.Unlock()
+
+	expected, ok := h.expectedTargets[floatingIPID]
+	if !ok {
+		return false
+	}
+	if !now.Before(expected.until) {
+		delete(h.expectedTargets, floatingIPID)
+		return false
+	}
+	return slices.Contains(expected.serverIDs, serverID)
+}
+
+// checkDrift checks whether the target of a floating IP changed without flipper moving it since the previous poll,
+// and if so applies the drift policy of the group and notifies about it. If the provider can tell when the floating
+// IP was assigned, that is looked up outside the loop and the notification follows when it is known.
+func (h *HealthKeeper) checkDrift(
+	ctx context.Context,
+	previous resource.FloatingIP,
+	updated resource.FloatingIP,
+	previousPollAt time.Time,
+) {
+	if previous.CurrentTarget == updated.CurrentTarget {
+		return
+	}
+
+	now := h.clock.Now()
+	if h.isExpectedTarget(now, updated.ID(), updated.CurrentTarget) {
+		// Flipper moved the floating IP away from its pinned server (e.g. because it is unhealthy), so the pin no
+		// longer applies.
+		if pin, ok := h.pins[updated.ID()]; ok && pin.ServerID != updated.CurrentTarget {
+			h.setPin(updated.ID(), plan.Pin{})
+			h.syncPins(ctx)
+		}
+		return
+	}
+
+	drift := resource.Drift{
+		FloatingIP: updated,
+		From:       previous.CurrentTarget,
+		To:         updated.CurrentTarget,
+		DetectedAt: now,
+	}
+	// Floating IPs can only be pinned to servers in the group.
+	var pin plan.Pin
+	if _, inGroup := h.state.Servers[drift.To]; inGroup {
+		switch h.cfg.Drift.PolicyOrDefault() {
+		case cfgmodel.DriftPolicyAdopt:
+			pin = plan.Pin{
+				ServerID: drift.To,
+				Until:    now.Add(h.cfg.Drift.AdoptDurationOrDefault()),
+				Reason:   "adopted manual change",
+			}
+		case cfgmodel.DriftPolicyAlert:
+			pin = plan.Pin{
+				ServerID: drift.To,
+				Until:    now.Add(h.cfg.Drift.AlertDurationOrDefault()),
+				Reason:   "manual change",
+			}
+		}
+	}
+	h.setPin(updated.ID(), pin)
+	h.syncPins(ctx)
+
+	h.logger.WarnContext(ctx, "Floating IP was moved outside of flipper.",
+		slog.String("floating_ip_id", updated.ID()),
+		slog.String("from_server_id", drift.From),
+		slog.String("to_server_id", drift.To),
+		slog.String("drift_policy", h.cfg.Drift.PolicyOrDefault()),
+	)
+	report := driftReport{drift: drift, policy: h.cfg.Drift.PolicyOrDefault(), pin: pin}
+	history, ok := h.provider.(resource.AssignmentHistoryProvider)
+	if !ok {
+		h.publishDrift(ctx, report)
+		return
+	}
+	var since time.Time
+	if !previousPollAt.IsZero() {
+		since = previousPollAt.Add(-assignmentClockSkew)
+	}
+	go h.lookupAssignment(ctx, history, report, since)
+}
+
+// lookupAssignment asks the provider when the floating IP of the drift was assigned, and sends the report to the
+// loop to be published. The report is published without the assignment if the provider can't tell.
+func (h *HealthKeeper) lookupAssignment(
+	ctx context.Context,
+	history resource.AssignmentHistoryProvider,
+	report driftReport,
+	since time.Time,
+) {
+	historyCtx, cancel := context.WithTimeout(ctx, lastAssignmentTimeout)
+	assignment, err := history.LastAssignment(historyCtx, report.drift.FloatingIP, since)
+	cancel()
+	if err != nil {
+		h.logger.WarnContext(ctx, "Failed to get last assignment of floating IP.",
+			slog.String("floating_ip_id", report.drift.FloatingIP.ID()),
+			slog.String("error", err.Error()),
+		)
+	} else {
+		report.drift.Assignment = &assignment
+	}
+
+	select {
+	case <-ctx.Done():
+	case h.drifts <- report:
+	}
+}
+
+// publishDrift publishes a drift with the current state of the group.
+func (h *HealthKeeper) publishDrift(ctx context.Context, report driftReport) {
+	h.events.Publish(ctx, event.FloatingIPDrifted{
+		Meta:   event.NewMeta(h.cfg, h.clock.Now()),
+		Drift:  report.drift,
+		Policy: report.policy,
+		Pin:    report.pin,
+		State:  h.state,
+	})
+}
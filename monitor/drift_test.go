@@ -0,0 +1,148 @@
+package monitor
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+	"github.com/gzuidhof/flipper/event"
+	"github.com/gzuidhof/flipper/notification"
+	"github.com/gzuidhof/flipper/plan"
+	"github.com/gzuidhof/flipper/provider/mock"
+	"github.com/gzuidhof/flipper/resource"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingNotifier records the messages it is asked to send.
+type recordingNotifier struct {
+	messages []string
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, message string) error {
+	n.messages = append(n.messages, message)
+	return nil
+}
+
+// notifyingBus returns a bus that sends notifications about its events through the notifier.
+func notifyingBus(notifier notification.Notifier) *event.Bus {
+	bus := event.NewBus()
+	bus.Subscribe(notification.EventHandler(notifier))
+	return bus
+}
+
+func TestCheckDrift(t *testing.T) {
+	t.Parallel()
+
+	previous := resource.FloatingIP{Provider: resource.ProviderNameMock, HetznerID: 11, CurrentTarget: "1"}
+	moved := previous
+	moved.CurrentTarget = "2"
+
+	for _, tc := range []struct {
+		name     string
+		drift    cfgmodel.DriftConfig
+		expected bool
+
+		expectedNotified bool
+		expectedPin      bool
+		expectedExpiry   bool
+	}{
+		{
+			name:             "revert",
+			drift:            cfgmodel.DriftConfig{},
+			expectedNotified: true,
+		},
+		{
+			name:             "adopt",
+			drift:            cfgmodel.DriftConfig{Policy: cfgmodel.DriftPolicyAdopt},
+			expectedNotified: true,
+			expectedPin:      true,
+			expectedExpiry:   true,
+		},
+		{
+			name:             "alert",
+			drift:            cfgmodel.DriftConfig{Policy: cfgmodel.DriftPolicyAlert},
+			expectedNotified: true,
+			expectedPin:      true,
+			expectedExpiry:   true,
+		},
+		{
+			name:     "moved_by_flipper",
+			drift:    cfgmodel.DriftConfig{Policy: cfgmodel.DriftPolicyAdopt},
+			expected: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			notifier := &recordingNotifier{}
+			h := NewHealthKeeper(cfgmodel.GroupConfig{Drift: tc.drift}, slog.Default(), mock.NewProvider(), notifyingBus(notifier))
+			h.state = plan.NewState([]resource.FloatingIP{moved}, []*resource.WithStatus[resource.Server]{
+				resource.NewWithStatus(resource.Server{HetznerID: 1, ServerName: "lb-1"}, resource.State{}),
+				resource.NewWithStatus(resource.Server{HetznerID: 2, ServerName: "lb-2"}, resource.State{}),
+			})
+			if tc.expected {
+				h.expectTargets(h.state, []plan.ReassignFloatingIPAction{{FloatingIPID: "11", ServerID: "2"}},
+					time.Now().Add(time.Minute),
+				)
+			}
+
+			h.checkDrift(context.Background(), previous, moved, time.Now().Add(-time.Minute))
+
+			assert.Equal(t, tc.expectedNotified, len(notifier.messages) == 1)
+			pin, pinned := h.pins["11"]
+			assert.Equal(t, tc.expectedPin, pinned)
+			if pinned {
+				assert.Equal(t, "2", pin.ServerID)
+				assert.Equal(t, tc.expectedExpiry, !pin.Until.IsZero())
+			}
+		})
+	}
+}
+
+// historyProvider is a mock provider that tells when floating IPs were assigned.
+type historyProvider struct {
+	*mock.Provider
+
+	assignment resource.Assignment
+	since      chan time.Time
+}
+
+func (p *historyProvider) LastAssignment(
+	_ context.Context,
+	_ resource.FloatingIP,
+	since time.Time,
+) (resource.Assignment, error) {
+	p.since <- since
+	return p.assignment, nil
+}
+
+func TestCheckDriftLastAssignment(t *testing.T) {
+	t.Parallel()
+
+	previous := resource.FloatingIP{Provider: resource.ProviderNameMock, HetznerID: 11, CurrentTarget: "1"}
+	moved := previous
+	moved.CurrentTarget = "2"
+
+	notifier := &recordingNotifier{}
+	assignedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	provider := &historyProvider{
+		Provider:   mock.NewProvider(),
+		assignment: resource.Assignment{At: assignedAt},
+		since:      make(chan time.Time, 1),
+	}
+	h := NewHealthKeeper(cfgmodel.GroupConfig{}, slog.Default(), provider, notifyingBus(notifier))
+	h.state = plan.NewState([]resource.FloatingIP{moved}, nil)
+
+	previousPollAt := assignedAt.Add(-time.Minute)
+	h.checkDrift(context.Background(), previous, moved, previousPollAt)
+	assert.Empty(t, notifier.messages, "the notification waits for the assignment")
+
+	// The assignment is looked up outside the loop, the loop publishes the report.
+	report := <-h.drifts
+	assert.Equal(t, previousPollAt.Add(-assignmentClockSkew), <-provider.since)
+	h.publishDrift(context.Background(), report)
+	assert.Len(t, notifier.messages, 1)
+	assert.Contains(t, notifier.messages[0], "2024-05-01 12:00:00")
+}
@@ -0,0 +1,31 @@
+package monitor
+
+import "errors"
+
+// ErrGroupNotFound is returned when a group with the given ID is not being monitored.
+var ErrGroupNotFound = errors.New("group not found")
+
+// ErrServerNotFound is returned when a server with the given ID is not part of a group.
+var ErrServerNotFound = errors.New("server not found")
+
+// ErrFloatingIPNotFound is returned when a floating IP with the given ID is not part of a group.
+var ErrFloatingIPNotFound = errors.New("floating IP not found")
+
+// ErrPlanNotFound is returned when a plan with the given ID is not pending approval.
+var ErrPlanNotFound = errors.New("plan not found")
+
+// ErrPlanStale is returned when an approved plan was made for resources that changed since.
+var ErrPlanStale = errors.New("plan is stale")
+
+// ErrInvalidApprovalToken is returned when the token to approve or reject a plan is not valid.
+var ErrInvalidApprovalToken = errors.New("invalid approval token")
+
+// ErrConsensusDisabled is returned when the view of this node is requested, but consensus is disabled.
+var ErrConsensusDisabled = errors.New("consensus is disabled")
+
+// ErrMaintenanceWindowNotFound is returned when a maintenance window with the given ID was not set through the
+// admin API.
+var ErrMaintenanceWindowNotFound = errors.New("maintenance window not found")
+
+// ErrMaintenanceWindowInConfig is returned when a maintenance window of the config is changed through the admin API.
+var ErrMaintenanceWindowInConfig = errors.New("maintenance window is part of the config")
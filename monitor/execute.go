@@ -0,0 +1,235 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+	"github.com/gzuidhof/flipper/event"
+	"github.com/gzuidhof/flipper/plan"
+	"golang.org/x/sync/errgroup"
+)
+
+// executePlan executes the actions of a plan with bounded concurrency, retrying failed reassignments.
+// What happens when an action keeps failing depends on the failure policy of the group: the remaining actions
+// are executed anyway, are skipped, or are skipped and the completed moves are rolled back.
+func (g *Group) executePlan(
+	ctx context.Context,
+	logger *slog.Logger,
+	state plan.State,
+	actionPlan plan.Plan,
+) plan.ExecutionReport {
+	ctx, cancel := context.WithTimeout(ctx, g.cfg.PlanApplyTimeoutOrDefault())
+	defer cancel()
+
+	onFailure := g.cfg.Execution.OnFailureOrDefault()
+	report := plan.ExecutionReport{
+		PlanID:    actionPlan.ID,
+		OnFailure: onFailure,
+		Results: g.executeActions(ctx, logger, state, actionPlan.ID, actionPlan.Actions,
+			onFailure != cfgmodel.OnFailureContinue, false,
+		),
+	}
+
+	if onFailure == cfgmodel.OnFailureRollback && report.Failed() {
+		logger.WarnContext(ctx, "Plan failed, rolling back the completed moves.")
+		g.rollback(ctx, logger, state, actionPlan.ID, report.Results)
+	}
+
+	if g.provider.Name() == "hetzner" {
+		// Hetzner keeps the floating IPs locked for a short while after assigning them.
+		// So we add a small sleep here to prevent a potential plan that happens right after from failing.
+		// This is a bit of a hack, but it's the simplest solution for now. A potential future solution
+		// could be to retry on lock errors (within the Hetzner provider perhaps).
+		select {
+		case <-ctx.Done():
+		case <-g.clock.After(time.Second):
+		}
+	}
+
+	return report
+}
+
+// errActionAborted is the cause of cancelling the actions after one of them has failed.
+var errActionAborted = errors.New("aborted after another action failed")
+
+// executeActions executes the actions concurrently and returns their results in the same order.
+// If abortOnFailure is set, no new actions are started after an action has failed, and those in flight are
+// cancelled and reported as skipped. Rollback is set if the actions roll back a plan that failed.
+func (g *Group) executeActions(
+	ctx context.Context,
+	logger *slog.Logger,
+	state plan.State,
+	planID uuid.UUID,
+	actions []plan.ReassignFloatingIPAction,
+	abortOnFailure bool,
+	rollback bool,
+) []plan.ActionResult {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	results := make([]plan.ActionResult, len(actions))
+	errgp := errgroup.Group{}
+	errgp.SetLimit(g.cfg.Execution.ConcurrencyOrDefault())
+	for i, action := range actions {
+		errgp.Go(func() error {
+			if ctx.Err() != nil {
+				results[i] = plan.ActionResult{
+					Action: action, Status: plan.ActionSkipped, Error: context.Cause(ctx).Error(),
+				}
+				return nil
+			}
+
+			results[i] = g.executeAction(ctx, logger, state, planID, action, rollback)
+			if results[i].Status != plan.ActionFailed {
+				return nil
+			}
+			if errors.Is(context.Cause(ctx), errActionAborted) {
+				// Another action failed while this one was in flight, it did not get all of its attempts.
+				results[i].Status = plan.ActionSkipped
+				results[i].Error = errActionAborted.Error()
+				return nil
+			}
+			if abortOnFailure {
+				logger.ErrorContext(ctx, "Action failed, aborting plan.",
+					slog.String("floating_ip_id", action.FloatingIPID),
+				)
+				cancel(errActionAborted)
+			}
+			return nil
+		})
+	}
+	_ = errgp.Wait()
+
+	return results
+}
+
+// executeAction reassigns a single floating IP, retrying with exponential backoff.
+func (g *Group) executeAction(
+	ctx context.Context,
+	logger *slog.Logger,
+	state plan.State,
+	planID uuid.UUID,
+	action plan.ReassignFloatingIPAction,
+	rollback bool,
+) plan.ActionResult {
+	result := plan.ActionResult{Action: action, Status: plan.ActionFailed}
+
+	flip, ok := state.FloatingIPs[action.FloatingIPID]
+	if !ok {
+		logger.ErrorContext(ctx, "Floating IP not found.", slog.String("floating_ip_id", action.FloatingIPID))
+		result.Error = fmt.Sprintf("floating IP not found for %s", action.FloatingIPID)
+		return result
+	}
+	serverWithStatus, ok := state.Servers[action.ServerID]
+	if !ok {
+		logger.ErrorContext(ctx, "Server not found.", slog.String("server_id", action.ServerID))
+		result.Error = fmt.Sprintf("server not found for %s", action.ServerID)
+		return result
+	}
+
+	backoff := g.cfg.Execution.RetryBackoffOrDefault()
+	maxAttempts := g.cfg.Execution.MaxAttemptsOrDefault()
+	for result.Attempts < maxAttempts {
+		result.Attempts++
+
+		start := g.clock.Now()
+		err := g.provider.AssignFloatingIP(ctx, flip, serverWithStatus.Resource)
+		call := event.ProviderCall{
+			Meta:       event.NewMeta(g.cfg, g.clock.Now()),
+			Operation:  event.OperationAssignFloatingIP,
+			PlanID:     planID,
+			FloatingIP: flip,
+			Server:     serverWithStatus.Resource,
+			Rollback:   rollback,
+			Attempt:    result.Attempts,
+			Duration:   g.clock.Now().Sub(start),
+		}
+		if err != nil {
+			call.Error = err.Error()
+		}
+		g.events.Publish(ctx, call)
+
+		if err == nil {
+			logger.InfoContext(ctx, "Floating IP assigned.",
+				slog.String("floating_ip_id", flip.ID()),
+				slog.String("server_id", serverWithStatus.Resource.ID()),
+				slog.Int("attempts", result.Attempts),
+				slog.Any("reasons", action.ReasonMessages()),
+			)
+			result.Status = plan.ActionSucceeded
+			result.Error = ""
+			return result
+		}
+
+		result.Error = err.Error()
+		logger.ErrorContext(ctx, "Failed to assign floating IP",
+			slog.String("error", err.Error()),
+			slog.String("floating_ip_id", flip.ID()),
+			slog.String("server_id", serverWithStatus.Resource.ID()),
+			slog.Int("attempt", result.Attempts),
+		)
+		if result.Attempts >= maxAttempts || errors.Is(err, context.Canceled) ||
+			errors.Is(err, context.DeadlineExceeded) {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return result
+		case <-g.clock.After(backoff):
+		}
+		backoff *= 2
+	}
+
+	return result
+}
+
+// rollback moves the floating IPs of the succeeded actions back to the server they targeted before the plan.
+// The results are updated in place.
+func (g *Group) rollback(
+	ctx context.Context,
+	logger *slog.Logger,
+	state plan.State,
+	planID uuid.UUID,
+	results []plan.ActionResult,
+) {
+	// The plan's context may already be done (e.g. on timeout), the rollback gets its own deadline.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), g.cfg.PlanApplyTimeoutOrDefault())
+	defer cancel()
+
+	var rollbackActions []plan.ReassignFloatingIPAction
+	var indices []int
+	for i, result := range results {
+		if result.Status != plan.ActionSucceeded {
+			continue
+		}
+
+		previousTarget := state.FloatingIPs[result.Action.FloatingIPID].CurrentTarget
+		if previousTarget == "" {
+			// Floating IPs can not be unassigned, so there is nothing to roll back to.
+			results[i].Status = plan.ActionRollbackFailed
+			results[i].Error = "no previous target to roll back to"
+			continue
+		}
+		rollbackActions = append(rollbackActions, plan.ReassignFloatingIPAction{
+			FloatingIPID: result.Action.FloatingIPID,
+			ServerID:     previousTarget,
+		})
+		indices = append(indices, i)
+	}
+
+	for j, rollbackResult := range g.executeActions(ctx, logger, state, planID, rollbackActions, false, true) {
+		i := indices[j]
+		if rollbackResult.Status == plan.ActionSucceeded {
+			results[i].Status = plan.ActionRolledBack
+			continue
+		}
+		results[i].Status = plan.ActionRollbackFailed
+		results[i].Error = "rollback: " + rollbackResult.Error
+	}
+}
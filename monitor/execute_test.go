@@ -0,0 +1,224 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/gzuidhof/flipper/clock"
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+	"github.com/gzuidhof/flipper/event"
+	"github.com/gzuidhof/flipper/plan"
+	"github.com/gzuidhof/flipper/provider/mock"
+	"github.com/gzuidhof/flipper/resource"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecutePlan(t *testing.T) {
+	t.Parallel()
+
+	errLocked := errors.New("locked")
+
+	newProvider := func() *mock.Provider {
+		provider := mock.NewProvider()
+		provider.Servers = []resource.Server{
+			{Provider: resource.ProviderNameMock, ServerName: "mock-server-1", HetznerID: 1},
+			{Provider: resource.ProviderNameMock, ServerName: "mock-server-2", HetznerID: 2},
+		}
+		provider.FloatingIPs = []resource.FloatingIP{
+			{Provider: resource.ProviderNameMock, FloatingIPName: "a", HetznerID: 11, CurrentTarget: "1"},
+			{Provider: resource.ProviderNameMock, FloatingIPName: "b", HetznerID: 12, CurrentTarget: "1"},
+			{Provider: resource.ProviderNameMock, FloatingIPName: "c", HetznerID: 13, CurrentTarget: "1"},
+		}
+		return provider
+	}
+	actionPlan := plan.Plan{Actions: []plan.ReassignFloatingIPAction{
+		{FloatingIPID: "11", ServerID: "2"},
+		{FloatingIPID: "12", ServerID: "2"},
+		{FloatingIPID: "13", ServerID: "2"},
+	}}
+	failFloatingIP := func(id string) func(resource.FloatingIP, resource.Server) error {
+		return func(fip resource.FloatingIP, server resource.Server) error {
+			if fip.ID() == id && server.ID() == "2" {
+				return errLocked
+			}
+			return nil
+		}
+	}
+
+	for _, tc := range []struct {
+		name      string
+		execution cfgmodel.ExecutionConfig
+		failFunc  func(resource.FloatingIP, resource.Server) error
+
+		expectedStatuses []plan.ActionStatus
+		expectedAttempts []int
+		expectedTargets  []string
+	}{
+		{
+			name:             "success",
+			execution:        cfgmodel.ExecutionConfig{},
+			expectedStatuses: []plan.ActionStatus{plan.ActionSucceeded, plan.ActionSucceeded, plan.ActionSucceeded},
+			expectedAttempts: []int{1, 1, 1},
+			expectedTargets:  []string{"2", "2", "2"},
+		},
+		{
+			name:      "retry",
+			execution: cfgmodel.ExecutionConfig{},
+			failFunc: func() func(resource.FloatingIP, resource.Server) error {
+				failures := 0
+				return func(fip resource.FloatingIP, _ resource.Server) error {
+					if fip.ID() == "12" && failures < 2 {
+						failures++
+						return errLocked
+					}
+					return nil
+				}
+			}(),
+			expectedStatuses: []plan.ActionStatus{plan.ActionSucceeded, plan.ActionSucceeded, plan.ActionSucceeded},
+			expectedAttempts: []int{1, 3, 1},
+			expectedTargets:  []string{"2", "2", "2"},
+		},
+		{
+			name:             "continue",
+			execution:        cfgmodel.ExecutionConfig{Concurrency: 1, OnFailure: cfgmodel.OnFailureContinue},
+			failFunc:         failFloatingIP("12"),
+			expectedStatuses: []plan.ActionStatus{plan.ActionSucceeded, plan.ActionFailed, plan.ActionSucceeded},
+			expectedAttempts: []int{1, 3, 1},
+			expectedTargets:  []string{"2", "1", "2"},
+		},
+		{
+			name:             "abort",
+			execution:        cfgmodel.ExecutionConfig{Concurrency: 1, OnFailure: cfgmodel.OnFailureAbort},
+			failFunc:         failFloatingIP("12"),
+			expectedStatuses: []plan.ActionStatus{plan.ActionSucceeded, plan.ActionFailed, plan.ActionSkipped},
+			expectedAttempts: []int{1, 3, 0},
+			expectedTargets:  []string{"2", "1", "1"},
+		},
+		{
+			name:             "rollback",
+			execution:        cfgmodel.ExecutionConfig{Concurrency: 1, OnFailure: cfgmodel.OnFailureRollback},
+			failFunc:         failFloatingIP("12"),
+			expectedStatuses: []plan.ActionStatus{plan.ActionRolledBack, plan.ActionFailed, plan.ActionSkipped},
+			expectedAttempts: []int{1, 3, 0},
+			expectedTargets:  []string{"1", "1", "1"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			provider := newProvider()
+			provider.AssignFloatingIPErrorFunc = tc.failFunc
+
+			tc.execution.RetryBackoff = time.Millisecond
+			recorder := event.NewRecorder(100)
+			g := &Group{
+				cfg:      cfgmodel.GroupConfig{Execution: tc.execution},
+				logger:   slog.Default(),
+				provider: provider,
+				events:   event.NewBus(),
+				clock:    clock.Real{},
+			}
+			g.events.Subscribe(recorder.Handle)
+
+			state := plan.NewStateFromGroup(resource.Group{
+				FloatingIPs: provider.FloatingIPs,
+				Servers:     provider.Servers,
+			})
+			report := g.executePlan(context.Background(), g.logger, state, actionPlan)
+
+			var statuses []plan.ActionStatus
+			var attempts []int
+			for _, result := range report.Results {
+				statuses = append(statuses, result.Status)
+				attempts = append(attempts, result.Attempts)
+			}
+			assert.Equal(t, tc.expectedStatuses, statuses)
+			assert.Equal(t, tc.expectedAttempts, attempts)
+
+			// Every attempt is published as a provider call, the rollbacks come on top.
+			numCalls := 0
+			for _, e := range recorder.Events() {
+				if call, ok := e.(event.ProviderCall); ok && !call.Rollback {
+					numCalls++
+				}
+			}
+			sum := 0
+			for _, n := range attempts {
+				sum += n
+			}
+			assert.Equal(t, sum, numCalls)
+
+			var targets []string
+			for _, flip := range provider.FloatingIPs {
+				targets = append(targets, flip.CurrentTarget)
+			}
+			assert.Equal(t, tc.expectedTargets, targets)
+		})
+	}
+}
+
+// inFlightProvider fails floating IP 12 once floating IP 11 is assigned and floating IP 13 is in flight, and keeps
+// 13 in flight until it is cancelled.
+type inFlightProvider struct {
+	*mock.Provider
+
+	assigned chan struct{}
+	inFlight chan struct{}
+}
+
+func (p *inFlightProvider) AssignFloatingIP(ctx context.Context, fip resource.FloatingIP, server resource.Server) error {
+	switch fip.ID() {
+	case "12":
+		<-p.assigned
+		<-p.inFlight
+		return errors.New("locked")
+	case "13":
+		close(p.inFlight)
+		<-ctx.Done()
+		return ctx.Err()
+	default:
+		defer close(p.assigned)
+		return p.Provider.AssignFloatingIP(ctx, fip, server)
+	}
+}
+
+func TestExecutePlanAbortInFlight(t *testing.T) {
+	t.Parallel()
+
+	provider := &inFlightProvider{
+		Provider: mock.NewProvider(), assigned: make(chan struct{}), inFlight: make(chan struct{}),
+	}
+	provider.Servers = []resource.Server{{Provider: resource.ProviderNameMock, ServerName: "lb-2", HetznerID: 2}}
+	for _, id := range []int64{11, 12, 13} {
+		provider.FloatingIPs = append(provider.FloatingIPs,
+			resource.FloatingIP{Provider: resource.ProviderNameMock, HetznerID: id, CurrentTarget: "1"},
+		)
+	}
+	g := &Group{
+		cfg: cfgmodel.GroupConfig{Execution: cfgmodel.ExecutionConfig{
+			Concurrency: 3, MaxAttempts: 1, OnFailure: cfgmodel.OnFailureAbort,
+		}},
+		logger:   slog.Default(),
+		provider: provider,
+		events:   event.NewBus(),
+		clock:    clock.Real{},
+	}
+
+	state := plan.NewStateFromGroup(resource.Group{FloatingIPs: provider.FloatingIPs, Servers: provider.Servers})
+	actionPlan := plan.Plan{Actions: []plan.ReassignFloatingIPAction{
+		{FloatingIPID: "11", ServerID: "2"},
+		{FloatingIPID: "12", ServerID: "2"},
+		{FloatingIPID: "13", ServerID: "2"},
+	}}
+	report := g.executePlan(context.Background(), g.logger, state, actionPlan)
+
+	var statuses []plan.ActionStatus
+	for _, result := range report.Results {
+		statuses = append(statuses, result.Status)
+	}
+	assert.Equal(t, []plan.ActionStatus{plan.ActionSucceeded, plan.ActionFailed, plan.ActionSkipped}, statuses)
+	assert.Equal(t, errActionAborted.Error(), report.Results[2].Error)
+}
@@ -4,25 +4,79 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/gzuidhof/flipper/buildinfo"
+	"github.com/gzuidhof/flipper/checker"
+	"github.com/gzuidhof/flipper/clock"
 	"github.com/gzuidhof/flipper/config/cfgmodel"
-	"github.com/gzuidhof/flipper/notification"
-	"github.com/gzuidhof/flipper/notification/notificationtemplate"
+	"github.com/gzuidhof/flipper/event"
+	"github.com/gzuidhof/flipper/leader"
+	"github.com/gzuidhof/flipper/maintenance"
 	"github.com/gzuidhof/flipper/plan"
 	"github.com/gzuidhof/flipper/resource"
+	"github.com/gzuidhof/flipper/store"
+	"github.com/gzuidhof/flipper/trace"
 )
 
 // Group monitors a group of resources and actions on them if necessary.
 type Group struct {
+	// cfg is only changed from the group loop, while holding cfgMu. Use config() outside of the loop.
 	cfg      cfgmodel.GroupConfig
+	cfgMu    sync.Mutex
 	logger   *slog.Logger
 	provider resource.Provider
-	notifier notification.Notifier
+	events   *event.Bus
 
 	watcher      *ResourcesWatcher
 	healthkeeper *HealthKeeper
+	guard        *SafetyGuard
+	// leader tells whether this instance may execute plans, when multiple flipper instances run.
+	leader leader.Status
+
+	// lastBlocked is the reason the last plan was blocked, it's used to avoid repeating the same notification.
+	lastBlocked string
+
+	lastPlanMu sync.Mutex
+	// lastPlan is the most recent plan that required actions, nil if there was none yet.
+	lastPlan *plan.Plan
+
+	pendingMu sync.Mutex
+	// pending is the plan that waits for approval, nil if there is none. It is only replaced from the group loop.
+	pending *PendingPlan
+	// pendingTimer fires when the pending plan expires, it is only used from the group loop.
+	pendingTimer clock.Timer
+	// decisions is used to approve or reject the pending plan.
+	decisions chan approvalDecision
+	// reconfigures is used to change the config of the group while it runs.
+	reconfigures chan cfgmodel.GroupConfig
+
+	// recorder records the inputs of the group in a trace, nil if recording is disabled.
+	recorder *trace.Recorder
+	// clock tells the time, it is a virtual clock in simulations.
+	clock clock.Clock
+}
+
+// GroupOption configures a group.
+type GroupOption func(g *Group)
+
+// WithClock sets the clock of the group: the resources are polled, the servers are checked and the plans are
+// timed by it. It defaults to the real clock.
+func WithClock(clk clock.Clock) GroupOption {
+	return func(g *Group) {
+		g.clock = clk
+		g.watcher.clock = clk
+		g.healthkeeper.clock = clk
+	}
+}
+
+// WithHTTPCheckFactory sets how the health checks of the servers are created, they make real HTTP requests by
+// default.
+func WithHTTPCheckFactory(factory checker.HTTPCheckFactory) GroupOption {
+	return func(g *Group) {
+		g.healthkeeper.newHTTPCheck = factory
+	}
 }
 
 // NewGroup creates a new monitor group from a config and a provider.
@@ -30,7 +84,8 @@ func NewGroup(
 	cfg cfgmodel.GroupConfig,
 	provider resource.Provider,
 	logger *slog.Logger,
-	notifier notification.Notifier,
+	events *event.Bus,
+	opts ...GroupOption,
 ) *Group {
 	logger = logger.With(
 		slog.String("group", cfg.DisplayName),
@@ -39,67 +94,159 @@ func NewGroup(
 	)
 
 	watcher := NewResourcesWatcher(cfg, logger, provider)
-	healthkeeper := NewHealthKeeper(cfg, logger, provider, notifier)
+	healthkeeper := NewHealthKeeper(cfg, logger, provider, events)
 
-	return &Group{
-		cfg:      cfg,
-		watcher:  watcher,
-		logger:   logger,
-		notifier: notifier,
+	g := &Group{
+		cfg:     cfg,
+		watcher: watcher,
+		logger:  logger,
+		events:  events,
+		clock:   clock.Real{},
 
 		healthkeeper: healthkeeper,
 		provider:     provider,
+		guard:        NewSafetyGuard(cfg.Safety),
+		leader:       leader.Always{},
+		decisions:    make(chan approvalDecision),
+		reconfigures: make(chan cfgmodel.GroupConfig),
+	}
+	for _, opt := range opts {
+		opt(g)
 	}
+	return g
 }
 
-func (g *Group) executePlan(ctx context.Context, logger *slog.Logger, state plan.State, actionPlan plan.Plan) error {
-	ctx, cancel := context.WithTimeout(ctx, g.cfg.PlanApplyTimeoutOrDefault())
-	defer cancel()
+// useStore keeps the state of the group in the given store. It must be called before the group is started.
+func (g *Group) useStore(cfg cfgmodel.StoreConfig, st store.Store) {
+	g.healthkeeper.persisted = newPersistedState(cfg, st, g.cfg.ID, g.logger)
+}
 
-	for _, action := range actionPlan.Actions {
-		if ctx.Err() != nil {
-			logger.ErrorContext(ctx, "Context cancelled, aborting plan.")
-			return fmt.Errorf("plan cancelled because of context: %w", ctx.Err())
-		}
-		flip, ok := state.FloatingIPs[action.FloatingIPID]
-		if !ok {
-			logger.ErrorContext(ctx, "Floating IP not found, plan will be aborted.",
-				slog.String("floating_ip_id", action.FloatingIPID),
-			)
-			return fmt.Errorf("floating IP not found for %s", action.FloatingIPID)
-		}
-		serverWithStatus, ok := state.Servers[action.ServerID]
-		if !ok {
-			logger.ErrorContext(ctx, "Server not found, plan will be aborted.",
-				slog.String("server_id", action.ServerID),
-			)
-			return fmt.Errorf("server not found for %s", action.ServerID)
-		}
+// useTrace records the inputs of the group in a trace. It must be called before the group is started.
+func (g *Group) useTrace(recorder *trace.Recorder) {
+	g.recorder = recorder
+	g.watcher.recorder = recorder
+	g.healthkeeper.recorder = recorder
+}
 
-		err := g.provider.AssignFloatingIP(ctx, flip, serverWithStatus.Resource)
-		if err != nil {
-			logger.ErrorContext(ctx, "Failed to assign floating IP",
-				slog.String("error", err.Error()),
-				slog.String("floating_ip_id", flip.ID()),
-				slog.String("server_id", serverWithStatus.Resource.ID()),
-			)
-			return fmt.Errorf("failed to assign floating IP: %w", err)
-		}
-		logger.InfoContext(ctx, "Floating IP assigned.",
-			slog.String("floating_ip_id", flip.ID()),
-			slog.String("server_id", serverWithStatus.Resource.ID()),
-		)
+// ID returns the ID of the group.
+func (g *Group) ID() string {
+	return g.config().ID
+}
+
+// config returns the current config of the group.
+func (g *Group) config() cfgmodel.GroupConfig {
+	g.cfgMu.Lock()
+	defer g.cfgMu.Unlock()
+
+	return g.cfg
+}
+
+// Reconfigure changes the config of the running group. Servers, floating IPs and their health are kept, only the
+// health checkers are restarted if the checks changed. The provider can not be changed this way.
+func (g *Group) Reconfigure(ctx context.Context, cfg cfgmodel.GroupConfig) error {
+	select {
+	case g.reconfigures <- cfg:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to reconfigure group: %w", ctx.Err())
+	}
+}
+
+// applyConfig changes the config of the group, it is only called from the group loop.
+func (g *Group) applyConfig(ctx context.Context, cfg cfgmodel.GroupConfig) {
+	g.cfgMu.Lock()
+	g.cfg = cfg
+	g.cfgMu.Unlock()
+
+	if g.recorder != nil {
+		g.recorder.Record(trace.NewReconfigure(g.clock.Now(), cfg))
 	}
+	g.guard.Reconfigure(cfg.Safety)
+	g.watcher.Reconfigure(cfg)
+	g.healthkeeper.Reconfigure(cfg)
+	g.logger.InfoContext(ctx, "Group reconfigured.")
+}
+
+// ResetFlapping resets the flap damping state of a server in the group.
+func (g *Group) ResetFlapping(serverID string) error {
+	return g.healthkeeper.ResetFlapping(serverID)
+}
 
-	if g.provider.Name() == "hetzner" {
-		// Hetzner keeps the floating IPs locked for a short while after assigning them.
-		// So we add a small sleep here to prevent a potential plan that happens right after from failing.
-		// This is a bit of a hack, but it's the simplest solution for now. A potential future solution
-		// could be to retry on lock errors (within the Hetzner provider perhaps).
-		time.Sleep(time.Second)
+// Pin pins a floating IP in the group to a server, or removes its pin if the pin is the zero pin.
+func (g *Group) Pin(ctx context.Context, floatingIPID string, pin plan.Pin) error {
+	return g.healthkeeper.Pin(ctx, floatingIPID, pin)
+}
+
+// Pins returns the active pins in the group by floating IP ID.
+func (g *Group) Pins() map[string]plan.Pin {
+	return g.healthkeeper.Pins()
+}
+
+// SetMaintenance sets a maintenance window in the group, or removes it if the window is nil.
+func (g *Group) SetMaintenance(ctx context.Context, windowID string, window *maintenance.Window) error {
+	return g.healthkeeper.SetMaintenance(ctx, windowID, window)
+}
+
+// Maintenance returns the status of the maintenance windows in the group.
+func (g *Group) Maintenance() []MaintenanceStatus {
+	return g.healthkeeper.Maintenance()
+}
+
+// LastPlan returns the most recent plan that required actions, it returns false if there was none yet.
+func (g *Group) LastPlan() (plan.Plan, bool) {
+	g.lastPlanMu.Lock()
+	defer g.lastPlanMu.Unlock()
+
+	if g.lastPlan == nil {
+		return plan.Plan{}, false
 	}
+	return *g.lastPlan, true
+}
+
+func (g *Group) setLastPlan(p plan.Plan) {
+	g.lastPlanMu.Lock()
+	defer g.lastPlanMu.Unlock()
 
-	return nil
+	g.lastPlan = &p
+}
+
+// Failback requests the floating IPs in the group to be moved back to their preferred servers right away,
+// regardless of the failback policy.
+func (g *Group) Failback() {
+	g.healthkeeper.RequestFailback()
+}
+
+// Resync requests the resources of the group to be polled right away, instead of at the next poll interval.
+func (g *Group) Resync() {
+	g.watcher.Resync()
+}
+
+// OverrideSafety lifts the safety limits of the group for the configured override duration.
+// It returns the time until which the override is active.
+func (g *Group) OverrideSafety(ctx context.Context) time.Time {
+	until := g.guard.Override(g.clock.Now())
+	g.logger.WarnContext(ctx, "Safety limits overridden.", slog.Time("until", until))
+	g.events.Publish(ctx, event.SafetyOverridden{Meta: event.NewMeta(g.config(), g.clock.Now()), Until: until})
+	return until
+}
+
+// notifyBlocked notifies that a plan was blocked by the safety limits. The same reason is only reported once
+// in a row, so that a plan that keeps getting blocked does not cause a flood of notifications.
+func (g *Group) notifyBlocked(ctx context.Context, logger *slog.Logger, action HealthKeeperAction, err error) {
+	logger.ErrorContext(ctx, "Plan blocked by safety limits.", slog.String("error", err.Error()))
+
+	key := err.Error() + action.Plan.String()
+	if key == g.lastBlocked {
+		return
+	}
+	g.lastBlocked = key
+
+	g.events.Publish(ctx, event.PlanBlocked{
+		Meta:   event.NewMeta(g.cfg, g.clock.Now()),
+		Plan:   action.Plan,
+		Reason: err.Error(),
+		State:  action.State,
+	})
 }
 
 // Start watching the resources and performing health checks.
@@ -108,22 +255,27 @@ func (g *Group) Start(ctx context.Context) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	msg := fmt.Sprintf(":eyes: Starting monitor for group **%s** (`%s`). Flipper version `%s`. ",
-		g.cfg.DisplayName, g.cfg.ID, buildinfo.Version())
-
-	if g.cfg.ReadOnly {
-		msg += "\n:lock: **Read-only mode** enabled, no actions will be taken. Only unhealthy/healthy notifications" +
-			" will be sent."
+	g.events.Publish(ctx, event.MonitorStarted{
+		Meta:    event.NewMeta(g.cfg, g.clock.Now()),
+		Version: buildinfo.Version(),
+	})
+	if g.recorder != nil {
+		g.recorder.Record(trace.NewStart(g.clock.Now(), g.cfg))
 	}
 
-	_ = g.notifier.Notify(ctx, msg)
-
 	updateChan := make(chan ResourceUpdate, 16)
 	errChan := make(chan error, 16)
 
 	actionChan := make(chan HealthKeeperAction)
 	defer close(actionChan)
 
+	restored := g.healthkeeper.persisted.restore(ctx, g.clock.Now())
+	for _, record := range restored.Plans {
+		// The moves of recent plans still count towards the safety limits.
+		g.guard.RecordMoves(record.ExecutedAt, len(record.Report.Results))
+	}
+	g.healthkeeper.restore(restored)
+
 	g.logger.DebugContext(ctx, "Starting resource watcher.")
 	go g.watcher.Start(ctx, updateChan, errChan)
 	go g.healthkeeper.Start(ctx, updateChan, actionChan)
@@ -157,53 +309,132 @@ func (g *Group) Start(ctx context.Context) error {
 				continue
 			}
 
+			if action.Plan.Empty() {
+				// The moves of the previous plan are no longer needed, e.g. because the server recovered.
+				g.dropPending(ctx)
+				continue
+			}
+
 			logger := g.logger.With(
 				slog.String("plan_id", action.Plan.ID.String()),
 			)
+			g.setLastPlan(action.Plan)
 
 			if g.cfg.ReadOnly {
 				logger.InfoContext(ctx, "Read-only group, not executing plan.")
 				continue
 			}
+			if !g.leader.IsLeader() {
+				logger.InfoContext(ctx, "Not the leader, not executing plan.")
+				continue
+			}
 
-			logger.InfoContext(ctx, "Executing plan.")
-			_ = g.notifier.Notify(ctx,
-				notificationtemplate.RenderPlanExecution(g.cfg, action.State, action.Plan),
-			)
-
-			err := g.executePlan(ctx, logger, action.State, action.Plan)
-			if err != nil {
-				_ = g.notifier.Notify(ctx,
-					fmt.Sprintf("💥 Failed to execute plan `%s` for group **%s** (`%s`).\n",
-						action.Plan.ID,
-						g.cfg.DisplayName,
-						g.cfg.ID,
-					)+
-						fmt.Sprintf("Error: `%s`\n", err.Error()),
-				)
-				logger.ErrorContext(ctx, "Failed to execute plan.",
-					slog.String("error", err.Error()),
-				)
+			// Moves that need approval wait in the pending queue, the rest of the plan is executed right away.
+			auto, needsApproval := g.splitForApproval(action)
+			if len(needsApproval.Actions) > 0 {
+				g.propose(ctx, logger, action, needsApproval)
+				if len(auto.Actions) == 0 {
+					continue
+				}
+				action.Plan = auto
 			} else {
-				msg := fmt.Sprintf("🚀 **Plan** `%s` **executed successfully** for group **%s** (`%s`).",
-					action.Plan.ID,
-					g.cfg.DisplayName,
-					g.cfg.ID,
-				)
-				numUnhealthy := action.State.NumUnhealthyServers()
+				// The moves that were waiting for approval are no longer part of the plan.
+				g.dropPending(ctx)
+			}
 
-				if numUnhealthy > 0 {
-					msg += fmt.Sprintf("\n**Note there are still %d _unhealthy_ servers 🔥.**", numUnhealthy)
-				} else {
-					msg += "\n No servers are **_unhealthy_**."
-				}
+			if sequence, executed := g.execute(ctx, logger, action, updateChan, errChan); executed {
+				minSequence = sequence
+			}
+		case decision := <-g.decisions:
+			action, err := g.decide(ctx, decision, minSequence)
+			decision.result <- err
+			if err != nil || !decision.approve {
+				continue
+			}
 
-				_ = g.notifier.Notify(ctx, msg)
-				logger.InfoContext(ctx, "Plan executed successfully.",
-					slog.Int("num_unhealthy_servers", numUnhealthy))
+			logger := g.logger.With(slog.String("plan_id", action.Plan.ID.String()))
+			if sequence, executed := g.execute(ctx, logger, action, updateChan, errChan); executed {
+				minSequence = sequence
+			}
+		case cfg := <-g.reconfigures:
+			g.applyConfig(ctx, cfg)
+		case <-g.pendingExpired():
+			action, approved := g.expirePending(ctx, minSequence)
+			if !approved {
+				continue
 			}
 
-			minSequence = g.watcher.performUpdate(ctx, updateChan, errChan, true)
+			logger := g.logger.With(slog.String("plan_id", action.Plan.ID.String()))
+			if sequence, executed := g.execute(ctx, logger, action, updateChan, errChan); executed {
+				minSequence = sequence
+			}
 		}
 	}
 }
+
+// expectationWindow is how long the moves of a plan are expected to show up in the resource updates. Executing,
+// rolling back and verifying the plan all happen within it.
+func expectationWindow(cfg cfgmodel.GroupConfig) time.Duration {
+	return 2*cfg.PlanApplyTimeoutOrDefault() + cfg.Execution.Verification.TimeoutOrDefault() +
+		2*cfg.PollIntervalOrDefault()
+}
+
+// execute executes the plan of an action if the safety limits allow it, then notifies about and verifies the
+// outcome. It returns the minimum resources sequence for new actions, and false if the plan was not executed.
+func (g *Group) execute(
+	ctx context.Context,
+	logger *slog.Logger,
+	action HealthKeeperAction,
+	updateChan chan<- ResourceUpdate,
+	errChan chan<- error,
+) (uint64, bool) {
+	// Leadership may have been lost while the plan waited for approval.
+	if !g.leader.IsLeader() {
+		logger.InfoContext(ctx, "Not the leader, not executing plan.")
+		return 0, false
+	}
+	if err := g.guard.Check(g.clock.Now(), action.State, action.Plan); err != nil {
+		g.notifyBlocked(ctx, logger, action, err)
+		return 0, false
+	}
+	g.lastBlocked = ""
+
+	logger.InfoContext(ctx, "Executing plan.")
+	g.events.Publish(ctx, event.PlanExecuting{
+		Meta:  event.NewMeta(g.cfg, g.clock.Now()),
+		Plan:  action.Plan,
+		State: action.State,
+	})
+
+	// The moves show up in the next resource updates, they should not be reported as drift.
+	g.healthkeeper.expectTargets(action.State, action.Plan.Actions, g.clock.Now().Add(expectationWindow(g.cfg)))
+	report := g.executePlan(ctx, logger, action.State, action.Plan)
+	// Failed plans may still have moved some floating IPs, so we count all of them to be safe.
+	g.guard.RecordMoves(g.clock.Now(), len(action.Plan.Actions))
+	g.healthkeeper.persisted.addPlan(g.clock.Now(), report)
+	g.healthkeeper.persisted.save(ctx, g.clock.Now())
+	g.events.Publish(ctx, event.PlanExecuted{
+		Meta:   event.NewMeta(g.cfg, g.clock.Now()),
+		Report: report,
+		Failed: report.Failed(),
+		State:  action.State,
+	})
+	if report.Failed() {
+		logger.ErrorContext(ctx, "Failed to execute plan.",
+			slog.Int("num_succeeded", report.Count(plan.ActionSucceeded)),
+			slog.Int("num_failed", report.Count(plan.ActionFailed)),
+			slog.Int("num_skipped", report.Count(plan.ActionSkipped)),
+			slog.Int("num_rolled_back", report.Count(plan.ActionRolledBack)),
+			slog.Int("num_rollback_failed", report.Count(plan.ActionRollbackFailed)),
+		)
+	} else {
+		logger.InfoContext(ctx, "Plan executed successfully.",
+			slog.Int("num_unhealthy_servers", action.State.NumUnhealthyServers()))
+	}
+
+	if !g.cfg.Execution.Verification.Disabled {
+		g.verifyAndNotify(ctx, logger, action.State, report)
+	}
+
+	return g.watcher.performUpdate(ctx, updateChan, errChan, true), true
+}
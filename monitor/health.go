@@ -4,13 +4,20 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/gzuidhof/flipper/checker"
+	"github.com/gzuidhof/flipper/clock"
 	"github.com/gzuidhof/flipper/config/cfgmodel"
-	"github.com/gzuidhof/flipper/notification"
-	"github.com/gzuidhof/flipper/notification/notificationtemplate"
+	"github.com/gzuidhof/flipper/consensus"
+	"github.com/gzuidhof/flipper/event"
+	"github.com/gzuidhof/flipper/maintenance"
 	"github.com/gzuidhof/flipper/plan"
 	"github.com/gzuidhof/flipper/resource"
+	"github.com/gzuidhof/flipper/store"
+	"github.com/gzuidhof/flipper/trace"
 )
 
 // HealthKeeper keeps track of the health of the resources in a group and generates actions to keep them healthy.
@@ -19,14 +26,99 @@ type HealthKeeper struct {
 	cfg      cfgmodel.GroupConfig
 	provider resource.Provider
 	logger   *slog.Logger
-	notifier notification.Notifier
+	events   *event.Bus
 
 	didReceiveInitialResourcesUpdate bool
 
 	serverWatcherCancel map[string]context.CancelFunc
 
+	// serverCheckers contains the running checker of every server, by server ID.
+	// It is guarded by serverCheckersMu as it is also accessed from outside the healthkeeper loop.
+	serverCheckers   map[string]*checker.Server
+	serverCheckersMu sync.Mutex
+
 	state             plan.State
+	policy            plan.Policy
 	resourcesSequence uint64
+	// movesPlanned is true if the last plan sent to the group had moves. Once they are no longer needed, an empty
+	// plan is sent so that the group drops them if they wait for approval.
+	movesPlanned bool
+	// warnings are the warnings of the last plan. Changes are published for plans without actions, as those are
+	// not published otherwise.
+	warnings []plan.Warning
+
+	// failbackRequests is used to request a plan that ignores the failback policy.
+	failbackRequests chan struct{}
+
+	// pins are the pins set through the admin API or by the drift policy, by floating IP ID.
+	// They are only accessed from the healthkeeper loop, pinRequests is used to change them from outside.
+	pins        map[string]plan.Pin
+	pinRequests chan pinRequest
+
+	// activePins are all active pins, including those set on the floating IPs, for use outside the loop.
+	activePins   map[string]plan.Pin
+	activePinsMu sync.Mutex
+
+	// configWindows are the maintenance windows of the config, apiWindows those set through the admin API by
+	// window ID. They are only accessed from the healthkeeper loop, maintenanceRequests is used to change the
+	// windows of the admin API from outside.
+	configWindows       []maintenance.Window
+	apiWindows          map[string]maintenance.Window
+	maintenanceRequests chan maintenanceRequest
+	// activeWindows are the servers covered by the windows that were active when they were last synced, by window
+	// ID, to notice when they start and end. maintenanceTimer fires at maintenanceNext, when the next window starts
+	// or ends.
+	activeWindows    map[string][]resource.Server
+	maintenanceTimer clock.Timer
+	maintenanceNext  time.Time
+	// maintenanceStatus is the status of the windows, for use outside the loop.
+	maintenanceStatus   []MaintenanceStatus
+	maintenanceStatusMu sync.Mutex
+
+	// expectedTargets are the targets floating IPs may get while flipper moves them, by floating IP ID.
+	// Other changes of their targets are drift.
+	expectedTargets   map[string]expectedTarget
+	expectedTargetsMu sync.Mutex
+	// drifts receives the drift reports whose assignment was looked up outside the loop, to be published.
+	drifts chan driftReport
+
+	// dependencies are the groups this group depends on, dependents are the groups that depend on this group.
+	// Both are guarded by dependenciesMu, as they are wired again when the config is reloaded.
+	dependencies   []dependency
+	dependents     []*HealthKeeper
+	dependenciesMu sync.Mutex
+	// planRequests is used to request a new plan, e.g. when the health of a group this group depends on changes.
+	planRequests chan struct{}
+
+	// locationHealth is the health of the servers by location, for use by the dependents.
+	locationHealth   map[string]LocationHealth
+	locationHealthMu sync.Mutex
+
+	// persisted keeps the state across restarts. restoredServers are the stored server states that the server
+	// checkers have not continued from yet, warmStart is true if a plan should be made right away as a result.
+	persisted       *persistedState
+	restoredServers map[string]store.ServerState
+	warmStart       bool
+
+	// consensus combines the views of the flipper nodes on the health of the servers, nil if there is only this
+	// node. overridden are the verdicts of the servers whose agreed status differs from what this node sees.
+	consensus  *consensus.Node
+	overridden map[string]consensus.Verdict
+
+	// clock tells the time, it is a virtual clock while replaying a trace or running a simulation.
+	clock clock.Clock
+	// newHTTPCheck creates the HTTP checks of the server checkers, nil to use the default.
+	newHTTPCheck checker.HTTPCheckFactory
+	// recorder records the health check results in a trace, nil if recording is disabled. replaying is true while
+	// a trace is replayed, the server checkers are not started then but are fed the recorded results.
+	recorder  *trace.Recorder
+	replaying bool
+
+	// pendingConfig is the config to change to, set by Reconfigure. reconfigures signals the healthkeeper loop
+	// to apply it, so that Reconfigure never blocks while the loop is sending an action.
+	pendingConfig   *cfgmodel.GroupConfig
+	pendingConfigMu sync.Mutex
+	reconfigures    chan struct{}
 }
 
 // HealthKeeperAction is an action that the healthkeeper requests to be executed.
@@ -42,16 +134,33 @@ func NewHealthKeeper(
 	cfg cfgmodel.GroupConfig,
 	logger *slog.Logger,
 	provider resource.Provider,
-	notifier notification.Notifier,
+	events *event.Bus,
 ) *HealthKeeper {
 	return &HealthKeeper{
 		cfg:      cfg,
 		provider: provider,
 		logger:   logger,
-		notifier: notifier,
+		events:   events,
 
 		serverWatcherCancel: make(map[string]context.CancelFunc),
+		serverCheckers:      make(map[string]*checker.Server),
 		state:               plan.NewStateFromGroup(resource.Group{}),
+		policy:              policyFromConfig(cfg),
+		failbackRequests:    make(chan struct{}, 1),
+		pins:                make(map[string]plan.Pin),
+		pinRequests:         make(chan pinRequest),
+		activePins:          make(map[string]plan.Pin),
+		configWindows:       windowsFromConfig(cfg, logger),
+		apiWindows:          make(map[string]maintenance.Window),
+		maintenanceRequests: make(chan maintenanceRequest),
+		activeWindows:       make(map[string][]resource.Server),
+		expectedTargets:     make(map[string]expectedTarget),
+		drifts:              make(chan driftReport),
+		planRequests:        make(chan struct{}, 1),
+		reconfigures:        make(chan struct{}, 1),
+		locationHealth:      make(map[string]LocationHealth),
+		persisted:           newPersistedState(cfgmodel.StoreConfig{}, store.Noop{}, cfg.ID, logger),
+		clock:               clock.Real{},
 	}
 }
 
@@ -73,34 +182,32 @@ func (h *HealthKeeper) updateResourceChanges(
 	// added or removed. That shouldn't really happen unexpectedly.
 	//
 	// We expect this to fire once when the healthkeeper starts up: then let's not notify.
-	if h.didReceiveInitialResourcesUpdate && !changeset.IsUpdatesOnly() {
-		msg := fmt.Sprintf("⚡️ Resources in group **%s** (`%s`) being watched changed substantially.\n",
-			h.cfg.DisplayName, h.cfg.ID,
-		) + fmt.Sprintf("```\n%s\n```", changeset) // TODO: prettyprint this through some template.
-		_ = h.notifier.Notify(ctx, msg)
+	initial := !h.didReceiveInitialResourcesUpdate
+	if !initial && !changeset.IsUpdatesOnly() {
+		h.events.Publish(ctx, event.ResourcesChanged{
+			Meta:      event.NewMeta(h.cfg, h.clock.Now()),
+			Changeset: changeset,
+		})
 	}
 	h.didReceiveInitialResourcesUpdate = true
 
-	startServerChecker := func(ctx context.Context, server resource.Server) {
-		serverWithStatus := resource.NewWithStatus(server, resource.State{Status: resource.StatusUnknown})
-		serverChecker := checker.NewServerChecker(h.cfg.Checks, serverWithStatus)
-		ctx, cancel := context.WithCancel(ctx)
-		h.serverWatcherCancel[server.ID()] = cancel
-		h.state.Servers[server.ID()] = serverWithStatus
-		go serverChecker.Start(ctx, recvUpdateChan)
-	}
-
 	for _, fip := range changeset.FloatingIPs.Added {
 		h.state.FloatingIPs[fip.ID()] = fip
 	}
 	for _, fip := range changeset.FloatingIPs.Updated {
+		previous := h.state.FloatingIPs[fip.ID()]
 		h.state.FloatingIPs[fip.ID()] = fip
+		if !initial {
+			h.checkDrift(ctx, previous, fip, rup.PreviousPollAt)
+		}
 	}
 	for _, fip := range changeset.FloatingIPs.Removed {
 		delete(h.state.FloatingIPs, fip.ID())
+		h.setPin(fip.ID(), plan.Pin{})
 	}
+	h.syncPins(ctx)
 	for _, server := range changeset.Servers.Added {
-		startServerChecker(ctx, server)
+		h.startServerChecker(ctx, server, recvUpdateChan)
 	}
 	for _, server := range changeset.Servers.Updated {
 		cancelServerWatcher, ok := h.serverWatcherCancel[server.ID()]
@@ -108,7 +215,7 @@ func (h *HealthKeeper) updateResourceChanges(
 			cancelServerWatcher()
 		}
 		delete(h.state.Servers, server.ID())
-		startServerChecker(ctx, server)
+		h.startServerChecker(ctx, server, recvUpdateChan)
 	}
 	for _, server := range changeset.Servers.Removed {
 		cancelServerWatcher, ok := h.serverWatcherCancel[server.ID()]
@@ -116,9 +223,79 @@ func (h *HealthKeeper) updateResourceChanges(
 			cancelServerWatcher()
 		}
 		delete(h.state.Servers, server.ID())
+		h.setServerChecker(server.ID(), nil)
+		h.persisted.removeServer(server.ID())
+		h.unshareServer(server.ID())
+	}
+	// Servers that were added or changed may be in a maintenance window.
+	h.syncMaintenance(ctx)
+}
+
+// startServerChecker starts checking the health of a server. If there is a stored state for the server, the
+// checker continues from it.
+func (h *HealthKeeper) startServerChecker(
+	ctx context.Context,
+	server resource.Server,
+	recvUpdateChan chan<- checker.ServerCheckUpdate,
+) {
+	serverWithStatus := resource.NewWithStatus(server, resource.State{Status: resource.StatusUnknown})
+	opts := []checker.ServerOption{checker.WithClock(h.clock)}
+	if h.newHTTPCheck != nil {
+		opts = append(opts, checker.WithHTTPCheckFactory(h.newHTTPCheck))
+	}
+	serverChecker := checker.NewServerChecker(h.cfg.Checks, h.cfg.FlapDamping, serverWithStatus, opts...)
+	if restored, ok := h.restoredServers[server.ID()]; ok {
+		// The flap damping history is not stored, so the server starts out not flapping.
+		state := restored.State
+		state.Flapping = false
+		state.HoldDownUntil = time.Time{}
+		serverWithStatus.SetState(state)
+		serverChecker.Restore(restored.Checks)
+		delete(h.restoredServers, server.ID())
+		h.warmStart = true
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	h.serverWatcherCancel[server.ID()] = cancel
+	h.state.Servers[server.ID()] = serverWithStatus
+	h.setServerChecker(server.ID(), serverChecker)
+	if !h.replaying {
+		go serverChecker.Start(ctx, recvUpdateChan)
 	}
 }
 
+// setServerChecker sets (or removes, if nil) the checker for a server.
+func (h *HealthKeeper) setServerChecker(serverID string, serverChecker *checker.Server) {
+	h.serverCheckersMu.Lock()
+	defer h.serverCheckersMu.Unlock()
+
+	if serverChecker == nil {
+		delete(h.serverCheckers, serverID)
+		return
+	}
+	h.serverCheckers[serverID] = serverChecker
+}
+
+// serverChecker returns the checker of a server, nil if the server is not checked.
+func (h *HealthKeeper) serverChecker(serverID string) *checker.Server {
+	h.serverCheckersMu.Lock()
+	defer h.serverCheckersMu.Unlock()
+
+	return h.serverCheckers[serverID]
+}
+
+// ResetFlapping resets the flap damping state of a server, so it can be targeted again right away.
+// It is safe to call from any goroutine.
+func (h *HealthKeeper) ResetFlapping(serverID string) error {
+	serverChecker := h.serverChecker(serverID)
+	if serverChecker == nil {
+		return fmt.Errorf("%w: %s", ErrServerNotFound, serverID)
+	}
+
+	h.logger.Info("Resetting flap damping of server.", slog.String("server_id", serverID))
+	serverChecker.ResetFlapping()
+	return nil
+}
+
 // Start monitoring the given resources, stopping anything it was watching before.
 // This function blocks until the context is cancelled.
 // It will send actions to the actionChan when it detects that an action is required to keep the resources healthy.
@@ -131,99 +308,357 @@ func (h *HealthKeeper) Start(
 	// We use a single channel to receive updates from all the server checkers.
 	serverCheckUpdateChan := make(chan checker.ServerCheckUpdate, 32)
 
+	checkpoint := h.clock.NewTicker(h.persisted.cfg.CheckpointIntervalOrDefault())
+	defer checkpoint.Stop()
+	defer h.setMaintenanceTimer(time.Time{}, time.Time{})
+
 	for {
 		select {
 		case <-ctx.Done():
 			return
+		case <-checkpoint.C():
+			h.persisted.save(ctx, h.clock.Now())
 		case resourcesUpdate := <-resourceChanges:
-			h.logger.InfoContext(ctx, "Resources changed, updating healthkeeper state.")
-			h.updateResourceChanges(ctx, resourcesUpdate, serverCheckUpdateChan)
+			h.handleResourcesUpdate(ctx, resourcesUpdate, serverCheckUpdateChan, actionChan)
 		case update := <-serverCheckUpdateChan:
-			// The actual most recent check's update that triggered this event.
-			mostRecentUpdate := update.Result.LastUpdate()
-			serverID := update.Server.ID()
-
-			if h.state.Servers[serverID] == nil {
-				// Unlikely, but it's possible there could be updates buffered that have since been removed.
-				h.logger.WarnContext(ctx, "Server not found in state, skipping update.",
-					slog.String("server_id", serverID),
-				)
-				continue
+			h.handleServerCheckUpdate(ctx, update, actionChan)
+		case report := <-h.drifts:
+			h.publishDrift(ctx, report)
+		case <-h.failbackRequests:
+			h.logger.InfoContext(ctx, "Failback requested, creating plan ignoring the failback policy.")
+			h.planAndSendAction(ctx, actionChan, plan.WithForcedFailback())
+		case req := <-h.pinRequests:
+			err := h.handlePinRequest(ctx, req)
+			req.result <- err
+			if err == nil {
+				h.planAndSendAction(ctx, actionChan)
+			}
+		case req := <-h.maintenanceRequests:
+			err := h.handleMaintenanceRequest(ctx, req)
+			req.result <- err
+			if err == nil {
+				h.planAndSendAction(ctx, actionChan)
 			}
+		case <-h.maintenanceChanges():
+			// A maintenance window started or ended, the timer is set again when the windows are synced.
+			h.maintenanceTimer = nil
+			h.planAndSendAction(ctx, actionChan)
+		case <-h.planRequests:
+			h.logger.DebugContext(ctx, "New plan requested.")
+			h.planAndSendAction(ctx, actionChan)
+		case <-h.reconfigures:
+			h.applyConfig(ctx, serverCheckUpdateChan, actionChan)
+		}
+	}
+}
 
-			logger := h.logger.With(
-				slog.String("server_id", serverID),
-				slog.String("server_name", update.Server.Name()),
-				slog.String("check_id", mostRecentUpdate.ID),
-				slog.String("server_state", string(update.ServerState.Status)),
-			)
+// handleResourcesUpdate applies a change of the resources of the group.
+func (h *HealthKeeper) handleResourcesUpdate(
+	ctx context.Context,
+	resourcesUpdate ResourceUpdate,
+	recvUpdateChan chan<- checker.ServerCheckUpdate,
+	actionChan chan<- HealthKeeperAction,
+) {
+	h.logger.InfoContext(ctx, "Resources changed, updating healthkeeper state.")
+	h.updateResourceChanges(ctx, resourcesUpdate, recvUpdateChan)
+	h.publishLocationHealth()
+	if h.warmStart {
+		// The servers continue from their stored states, there is no need to wait for the checks.
+		h.warmStart = false
+		h.logger.InfoContext(ctx, "Continuing from stored server states.")
+		h.planAndSendAction(ctx, actionChan)
+	}
+}
 
-			logger.DebugContext(ctx, "Server health check completed.",
-				slog.Uint64("rise", mostRecentUpdate.Rise),
-				slog.Uint64("fall", mostRecentUpdate.Fall),
-				slog.Duration("duration", mostRecentUpdate.Duration),
-			)
+// handleServerCheckUpdate applies an update of the health of a server, and plans with the new state.
+func (h *HealthKeeper) handleServerCheckUpdate(
+	ctx context.Context,
+	update checker.ServerCheckUpdate,
+	actionChan chan<- HealthKeeperAction,
+) {
+	// The actual most recent check's update that triggered this event.
+	mostRecentUpdate := update.Result.LastUpdate()
+	serverID := update.Server.ID()
+	h.recordCheck(serverID, update)
 
-			if update.ServerStateChanged {
-				logger.InfoContext(ctx, "Server state changed.")
-				if update.ServerState.Status == resource.StatusUnhealthy {
-					// TODO improve the formatting here.. This is a bit of a mess.
-					// We should use a template for this (and the below healthy message).
-					_ = h.notifier.Notify(ctx,
-						fmt.Sprintf(":fire: Server [**`%s`**](%s) in location `%s` became **_unhealthy_**.\n",
-							update.Server.Name(),
-							update.Server.URL,
-							update.Server.Location,
-						)+
-							fmt.Sprintf(
-								"```\n%s\n\n%+v\n```\n",
-								update.Result.LastUpdate().ID,
-								update.UnhealthyChecks(),
-							)+notificationtemplate.RenderState(h.cfg, h.state),
-					)
-
-					h.logger.ErrorContext(ctx, "Server became unhealthy.",
-						slog.String("unhealthy_checks", fmt.Sprintf("%+v", update.UnhealthyChecks())), // TODO: improve.
-					)
-				}
-
-				if update.ServerState.Status == resource.StatusHealthy &&
-					update.PreviousServerState.Status != resource.StatusUnknown {
-					_ = h.notifier.Notify(ctx,
-						fmt.Sprintf(
-							"✅ Server [**`%s`**](%s) in location `%s` became **_healthy_** again.\n",
-							update.Server.Name(),
-							update.Server.URL,
-							update.Server.Location,
-						)+notificationtemplate.RenderState(h.cfg, h.state),
-					)
-				}
-			}
+	if h.state.Servers[serverID] == nil {
+		// Unlikely, but it's possible there could be updates buffered that have since been removed.
+		h.logger.WarnContext(ctx, "Server not found in state, skipping update.",
+			slog.String("server_id", serverID),
+		)
+		return
+	}
 
-			actionPlan := plan.New(h.state)
-			if actionPlan.Empty() {
-				h.logger.DebugContext(ctx, "No actions required.")
-				continue
-			}
+	logger := h.logger.With(
+		slog.String("server_id", serverID),
+		slog.String("server_name", update.Server.Name()),
+		slog.String("check_id", mostRecentUpdate.ID),
+		slog.String("server_state", string(update.ServerState.Status)),
+	)
 
-			if !h.cfg.PlanApplyWithUnkownStatus && h.state.HasServersWithUnknownStatus() {
-				// It's best if we wait until all servers have a known status before considering applying any plan.
-				// Especially during startup, when would end up creating a lot of unnecessary actions.
-				h.logger.DebugContext(ctx, "There are still servers with unknown status, deferring plan.")
-				continue
-			}
+	logger.DebugContext(ctx, "Server health check completed.",
+		slog.Uint64("rise", mostRecentUpdate.Rise),
+		slog.Uint64("fall", mostRecentUpdate.Fall),
+		slog.Duration("duration", mostRecentUpdate.Duration),
+	)
+
+	h.shareServerState(serverID, update.ServerState)
+	h.persisted.setServer(serverID, store.ServerState{
+		State:  update.ServerState,
+		Checks: update.Result.CheckStates(),
+	})
+	if update.ServerStateChanged || update.FamilyStateChanged || update.FlappingChanged {
+		h.persisted.save(ctx, h.clock.Now())
+	}
 
-			h.logger.ErrorContext(ctx, "Actions required.",
-				slog.String("plan", actionPlan.String()),
-				slog.String("plan_id", actionPlan.ID.String()),
-				slog.String("unhealthy_floating_ips", fmt.Sprintf("%+v", h.state.UnhealthyFloatingIPs())),
+	h.syncPins(ctx)
+	if update.ServerStateChanged {
+		logger.InfoContext(ctx, "Server state changed.")
+		if update.ServerState.Status == resource.StatusUnhealthy {
+			h.logger.ErrorContext(ctx, "Server became unhealthy.",
+				slog.String("unhealthy_checks", fmt.Sprintf("%+v", update.UnhealthyChecks())), // TODO: improve.
+			)
+		}
+	} else if update.FamilyStateChanged && update.ServerState.Status != resource.StatusUnknown {
+		// The overall status did not change, but the health over IPv4 or IPv6 did. This only affects the
+		// floating IPs of that IP family.
+		logger.InfoContext(ctx, "Server IP family state changed.",
+			slog.String("ipv4_state", string(update.ServerState.IPv4)),
+			slog.String("ipv6_state", string(update.ServerState.IPv6)),
+		)
+	}
+	if update.FlappingChanged {
+		if update.ServerState.Flapping {
+			logger.WarnContext(ctx, "Server is flapping, holding it down.",
+				slog.Time("hold_down_until", update.ServerState.HoldDownUntil),
 			)
+		} else {
+			logger.InfoContext(ctx, "Server is no longer flapping.")
+		}
+	}
+	if update.ServerStateChanged || update.FamilyStateChanged || update.FlappingChanged {
+		h.events.Publish(ctx, event.ServerStateChanged{
+			Meta:            event.NewMeta(h.cfg, h.clock.Now()),
+			Server:          *update.Server,
+			Previous:        update.PreviousServerState,
+			Current:         update.ServerState,
+			StatusChanged:   update.ServerStateChanged,
+			FamilyChanged:   update.FamilyStateChanged,
+			FlappingChanged: update.FlappingChanged,
+			CheckID:         update.Result.LastUpdate().ID,
+			UnhealthyChecks: update.UnhealthyChecks(),
+			Verdict:         h.verdict(h.state.Servers[serverID]),
+			Maintenance:     h.serverMaintenance(serverID),
+			State:           h.state,
+		})
+	}
 
-			actionChan <- HealthKeeperAction{
-				State:             h.state,
-				Plan:              actionPlan,
-				ResourcesSequence: h.resourcesSequence,
+	h.publishLocationHealth()
+	h.planAndSendAction(ctx, actionChan)
+}
+
+// recordCheck records the result of a health check of a server in the trace, if recording is enabled.
+func (h *HealthKeeper) recordCheck(serverID string, update checker.ServerCheckUpdate) {
+	if h.recorder == nil {
+		return
+	}
+	entry := trace.Entry{Time: h.clock.Now(), Kind: trace.KindResetFlapping, GroupID: h.cfg.ID, ServerID: serverID}
+	if !update.FlappingReset {
+		last := update.Result.LastUpdate()
+		entry.Kind = trace.KindCheck
+		entry.Check = trace.NewCheckResult(last.ID, last.Duration, last.ResultType)
+	}
+	h.recorder.Record(entry)
+}
+
+// restore continues from the stored state of the group. It must be called before the healthkeeper is started.
+func (h *HealthKeeper) restore(state store.GroupState) {
+	h.restoredServers = state.Servers
+	for floatingIPID, pin := range state.Pins {
+		h.pins[floatingIPID] = pin
+	}
+	for windowID, window := range state.Maintenance {
+		h.apiWindows[windowID] = window
+	}
+}
+
+// planAndSendAction creates a plan for the current state, and sends it as an action if it is not empty.
+func (h *HealthKeeper) planAndSendAction(
+	ctx context.Context,
+	actionChan chan<- HealthKeeperAction,
+	opts ...plan.Option,
+) {
+	h.syncPins(ctx)
+	h.syncMaintenance(ctx)
+	h.syncDependencies(ctx)
+	opts = append([]plan.Option{plan.WithPolicy(h.policy), plan.WithNow(h.clock.Now())}, opts...)
+
+	state := h.consensusState(ctx)
+	actionPlan := plan.New(state, opts...)
+	if len(actionPlan.PendingFailbacks) > 0 {
+		h.logger.DebugContext(ctx, "Failbacks held back by the failback policy.",
+			slog.Int("num_pending_failbacks", len(actionPlan.PendingFailbacks)),
+		)
+	}
+	if !h.cfg.PlanApplyWithUnkownStatus && state.HasServersWithUnknownStatus() {
+		// It's best if we wait until all servers have a known status before considering applying any plan.
+		// Especially during startup, when would end up creating a lot of unnecessary actions.
+		if !actionPlan.Empty() || h.movesPlanned {
+			h.logger.DebugContext(ctx, "There are still servers with unknown status, deferring plan.")
+		}
+		return
+	}
+
+	h.syncWarnings(ctx, state, actionPlan)
+	if actionPlan.Empty() && !h.movesPlanned {
+		h.logger.DebugContext(ctx, "No actions required.")
+		return
+	}
+
+	h.movesPlanned = !actionPlan.Empty()
+	if actionPlan.Empty() {
+		h.logger.DebugContext(ctx, "No actions required anymore.")
+		h.sendAction(ctx, actionChan, HealthKeeperAction{State: state, Plan: actionPlan})
+		return
+	}
+
+	h.logger.ErrorContext(ctx, "Actions required.",
+		slog.String("plan", actionPlan.String()),
+		slog.String("plan_id", actionPlan.ID.String()),
+		slog.String("unhealthy_floating_ips", fmt.Sprintf("%+v", state.UnhealthyFloatingIPs())),
+	)
+	for _, action := range actionPlan.Actions {
+		h.logger.InfoContext(ctx, "Planned floating IP reassignment.",
+			slog.String("plan_id", actionPlan.ID.String()),
+			slog.String("floating_ip_id", action.FloatingIPID),
+			slog.String("server_id", action.ServerID),
+			slog.Any("reasons", action.ReasonMessages()),
+			slog.Any("alternatives", action.Alternatives),
+		)
+	}
+
+	h.events.Publish(ctx, event.PlanCreated{
+		Meta:  event.NewMeta(h.cfg, h.clock.Now()),
+		Plan:  actionPlan,
+		State: state,
+	})
+	h.sendAction(ctx, actionChan, HealthKeeperAction{State: state, Plan: actionPlan})
+}
+
+// syncWarnings publishes the warnings of a plan without actions if they differ from those of the previous plan.
+// Warnings of plans with actions are published as part of the plan.
+func (h *HealthKeeper) syncWarnings(ctx context.Context, state plan.State, actionPlan plan.Plan) {
+	if sameWarnings(h.warnings, actionPlan.Warnings) {
+		return
+	}
+	h.warnings = actionPlan.Warnings
+	if !actionPlan.Empty() {
+		return
+	}
+
+	h.logger.WarnContext(ctx, "Plan warnings changed.", slog.Any("warnings", actionPlan.Warnings))
+	h.events.Publish(ctx, event.PlanWarningsChanged{
+		Meta:     event.NewMeta(h.cfg, h.clock.Now()),
+		Warnings: actionPlan.Warnings,
+		State:    state,
+	})
+}
+
+// sameWarnings returns true if both lists contain the same warnings, in any order.
+func sameWarnings(a, b []plan.Warning) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	counts := make(map[plan.Warning]int, len(a))
+	for _, warning := range a {
+		counts[warning]++
+	}
+	for _, warning := range b {
+		if counts[warning] == 0 {
+			return false
+		}
+		counts[warning]--
+	}
+	return true
+}
+
+// sendAction sends an action for the current resources to the group.
+func (h *HealthKeeper) sendAction(ctx context.Context, actionChan chan<- HealthKeeperAction, action HealthKeeperAction) {
+	action.ResourcesSequence = h.resourcesSequence
+	select {
+	case actionChan <- action:
+	case <-ctx.Done(): // The group stopped, e.g. because it was removed from the config.
+	}
+}
+
+// Reconfigure changes the config of the healthkeeper. It is applied asynchronously by the healthkeeper loop.
+// It is safe to call from any goroutine.
+func (h *HealthKeeper) Reconfigure(cfg cfgmodel.GroupConfig) {
+	h.pendingConfigMu.Lock()
+	h.pendingConfig = &cfg
+	h.pendingConfigMu.Unlock()
+
+	select {
+	case h.reconfigures <- struct{}{}:
+	default: // A change is already pending, it will apply the latest config.
+	}
+}
+
+// applyConfig applies the pending config. If the checks changed, the server checkers are restarted and continue
+// from the current health of the servers.
+func (h *HealthKeeper) applyConfig(
+	ctx context.Context,
+	recvUpdateChan chan<- checker.ServerCheckUpdate,
+	actionChan chan<- HealthKeeperAction,
+) {
+	h.pendingConfigMu.Lock()
+	cfg := h.pendingConfig
+	h.pendingConfig = nil
+	h.pendingConfigMu.Unlock()
+	if cfg == nil {
+		return
+	}
+
+	restartCheckers := !slices.Equal(h.cfg.Checks, cfg.Checks) || h.cfg.FlapDamping != cfg.FlapDamping
+	h.cfg = *cfg
+	h.policy = policyFromConfig(*cfg)
+	h.configWindows = windowsFromConfig(*cfg, h.logger)
+
+	if restartCheckers {
+		h.logger.InfoContext(ctx, "Health checks changed, restarting server checkers.")
+		if h.restoredServers == nil {
+			h.restoredServers = make(map[string]store.ServerState)
+		}
+		for serverID, server := range h.state.Servers {
+			if cancel, ok := h.serverWatcherCancel[serverID]; ok {
+				cancel()
 			}
+			// Checks that are still configured continue from their counters, new ones start out fresh.
+			h.restoredServers[serverID] = store.ServerState{
+				State:  server.State(),
+				Checks: h.persisted.server(serverID).Checks,
+			}
+			h.startServerChecker(ctx, server.Resource, recvUpdateChan)
 		}
+		h.warmStart = false
+	}
+
+	h.planAndSendAction(ctx, actionChan)
+}
+
+// RequestPlan requests a new plan for the current state. It is safe to call from any goroutine.
+func (h *HealthKeeper) RequestPlan() {
+	select {
+	case h.planRequests <- struct{}{}:
+	default: // A request is already pending, it will plan with the latest state.
+	}
+}
+
+// RequestFailback requests a plan that moves floating IPs back to their preferred servers right away, ignoring
+// the failback policy. It is safe to call from any goroutine.
+func (h *HealthKeeper) RequestFailback() {
+	select {
+	case h.failbackRequests <- struct{}{}:
+	default: // A failback is already pending.
 	}
 }
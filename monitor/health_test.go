@@ -0,0 +1,54 @@
+package monitor
+
+import (
+	"context"
+	"log/slog"
+	"net/netip"
+	"testing"
+
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+	"github.com/gzuidhof/flipper/plan"
+	"github.com/gzuidhof/flipper/provider/mock"
+	"github.com/gzuidhof/flipper/resource"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanAndSendActionPairSplit(t *testing.T) {
+	t.Parallel()
+
+	// The pair is already split up, as no server is healthy for both IP families. That is what a new plan would do
+	// too, so it has no actions, only the warnings.
+	flips := []resource.FloatingIP{
+		{
+			HetznerID: 11, FloatingIPName: "web-v4", Location: "nbg1", CurrentTarget: "1", ResourceIndex: -1,
+			IP: netip.MustParseAddr("192.0.2.1"),
+		},
+		{
+			HetznerID: 12, FloatingIPName: "web-v6", Location: "nbg1", CurrentTarget: "2", ResourceIndex: -1,
+			IP: netip.MustParseAddr("2001:db8::1"),
+		},
+	}
+	servers := []*resource.WithStatus[resource.Server]{
+		resource.NewWithStatus(resource.Server{HetznerID: 1, ServerName: "lb-1", Location: "nbg1"}, resource.State{
+			Status: resource.StatusUnhealthy, IPv4: resource.StatusHealthy, IPv6: resource.StatusUnhealthy,
+		}),
+		resource.NewWithStatus(resource.Server{HetznerID: 2, ServerName: "lb-2", Location: "nbg1"}, resource.State{
+			Status: resource.StatusUnhealthy, IPv4: resource.StatusUnhealthy, IPv6: resource.StatusHealthy,
+		}),
+	}
+
+	notifier := &recordingNotifier{}
+	cfg := cfgmodel.GroupConfig{ID: "lb", Pairing: cfgmodel.PairingConfig{ByName: true}}
+	h := NewHealthKeeper(cfg, slog.Default(), mock.NewProvider(), notifyingBus(notifier))
+	h.state = plan.NewState(flips, servers)
+
+	actionChan := make(chan HealthKeeperAction, 1)
+	h.planAndSendAction(context.Background(), actionChan)
+	h.planAndSendAction(context.Background(), actionChan)
+
+	assert.Empty(t, actionChan, "there is nothing to execute")
+	require.Len(t, notifier.messages, 1, "the split is reported once")
+	assert.Contains(t, notifier.messages[0], "**`web-v4`**: pair split")
+	assert.Contains(t, notifier.messages[0], "**`web-v6`**: pair split")
+}
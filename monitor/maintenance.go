@@ -0,0 +1,254 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+	"github.com/gzuidhof/flipper/event"
+	"github.com/gzuidhof/flipper/maintenance"
+	"github.com/gzuidhof/flipper/resource"
+)
+
+// Sources of maintenance windows.
+const (
+	// MaintenanceSourceConfig is the source of the maintenance windows of the config.
+	MaintenanceSourceConfig = "config"
+	// MaintenanceSourceAPI is the source of the maintenance windows set through the admin API.
+	MaintenanceSourceAPI = "api"
+)
+
+// MaintenanceStatus is the status of a maintenance window of a group.
+type MaintenanceStatus struct {
+	Window maintenance.Window `json:"window"`
+	// Source is where the window comes from, MaintenanceSourceConfig or MaintenanceSourceAPI.
+	Source string `json:"source"`
+	// Active is true if the window is active, NextChange is when it starts or ends next, the zero time if never.
+	Active     bool      `json:"active"`
+	NextChange time.Time `json:"next_change"`
+	// Servers are the names of the servers the window applies to.
+	Servers []string `json:"servers"`
+}
+
+// maintenanceRequest is a request to set a maintenance window, or to remove it if the window is nil.
+type maintenanceRequest struct {
+	windowID string
+	window   *maintenance.Window
+	result   chan error
+}
+
+// SetMaintenance sets a maintenance window, replacing the window with the same ID, or removes it if the window is
+// nil. A plan is created right away. It is safe to call from any goroutine, it blocks until the healthkeeper
+// handled the request.
+func (h *HealthKeeper) SetMaintenance(ctx context.Context, windowID string, window *maintenance.Window) error {
+	req := maintenanceRequest{windowID: windowID, window: window, result: make(chan error, 1)}
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("failed to request maintenance window: %w", ctx.Err())
+	case h.maintenanceRequests <- req:
+	}
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("failed to wait for maintenance window: %w", ctx.Err())
+	case err := <-req.result:
+		return err
+	}
+}
+
+// Maintenance returns the status of the maintenance windows, those of the config first.
+// It is safe to call from any goroutine.
+func (h *HealthKeeper) Maintenance() []MaintenanceStatus {
+	h.maintenanceStatusMu.Lock()
+	defer h.maintenanceStatusMu.Unlock()
+
+	return slices.Clone(h.maintenanceStatus)
+}
+
+// handleMaintenanceRequest validates and applies a maintenance request.
+func (h *HealthKeeper) handleMaintenanceRequest(ctx context.Context, req maintenanceRequest) error {
+	for _, window := range h.configWindows {
+		if window.ID == req.windowID {
+			return fmt.Errorf("%w: %s", ErrMaintenanceWindowInConfig, req.windowID)
+		}
+	}
+
+	if req.window == nil {
+		if _, ok := h.apiWindows[req.windowID]; !ok {
+			return fmt.Errorf("%w: %s", ErrMaintenanceWindowNotFound, req.windowID)
+		}
+		h.logger.InfoContext(ctx, "Removing maintenance window.", slog.String("window_id", req.windowID))
+		delete(h.apiWindows, req.windowID)
+		h.syncMaintenance(ctx)
+		return nil
+	}
+
+	window := *req.window
+	window.ID = req.windowID
+	if err := window.Validate(); err != nil {
+		return err //nolint:wrapcheck // The error describes the window.
+	}
+	h.logger.InfoContext(ctx, "Setting maintenance window.",
+		slog.String("window_id", window.ID),
+		slog.String("mode", string(window.ModeOrDefault())),
+	)
+	h.apiWindows[window.ID] = window
+	h.syncMaintenance(ctx)
+	return nil
+}
+
+// windowsFromConfig returns the maintenance windows of the config. The config is validated, windows that are
+// invalid anyway are logged and left out.
+func windowsFromConfig(cfg cfgmodel.GroupConfig, logger *slog.Logger) []maintenance.Window {
+	windows := make([]maintenance.Window, 0, len(cfg.Maintenance))
+	for _, windowCfg := range cfg.Maintenance {
+		window, err := windowCfg.Window()
+		if err != nil {
+			logger.Error("Invalid maintenance window, ignoring it.",
+				slog.String("window_id", windowCfg.ID),
+				slog.String("error", err.Error()),
+			)
+			continue
+		}
+		windows = append(windows, window)
+	}
+	return windows
+}
+
+// syncMaintenance removes the maintenance windows of the admin API that ended, updates the active windows of the
+// state, and notifies when windows start or end. The timer is set to the next start or end of a window.
+func (h *HealthKeeper) syncMaintenance(ctx context.Context) {
+	now := h.clock.Now()
+	for id, window := range h.apiWindows {
+		if window.Expired(now) {
+			h.logger.InfoContext(ctx, "Maintenance window ended, removing it.", slog.String("window_id", id))
+			delete(h.apiWindows, id)
+		}
+	}
+	if h.persisted.setMaintenance(h.apiWindows) {
+		h.persisted.save(ctx, now)
+	}
+
+	windows := slices.Clone(h.configWindows)
+	sources := make([]string, len(windows), len(windows)+len(h.apiWindows))
+	for i := range windows {
+		sources[i] = MaintenanceSourceConfig
+	}
+	for _, id := range sortedKeys(h.apiWindows) {
+		windows = append(windows, h.apiWindows[id])
+		sources = append(sources, MaintenanceSourceAPI)
+	}
+
+	serverIDs := sortedKeys(h.state.Servers)
+	// When several windows apply to a server, the first one that is active applies.
+	active := map[string]maintenance.Window{}
+	activeWindows := map[string][]resource.Server{}
+	statuses := make([]MaintenanceStatus, 0, len(windows))
+	var next time.Time
+	for i, window := range windows {
+		until, ok := window.Active(now)
+		status := MaintenanceStatus{
+			Window:     window,
+			Source:     sources[i],
+			Active:     ok,
+			NextChange: window.NextChange(now),
+			Servers:    []string{},
+		}
+		var covered []resource.Server
+		for _, serverID := range serverIDs {
+			server := h.state.Servers[serverID].Resource
+			if !window.Covers(server) {
+				continue
+			}
+			covered = append(covered, server)
+			status.Servers = append(status.Servers, server.Name())
+			if _, alreadyActive := active[serverID]; ok && !alreadyActive {
+				active[serverID] = window
+			}
+		}
+		statuses = append(statuses, status)
+
+		if !status.NextChange.IsZero() && (next.IsZero() || status.NextChange.Before(next)) {
+			next = status.NextChange
+		}
+		if !ok {
+			continue
+		}
+		activeWindows[window.ID] = covered
+		if _, wasActive := h.activeWindows[window.ID]; !wasActive {
+			h.logger.InfoContext(ctx, "Maintenance window started.",
+				slog.String("window_id", window.ID),
+				slog.Time("until", until),
+			)
+			h.events.Publish(ctx, event.MaintenanceStarted{
+				Meta:    event.NewMeta(h.cfg, now),
+				Window:  window,
+				Until:   until,
+				Servers: covered,
+			})
+		}
+	}
+	for _, id := range sortedKeys(h.activeWindows) {
+		if _, active := activeWindows[id]; active {
+			continue
+		}
+		h.logger.InfoContext(ctx, "Maintenance window ended.", slog.String("window_id", id))
+		h.events.Publish(ctx, event.MaintenanceEnded{
+			Meta:     event.NewMeta(h.cfg, now),
+			WindowID: id,
+			Servers:  h.activeWindows[id],
+		})
+	}
+
+	// The state is shared with the actions that were sent, so the map is replaced rather than changed.
+	h.state.Maintenance = active
+	h.activeWindows = activeWindows
+	h.setMaintenanceTimer(now, next)
+
+	h.maintenanceStatusMu.Lock()
+	defer h.maintenanceStatusMu.Unlock()
+	h.maintenanceStatus = statuses
+}
+
+// setMaintenanceTimer sets the timer to fire at the next time, or stops it if the next time is the zero time.
+// The timer is left alone if it already fires at that time.
+func (h *HealthKeeper) setMaintenanceTimer(now, next time.Time) {
+	if h.maintenanceTimer != nil && next.Equal(h.maintenanceNext) {
+		return
+	}
+	if h.maintenanceTimer != nil {
+		// The timer may have fired while the healthkeeper was busy, its time is no longer of interest.
+		if !h.maintenanceTimer.Stop() {
+			select {
+			case <-h.maintenanceTimer.C():
+			default:
+			}
+		}
+		h.maintenanceTimer = nil
+	}
+	h.maintenanceNext = next
+	if !next.IsZero() {
+		h.maintenanceTimer = h.clock.NewTimer(next.Sub(now))
+	}
+}
+
+// maintenanceChanges returns the channel of the timer that fires when a maintenance window starts or ends, nil if
+// no window starts or ends.
+func (h *HealthKeeper) maintenanceChanges() <-chan time.Time {
+	if h.maintenanceTimer == nil {
+		return nil
+	}
+	return h.maintenanceTimer.C()
+}
+
+// serverMaintenance returns the active maintenance window of a server, nil if it is not in maintenance.
+func (h *HealthKeeper) serverMaintenance(serverID string) *maintenance.Window {
+	window, ok := h.state.Maintenance[serverID]
+	if !ok {
+		return nil
+	}
+	return &window
+}
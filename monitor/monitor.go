@@ -4,12 +4,23 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"sync"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/gzuidhof/flipper/audit"
+	"github.com/gzuidhof/flipper/clock"
 	"github.com/gzuidhof/flipper/config/cfgmodel"
+	"github.com/gzuidhof/flipper/consensus"
+	"github.com/gzuidhof/flipper/event"
+	"github.com/gzuidhof/flipper/leader"
+	"github.com/gzuidhof/flipper/maintenance"
 	"github.com/gzuidhof/flipper/notification"
+	"github.com/gzuidhof/flipper/plan"
 	"github.com/gzuidhof/flipper/provider/hetzner"
 	"github.com/gzuidhof/flipper/resource"
-	"golang.org/x/sync/errgroup"
+	"github.com/gzuidhof/flipper/store"
+	"github.com/gzuidhof/flipper/trace"
 )
 
 //nolint:ireturn,nolintlint // This is a factory function.
@@ -25,10 +36,56 @@ func buildProvider(ctx context.Context, group cfgmodel.GroupConfig) (resource.Pr
 	return provider, nil
 }
 
+// recentEvents is the number of most recent events that are kept for the HTTP API.
+const recentEvents = 100
+
 // Monitor watches resources. It supports watching multiple groups of resources in parallel.
 type Monitor struct {
 	didStart bool
+	logger   *slog.Logger
+	notifier *notification.Swappable
+	store    store.Store
+	// events is the bus the groups publish their events on, recent keeps the most recent of them.
+	events *event.Bus
+	recent *event.Recorder
+	// auditLog records the decisions and actions of all groups, nil if the audit log is disabled.
+	auditLog *audit.Log
+	// recorder records the inputs of all groups in a trace, nil if recording is disabled.
+	recorder *trace.Recorder
+	// newProvider creates the provider of a group.
+	newProvider func(ctx context.Context, cfg cfgmodel.GroupConfig) (resource.Provider, error)
+	// clock is the clock of the monitor and its groups.
+	clock clock.Clock
+
+	// cfg is the current config. It is guarded by reloadMu, which is held while the groups are started or the
+	// config is reloaded.
+	cfg      *cfgmodel.Config
+	reloadMu sync.Mutex
+
+	// groups are the groups being monitored. The slice is replaced when the config is reloaded.
 	groups   []*Group
+	groupsMu sync.RWMutex
+
+	// watchCtx is the context of Watch, groups that are added by a reload are started with it. stops cancels the
+	// running groups by group ID, running tracks them so that Watch can wait for them to stop.
+	watchCtx context.Context //nolint:containedctx // Groups can be added while watching.
+	stops    map[string]context.CancelFunc
+	running  sync.WaitGroup
+
+	// elector takes part in the leader election, nil if leader election is disabled.
+	elector *leader.Elector
+	// node shares health check results with other flipper nodes, nil if consensus is disabled.
+	node *consensus.Node
+}
+
+// Option configures a monitor.
+type Option func(m *Monitor)
+
+// WithMonitorClock sets the clock of the monitor and the groups it creates. It defaults to the real clock.
+func WithMonitorClock(clk clock.Clock) Option {
+	return func(m *Monitor) {
+		m.clock = clk
+	}
 }
 
 // New creates a new monitor from a config.
@@ -37,44 +94,370 @@ func New(
 	cfg *cfgmodel.Config,
 	logger *slog.Logger,
 	notifier notification.Notifier,
+	opts ...Option,
 ) (*Monitor, error) {
-	groups := make([]*Group, len(cfg.Groups))
-
 	if len(cfg.Groups) == 0 {
 		return nil, fmt.Errorf("no groups to monitor, check your configuration")
 	}
 
-	for i, group := range cfg.Groups {
-		provider, err := buildProvider(ctx, group)
+	st, err := store.New(cfg.Store)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create store: %w", err)
+	}
+
+	m := &Monitor{
+		logger: logger,
+		// The notifier is swapped when the notification targets change on a reload.
+		notifier:    notification.NewSwappable(notifier),
+		store:       st,
+		events:      event.NewBus(),
+		recent:      event.NewRecorder(recentEvents),
+		newProvider: buildProvider,
+		clock:       clock.Real{},
+		cfg:         cfg,
+		stops:       make(map[string]context.CancelFunc),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	m.events.Subscribe(notification.EventHandler(m.notifier))
+	m.events.Subscribe(m.recent.Handle)
+	if cfg.Audit.Enabled {
+		m.auditLog, err = audit.Open(cfg.Audit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit log: %w", err)
+		}
+		m.events.Subscribe(audit.Handler(m.auditLog, logger))
+	}
+	if cfg.Trace.Enabled {
+		m.recorder, err = trace.Open(cfg.Trace.Path, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open trace: %w", err)
+		}
+	}
+
+	if cfg.LeaderElection.Enabled {
+		m.elector = leader.NewElector(cfg.LeaderElection, buildLeaseBackend(cfg.LeaderElection, cfg.Groups), logger)
+		m.elector.OnChange(m.leaderChanged)
+	}
+	if cfg.Consensus.Enabled {
+		m.node = consensus.NewNode(cfg.Consensus, logger)
+		m.node.OnChange(func(context.Context) {
+			// The status the nodes agree on may have changed, the groups plan again with it.
+			for _, group := range m.groupList() {
+				group.healthkeeper.RequestPlan()
+			}
+		})
+	}
+
+	groups := make([]*Group, len(cfg.Groups))
+	for i, groupCfg := range cfg.Groups {
+		groups[i], err = m.newGroup(ctx, groupCfg)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create provider: %w", err)
+			return nil, err
 		}
+	}
+	wireDependencies(groups)
+	m.groups = groups
+
+	return m, nil
+}
+
+// newGroup creates a group that uses the store, leader election and consensus of the monitor.
+func (w *Monitor) newGroup(ctx context.Context, cfg cfgmodel.GroupConfig) (*Group, error) {
+	provider, err := w.newProvider(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provider: %w", err)
+	}
+
+	group := NewGroup(cfg, provider, w.logger, w.events, WithClock(w.clock))
+	group.useStore(w.cfg.Store, w.store)
+	if w.recorder != nil {
+		group.useTrace(w.recorder)
+	}
+	if w.elector != nil {
+		group.leader = w.elector
+	}
+	if w.node != nil {
+		group.healthkeeper.consensus = w.node
+	}
+	return group, nil
+}
+
+// groupList returns the groups being monitored.
+func (w *Monitor) groupList() []*Group {
+	w.groupsMu.RLock()
+	defer w.groupsMu.RUnlock()
+
+	return w.groups
+}
+
+//nolint:ireturn // This is a factory function.
+func buildLeaseBackend(cfg cfgmodel.LeaderElectionConfig, groups []cfgmodel.GroupConfig) leader.Backend {
+	if cfg.Backend == cfgmodel.LeaseBackendHetzner {
+		return hetzner.NewLeaseBackend(cfg.Hetzner, groups)
+	}
+	return leader.NewFile(cfg.File.Path)
+}
+
+// leaderChanged is called when this instance becomes or stops being the leader.
+func (w *Monitor) leaderChanged(ctx context.Context, isLeader bool, lease leader.Lease) {
+	e := event.LeadershipChanged{
+		Meta:     event.Meta{At: w.clock.Now()},
+		Identity: w.elector.Identity(),
+		Leader:   isLeader,
+	}
+	if !isLeader {
+		e.Holder = lease.Holder
+	}
+	w.events.Publish(ctx, e)
+	if !isLeader {
+		return
+	}
 
-		groups[i] = NewGroup(group, provider, logger, notifier)
+	// Plans that were made while following were not executed, the groups plan again right away.
+	for _, group := range w.groupList() {
+		group.healthkeeper.RequestPlan()
 	}
+}
+
+// Subscribe makes the handler receive the events of all groups from now on.
+func (w *Monitor) Subscribe(handler event.Handler) {
+	w.events.Subscribe(handler)
+}
 
-	return &Monitor{
-		groups: groups,
-	}, nil
+// RecentEvents returns the most recent events of all groups, oldest first.
+func (w *Monitor) RecentEvents() []event.Event {
+	return w.recent.Events()
 }
 
 // Watch starts monitoring the resources. To gracefully stop watching, cancel the context.
+// This function blocks until the context is cancelled and all groups stopped.
 func (w *Monitor) Watch(ctx context.Context) error {
+	w.reloadMu.Lock()
 	if w.didStart {
+		w.reloadMu.Unlock()
 		return fmt.Errorf("watcher already started")
 	}
 	w.didStart = true
+	w.watchCtx = ctx
 
-	errgrp := errgroup.Group{}
-	errggrp, ctx := errgroup.WithContext(ctx)
+	if w.elector != nil {
+		w.running.Add(1)
+		go func() {
+			defer w.running.Done()
+			w.elector.Start(ctx)
+		}()
+	}
+	if w.node != nil {
+		w.running.Add(1)
+		go func() {
+			defer w.running.Done()
+			w.node.Start(ctx)
+		}()
+	}
 
-	for _, group := range w.groups {
-		group := group
-		errggrp.Go(func() error {
-			return group.Start(ctx)
-		})
+	for _, group := range w.groupList() {
+		w.startGroup(group)
+	}
+	w.reloadMu.Unlock()
+
+	<-ctx.Done()
+	w.running.Wait()
+
+	if w.auditLog != nil {
+		if err := w.auditLog.Close(); err != nil {
+			return fmt.Errorf("failed to close audit log: %w", err)
+		}
+	}
+	if w.recorder != nil {
+		if err := w.recorder.Close(); err != nil {
+			return fmt.Errorf("failed to close trace: %w", err)
+		}
+	}
+	return nil
+}
+
+// startGroup starts a group with its own context, so that it can be stopped on its own.
+// The caller must hold reloadMu.
+func (w *Monitor) startGroup(group *Group) {
+	ctx, cancel := context.WithCancel(w.watchCtx)
+	w.stops[group.ID()] = cancel
+
+	w.running.Add(1)
+	go func() {
+		defer w.running.Done()
+		if err := group.Start(ctx); err != nil {
+			w.logger.ErrorContext(ctx, "Group stopped with an error.",
+				slog.String("group_id", group.ID()),
+				slog.String("error", err.Error()),
+			)
+		}
+	}()
+}
+
+// stopGroup stops a running group. The caller must hold reloadMu.
+func (w *Monitor) stopGroup(groupID string) {
+	if cancel, ok := w.stops[groupID]; ok {
+		cancel()
+		delete(w.stops, groupID)
+	}
+	if w.node != nil {
+		w.node.RemoveGroup(groupID)
+	}
+}
+
+// group returns the group with the given ID.
+func (w *Monitor) group(groupID string) (*Group, error) {
+	for _, group := range w.groupList() {
+		if group.ID() == groupID {
+			return group, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrGroupNotFound, groupID)
+}
+
+// ResetFlapping resets the flap damping state of a server, lifting its hold-down.
+func (w *Monitor) ResetFlapping(groupID, serverID string) error {
+	group, err := w.group(groupID)
+	if err != nil {
+		return err
+	}
+	return group.ResetFlapping(serverID)
+}
+
+// Failback requests the floating IPs in a group to be moved back to their preferred servers right away,
+// regardless of the failback policy.
+func (w *Monitor) Failback(groupID string) error {
+	group, err := w.group(groupID)
+	if err != nil {
+		return err
+	}
+	group.Failback()
+	return nil
+}
+
+// Resync requests the resources of a group to be polled right away, instead of at its next poll interval.
+func (w *Monitor) Resync(groupID string) error {
+	group, err := w.group(groupID)
+	if err != nil {
+		return err
+	}
+	group.Resync()
+	return nil
+}
+
+// ResyncAll requests the resources of all groups to be polled right away.
+func (w *Monitor) ResyncAll() {
+	for _, group := range w.groupList() {
+		group.Resync()
+	}
+}
+
+// OverrideSafety lifts the safety limits of a group for the configured override duration.
+// It returns the time until which the override is active.
+func (w *Monitor) OverrideSafety(ctx context.Context, groupID string) (time.Time, error) {
+	group, err := w.group(groupID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return group.OverrideSafety(ctx), nil
+}
+
+// LastPlan returns the most recent plan of a group that required actions, it returns false if there was none yet.
+func (w *Monitor) LastPlan(groupID string) (plan.Plan, bool, error) {
+	group, err := w.group(groupID)
+	if err != nil {
+		return plan.Plan{}, false, err
 	}
+	p, ok := group.LastPlan()
+	return p, ok, nil
+}
+
+// Pin pins a floating IP in a group to a server, or removes its pin if the pin is the zero pin.
+func (w *Monitor) Pin(ctx context.Context, groupID, floatingIPID string, pin plan.Pin) error {
+	group, err := w.group(groupID)
+	if err != nil {
+		return err
+	}
+	return group.Pin(ctx, floatingIPID, pin)
+}
+
+// Pins returns the active pins in a group by floating IP ID.
+func (w *Monitor) Pins(groupID string) (map[string]plan.Pin, error) {
+	group, err := w.group(groupID)
+	if err != nil {
+		return nil, err
+	}
+	return group.Pins(), nil
+}
+
+// SetMaintenance sets a maintenance window in a group, or removes it if the window is nil.
+func (w *Monitor) SetMaintenance(ctx context.Context, groupID, windowID string, window *maintenance.Window) error {
+	group, err := w.group(groupID)
+	if err != nil {
+		return err
+	}
+	return group.SetMaintenance(ctx, windowID, window)
+}
+
+// Maintenance returns the status of the maintenance windows in a group.
+func (w *Monitor) Maintenance(groupID string) ([]MaintenanceStatus, error) {
+	group, err := w.group(groupID)
+	if err != nil {
+		return nil, err
+	}
+	return group.Maintenance(), nil
+}
+
+// PendingPlan returns the plan of a group that waits for approval, it returns false if there is none.
+func (w *Monitor) PendingPlan(groupID string) (PendingPlan, bool, error) {
+	group, err := w.group(groupID)
+	if err != nil {
+		return PendingPlan{}, false, err
+	}
+	p, ok := group.PendingPlan()
+	return p, ok, nil
+}
+
+// Decide approves or rejects the plan of a group that waits for approval. The token is the token from the links in
+// the notification of the plan, it must be empty if the request is authorized otherwise.
+func (w *Monitor) Decide(ctx context.Context, groupID string, planID uuid.UUID, approve bool, token string) error {
+	group, err := w.group(groupID)
+	if err != nil {
+		return err
+	}
+	return group.Decide(ctx, planID, approve, token)
+}
+
+// LeaderStatus describes the leader election from the point of view of this instance.
+type LeaderStatus struct {
+	// Enabled is false if leader election is disabled, this instance is then always the leader.
+	Enabled  bool   `json:"enabled"`
+	Identity string `json:"identity,omitempty"`
+	IsLeader bool   `json:"is_leader"`
+	// Lease is the lease as it was last seen, it may be held by another instance.
+	Lease *leader.Lease `json:"lease,omitempty"`
+}
+
+// Leader returns the status of the leader election.
+func (w *Monitor) Leader() LeaderStatus {
+	if w.elector == nil {
+		return LeaderStatus{IsLeader: true}
+	}
+	lease := w.elector.Lease()
+	return LeaderStatus{
+		Enabled:  true,
+		Identity: w.elector.Identity(),
+		IsLeader: w.elector.IsLeader(),
+		Lease:    &lease,
+	}
+}
 
-	//nolint:wrapcheck // we wrap the error in the subroutines.
-	return errgrp.Wait()
+// ConsensusView returns the view of this node on the health of the servers, to share with the other nodes.
+func (w *Monitor) ConsensusView() (consensus.View, error) {
+	if w.node == nil {
+		return consensus.View{}, ErrConsensusDisabled
+	}
+	return w.node.View(), nil
 }
@@ -0,0 +1,130 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+
+	"github.com/gzuidhof/flipper/event"
+	"github.com/gzuidhof/flipper/plan"
+)
+
+// pinRequest is a request to pin a floating IP, or to remove its pin if the pin is the zero pin.
+type pinRequest struct {
+	floatingIPID string
+	pin          plan.Pin
+	result       chan error
+}
+
+// Pin pins a floating IP to a server, or removes its pin if the pin is the zero pin. A plan is created right away.
+// It is safe to call from any goroutine, it blocks until the healthkeeper handled the request.
+func (h *HealthKeeper) Pin(ctx context.Context, floatingIPID string, pin plan.Pin) error {
+	req := pinRequest{floatingIPID: floatingIPID, pin: pin, result: make(chan error, 1)}
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("failed to request pin: %w", ctx.Err())
+	case h.pinRequests <- req:
+	}
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("failed to wait for pin: %w", ctx.Err())
+	case err := <-req.result:
+		return err
+	}
+}
+
+// Pins returns the active pins by floating IP ID, including those set on the floating IPs themselves.
+// It is safe to call from any goroutine.
+func (h *HealthKeeper) Pins() map[string]plan.Pin {
+	h.activePinsMu.Lock()
+	defer h.activePinsMu.Unlock()
+
+	return maps.Clone(h.activePins)
+}
+
+// handlePinRequest validates and applies a pin request.
+func (h *HealthKeeper) handlePinRequest(ctx context.Context, req pinRequest) error {
+	flip, ok := h.state.FloatingIPs[req.floatingIPID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrFloatingIPNotFound, req.floatingIPID)
+	}
+
+	if req.pin.ServerID == "" {
+		h.logger.InfoContext(ctx, "Removing pin of floating IP.", slog.String("floating_ip_id", flip.ID()))
+		h.setPin(flip.ID(), plan.Pin{})
+		h.syncPins(ctx)
+		h.events.Publish(ctx, event.FloatingIPUnpinned{
+			Meta:           event.NewMeta(h.cfg, h.clock.Now()),
+			FloatingIPID:   flip.ID(),
+			FloatingIPName: flip.Name(),
+		})
+		return nil
+	}
+
+	server, ok := h.state.Servers[req.pin.ServerID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrServerNotFound, req.pin.ServerID)
+	}
+
+	h.logger.InfoContext(ctx, "Pinning floating IP.",
+		slog.String("floating_ip_id", flip.ID()),
+		slog.String("server_id", server.Resource.ID()),
+		slog.Time("until", req.pin.Until),
+		slog.Bool("force", req.pin.Force),
+	)
+	h.setPin(flip.ID(), req.pin)
+	h.syncPins(ctx)
+	h.events.Publish(ctx, event.FloatingIPPinned{
+		Meta:       event.NewMeta(h.cfg, h.clock.Now()),
+		FloatingIP: flip,
+		Server:     server.Resource,
+		Pin:        req.pin,
+		State:      h.state,
+	})
+	return nil
+}
+
+// setPin pins a floating IP, or removes its pin if the pin is the zero pin.
+// It must only be called from the healthkeeper loop, followed by syncPins.
+func (h *HealthKeeper) setPin(floatingIPID string, pin plan.Pin) {
+	if pin.ServerID == "" {
+		delete(h.pins, floatingIPID)
+		return
+	}
+	h.pins[floatingIPID] = pin
+}
+
+// syncPins removes the expired pins, and updates the pins of the state and the active pins.
+func (h *HealthKeeper) syncPins(ctx context.Context) {
+	now := h.clock.Now()
+	for floatingIPID, pin := range h.pins {
+		if pin.Active(now) {
+			continue
+		}
+		delete(h.pins, floatingIPID)
+
+		var name string
+		if flip, ok := h.state.FloatingIPs[floatingIPID]; ok {
+			name = flip.Name()
+		}
+		h.logger.InfoContext(ctx, "Pin of floating IP expired.", slog.String("floating_ip_id", floatingIPID))
+		h.events.Publish(ctx, event.FloatingIPUnpinned{
+			Meta:           event.NewMeta(h.cfg, now),
+			FloatingIPID:   floatingIPID,
+			FloatingIPName: name,
+			Expired:        true,
+		})
+	}
+
+	// The state is shared with the actions that were sent, so the map is replaced rather than changed.
+	h.state.Pins = maps.Clone(h.pins)
+	if h.persisted.setPins(h.pins) {
+		h.persisted.save(ctx, h.clock.Now())
+	}
+
+	h.activePinsMu.Lock()
+	defer h.activePinsMu.Unlock()
+	h.activePins = h.state.ActivePins(now)
+}
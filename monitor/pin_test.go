@@ -0,0 +1,58 @@
+package monitor
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+	"github.com/gzuidhof/flipper/plan"
+	"github.com/gzuidhof/flipper/provider/mock"
+	"github.com/gzuidhof/flipper/resource"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandlePinRequest(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	notifier := &recordingNotifier{}
+	h := NewHealthKeeper(cfgmodel.GroupConfig{}, slog.Default(), mock.NewProvider(), notifyingBus(notifier))
+	h.state = plan.NewState(
+		[]resource.FloatingIP{
+			{HetznerID: 11, CurrentTarget: "1"},
+			{HetznerID: 12, CurrentTarget: "1", Pin: resource.Pin{ServerName: "lb-1"}},
+		},
+		[]*resource.WithStatus[resource.Server]{
+			resource.NewWithStatus(resource.Server{HetznerID: 1, ServerName: "lb-1"}, resource.State{}),
+			resource.NewWithStatus(resource.Server{HetznerID: 2, ServerName: "lb-2"}, resource.State{}),
+		},
+	)
+	pin := plan.Pin{ServerID: "2", Until: time.Now().Add(time.Hour)}
+
+	err := h.handlePinRequest(ctx, pinRequest{floatingIPID: "13", pin: pin})
+	assert.ErrorIs(t, err, ErrFloatingIPNotFound)
+
+	err = h.handlePinRequest(ctx, pinRequest{floatingIPID: "11", pin: plan.Pin{ServerID: "3"}})
+	assert.ErrorIs(t, err, ErrServerNotFound)
+
+	err = h.handlePinRequest(ctx, pinRequest{floatingIPID: "11", pin: pin})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]plan.Pin{
+		"11": pin,
+		"12": {ServerID: "1", Reason: "label"},
+	}, h.Pins())
+	assert.Equal(t, map[string]plan.Pin{"11": pin}, h.state.Pins)
+
+	err = h.handlePinRequest(ctx, pinRequest{floatingIPID: "11"})
+	assert.NoError(t, err)
+	assert.NotContains(t, h.Pins(), "11")
+
+	// Expired pins are removed, with a notification.
+	h.setPin("11", plan.Pin{ServerID: "2", Until: time.Now().Add(-time.Minute)})
+	numMessages := len(notifier.messages)
+	h.syncPins(ctx)
+	assert.NotContains(t, h.Pins(), "11")
+	assert.Len(t, notifier.messages, numMessages+1)
+}
@@ -0,0 +1,53 @@
+package monitor
+
+import (
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+	"github.com/gzuidhof/flipper/plan"
+)
+
+// policyFromConfig creates the plan policy for a group from its config.
+func policyFromConfig(cfg cfgmodel.GroupConfig) plan.Policy {
+	policy := plan.Policy{
+		Strategy:    plan.Strategy(cfg.Strategy),
+		Failback:    failbackFromConfig(cfg.Failback),
+		FloatingIPs: make(map[string]plan.FloatingIPPolicy, len(cfg.FloatingIPOverrides)),
+		Servers:     make(map[string]plan.ServerPolicy, len(cfg.ServerOverrides)),
+		PairByName:  cfg.Pairing.ByName,
+
+		LocationFallbacks: cfg.LocationFallbacks,
+		HomeLocationOnly:  cfg.HomeLocationOnly,
+	}
+	if policy.Failback.Policy == "" {
+		policy.Failback.Policy = plan.FailbackImmediate
+	}
+
+	for _, override := range cfg.FloatingIPOverrides {
+		flipPolicy := plan.FloatingIPPolicy{
+			Priority:          override.Priority,
+			LocationFallbacks: override.LocationFallbacks,
+			HomeLocationOnly:  override.HomeLocationOnly,
+		}
+		if override.Failback.Policy != "" {
+			failback := failbackFromConfig(override.Failback)
+			flipPolicy.Failback = &failback
+		}
+		policy.FloatingIPs[override.Name] = flipPolicy
+	}
+
+	for _, override := range cfg.ServerOverrides {
+		policy.Servers[override.Name] = plan.ServerPolicy{
+			Weight:         override.Weight,
+			MaxFloatingIPs: override.MaxFloatingIPs,
+			Priority:       override.Priority,
+		}
+	}
+
+	return policy
+}
+
+func failbackFromConfig(cfg cfgmodel.FailbackConfig) plan.Failback {
+	return plan.Failback{
+		Policy: plan.FailbackPolicy(cfg.Policy),
+		Delay:  cfg.DelayOrDefault(),
+	}
+}
@@ -0,0 +1,189 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+	"github.com/gzuidhof/flipper/event"
+	"github.com/gzuidhof/flipper/notification"
+)
+
+// ReloadResult describes what changed when the config was reloaded.
+type ReloadResult struct {
+	// Added, Removed, Restarted and Reconfigured are the IDs of the groups that changed. Groups are restarted if
+	// their provider changed, other changes are applied while they run, keeping the health of their servers.
+	Added        []string
+	Removed      []string
+	Restarted    []string
+	Reconfigured []string
+
+	// NotificationsChanged is true if the notification targets changed.
+	NotificationsChanged bool
+
+	// RequiresRestart lists the sections of the config that changed but can not be reloaded. Their changes only
+	// take effect after a restart.
+	RequiresRestart []string
+}
+
+// requiresRestart returns the sections of the config that changed but can not be reloaded.
+func requiresRestart(old, cfg *cfgmodel.Config) []string {
+	sections := []struct {
+		name    string
+		changed bool
+	}{
+		{"server", !reflect.DeepEqual(old.Server, cfg.Server)},
+		{"telemetry", !reflect.DeepEqual(old.Telemetry, cfg.Telemetry)},
+		{"heartbeat", old.Heartbeat != cfg.Heartbeat},
+		{"service", !reflect.DeepEqual(old.Service, cfg.Service)},
+		{"store", old.Store != cfg.Store},
+		{"leader_election", old.LeaderElection != cfg.LeaderElection},
+		{"consensus", !reflect.DeepEqual(old.Consensus, cfg.Consensus)},
+		{"reload", old.Reload != cfg.Reload},
+		{"audit", old.Audit != cfg.Audit},
+		{"trace", old.Trace != cfg.Trace},
+	}
+
+	var names []string
+	for _, section := range sections {
+		if section.changed {
+			names = append(names, section.name)
+		}
+	}
+	return names
+}
+
+// needsRestart returns true if a change of the group config can not be applied while the group runs.
+func needsRestart(old, cfg cfgmodel.GroupConfig) bool {
+	return old.Provider != cfg.Provider || !reflect.DeepEqual(old.Hetzner, cfg.Hetzner)
+}
+
+// Reload changes the config of the monitor while it runs. Groups are added, removed, restarted or reconfigured in
+// place, so that the health of the servers of the other groups is kept. The config must have been validated. If
+// a new group can not be created, nothing is changed and the current config is kept.
+func (w *Monitor) Reload(ctx context.Context, cfg *cfgmodel.Config) (ReloadResult, error) {
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+
+	if len(cfg.Groups) == 0 {
+		return ReloadResult{}, fmt.Errorf("no groups to monitor, check your configuration")
+	}
+
+	result := ReloadResult{RequiresRestart: requiresRestart(w.cfg, cfg)}
+
+	current := make(map[string]*Group)
+	for _, group := range w.groupList() {
+		current[group.ID()] = group
+	}
+	// The groups apply their new config asynchronously, so the config of the monitor is compared against.
+	previous := make(map[string]cfgmodel.GroupConfig)
+	for _, groupCfg := range w.cfg.Groups {
+		previous[groupCfg.ID] = groupCfg
+	}
+
+	// All groups that need to be created are created first, so that nothing changes if one of them fails.
+	groups := make([]*Group, len(cfg.Groups))
+	created := make(map[string]bool)
+	reconfigured := make(map[*Group]cfgmodel.GroupConfig)
+	for i, groupCfg := range cfg.Groups {
+		existing, ok := current[groupCfg.ID]
+		delete(current, groupCfg.ID)
+		// Groups that do not run yet are created again rather than reconfigured.
+		switch {
+		case ok && reflect.DeepEqual(previous[groupCfg.ID], groupCfg):
+			groups[i] = existing
+			continue
+		case ok && !needsRestart(previous[groupCfg.ID], groupCfg) && w.watchCtx != nil:
+			groups[i] = existing
+			reconfigured[existing] = groupCfg
+			result.Reconfigured = append(result.Reconfigured, groupCfg.ID)
+			continue
+		case ok:
+			result.Restarted = append(result.Restarted, groupCfg.ID)
+		default:
+			result.Added = append(result.Added, groupCfg.ID)
+		}
+
+		group, err := w.newGroup(ctx, groupCfg)
+		if err != nil {
+			return ReloadResult{}, fmt.Errorf("failed to create group %s: %w", groupCfg.ID, err)
+		}
+		groups[i] = group
+		created[groupCfg.ID] = true
+	}
+	for _, group := range w.groupList() {
+		// The groups that are left are not in the new config.
+		if _, ok := current[group.ID()]; ok {
+			result.Removed = append(result.Removed, group.ID())
+		}
+	}
+
+	if !reflect.DeepEqual(w.cfg.Notifications, cfg.Notifications) {
+		notifier, err := notification.NewNotifierFromConfig(cfg.Notifications, w.logger)
+		if err != nil {
+			return ReloadResult{}, fmt.Errorf("failed to create notifier: %w", err)
+		}
+		w.notifier.Swap(notifier)
+		result.NotificationsChanged = true
+	}
+
+	// Stop the groups that were removed or are replaced before their replacements start.
+	for _, groupID := range append(result.Removed, result.Restarted...) {
+		w.stopGroup(groupID)
+	}
+
+	w.groupsMu.Lock()
+	w.groups = groups
+	w.groupsMu.Unlock()
+	wireDependencies(groups)
+
+	for group, groupCfg := range reconfigured {
+		if err := group.Reconfigure(ctx, groupCfg); err != nil {
+			w.logger.ErrorContext(ctx, "Failed to reconfigure group.",
+				slog.String("group_id", group.ID()),
+				slog.String("error", err.Error()),
+			)
+		}
+	}
+	if w.watchCtx != nil {
+		for _, group := range groups {
+			if created[group.ID()] {
+				w.startGroup(group)
+			}
+		}
+	}
+
+	// The store is not reloaded, new groups keep using the store that is in use.
+	next := *cfg
+	next.Store = w.cfg.Store
+	w.cfg = &next
+
+	w.logger.InfoContext(ctx, "Configuration reloaded.",
+		slog.Any("added", result.Added),
+		slog.Any("removed", result.Removed),
+		slog.Any("restarted", result.Restarted),
+		slog.Any("reconfigured", result.Reconfigured),
+		slog.Bool("notifications_changed", result.NotificationsChanged),
+		slog.Any("requires_restart", result.RequiresRestart),
+	)
+	w.events.Publish(ctx, event.ConfigReloaded{
+		Meta:                 event.Meta{At: w.clock.Now()},
+		Added:                result.Added,
+		Removed:              result.Removed,
+		Restarted:            result.Restarted,
+		Reconfigured:         result.Reconfigured,
+		NotificationsChanged: result.NotificationsChanged,
+		RequiresRestart:      result.RequiresRestart,
+	})
+	return result, nil
+}
+
+// ReloadFailed notifies that the config could not be reloaded, and that the current config is kept.
+func (w *Monitor) ReloadFailed(ctx context.Context, err error) {
+	w.logger.ErrorContext(ctx, "Failed to reload configuration, keeping the current configuration.",
+		slog.String("error", err.Error()),
+	)
+	w.events.Publish(ctx, event.ConfigReloadFailed{Meta: event.Meta{At: w.clock.Now()}, Error: err.Error()})
+}
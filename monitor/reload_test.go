@@ -0,0 +1,101 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/gzuidhof/flipper/clock"
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+	"github.com/gzuidhof/flipper/event"
+	"github.com/gzuidhof/flipper/notification"
+	"github.com/gzuidhof/flipper/provider/mock"
+	"github.com/gzuidhof/flipper/resource"
+	"github.com/gzuidhof/flipper/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReload(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	group := func(id string) cfgmodel.GroupConfig {
+		return cfgmodel.GroupConfig{ID: id, Provider: string(resource.ProviderNameMock), PollInterval: time.Hour}
+	}
+	errProvider := errors.New("provider failed")
+
+	m := &Monitor{
+		logger:   slog.Default(),
+		notifier: notification.NewSwappable(&notification.NoopNotifier{}),
+		store:    store.Noop{},
+		events:   event.NewBus(),
+		newProvider: func(_ context.Context, cfg cfgmodel.GroupConfig) (resource.Provider, error) {
+			if cfg.ID == "broken" {
+				return nil, errProvider
+			}
+			return mock.NewProvider(), nil
+		},
+		clock: clock.Real{},
+		cfg:   &cfgmodel.Config{},
+		stops: make(map[string]context.CancelFunc),
+	}
+
+	result, err := m.Reload(ctx, &cfgmodel.Config{Groups: []cfgmodel.GroupConfig{group("a"), group("b")}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, result.Added)
+
+	watchDone := make(chan error)
+	go func() { watchDone <- m.Watch(ctx) }()
+	require.Eventually(t, func() bool {
+		m.reloadMu.Lock()
+		defer m.reloadMu.Unlock()
+		return len(m.stops) == 2
+	}, time.Second, time.Millisecond)
+
+	groupA, err := m.group("a")
+	require.NoError(t, err)
+
+	changedA := group("a")
+	changedA.PollInterval = time.Minute
+	result, err = m.Reload(ctx, &cfgmodel.Config{Groups: []cfgmodel.GroupConfig{changedA, group("b"), group("c")}})
+	require.NoError(t, err)
+	assert.Equal(t, ReloadResult{Added: []string{"c"}, Reconfigured: []string{"a"}}, result)
+	reloadedA, err := m.group("a")
+	require.NoError(t, err)
+	assert.Same(t, groupA, reloadedA, "the group is reconfigured in place")
+	assert.Eventually(t, func() bool {
+		return reloadedA.config().PollInterval == time.Minute
+	}, time.Second, time.Millisecond)
+
+	changedC := group("c")
+	changedC.Hetzner.APIToken = "other"
+	result, err = m.Reload(ctx, &cfgmodel.Config{
+		Groups: []cfgmodel.GroupConfig{changedA, changedC},
+		Server: cfgmodel.ServerConfig{Port: 1234},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ReloadResult{
+		Removed:         []string{"b"},
+		Restarted:       []string{"c"},
+		RequiresRestart: []string{"server"},
+	}, result)
+	_, err = m.group("b")
+	require.ErrorIs(t, err, ErrGroupNotFound)
+
+	_, err = m.Reload(ctx, &cfgmodel.Config{Groups: []cfgmodel.GroupConfig{group("broken")}})
+	require.ErrorIs(t, err, errProvider)
+	assert.Len(t, m.groupList(), 2, "the current groups are kept")
+
+	cancel()
+	select {
+	case err := <-watchDone:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch did not stop")
+	}
+}
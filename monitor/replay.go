@@ -0,0 +1,167 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/gzuidhof/flipper/clock"
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+	"github.com/gzuidhof/flipper/event"
+	"github.com/gzuidhof/flipper/plan"
+	"github.com/gzuidhof/flipper/resource"
+	"github.com/gzuidhof/flipper/trace"
+)
+
+// ReplayedPlan is a plan that was made while replaying a trace.
+type ReplayedPlan struct {
+	// Time is the time in the trace at which the plan was made.
+	Time    time.Time                       `json:"time"`
+	GroupID string                          `json:"group_id"`
+	Actions []plan.ReassignFloatingIPAction `json:"actions"`
+}
+
+// replayedGroup is a group of which the inputs are replayed.
+type replayedGroup struct {
+	healthkeeper *HealthKeeper
+	// resources are the resources of the last poll, the changes of the resources are made from them.
+	resources resource.Group
+	sequence  uint64
+	// actions receives the plans of the healthkeeper. Every entry makes at most one plan, which is collected
+	// before the next entry is replayed.
+	actions chan HealthKeeperAction
+}
+
+type replayer struct {
+	logger *slog.Logger
+	clock  *clock.Virtual
+	groups map[string]*replayedGroup
+	plans  []ReplayedPlan
+}
+
+// Replay feeds the entries of a trace through the healthkeepers of their groups, with a virtual clock that follows
+// the times of the entries, and returns the plans that were made, in order. Replaying the same trace always
+// results in the same plans.
+//
+// The plans are assumed to be executed, their moves show up in the polls that were recorded after them. Inputs
+// that are not recorded, like pins set through the admin API, the views of other nodes and the health of the
+// groups a group depends on, are not replayed.
+func Replay(ctx context.Context, r io.Reader, logger *slog.Logger) ([]ReplayedPlan, error) {
+	rp := &replayer{
+		logger: logger,
+		clock:  clock.NewVirtual(time.Time{}),
+		groups: make(map[string]*replayedGroup),
+	}
+	err := trace.Read(r, func(entry trace.Entry) error {
+		return rp.replay(ctx, entry)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay trace: %w", err)
+	}
+	return rp.plans, nil
+}
+
+// replay applies an entry of the trace, and collects the plan that it results in.
+func (rp *replayer) replay(ctx context.Context, entry trace.Entry) error {
+	rp.clock.Set(entry.Time)
+
+	if entry.Kind == trace.KindStart {
+		if entry.Config == nil {
+			return fmt.Errorf("start of group %s without a config", entry.GroupID)
+		}
+		// A group that starts again, e.g. after a restart, starts from scratch.
+		rp.groups[entry.GroupID] = rp.newGroup(*entry.Config)
+		return nil
+	}
+
+	group, ok := rp.groups[entry.GroupID]
+	if !ok {
+		return fmt.Errorf("%s entry of group %s before it started", entry.Kind, entry.GroupID)
+	}
+	h := group.healthkeeper
+
+	switch entry.Kind {
+	case trace.KindReconfigure:
+		if entry.Config == nil {
+			return fmt.Errorf("reconfigure of group %s without a config", entry.GroupID)
+		}
+		h.Reconfigure(*entry.Config)
+		h.applyConfig(ctx, nil, group.actions)
+	case trace.KindPoll:
+		if entry.Resources == nil {
+			return fmt.Errorf("poll of group %s without resources", entry.GroupID)
+		}
+		changeset := resource.NewGroupChangeset(group.resources, *entry.Resources)
+		group.resources = *entry.Resources
+		if changeset.Empty() {
+			break
+		}
+		group.sequence++
+		h.handleResourcesUpdate(ctx, ResourceUpdate{
+			Sequence:  group.sequence,
+			Resources: *entry.Resources,
+			Changeset: changeset,
+		}, nil, group.actions)
+	case trace.KindCheck:
+		if entry.Check == nil {
+			return fmt.Errorf("check of server %s without a result", entry.ServerID)
+		}
+		serverChecker := h.serverChecker(entry.ServerID)
+		if serverChecker == nil {
+			// The result was buffered while the server was removed.
+			break
+		}
+		update, ok := serverChecker.Replay(
+			entry.Check.ID, entry.Check.HTTPCheckResult(), entry.Time, entry.Check.Duration,
+		)
+		if !ok {
+			return fmt.Errorf("unknown check %s of server %s", entry.Check.ID, entry.ServerID)
+		}
+		h.handleServerCheckUpdate(ctx, update, group.actions)
+	case trace.KindResetFlapping:
+		if serverChecker := h.serverChecker(entry.ServerID); serverChecker != nil {
+			if update, ok := serverChecker.ReplayResetFlapping(); ok {
+				h.handleServerCheckUpdate(ctx, update, group.actions)
+			}
+		}
+	default:
+		// Entries that this version does not know about are skipped.
+		rp.logger.DebugContext(ctx, "Skipping trace entry of unknown kind.", slog.String("kind", string(entry.Kind)))
+	}
+
+	rp.collect(entry.GroupID, group)
+	return nil
+}
+
+// newGroup creates the healthkeeper of a group that is replayed.
+func (rp *replayer) newGroup(cfg cfgmodel.GroupConfig) *replayedGroup {
+	logger := rp.logger.With(slog.String("group_id", cfg.ID))
+	h := NewHealthKeeper(cfg, logger, nil, event.NewBus())
+	h.clock = rp.clock
+	h.replaying = true
+	return &replayedGroup{healthkeeper: h, actions: make(chan HealthKeeperAction, 1)}
+}
+
+// collect collects the plan the healthkeeper of a group made, if any.
+func (rp *replayer) collect(groupID string, group *replayedGroup) {
+	select {
+	case action := <-group.actions:
+		if action.Plan.Empty() {
+			// The moves of the previous plan are no longer needed, there is nothing to replay.
+			return
+		}
+		rp.plans = append(rp.plans, ReplayedPlan{
+			Time:    rp.clock.Now(),
+			GroupID: groupID,
+			Actions: action.Plan.Actions,
+		})
+		h := group.healthkeeper
+		if !h.cfg.ReadOnly {
+			// The moves of the plan show up in the next polls, they are not drift.
+			h.expectTargets(action.State, action.Plan.Actions, rp.clock.Now().Add(expectationWindow(h.cfg)))
+		}
+	default:
+	}
+}
@@ -0,0 +1,96 @@
+package monitor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+	"github.com/gzuidhof/flipper/resource"
+	"github.com/gzuidhof/flipper/trace"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplay(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time { return start.Add(time.Duration(seconds) * time.Second) }
+
+	cfg := cfgmodel.GroupConfig{
+		ID:       "lb",
+		Provider: string(resource.ProviderNameMock),
+		Checks:   []cfgmodel.HealthCheckConfig{{ID: "http", Type: "http", Fall: 2}},
+	}
+	// The servers differ in resource index, so that the floating IP prefers the first server while it is healthy.
+	server := func(id int64, index int, ip string) resource.Server {
+		return resource.Server{
+			Provider:      resource.ProviderNameMock,
+			HetznerID:     id,
+			Location:      "fsn1",
+			ResourceIndex: index,
+			PublicIPv4:    netip.MustParseAddr(ip),
+		}
+	}
+	flip := resource.FloatingIP{
+		Provider:      resource.ProviderNameMock,
+		HetznerID:     11,
+		Location:      "fsn1",
+		IP:            netip.MustParseAddr("192.0.2.1"),
+		CurrentTarget: "1",
+	}
+	resources := resource.Group{
+		FloatingIPs: []resource.FloatingIP{flip},
+		Servers:     []resource.Server{server(1, 0, "198.51.100.1"), server(2, 1, "198.51.100.2")},
+	}
+	moved := resources
+	moved.FloatingIPs = []resource.FloatingIP{flip}
+	moved.FloatingIPs[0].CurrentTarget = "2"
+
+	check := func(seconds int, serverID string, failed bool) trace.Entry {
+		result := &trace.CheckResult{ID: "http__ipv4", Duration: time.Millisecond, StatusCode: 200}
+		if failed {
+			result = &trace.CheckResult{ID: "http__ipv4", Duration: time.Millisecond, Error: "connection refused"}
+		}
+		return trace.Entry{Time: at(seconds), Kind: trace.KindCheck, GroupID: "lb", ServerID: serverID, Check: result}
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, entry := range []trace.Entry{
+		trace.NewStart(at(0), cfg),
+		{Time: at(0), Kind: trace.KindPoll, GroupID: "lb", Resources: &resources},
+		check(1, "1", false),
+		check(1, "2", false),
+		check(60, "1", true),
+		// The second failure makes the server unhealthy.
+		check(120, "1", true),
+		// The move shows up in the next poll, it is not drift.
+		{Time: at(121), Kind: trace.KindPoll, GroupID: "lb", Resources: &moved},
+		check(180, "2", false),
+	} {
+		require.NoError(t, encoder.Encode(entry))
+	}
+
+	plans, err := Replay(context.Background(), bytes.NewReader(buf.Bytes()), slog.Default())
+	require.NoError(t, err)
+	require.Len(t, plans, 1)
+	assert.Equal(t, at(120), plans[0].Time)
+	assert.Equal(t, "lb", plans[0].GroupID)
+	require.Len(t, plans[0].Actions, 1)
+	assert.Equal(t, "11", plans[0].Actions[0].FloatingIPID)
+	assert.Equal(t, "2", plans[0].Actions[0].ServerID)
+
+	again, err := Replay(context.Background(), bytes.NewReader(buf.Bytes()), slog.Default())
+	require.NoError(t, err)
+	assert.Equal(t, plans, again, "replaying is deterministic")
+
+	_, err = Replay(context.Background(), bytes.NewReader([]byte(`{"kind":"poll","group_id":"other"}`)),
+		slog.Default())
+	require.Error(t, err, "entries of groups that did not start are rejected")
+}
@@ -7,8 +7,10 @@ import (
 	"sync"
 	"time"
 
+	"github.com/gzuidhof/flipper/clock"
 	"github.com/gzuidhof/flipper/config/cfgmodel"
 	"github.com/gzuidhof/flipper/resource"
+	"github.com/gzuidhof/flipper/trace"
 )
 
 // ResourceUpdate contains the current resources and the changeset since the last update.
@@ -18,6 +20,9 @@ type ResourceUpdate struct {
 	// Resources is the current state of the resources.
 	Resources resource.Group
 	Changeset resource.GroupChangeset
+	// PreviousPollAt is when the resources were last polled successfully before this update, the changes happened
+	// since. It is the zero time for the first update.
+	PreviousPollAt time.Time
 }
 
 // ResourcesWatcher watches for changes of the resources in a resource group itself.
@@ -34,6 +39,18 @@ type ResourcesWatcher struct {
 	resources resource.Group
 
 	currentSequence uint64
+	// polledAt is when the resources were last polled successfully, it is guarded by updateMutex.
+	polledAt time.Time
+
+	// recorder records the polled resources in a trace, nil if recording is disabled.
+	recorder *trace.Recorder
+	// clock tells the time and ticks at the poll interval.
+	clock clock.Clock
+
+	// reconfigured is used to restart the poll ticker when the poll interval may have changed.
+	reconfigured chan struct{}
+	// resyncs is used to poll the resources right away, instead of at the next poll interval.
+	resyncs chan struct{}
 }
 
 // NewResourcesWatcher creates a new resource watcher for a group.
@@ -42,12 +59,62 @@ func NewResourcesWatcher(cfg cfgmodel.GroupConfig, logger *slog.Logger, provider
 		cfg:      cfg,
 		provider: provider,
 		logger:   logger,
+		clock:    clock.Real{},
+
+		reconfigured: make(chan struct{}, 1),
+		resyncs:      make(chan struct{}, 1),
+	}
+}
+
+// config returns the current config of the watcher.
+func (w *ResourcesWatcher) config() cfgmodel.GroupConfig {
+	w.Lock()
+	defer w.Unlock()
+
+	return w.cfg
+}
+
+// Reconfigure changes the config of the watcher, e.g. its poll interval. It is safe to call from any goroutine.
+func (w *ResourcesWatcher) Reconfigure(cfg cfgmodel.GroupConfig) {
+	w.Lock()
+	w.cfg = cfg
+	w.Unlock()
+
+	select {
+	case w.reconfigured <- struct{}{}:
+	default: // The ticker is restarted already.
+	}
+}
+
+// Resync requests the resources to be polled right away, e.g. after a scale-out. Requests made while a poll is
+// requested already are combined. It is safe to call from any goroutine.
+func (w *ResourcesWatcher) Resync() {
+	select {
+	case w.resyncs <- struct{}{}:
+	default: // A resync is requested already.
+	}
+}
+
+// changed asks the provider whether the resources may have changed since it was last asked. Errors are only logged,
+// the changes are picked up by the next poll anyway.
+func (w *ResourcesWatcher) changed(ctx context.Context, changeWatcher resource.ChangeWatcher) bool {
+	watchCtx, cancel := context.WithTimeout(ctx, w.config().PollTimeoutOrDefault())
+	defer cancel()
+
+	changed, err := changeWatcher.Changed(watchCtx)
+	if err != nil {
+		// Errors during shutdown are expected.
+		if ctx.Err() == nil {
+			w.logger.WarnContext(ctx, "Failed to watch resources for changes.", slog.String("error", err.Error()))
+		}
+		return false
 	}
+	return changed
 }
 
 // poll the resources from the provider with the configured timeout.
 func (w *ResourcesWatcher) poll(ctx context.Context) (resource.Group, error) {
-	ctx, cancel := context.WithTimeout(ctx, w.cfg.PollTimeoutOrDefault())
+	ctx, cancel := context.WithTimeout(ctx, w.config().PollTimeoutOrDefault())
 	defer cancel()
 
 	g, err := w.provider.Poll(ctx)
@@ -69,6 +136,15 @@ func (w *ResourcesWatcher) Update(ctx context.Context) (resource.Group, resource
 		return resource.Group{}, resource.GroupChangeset{}, fmt.Errorf("context canceled")
 	}
 
+	if w.recorder != nil {
+		w.recorder.Record(trace.Entry{
+			Time:      w.clock.Now(),
+			Kind:      trace.KindPoll,
+			GroupID:   w.config().ID,
+			Resources: &newResources,
+		})
+	}
+
 	w.Lock()
 	cs := resource.NewGroupChangeset(w.resources, newResources)
 	w.resources = newResources
@@ -97,6 +173,7 @@ func (w *ResourcesWatcher) performUpdate(
 
 	seq := w.bumpSequence()
 
+	polledAt := w.clock.Now()
 	r, cs, err := w.Update(ctx)
 	if ctx.Err() != nil {
 		return seq
@@ -105,11 +182,14 @@ func (w *ResourcesWatcher) performUpdate(
 		onError <- err
 		return seq
 	}
+	previousPollAt := w.polledAt
+	w.polledAt = polledAt
 	if forceSendUpdate || !cs.Empty() {
 		onChange <- ResourceUpdate{
-			Resources: r,
-			Changeset: cs,
-			Sequence:  seq,
+			Resources:      r,
+			Changeset:      cs,
+			Sequence:       seq,
+			PreviousPollAt: previousPollAt,
 		}
 	}
 	return seq
@@ -123,11 +203,22 @@ func (w *ResourcesWatcher) Start(ctx context.Context, onChange chan<- ResourceUp
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	ticker := time.NewTicker(w.cfg.PollIntervalOrDefault())
+	ticker := w.clock.NewTicker(w.config().PollIntervalOrDefault())
 	defer ticker.Stop()
 	defer close(onChange)
 	defer close(onError)
 
+	// Without a provider that can watch for changes, the channel stays nil and never fires.
+	var changes <-chan time.Time
+	changeWatcher, ok := w.provider.(resource.ChangeWatcher)
+	if ok && changeWatcher.WatchInterval() > 0 {
+		watchTicker := w.clock.NewTicker(changeWatcher.WatchInterval())
+		defer watchTicker.Stop()
+		changes = watchTicker.C()
+		// The changes are watched from before the initial update, so that none are missed.
+		w.changed(ctx, changeWatcher)
+	}
+
 	slog.DebugContext(ctx, "Watcher performing initial resources update.")
 	w.performUpdate(ctx, onChange, onError, false)
 
@@ -135,9 +226,22 @@ func (w *ResourcesWatcher) Start(ctx context.Context, onChange chan<- ResourceUp
 		select {
 		case <-ctx.Done():
 			return
-		case <-ticker.C:
+		case <-ticker.C():
 			slog.DebugContext(ctx, "Watcher polling resources.")
 			w.performUpdate(ctx, onChange, onError, false)
+		case <-w.resyncs:
+			w.logger.InfoContext(ctx, "Resync requested, polling resources.")
+			w.performUpdate(ctx, onChange, onError, false)
+			ticker.Reset(w.config().PollIntervalOrDefault())
+		case <-changes:
+			if !w.changed(ctx, changeWatcher) {
+				continue
+			}
+			w.logger.InfoContext(ctx, "Resources changed, polling resources.")
+			w.performUpdate(ctx, onChange, onError, false)
+			ticker.Reset(w.config().PollIntervalOrDefault())
+		case <-w.reconfigured:
+			ticker.Reset(w.config().PollIntervalOrDefault())
 		}
 	}
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/gzuidhof/flipper/provider/mock"
 	"github.com/gzuidhof/flipper/resource"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestResourcesWatcher(t *testing.T) {
@@ -57,3 +59,63 @@ func TestResourcesWatcher(t *testing.T) {
 	assert.Len(t, cs.Servers.Added, 1)
 	fmt.Printf("%s", cs)
 }
+
+// changingProvider is a mock provider that reports changes when told to.
+type changingProvider struct {
+	*mock.Provider
+
+	changed atomic.Bool
+}
+
+func (p *changingProvider) WatchInterval() time.Duration { return 10 * time.Millisecond }
+
+func (p *changingProvider) Changed(_ context.Context) (bool, error) {
+	return p.changed.Swap(false), nil
+}
+
+func TestResourcesWatcherResync(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name   string
+		resync func(watcher *ResourcesWatcher, provider *changingProvider)
+	}{
+		{name: "resync", resync: func(watcher *ResourcesWatcher, _ *changingProvider) { watcher.Resync() }},
+		{name: "changed", resync: func(_ *ResourcesWatcher, provider *changingProvider) { provider.changed.Store(true) }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			provider := &changingProvider{Provider: mock.NewProvider()}
+			provider.Servers = append(provider.Servers,
+				resource.Server{Provider: resource.ProviderNameMock, ServerName: "mock-server-1", HetznerID: 1})
+			// The resources are never polled on the interval during the test.
+			watcher := NewResourcesWatcher(cfgmodel.GroupConfig{PollInterval: time.Hour}, slog.Default(), provider)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			onChange := make(chan ResourceUpdate, 1)
+			onError := make(chan error, 1)
+			go watcher.Start(ctx, onChange, onError)
+
+			nextAdded := func() string {
+				select {
+				case update := <-onChange:
+					require.Len(t, update.Changeset.Servers.Added, 1)
+					return update.Changeset.Servers.Added[0].Name()
+				case <-ctx.Done():
+					t.Fatal("the resources were not polled")
+					return ""
+				}
+			}
+			assert.Equal(t, "mock-server-1", nextAdded())
+
+			// The watcher does not poll again until it is asked to.
+			provider.Servers = append(provider.Servers,
+				resource.Server{Provider: resource.ProviderNameMock, ServerName: "mock-server-2", HetznerID: 2})
+			tc.resync(watcher, provider)
+			assert.Equal(t, "mock-server-2", nextAdded())
+		})
+	}
+}
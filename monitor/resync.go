@@ -0,0 +1,30 @@
+package monitor
+
+import (
+	"context"
+	"os"
+	"os/signal"
+)
+
+// ResyncOnSignal polls the resources of all groups right away when flipper receives a SIGUSR1 signal. It does
+// nothing on platforms without that signal.
+// This function blocks until the context is cancelled.
+func (w *Monitor) ResyncOnSignal(ctx context.Context) {
+	if len(resyncSignals) == 0 {
+		return
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, resyncSignals...)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			w.logger.InfoContext(ctx, "Received SIGUSR1, resyncing all groups.")
+			w.ResyncAll()
+		}
+	}
+}
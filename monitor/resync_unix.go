@@ -0,0 +1,13 @@
+//go:build !windows
+
+package monitor
+
+import (
+	"os"
+	"syscall"
+)
+
+// resyncSignals are the signals that resync all groups.
+//
+//nolint:gochecknoglobals // Fixed per platform.
+var resyncSignals = []os.Signal{syscall.SIGUSR1}
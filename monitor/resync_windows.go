@@ -0,0 +1,10 @@
+//go:build windows
+
+package monitor
+
+import "os"
+
+// resyncSignals are the signals that resync all groups, Windows has no SIGUSR1.
+//
+//nolint:gochecknoglobals // Fixed per platform.
+var resyncSignals []os.Signal
@@ -0,0 +1,104 @@
+package monitor
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+	"github.com/gzuidhof/flipper/plan"
+)
+
+// ErrPlanBlocked is returned when a plan exceeds the safety limits of a group.
+var ErrPlanBlocked = errors.New("plan blocked by safety limits")
+
+// SafetyGuard enforces the safety limits of a group on plans before they are executed.
+// It is safe for concurrent use.
+type SafetyGuard struct {
+	mu sync.Mutex
+	// cfg are the limits, they can be changed while the group runs.
+	cfg cfgmodel.SafetyConfig
+	// moves contains the time of every floating IP reassignment within the window.
+	moves []time.Time
+	// overrideUntil is the time until which the limits are overridden by a human.
+	overrideUntil time.Time
+}
+
+// NewSafetyGuard creates a new safety guard from a config.
+func NewSafetyGuard(cfg cfgmodel.SafetyConfig) *SafetyGuard {
+	return &SafetyGuard{cfg: cfg}
+}
+
+// Reconfigure changes the limits. The moves that were recorded so far still count.
+func (g *SafetyGuard) Reconfigure(cfg cfgmodel.SafetyConfig) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.cfg = cfg
+}
+
+// prune removes moves that fell out of the window. The caller must hold the lock.
+func (g *SafetyGuard) prune(now time.Time) {
+	cutoff := now.Add(-g.cfg.WindowOrDefault())
+	keep := g.moves[:0]
+	for _, t := range g.moves {
+		if t.After(cutoff) {
+			keep = append(keep, t)
+		}
+	}
+	g.moves = keep
+}
+
+// Check returns an error wrapping ErrPlanBlocked if the plan may not be executed at the given time.
+func (g *SafetyGuard) Check(now time.Time, state plan.State, p plan.Plan) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if now.Before(g.overrideUntil) {
+		return nil
+	}
+
+	if g.cfg.MaxUnhealthyFraction > 0 && len(state.Servers) > 0 {
+		fraction := float64(state.NumUnhealthyServers()) / float64(len(state.Servers))
+		if fraction > g.cfg.MaxUnhealthyFraction {
+			return fmt.Errorf("%w: %d of %d servers are unhealthy (%.0f%%), the limit is %.0f%%",
+				ErrPlanBlocked,
+				state.NumUnhealthyServers(), len(state.Servers),
+				fraction*100, g.cfg.MaxUnhealthyFraction*100,
+			)
+		}
+	}
+
+	if g.cfg.MaxMoves > 0 {
+		g.prune(now)
+		if len(g.moves)+len(p.Actions) > g.cfg.MaxMoves {
+			return fmt.Errorf("%w: plan has %d reassignments and %d were done in the last %s, the limit is %d",
+				ErrPlanBlocked,
+				len(p.Actions), len(g.moves), g.cfg.WindowOrDefault(), g.cfg.MaxMoves,
+			)
+		}
+	}
+
+	return nil
+}
+
+// RecordMoves records that the given number of floating IPs were reassigned at the given time.
+func (g *SafetyGuard) RecordMoves(now time.Time, n int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for i := 0; i < n; i++ {
+		g.moves = append(g.moves, now)
+	}
+}
+
+// Override lifts the safety limits from the given time for the configured override duration.
+// It returns the time until which the override is active.
+func (g *SafetyGuard) Override(now time.Time) time.Time {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.overrideUntil = now.Add(g.cfg.OverrideDurationOrDefault())
+	return g.overrideUntil
+}
@@ -0,0 +1,68 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+	"github.com/gzuidhof/flipper/plan"
+	"github.com/gzuidhof/flipper/resource"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSafetyGuard(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	newState := func(unhealthy, healthy int) plan.State {
+		servers := make([]*resource.WithStatus[resource.Server], 0, unhealthy+healthy)
+		for i := 0; i < unhealthy+healthy; i++ {
+			status := resource.StatusHealthy
+			if i < unhealthy {
+				status = resource.StatusUnhealthy
+			}
+			servers = append(servers, resource.NewWithStatus(
+				resource.Server{HetznerID: int64(i + 1)}, resource.State{Status: status},
+			))
+		}
+		return plan.NewState(nil, servers)
+	}
+	planWithMoves := func(n int) plan.Plan {
+		p := plan.Plan{}
+		for i := 0; i < n; i++ {
+			p.AddAction(plan.ReassignFloatingIPAction{})
+		}
+		return p
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+		g := NewSafetyGuard(cfgmodel.SafetyConfig{})
+		assert.NoError(t, g.Check(now, newState(4, 0), planWithMoves(100)))
+	})
+
+	t.Run("unhealthy_fraction", func(t *testing.T) {
+		t.Parallel()
+		g := NewSafetyGuard(cfgmodel.SafetyConfig{MaxUnhealthyFraction: 0.5})
+		assert.NoError(t, g.Check(now, newState(2, 2), planWithMoves(2)))
+		assert.ErrorIs(t, g.Check(now, newState(3, 1), planWithMoves(2)), ErrPlanBlocked)
+
+		g.Override(now)
+		assert.NoError(t, g.Check(now.Add(time.Minute), newState(3, 1), planWithMoves(2)))
+		assert.ErrorIs(t, g.Check(now.Add(time.Hour), newState(3, 1), planWithMoves(2)), ErrPlanBlocked)
+	})
+
+	t.Run("max_moves", func(t *testing.T) {
+		t.Parallel()
+		g := NewSafetyGuard(cfgmodel.SafetyConfig{MaxMoves: 3, Window: 10 * time.Minute})
+		assert.NoError(t, g.Check(now, newState(0, 2), planWithMoves(2)))
+		g.RecordMoves(now, 2)
+
+		assert.ErrorIs(t, g.Check(now.Add(time.Minute), newState(0, 2), planWithMoves(2)), ErrPlanBlocked)
+		assert.NoError(t, g.Check(now.Add(time.Minute), newState(0, 2), planWithMoves(1)))
+
+		// The earlier moves fall out of the window.
+		assert.NoError(t, g.Check(now.Add(11*time.Minute), newState(0, 2), planWithMoves(3)))
+	})
+}
@@ -0,0 +1,143 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+	"github.com/gzuidhof/flipper/maintenance"
+	"github.com/gzuidhof/flipper/plan"
+	"github.com/gzuidhof/flipper/store"
+)
+
+// persistedState keeps the state of a group in the store, so that it survives restarts.
+// It is shared by the group and its healthkeeper, and safe to use from any goroutine.
+type persistedState struct {
+	cfg     cfgmodel.StoreConfig
+	store   store.Store
+	groupID string
+	logger  *slog.Logger
+
+	mu    sync.Mutex
+	state store.GroupState
+}
+
+func newPersistedState(
+	cfg cfgmodel.StoreConfig,
+	st store.Store,
+	groupID string,
+	logger *slog.Logger,
+) *persistedState {
+	return &persistedState{
+		cfg:     cfg,
+		store:   st,
+		groupID: groupID,
+		logger:  logger,
+		state:   store.GroupState{Servers: map[string]store.ServerState{}},
+	}
+}
+
+// restore loads the stored state of the group. The server states are left out if they are older than the max
+// staleness: they may no longer be accurate. Pins, maintenance windows and plans have their own timestamps and are
+// always restored.
+func (p *persistedState) restore(ctx context.Context, now time.Time) store.GroupState {
+	state, err := p.store.Load(ctx, p.groupID)
+	if errors.Is(err, store.ErrNotFound) {
+		return store.GroupState{}
+	}
+	if err != nil {
+		p.logger.ErrorContext(ctx, "Failed to load stored state, starting from scratch.",
+			slog.String("error", err.Error()),
+		)
+		return store.GroupState{}
+	}
+
+	if age := now.Sub(state.SavedAt); age > p.cfg.MaxStalenessOrDefault() {
+		p.logger.InfoContext(ctx, "Stored server states are stale, not using them.",
+			slog.Duration("age", age),
+			slog.Time("saved_at", state.SavedAt),
+		)
+		state.Servers = nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// The restored server states stay current until their checks report.
+	maps.Copy(p.state.Servers, state.Servers)
+	p.state.Pins = maps.Clone(state.Pins)
+	p.state.Maintenance = maps.Clone(state.Maintenance)
+	p.state.Plans = state.Plans
+	return state
+}
+
+// setServer sets the state of a server. It is saved with the next save.
+func (p *persistedState) setServer(serverID string, state store.ServerState) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.state.Servers[serverID] = state
+}
+
+// server returns the state of a server, or the zero state if there is none.
+func (p *persistedState) server(serverID string) store.ServerState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.state.Servers[serverID]
+}
+
+// removeServer removes the state of a server. It is saved with the next save.
+func (p *persistedState) removeServer(serverID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.state.Servers, serverID)
+}
+
+// setPins sets the pins, and returns true if they changed. They are saved with the next save.
+func (p *persistedState) setPins(pins map[string]plan.Pin) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if maps.EqualFunc(p.state.Pins, pins, plan.Pin.Equal) {
+		return false
+	}
+	p.state.Pins = maps.Clone(pins)
+	return true
+}
+
+// setMaintenance sets the maintenance windows, and returns true if they changed. They are saved with the next save.
+func (p *persistedState) setMaintenance(windows map[string]maintenance.Window) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if maps.EqualFunc(p.state.Maintenance, windows, maintenance.Window.Equal) {
+		return false
+	}
+	p.state.Maintenance = maps.Clone(windows)
+	return true
+}
+
+// addPlan adds an executed plan, dropping the oldest plans beyond the max. It is saved with the next save.
+func (p *persistedState) addPlan(executedAt time.Time, report plan.ExecutionReport) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.state.Plans = append(p.state.Plans, store.PlanRecord{ExecutedAt: executedAt, Report: report})
+	if excess := len(p.state.Plans) - p.cfg.MaxPlansOrDefault(); excess > 0 {
+		p.state.Plans = p.state.Plans[excess:]
+	}
+}
+
+// save writes the state to the store, stamped with the given time. Errors are logged, not returning them: flipper keeps working without
+// the store, it only loses the ability to continue from recent state.
+func (p *persistedState) save(ctx context.Context, now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.state.SavedAt = now
+	if err := p.store.Save(ctx, p.groupID, p.state); err != nil {
+		p.logger.ErrorContext(ctx, "Failed to save state.", slog.String("error", err.Error()))
+	}
+}
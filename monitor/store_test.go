@@ -0,0 +1,81 @@
+package monitor
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+	"github.com/gzuidhof/flipper/plan"
+	"github.com/gzuidhof/flipper/resource"
+	"github.com/gzuidhof/flipper/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPersistedStateRestore(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	for _, tc := range []struct {
+		name    string
+		savedAt time.Time
+
+		expectedServers bool
+	}{
+		{
+			name:            "recent",
+			savedAt:         now.Add(-time.Minute),
+			expectedServers: true,
+		},
+		{
+			name:    "stale",
+			savedAt: now.Add(-time.Hour),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			st := store.NewMemory()
+			require.NoError(t, st.Save(ctx, "lb", store.GroupState{
+				SavedAt: tc.savedAt,
+				Servers: map[string]store.ServerState{"1": {State: resource.State{Status: resource.StatusHealthy}}},
+				Pins:    map[string]plan.Pin{"11": {ServerID: "1"}},
+			}))
+
+			p := newPersistedState(cfgmodel.StoreConfig{}, st, "lb", slog.Default())
+			restored := p.restore(ctx, now)
+			assert.Equal(t, tc.expectedServers, len(restored.Servers) == 1)
+			assert.Len(t, restored.Pins, 1, "pins are restored even if the server states are stale")
+
+			// The restored state is saved again as is.
+			p.save(ctx, now)
+			saved, err := st.Load(ctx, "lb")
+			require.NoError(t, err)
+			assert.True(t, saved.SavedAt.Equal(now))
+			assert.Equal(t, restored.Servers, saved.Servers)
+			assert.False(t, p.setPins(map[string]plan.Pin{"11": {ServerID: "1"}}))
+		})
+	}
+}
+
+func TestPersistedStatePlans(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	st := store.NewMemory()
+	p := newPersistedState(cfgmodel.StoreConfig{MaxPlans: 2}, st, "lb", slog.Default())
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		p.addPlan(start.Add(time.Duration(i)*time.Minute), plan.ExecutionReport{})
+	}
+	p.save(ctx, start)
+
+	saved, err := st.Load(ctx, "lb")
+	require.NoError(t, err)
+	require.Len(t, saved.Plans, 2)
+	assert.True(t, saved.Plans[0].ExecutedAt.Equal(start.Add(time.Minute)), "the oldest plan is dropped")
+}
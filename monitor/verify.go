@@ -0,0 +1,123 @@
+package monitor
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/gzuidhof/flipper/event"
+	"github.com/gzuidhof/flipper/plan"
+)
+
+// verifyPlan polls the provider until every floating IP that was moved by the plan targets the expected server.
+// It backs off between polls, and gives up when the verification timeout passes. It returns the floating IPs that
+// still diverge from the plan, or nil if the provider reflects the plan. If the provider could not be polled
+// successfully at all, every moved floating IP is returned along with the last poll error.
+func (g *Group) verifyPlan(
+	ctx context.Context,
+	logger *slog.Logger,
+	state plan.State,
+	report plan.ExecutionReport,
+) ([]plan.Divergence, error) {
+	cfg := g.cfg.Execution.Verification
+	expected := report.Expected(state)
+	if len(expected) == 0 {
+		return nil, nil
+	}
+
+	deadline := g.clock.Now().Add(cfg.TimeoutOrDefault())
+	backoff := cfg.InitialBackoffOrDefault()
+	var divergences []plan.Divergence
+	var pollErr error
+	polled := false
+	for {
+		pollCtx, cancel := context.WithTimeout(ctx, g.cfg.PollTimeoutOrDefault())
+		group, err := g.provider.Poll(pollCtx)
+		cancel()
+
+		pollErr = err
+		if err != nil {
+			logger.WarnContext(ctx, "Failed to poll provider to verify plan.", slog.String("error", err.Error()))
+		} else {
+			polled = true
+			actual := make(map[string]string, len(group.FloatingIPs))
+			for _, flip := range group.FloatingIPs {
+				actual[flip.ID()] = flip.CurrentTarget
+			}
+
+			divergences = divergences[:0]
+			for flipID, serverID := range expected {
+				if actual[flipID] != serverID {
+					divergences = append(divergences, plan.Divergence{
+						FloatingIPID:     flipID,
+						ExpectedServerID: serverID,
+						ActualServerID:   actual[flipID],
+					})
+				}
+			}
+			if len(divergences) == 0 {
+				logger.InfoContext(ctx, "Plan verified.")
+				return nil, nil
+			}
+		}
+
+		if ctx.Err() != nil || g.clock.Now().Add(backoff).After(deadline) {
+			break
+		}
+
+		logger.DebugContext(ctx, "Provider does not reflect plan yet, polling again.",
+			slog.Int("num_diverged", len(divergences)),
+			slog.Duration("backoff", backoff),
+		)
+		select {
+		case <-ctx.Done():
+		case <-g.clock.After(backoff):
+		}
+		backoff = min(backoff*2, cfg.MaxBackoffOrDefault())
+	}
+
+	if !polled {
+		// The provider could not be polled at all, so we can't tell which floating IPs diverged.
+		for flipID, serverID := range expected {
+			divergences = append(divergences, plan.Divergence{FloatingIPID: flipID, ExpectedServerID: serverID})
+		}
+	} else {
+		pollErr = nil
+	}
+	slices.SortFunc(divergences, func(a, b plan.Divergence) int {
+		return strings.Compare(a.FloatingIPID, b.FloatingIPID)
+	})
+	return divergences, pollErr
+}
+
+// verifyAndNotify verifies that the provider reflects the executed plan, and sends a "plan diverged" notification
+// if it doesn't. It blocks until the plan is verified or the verification timed out, so that no new plan is
+// executed in the meantime.
+func (g *Group) verifyAndNotify(
+	ctx context.Context,
+	logger *slog.Logger,
+	state plan.State,
+	report plan.ExecutionReport,
+) {
+	divergences, err := g.verifyPlan(ctx, logger, state, report)
+	if len(divergences) == 0 {
+		return
+	}
+
+	pollError := ""
+	if err != nil {
+		pollError = err.Error()
+	}
+	logger.ErrorContext(ctx, "Plan diverged, the provider does not reflect it.",
+		slog.Int("num_diverged", len(divergences)),
+		slog.String("poll_error", pollError),
+	)
+	g.events.Publish(ctx, event.PlanDiverged{
+		Meta:        event.NewMeta(g.config(), g.clock.Now()),
+		PlanID:      report.PlanID,
+		Divergences: divergences,
+		PollError:   pollError,
+		State:       state,
+	})
+}
@@ -0,0 +1,82 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/gzuidhof/flipper/clock"
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+	"github.com/gzuidhof/flipper/plan"
+	"github.com/gzuidhof/flipper/provider/mock"
+	"github.com/gzuidhof/flipper/resource"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyPlan(t *testing.T) {
+	t.Parallel()
+
+	errPoll := errors.New("poll failed")
+	report := plan.ExecutionReport{Results: []plan.ActionResult{
+		{Action: plan.ReassignFloatingIPAction{FloatingIPID: "11", ServerID: "2"}, Status: plan.ActionSucceeded},
+		{Action: plan.ReassignFloatingIPAction{FloatingIPID: "12", ServerID: "2"}, Status: plan.ActionFailed},
+	}}
+
+	for _, tc := range []struct {
+		name                string
+		actualTarget        string
+		pollError           error
+		expectedDivergences []plan.Divergence
+		expectedErr         error
+	}{
+		{
+			name:         "converged",
+			actualTarget: "2",
+		},
+		{
+			name:         "diverged",
+			actualTarget: "1",
+			expectedDivergences: []plan.Divergence{
+				{FloatingIPID: "11", ExpectedServerID: "2", ActualServerID: "1"},
+			},
+		},
+		{
+			name:      "poll_error",
+			pollError: errPoll,
+			expectedDivergences: []plan.Divergence{
+				{FloatingIPID: "11", ExpectedServerID: "2"},
+			},
+			expectedErr: errPoll,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			provider := mock.NewProvider()
+			provider.PollError = tc.pollError
+			provider.FloatingIPs = []resource.FloatingIP{
+				{Provider: resource.ProviderNameMock, HetznerID: 11, CurrentTarget: tc.actualTarget},
+				{Provider: resource.ProviderNameMock, HetznerID: 12, CurrentTarget: "1"},
+			}
+
+			g := &Group{
+				cfg: cfgmodel.GroupConfig{Execution: cfgmodel.ExecutionConfig{
+					Verification: cfgmodel.VerificationConfig{
+						Timeout:        20 * time.Millisecond,
+						InitialBackoff: time.Millisecond,
+					},
+				}},
+				logger:   slog.Default(),
+				provider: provider,
+				clock:    clock.Real{},
+			}
+
+			state := plan.NewState(provider.FloatingIPs, nil)
+			divergences, err := g.verifyPlan(context.Background(), g.logger, state, report)
+			assert.Equal(t, tc.expectedDivergences, divergences)
+			assert.ErrorIs(t, err, tc.expectedErr)
+		})
+	}
+}
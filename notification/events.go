@@ -0,0 +1,345 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+	"github.com/gzuidhof/flipper/event"
+	"github.com/gzuidhof/flipper/maintenance"
+	"github.com/gzuidhof/flipper/notification/notificationtemplate"
+	"github.com/gzuidhof/flipper/resource"
+)
+
+// EventHandler returns an event handler that sends notifications about the events through the notifier.
+func EventHandler(notifier Notifier) event.Handler {
+	return func(ctx context.Context, e event.Event) {
+		for _, message := range Messages(e) {
+			_ = notifier.Notify(ctx, message)
+		}
+	}
+}
+
+// Messages formats the notifications about an event in markdown. Most events result in a single notification,
+// some in none.
+func Messages(e event.Event) []string {
+	switch e := e.(type) {
+	case event.MonitorStarted:
+		return []string{monitorStartedMessage(e)}
+	case event.ResourcesChanged:
+		return []string{
+			fmt.Sprintf("⚡️ Resources in group **%s** (`%s`) being watched changed substantially.\n",
+				e.Group.DisplayName, e.Group.ID,
+			) + fmt.Sprintf("```\n%s\n```", e.Changeset), // TODO: prettyprint this through some template.
+		}
+	case event.ServerStateChanged:
+		return serverStateMessages(e)
+	case event.PlanWarningsChanged:
+		return []string{notificationtemplate.RenderPlanWarnings(groupConfig(e.Group), e.State, e.Warnings)}
+	case event.PlanBlocked:
+		return []string{notificationtemplate.RenderPlanBlocked(groupConfig(e.Group), e.State, e.Plan, e.Reason)}
+	case event.PlanExecuting:
+		return []string{notificationtemplate.RenderPlanExecution(groupConfig(e.Group), e.State, e.Plan)}
+	case event.PlanExecuted:
+		return []string{notificationtemplate.RenderExecutionReport(groupConfig(e.Group), e.State, e.Report)}
+	case event.PlanDiverged:
+		return []string{notificationtemplate.RenderPlanDiverged(
+			groupConfig(e.Group), e.State, e.PlanID, e.Divergences, e.PollError,
+		)}
+	case event.PlanPending:
+		return []string{planPendingMessage(e)}
+	case event.PlanDecided:
+		return []string{planDecidedMessage(e)}
+	case event.SafetyOverridden:
+		return []string{fmt.Sprintf(":unlock: Safety limits for group **%s** (`%s`) were **overridden** until `%s`.",
+			e.Group.DisplayName, e.Group.ID, e.Until.UTC().Format("2006-01-02 15:04:05"),
+		)}
+	case event.FloatingIPDrifted:
+		cfg := groupConfig(e.Group)
+		return []string{
+			notificationtemplate.RenderDrift(cfg, e.State, e.Drift, e.Policy, e.Pin) +
+				notificationtemplate.RenderState(cfg, e.State),
+		}
+	case event.FloatingIPPinned:
+		return []string{
+			fmt.Sprintf(":pushpin: Floating IP **`%s`** in group **%s** (`%s`) was **%s**.\n",
+				e.FloatingIP.Name(), e.Group.DisplayName, e.Group.ID, e.Pin.Describe("`"+e.Server.Name()+"`"),
+			) + notificationtemplate.RenderState(groupConfig(e.Group), e.State),
+		}
+	case event.FloatingIPUnpinned:
+		return []string{floatingIPUnpinnedMessage(e)}
+	case event.VerdictDiffers:
+		return []string{notificationtemplate.RenderVerdict(groupConfig(e.Group), e.Server, e.Verdict, true)}
+	case event.MaintenanceStarted:
+		return []string{maintenanceStartedMessage(e)}
+	case event.MaintenanceEnded:
+		return []string{fmt.Sprintf(":construction: Maintenance window `%s` in group **%s** (`%s`) **ended**.",
+			e.WindowID, e.Group.DisplayName, e.Group.ID,
+		)}
+	case event.LocationAvailabilityChanged:
+		return []string{locationAvailabilityMessage(e)}
+	case event.LeadershipChanged:
+		return []string{leadershipMessage(e)}
+	case event.ConfigReloaded:
+		return []string{":arrows_counterclockwise: Configuration **reloaded**.\n" + configReloadedSummary(e)}
+	case event.ConfigReloadFailed:
+		return []string{fmt.Sprintf(
+			":x: Failed to **reload** the configuration, keeping the current configuration.\n```\n%s\n```", e.Error,
+		)}
+	}
+	return nil
+}
+
+// groupConfig returns a group config with the fields of the group of an event, which are the fields the
+// templates use.
+func groupConfig(group event.Group) cfgmodel.GroupConfig {
+	return cfgmodel.GroupConfig{
+		ID:          group.ID,
+		DisplayName: group.DisplayName,
+		Provider:    group.Provider,
+		ReadOnly:    group.ReadOnly,
+	}
+}
+
+func monitorStartedMessage(e event.MonitorStarted) string {
+	msg := fmt.Sprintf(":eyes: Starting monitor for group **%s** (`%s`). Flipper version `%s`. ",
+		e.Group.DisplayName, e.Group.ID, e.Version)
+
+	if e.Group.ReadOnly {
+		msg += "\n:lock: **Read-only mode** enabled, no actions will be taken. Only unhealthy/healthy notifications" +
+			" will be sent."
+	}
+	return msg
+}
+
+func planPendingMessage(e event.PlanPending) string {
+	return notificationtemplate.RenderPlanPending(groupConfig(e.Group), e.State, e.Plan, notificationtemplate.Approval{
+		ExpiresAt:   e.ExpiresAt,
+		AutoApprove: e.AutoApprove,
+		Replaces:    e.Replaces,
+		ApproveURL:  e.ApproveURL,
+		RejectURL:   e.RejectURL,
+	})
+}
+
+func planDecidedMessage(e event.PlanDecided) string {
+	emoji, outcome := "", e.Decision
+	switch e.Decision {
+	case event.DecisionApproved:
+		emoji, outcome = "👍", "approved"
+	case event.DecisionAutoApproved:
+		emoji, outcome = "👍", "approved automatically after the approval timeout"
+	case event.DecisionRejected:
+		emoji, outcome = "❎", "rejected"
+	case event.DecisionExpired:
+		emoji, outcome = "⌛", "expired without approval"
+	case event.DecisionStale:
+		emoji, outcome = "⌛", "dropped, as the resources changed since it was proposed"
+	case event.DecisionUnneeded:
+		emoji, outcome = "🗑️", "dropped, as the moves are no longer needed"
+	}
+	return fmt.Sprintf("%s **Plan** `%s` for group **%s** (`%s`) was **%s**.",
+		emoji, e.Plan.ID, e.Group.DisplayName, e.Group.ID, outcome,
+	)
+}
+
+func floatingIPUnpinnedMessage(e event.FloatingIPUnpinned) string {
+	name := e.FloatingIPName
+	if name == "" {
+		name = e.FloatingIPID
+	}
+	if e.Expired {
+		return fmt.Sprintf(":pushpin: Pin of floating IP **`%s`** in group **%s** (`%s`) expired.",
+			name, e.Group.DisplayName, e.Group.ID,
+		)
+	}
+	return fmt.Sprintf(":pushpin: Floating IP **`%s`** in group **%s** (`%s`) was **unpinned**.",
+		name, e.Group.DisplayName, e.Group.ID,
+	)
+}
+
+func maintenanceStartedMessage(e event.MaintenanceStarted) string {
+	names := "no servers"
+	if len(e.Servers) > 0 {
+		quoted := make([]string, 0, len(e.Servers))
+		for _, server := range e.Servers {
+			quoted = append(quoted, "`"+server.Name()+"`")
+		}
+		names = strings.Join(quoted, ", ")
+	}
+	mode := "failover is suppressed for " + names
+	if e.Window.ModeOrDefault() == maintenance.ModeDrain {
+		mode = "draining " + names
+	}
+
+	description := e.Window.Describe()
+	if description != "" {
+		description = strings.ToUpper(description[:1]) + description[1:]
+	}
+	return fmt.Sprintf(":construction: %s in group **%s** (`%s`) **started**, %s until `%s`.",
+		description, e.Group.DisplayName, e.Group.ID, mode, e.Until.UTC().Format("2006-01-02 15:04:05"),
+	)
+}
+
+func locationAvailabilityMessage(e event.LocationAvailabilityChanged) string {
+	if e.Available {
+		return fmt.Sprintf(":link: Location `%s` is **available** again for group **%s** (`%s`).",
+			e.Location, e.Group.DisplayName, e.Group.ID,
+		)
+	}
+	return fmt.Sprintf(":link: Location `%s` is **unavailable** for group **%s** (`%s`): %s.",
+		e.Location, e.Group.DisplayName, e.Group.ID, e.Reason,
+	)
+}
+
+func leadershipMessage(e event.LeadershipChanged) string {
+	if e.Leader {
+		return fmt.Sprintf(":crown: Flipper instance `%s` is now the **leader**.", e.Identity)
+	}
+	holder := "no other instance"
+	if e.Holder != "" {
+		holder = fmt.Sprintf("`%s`", e.Holder)
+	}
+	return fmt.Sprintf(":warning: Flipper instance `%s` is **no longer the leader**, the leader is %s.",
+		e.Identity, holder,
+	)
+}
+
+// configReloadedSummary lists what changed when the config was reloaded in markdown.
+func configReloadedSummary(e event.ConfigReloaded) string {
+	var sb strings.Builder
+	list := func(title string, ids []string) {
+		if len(ids) == 0 {
+			return
+		}
+		fmt.Fprintf(&sb, "- %s: `%s`\n", title, strings.Join(ids, "`, `"))
+	}
+	list("Added groups", e.Added)
+	list("Removed groups", e.Removed)
+	list("Restarted groups", e.Restarted)
+	list("Reconfigured groups", e.Reconfigured)
+	if e.NotificationsChanged {
+		sb.WriteString("- Notification targets changed\n")
+	}
+	if len(e.RequiresRestart) > 0 {
+		fmt.Fprintf(&sb, ":warning: Changes to `%s` only take effect after a restart.\n",
+			strings.Join(e.RequiresRestart, "`, `"),
+		)
+	}
+	if sb.Len() == 0 {
+		return "Nothing changed."
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// serverStateMessages formats the notifications about a change of the state of a server. During a maintenance window
+// they are marked as expected, or left out if the window mutes them.
+func serverStateMessages(e event.ServerStateChanged) []string {
+	if e.Maintenance != nil && e.Maintenance.NotificationsOrDefault() == maintenance.NotificationsMute {
+		return nil
+	}
+	messages := serverStateChangeMessages(e)
+	if e.Maintenance != nil {
+		// The change is expected, e.g. because the server reboots for updates.
+		for i, message := range messages {
+			messages[i] = ":construction: *Expected during " + e.Maintenance.Describe() + ".*\n" + message
+		}
+	}
+	return messages
+}
+
+// serverStateChangeMessages formats the notifications about a change of the state of a server, regardless of
+// maintenance windows.
+func serverStateChangeMessages(e event.ServerStateChanged) []string {
+	cfg := groupConfig(e.Group)
+	var messages []string
+
+	switch {
+	case e.StatusChanged && e.Current.Status == resource.StatusUnhealthy:
+		// TODO improve the formatting here.. This is a bit of a mess.
+		// We should use a template for this (and the below healthy message).
+		messages = append(messages,
+			fmt.Sprintf(":fire: Server [**`%s`**](%s) in location `%s` became **_unhealthy_**%s.\n",
+				e.Server.Name(),
+				e.Server.URL,
+				e.Server.Location,
+				familiesSuffix(e.Current),
+			)+
+				fmt.Sprintf(
+					"```\n%s\n\n%+v\n```\n",
+					e.CheckID,
+					e.UnhealthyChecks,
+				)+nodeViews(e)+
+				notificationtemplate.RenderState(cfg, e.State),
+		)
+	case e.StatusChanged && e.Current.Status == resource.StatusHealthy && e.Previous.Status != resource.StatusUnknown:
+		messages = append(messages,
+			fmt.Sprintf(
+				"✅ Server [**`%s`**](%s) in location `%s` became **_healthy_** again.\n",
+				e.Server.Name(),
+				e.Server.URL,
+				e.Server.Location,
+			)+nodeViews(e)+
+				notificationtemplate.RenderState(cfg, e.State),
+		)
+	case !e.StatusChanged && e.FamilyChanged && e.Current.Status != resource.StatusUnknown:
+		// The overall status did not change, but the health over IPv4 or IPv6 did.
+		messages = append(messages,
+			fmt.Sprintf(":warning: Server [**`%s`**](%s) in location `%s` is now **_%s_**%s.\n",
+				e.Server.Name(),
+				e.Server.URL,
+				e.Server.Location,
+				e.Current.Status,
+				familiesSuffix(e.Current),
+			)+notificationtemplate.RenderState(cfg, e.State),
+		)
+	}
+
+	if !e.FlappingChanged {
+		return messages
+	}
+	if e.Current.Flapping {
+		return append(messages,
+			fmt.Sprintf(
+				":cyclone: Server [**`%s`**](%s) in location `%s` is **_flapping_**, "+
+					"it will not be targeted until at least `%s`.\n",
+				e.Server.Name(),
+				e.Server.URL,
+				e.Server.Location,
+				e.Current.HoldDownUntil.UTC().Format("2006-01-02 15:04:05"),
+			)+notificationtemplate.RenderState(cfg, e.State),
+		)
+	}
+	return append(messages,
+		fmt.Sprintf(
+			":ocean: Server [**`%s`**](%s) in location `%s` is no longer **_flapping_** and can be targeted again.\n",
+			e.Server.Name(),
+			e.Server.URL,
+			e.Server.Location,
+		)+notificationtemplate.RenderState(cfg, e.State),
+	)
+}
+
+// nodeViews renders the views of the nodes on the health of a server, it is empty if there are no other nodes.
+func nodeViews(e event.ServerStateChanged) string {
+	if e.Verdict == nil {
+		return ""
+	}
+	return notificationtemplate.RenderVerdict(groupConfig(e.Group), e.Server, *e.Verdict, false) + "\n"
+}
+
+// familiesSuffix returns a human readable suffix listing the unhealthy IP families of a partially unhealthy server.
+// It returns an empty string if the server is unhealthy for all IP families (or none).
+func familiesSuffix(state resource.State) string {
+	families := state.DegradedFamilies()
+	if len(families) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(families))
+	for _, family := range families {
+		names = append(names, "`"+family.String()+"`")
+	}
+	return " over " + strings.Join(names, ", ") + " only"
+}
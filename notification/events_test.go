@@ -0,0 +1,163 @@
+package notification
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gzuidhof/flipper/event"
+	"github.com/gzuidhof/flipper/maintenance"
+	"github.com/gzuidhof/flipper/plan"
+	"github.com/gzuidhof/flipper/resource"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerStateMessages(t *testing.T) {
+	t.Parallel()
+
+	server := resource.Server{HetznerID: 1, ServerName: "lb-1", Location: "fsn1"}
+	state := plan.NewState(nil, []*resource.WithStatus[resource.Server]{
+		resource.NewWithStatus(server, resource.State{Status: resource.StatusHealthy}),
+	})
+
+	for _, tc := range []struct {
+		name     string
+		event    event.ServerStateChanged
+		expected []string
+	}{
+		{
+			name: "unhealthy",
+			event: event.ServerStateChanged{
+				Previous:      resource.State{Status: resource.StatusHealthy},
+				Current:       resource.State{Status: resource.StatusUnhealthy},
+				StatusChanged: true,
+			},
+			expected: []string{":fire: Server [**`lb-1`**]"},
+		},
+		{
+			name: "healthy again",
+			event: event.ServerStateChanged{
+				Previous:      resource.State{Status: resource.StatusUnhealthy},
+				Current:       resource.State{Status: resource.StatusHealthy},
+				StatusChanged: true,
+			},
+			expected: []string{"✅ Server [**`lb-1`**]"},
+		},
+		{
+			name: "healthy at startup",
+			event: event.ServerStateChanged{
+				Previous:      resource.State{Status: resource.StatusUnknown},
+				Current:       resource.State{Status: resource.StatusHealthy},
+				StatusChanged: true,
+			},
+		},
+		{
+			name: "family and flapping",
+			event: event.ServerStateChanged{
+				Previous: resource.State{Status: resource.StatusHealthy},
+				Current: resource.State{
+					Status: resource.StatusHealthy, IPv4: resource.StatusHealthy, IPv6: resource.StatusUnhealthy,
+					Flapping: true,
+				},
+				FamilyChanged:   true,
+				FlappingChanged: true,
+			},
+			expected: []string{":warning: Server [**`lb-1`**]", ":cyclone: Server [**`lb-1`**]"},
+		},
+		{
+			name: "expected during maintenance",
+			event: event.ServerStateChanged{
+				Previous:      resource.State{Status: resource.StatusHealthy},
+				Current:       resource.State{Status: resource.StatusUnhealthy},
+				StatusChanged: true,
+				Maintenance:   &maintenance.Window{ID: "updates", Reason: "kernel updates"},
+			},
+			expected: []string{
+				":construction: *Expected during maintenance window `updates` (kernel updates).*\n:fire: Server",
+			},
+		},
+		{
+			name: "muted during maintenance",
+			event: event.ServerStateChanged{
+				Previous:      resource.State{Status: resource.StatusHealthy},
+				Current:       resource.State{Status: resource.StatusUnhealthy},
+				StatusChanged: true,
+				Maintenance:   &maintenance.Window{ID: "updates", Notifications: maintenance.NotificationsMute},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tc.event.Server = server
+			tc.event.State = state
+			messages := Messages(tc.event)
+			assert.Len(t, messages, len(tc.expected))
+			for i, prefix := range tc.expected {
+				assert.Contains(t, messages[i], prefix)
+			}
+		})
+	}
+}
+
+func TestMessages(t *testing.T) {
+	t.Parallel()
+
+	meta := event.Meta{Group: event.Group{ID: "lb", DisplayName: "Load balancers"}}
+	for _, tc := range []struct {
+		name     string
+		event    event.Event
+		expected string
+	}{
+		{
+			name:  "plan decided",
+			event: event.PlanDecided{Meta: meta, Decision: event.DecisionStale},
+			expected: "⌛ **Plan** `00000000-0000-0000-0000-000000000000` for group **Load balancers** (`lb`) was " +
+				"**dropped, as the resources changed since it was proposed**.",
+		},
+		{
+			name:     "pin expired",
+			event:    event.FloatingIPUnpinned{Meta: meta, FloatingIPID: "11", Expired: true},
+			expected: ":pushpin: Pin of floating IP **`11`** in group **Load balancers** (`lb`) expired.",
+		},
+		{
+			name: "maintenance started",
+			event: event.MaintenanceStarted{
+				Meta:    meta,
+				Window:  maintenance.Window{ID: "updates", Mode: maintenance.ModeDrain},
+				Until:   time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+				Servers: []resource.Server{{HetznerID: 1, ServerName: "lb-1"}, {HetznerID: 2, ServerName: "lb-2"}},
+			},
+			expected: ":construction: Maintenance window `updates` in group **Load balancers** (`lb`) **started**, " +
+				"draining `lb-1`, `lb-2` until `2024-01-01 12:00:00`.",
+		},
+		{
+			name:     "location unavailable",
+			event:    event.LocationAvailabilityChanged{Meta: meta, Location: "fsn1", Reason: "group `db` is down"},
+			expected: ":link: Location `fsn1` is **unavailable** for group **Load balancers** (`lb`): group `db` is down.",
+		},
+		{
+			name:     "no longer leader",
+			event:    event.LeadershipChanged{Identity: "a", Holder: "b"},
+			expected: ":warning: Flipper instance `a` is **no longer the leader**, the leader is `b`.",
+		},
+		{
+			name:     "reloaded without changes",
+			event:    event.ConfigReloaded{},
+			expected: ":arrows_counterclockwise: Configuration **reloaded**.\nNothing changed.",
+		},
+		{
+			name: "reloaded",
+			event: event.ConfigReloaded{
+				Added: []string{"a", "b"}, NotificationsChanged: true, RequiresRestart: []string{"server"},
+			},
+			expected: ":arrows_counterclockwise: Configuration **reloaded**.\n- Added groups: `a`, `b`\n" +
+				"- Notification targets changed\n:warning: Changes to `server` only take effect after a restart.",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, []string{tc.expected}, Messages(tc.event))
+		})
+	}
+}
@@ -6,8 +6,11 @@ import (
 	"fmt"
 	"html/template"
 	"log/slog"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/gzuidhof/flipper/config/cfgmodel"
+	"github.com/gzuidhof/flipper/consensus"
 	"github.com/gzuidhof/flipper/plan"
 	"github.com/gzuidhof/flipper/resource"
 )
@@ -19,9 +22,14 @@ var templateFS embed.FS
 var templates = template.Must(template.ParseFS(templateFS, "*.tmpl"))
 
 type planTemplateData struct {
+	// Blocked is the reason the plan was blocked, empty if it is not blocked.
+	Blocked string
+	// Approval is set if the plan waits for approval.
+	Approval                        *Approval
 	Cfg                             cfgmodel.GroupConfig
 	State                           plan.State
 	Plan                            plan.Plan
+	Pins                            map[string]plan.Pin
 	FloatingIPsByServer             map[string]resource.FloatingIPs
 	ToBeReassigned                  map[string]bool
 	UnassignedFloatingIPs           resource.FloatingIPs
@@ -31,25 +39,28 @@ type planTemplateData struct {
 type stateTemplateData struct {
 	Cfg                             cfgmodel.GroupConfig
 	State                           plan.State
+	Pins                            map[string]plan.Pin
 	FloatingIPsByServer             map[string]resource.FloatingIPs
 	ToBeReassigned                  map[string]bool
 	UnassignedFloatingIPs           resource.FloatingIPs
 	FloatingIPsTargetedOutsideGroup resource.FloatingIPs
 }
 
-// RenderPlanExecution renders a plan notification in markdown format.
-func RenderPlanExecution(cfg cfgmodel.GroupConfig, state plan.State, plan plan.Plan) string {
-	data := planTemplateData{
+func newPlanTemplateData(cfg cfgmodel.GroupConfig, state plan.State, plan plan.Plan) planTemplateData {
+	return planTemplateData{
 		Cfg:                 cfg,
 		State:               state,
 		Plan:                plan,
+		Pins:                state.ActivePins(time.Now()),
 		FloatingIPsByServer: state.FloatingIPsByServer(),
 		ToBeReassigned:      plan.ToBeReassignedMap(),
 
 		UnassignedFloatingIPs:           state.UnassignedFloatingIPs(),
 		FloatingIPsTargetedOutsideGroup: state.FloatingIPsTargetedOutsideGroup(),
 	}
+}
 
+func renderPlan(data planTemplateData) string {
 	buf := bytes.NewBuffer(nil)
 	err := templates.ExecuteTemplate(buf, "plan.go.tmpl", data)
 	if err != nil {
@@ -60,11 +71,199 @@ func RenderPlanExecution(cfg cfgmodel.GroupConfig, state plan.State, plan plan.P
 	return buf.String()
 }
 
+// RenderPlanExecution renders a plan notification in markdown format.
+func RenderPlanExecution(cfg cfgmodel.GroupConfig, state plan.State, plan plan.Plan) string {
+	return renderPlan(newPlanTemplateData(cfg, state, plan))
+}
+
+// RenderPlanBlocked renders a notification for a plan that was blocked by the safety limits in markdown format.
+func RenderPlanBlocked(cfg cfgmodel.GroupConfig, state plan.State, plan plan.Plan, reason string) string {
+	data := newPlanTemplateData(cfg, state, plan)
+	data.Blocked = reason
+	return renderPlan(data)
+}
+
+// Approval describes how a plan that waits for approval can be approved.
+type Approval struct {
+	// ExpiresAt is when the plan is dropped, or approved if AutoApprove is set.
+	ExpiresAt   time.Time
+	AutoApprove bool
+
+	// Replaces is the ID of the pending plan this plan replaces, empty if none.
+	Replaces string
+
+	// ApproveURL and RejectURL are links to approve or reject the plan, empty if links are not configured.
+	ApproveURL string
+	RejectURL  string
+}
+
+// RenderPlanPending renders a notification for a plan that waits for approval in markdown format.
+func RenderPlanPending(cfg cfgmodel.GroupConfig, state plan.State, plan plan.Plan, approval Approval) string {
+	data := newPlanTemplateData(cfg, state, plan)
+	data.Approval = &approval
+	return renderPlan(data)
+}
+
+type executionTemplateData struct {
+	Cfg          cfgmodel.GroupConfig
+	State        plan.State
+	Report       plan.ExecutionReport
+	NumUnhealthy int
+}
+
+// RenderExecutionReport renders the outcome of executing a plan in markdown format.
+func RenderExecutionReport(cfg cfgmodel.GroupConfig, state plan.State, report plan.ExecutionReport) string {
+	data := executionTemplateData{
+		Cfg:          cfg,
+		State:        state,
+		Report:       report,
+		NumUnhealthy: state.NumUnhealthyServers(),
+	}
+
+	buf := bytes.NewBuffer(nil)
+	err := templates.ExecuteTemplate(buf, "execution.go.tmpl", data)
+	if err != nil {
+		// Should never happen as the template is hardcoded.
+		slog.Error("failed to execute execution template", slog.String("error", err.Error()))
+		return fmt.Sprintf("failed to execute execution template: %s", err.Error())
+	}
+	return buf.String()
+}
+
+type divergedTemplateData struct {
+	Cfg         cfgmodel.GroupConfig
+	State       plan.State
+	PlanID      uuid.UUID
+	Divergences []plan.Divergence
+	PollError   string
+}
+
+// RenderPlanDiverged renders a notification for a plan that the provider does not reflect after executing it, in
+// markdown format. The poll error is empty if the provider could be polled.
+func RenderPlanDiverged(
+	cfg cfgmodel.GroupConfig,
+	state plan.State,
+	planID uuid.UUID,
+	divergences []plan.Divergence,
+	pollError string,
+) string {
+	data := divergedTemplateData{
+		Cfg:         cfg,
+		State:       state,
+		PlanID:      planID,
+		Divergences: divergences,
+		PollError:   pollError,
+	}
+
+	buf := bytes.NewBuffer(nil)
+	err := templates.ExecuteTemplate(buf, "diverged.go.tmpl", data)
+	if err != nil {
+		// Should never happen as the template is hardcoded.
+		slog.Error("failed to execute diverged template", slog.String("error", err.Error()))
+		return fmt.Sprintf("failed to execute diverged template: %s", err.Error())
+	}
+	return buf.String()
+}
+
+type warningsTemplateData struct {
+	Cfg      cfgmodel.GroupConfig
+	State    plan.State
+	Warnings []plan.Warning
+}
+
+// RenderPlanWarnings renders a notification for changed warnings of a plan without actions in markdown format.
+// Without warnings, it says that the previous warnings were resolved.
+func RenderPlanWarnings(cfg cfgmodel.GroupConfig, state plan.State, warnings []plan.Warning) string {
+	data := warningsTemplateData{
+		Cfg:      cfg,
+		State:    state,
+		Warnings: warnings,
+	}
+
+	buf := bytes.NewBuffer(nil)
+	err := templates.ExecuteTemplate(buf, "warnings.go.tmpl", data)
+	if err != nil {
+		// Should never happen as the template is hardcoded.
+		slog.Error("failed to execute warnings template", slog.String("error", err.Error()))
+		return fmt.Sprintf("failed to execute warnings template: %s", err.Error())
+	}
+	return buf.String()
+}
+
+type driftTemplateData struct {
+	Cfg    cfgmodel.GroupConfig
+	State  plan.State
+	Drift  resource.Drift
+	Policy string
+	Pin    plan.Pin
+}
+
+// RenderDrift renders a notification for a floating IP that was moved by someone other than flipper, in markdown
+// format. The pin is the pin that was created for the change according to the drift policy, the zero pin if the
+// change is reverted.
+func RenderDrift(
+	cfg cfgmodel.GroupConfig,
+	state plan.State,
+	drift resource.Drift,
+	policy string,
+	pin plan.Pin,
+) string {
+	data := driftTemplateData{
+		Cfg:    cfg,
+		State:  state,
+		Drift:  drift,
+		Policy: policy,
+		Pin:    pin,
+	}
+
+	buf := bytes.NewBuffer(nil)
+	err := templates.ExecuteTemplate(buf, "drift.go.tmpl", data)
+	if err != nil {
+		// Should never happen as the template is hardcoded.
+		slog.Error("failed to execute drift template", slog.String("error", err.Error()))
+		return fmt.Sprintf("failed to execute drift template: %s", err.Error())
+	}
+	return buf.String()
+}
+
+type consensusTemplateData struct {
+	Headline bool
+	Cfg      cfgmodel.GroupConfig
+	Server   resource.Server
+	Verdict  consensus.Verdict
+}
+
+// RenderVerdict renders the views of the nodes on the health of a server in markdown format. With the headline,
+// it also says that the status the nodes agreed on differs from what this node sees.
+func RenderVerdict(
+	cfg cfgmodel.GroupConfig,
+	server resource.Server,
+	verdict consensus.Verdict,
+	headline bool,
+) string {
+	data := consensusTemplateData{
+		Headline: headline,
+		Cfg:      cfg,
+		Server:   server,
+		Verdict:  verdict,
+	}
+
+	buf := bytes.NewBuffer(nil)
+	err := templates.ExecuteTemplate(buf, "consensus.go.tmpl", data)
+	if err != nil {
+		// Should never happen as the template is hardcoded.
+		slog.Error("failed to execute consensus template", slog.String("error", err.Error()))
+		return fmt.Sprintf("failed to execute consensus template: %s", err.Error())
+	}
+	return buf.String()
+}
+
 // RenderState renders a state notification in markdown format.
 func RenderState(cfg cfgmodel.GroupConfig, state plan.State) string {
 	data := stateTemplateData{
 		Cfg:                 cfg,
 		State:               state,
+		Pins:                state.ActivePins(time.Now()),
 		FloatingIPsByServer: state.FloatingIPsByServer(),
 		ToBeReassigned:      nil,
 
@@ -2,9 +2,13 @@ package notificationtemplate
 
 import (
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/gzuidhof/flipper/config/cfgmodel"
+	"github.com/gzuidhof/flipper/consensus"
 	"github.com/gzuidhof/flipper/plan"
+	"github.com/gzuidhof/flipper/resource"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -13,6 +17,207 @@ func TestRender(t *testing.T) {
 	out := RenderPlanExecution(cfgmodel.GroupConfig{}, plan.State{}, plan.Plan{})
 	assert.NotContains(t, out, "fail")
 
+	out = RenderPlanBlocked(cfgmodel.GroupConfig{}, plan.State{}, plan.Plan{}, "some reason")
+	assert.Contains(t, out, "some reason")
+
 	out = RenderState(cfgmodel.GroupConfig{}, plan.State{})
 	assert.NotContains(t, out, "fail")
 }
+
+func TestRenderNonHomeLocation(t *testing.T) {
+	srv := resource.Server{HetznerID: 1, ServerName: "lb-1", Location: "nbg1"}
+	flip := resource.FloatingIP{HetznerID: 2, FloatingIPName: "web", Location: "fsn1", CurrentTarget: "1"}
+	state := plan.NewState(
+		[]resource.FloatingIP{flip},
+		[]*resource.WithStatus[resource.Server]{resource.NewWithStatus(srv, resource.State{Status: resource.StatusHealthy})},
+	)
+
+	out := RenderState(cfgmodel.GroupConfig{}, state)
+	assert.Contains(t, out, "served outside of home location `fsn1`")
+
+	flip.CurrentTarget = ""
+	state.FloatingIPs[flip.ID()] = flip
+	out = RenderPlanExecution(cfgmodel.GroupConfig{}, state, plan.Plan{
+		Actions: []plan.ReassignFloatingIPAction{{FloatingIPID: flip.ID(), ServerID: srv.ID()}},
+	})
+	assert.Contains(t, out, "outside of home location `fsn1`")
+}
+
+func TestRenderPins(t *testing.T) {
+	srv := resource.Server{HetznerID: 1, ServerName: "lb-1", Location: "fsn1"}
+	state := plan.NewState(
+		[]resource.FloatingIP{
+			{HetznerID: 2, FloatingIPName: "web", Location: "fsn1", CurrentTarget: "1"},
+			{HetznerID: 3, FloatingIPName: "api", Location: "fsn1", CurrentTarget: "1", Pin: resource.Pin{ServerName: "lb-1"}},
+			{HetznerID: 4, FloatingIPName: "www", Location: "fsn1", CurrentTarget: "1"},
+		},
+		[]*resource.WithStatus[resource.Server]{resource.NewWithStatus(srv, resource.State{Status: resource.StatusHealthy})},
+	)
+	state.Pins = map[string]plan.Pin{
+		"2": {ServerID: "1", Until: time.Date(2100, 1, 2, 3, 4, 5, 0, time.UTC), Force: true},
+	}
+
+	out := RenderState(cfgmodel.GroupConfig{}, state)
+	assert.Contains(t, out, "📌 *pinned to `lb-1` (forced) until `2100-01-02 03:04:05`*")
+	assert.Contains(t, out, "`api`**]() `invalid IP` 📌 *pinned to `lb-1`*")
+	assert.NotContains(t, out, "`www`**]() `invalid IP` 📌", "floating IPs without a pin are not marked")
+}
+
+func TestRenderReasons(t *testing.T) {
+	srv := resource.Server{HetznerID: 1, ServerName: "lb-1", Location: "fsn1"}
+	flip := resource.FloatingIP{HetznerID: 2, FloatingIPName: "web", Location: "fsn1"}
+	state := plan.NewState(
+		[]resource.FloatingIP{flip},
+		[]*resource.WithStatus[resource.Server]{resource.NewWithStatus(srv, resource.State{Status: resource.StatusHealthy})},
+	)
+
+	out := RenderPlanExecution(cfgmodel.GroupConfig{}, state, plan.Plan{
+		Actions: []plan.ReassignFloatingIPAction{{
+			FloatingIPID: flip.ID(),
+			ServerID:     srv.ID(),
+			Reasons: []plan.Reason{
+				{Code: plan.ReasonUnassigned, Message: "not assigned to a server"},
+				{Code: plan.ReasonLeastLoaded, Message: "least-loaded candidate in fsn1"},
+			},
+		}},
+	})
+	assert.Contains(t, out, "*Why:* not assigned to a server; least-loaded candidate in fsn1")
+}
+
+func TestRenderExecutionReport(t *testing.T) {
+	srv := resource.Server{HetznerID: 1, ServerName: "lb-1", Location: "fsn1"}
+	flips := []resource.FloatingIP{
+		{HetznerID: 2, FloatingIPName: "web", Location: "fsn1"},
+		{HetznerID: 3, FloatingIPName: "api", Location: "fsn1"},
+	}
+	state := plan.NewState(
+		flips,
+		[]*resource.WithStatus[resource.Server]{resource.NewWithStatus(srv, resource.State{Status: resource.StatusHealthy})},
+	)
+
+	out := RenderExecutionReport(cfgmodel.GroupConfig{}, state, plan.ExecutionReport{
+		OnFailure: cfgmodel.OnFailureAbort,
+		Results: []plan.ActionResult{
+			{
+				Action: plan.ReassignFloatingIPAction{FloatingIPID: "2", ServerID: "1"},
+				Status: plan.ActionFailed, Attempts: 3, Error: "locked",
+			},
+			{
+				Action: plan.ReassignFloatingIPAction{FloatingIPID: "3", ServerID: "1"},
+				Status: plan.ActionSkipped,
+			},
+		},
+	})
+	assert.Contains(t, out, "failed")
+	assert.Contains(t, out, "❌ **`web`** ➤ **`lb-1`** *failed* after 3 attempts: `locked`")
+	assert.Contains(t, out, "⏭️ **`api`** ➤ **`lb-1`** *skipped*")
+}
+
+func TestRenderPlanDiverged(t *testing.T) {
+	state := plan.NewState(
+		[]resource.FloatingIP{{HetznerID: 2, FloatingIPName: "web"}},
+		[]*resource.WithStatus[resource.Server]{
+			resource.NewWithStatus(resource.Server{HetznerID: 1, ServerName: "lb-1"}, resource.State{}),
+		},
+	)
+
+	out := RenderPlanDiverged(cfgmodel.GroupConfig{}, state, uuid.UUID{}, []plan.Divergence{
+		{FloatingIPID: "2", ExpectedServerID: "1", ActualServerID: "9"},
+	}, "")
+	assert.Contains(t, out, "**`web`**: expected **`lb-1`**, actual **`9`** *(outside of group)*")
+}
+
+func TestRenderPlanWarnings(t *testing.T) {
+	state := plan.NewState([]resource.FloatingIP{{HetznerID: 2, FloatingIPName: "web"}}, nil)
+	cfg := cfgmodel.GroupConfig{ID: "lb", DisplayName: "Load balancers"}
+
+	out := RenderPlanWarnings(cfg, state, []plan.Warning{
+		{FloatingIPID: "2", Message: "pair split: no server is healthy for both IPv4 and IPv6"},
+		{FloatingIPID: "3", Message: "unassignable: all candidate servers are at their maximum number of floating IPs"},
+	})
+	assert.Contains(t, out, "**Plan warnings** for group **Load balancers** (`lb`) changed")
+	assert.Contains(t, out, "- ⚠️ **`web`**: pair split: no server is healthy for both IPv4 and IPv6")
+	assert.Contains(t, out, "- ⚠️ **`3`**: unassignable")
+
+	out = RenderPlanWarnings(cfg, state, nil)
+	assert.Contains(t, out, "were **resolved**")
+}
+
+func TestRenderDrift(t *testing.T) {
+	state := plan.NewState(
+		[]resource.FloatingIP{{HetznerID: 2, FloatingIPName: "web", CurrentTarget: "3"}},
+		[]*resource.WithStatus[resource.Server]{
+			resource.NewWithStatus(resource.Server{HetznerID: 1, ServerName: "lb-1"}, resource.State{}),
+			resource.NewWithStatus(resource.Server{HetznerID: 3, ServerName: "lb-2"}, resource.State{}),
+		},
+	)
+	drift := resource.Drift{
+		FloatingIP: state.FloatingIPs["2"],
+		From:       "1",
+		To:         "3",
+		DetectedAt: time.Date(2024, 1, 2, 3, 5, 0, 0, time.UTC),
+		Assignment: &resource.Assignment{At: time.Date(2024, 1, 2, 3, 4, 0, 0, time.UTC)},
+	}
+
+	pin := plan.Pin{ServerID: "3", Until: time.Date(2024, 1, 2, 4, 5, 0, 0, time.UTC)}
+	out := RenderDrift(cfgmodel.GroupConfig{}, state, drift, cfgmodel.DriftPolicyAdopt, pin)
+	assert.Contains(t, out, "**`web`**")
+	assert.Contains(t, out, "from **`lb-1`** to **`lb-2`**.")
+	assert.Contains(t, out, "Changed at `2024-01-02 03:04:00`, detected at `2024-01-02 03:05:00`.")
+	assert.Contains(t, out, "**adopted**")
+	assert.Contains(t, out, "until `2024-01-02 04:05:00`")
+
+	out = RenderDrift(cfgmodel.GroupConfig{}, state, drift, cfgmodel.DriftPolicyAlert, pin)
+	assert.Contains(t, out, "**left as is**")
+	assert.Contains(t, out, "until `2024-01-02 04:05:00` or until it is moved again")
+
+	drift.Assignment = nil
+	drift.From = ""
+	out = RenderDrift(cfgmodel.GroupConfig{}, state, drift, cfgmodel.DriftPolicyRevert, plan.Pin{})
+	assert.Contains(t, out, "from *unassigned* to **`lb-2`**.")
+	assert.Contains(t, out, "Detected at `2024-01-02 03:05:00`.")
+	assert.Contains(t, out, "**reverted**")
+}
+
+func TestRenderPlanPending(t *testing.T) {
+	expiresAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	out := RenderPlanPending(cfgmodel.GroupConfig{ID: "web"}, plan.State{}, plan.Plan{}, Approval{ExpiresAt: expiresAt})
+	assert.Contains(t, out, "needs approval")
+	assert.Contains(t, out, "It expires at `2024-01-02 03:04:05`.")
+	assert.Contains(t, out, "`POST /v1/groups/web/plans/00000000-0000-0000-0000-000000000000/approve`")
+
+	out = RenderPlanPending(cfgmodel.GroupConfig{}, plan.State{}, plan.Plan{}, Approval{
+		ExpiresAt:   expiresAt,
+		AutoApprove: true,
+		Replaces:    "previous-id",
+		ApproveURL:  "https://flipper.example.com/approve?token=abc",
+		RejectURL:   "https://flipper.example.com/reject?token=abc",
+	})
+	assert.Contains(t, out, "It replaces plan `previous-id`")
+	assert.Contains(t, out, "It is approved automatically at `2024-01-02 03:04:05`.")
+	assert.Contains(t, out, "[Approve](https://flipper.example.com/approve?token=abc)")
+}
+
+func TestRenderVerdict(t *testing.T) {
+	server := resource.Server{HetznerID: 1, ServerName: "lb-1"}
+	verdict := consensus.Decide([]consensus.Vote{
+		{NodeID: "a", Status: resource.StatusUnhealthy, Self: true},
+		{NodeID: "b", Status: resource.StatusHealthy},
+		{NodeID: "c", Status: resource.StatusHealthy},
+	}, 2)
+
+	out := RenderVerdict(cfgmodel.GroupConfig{ID: "lb"}, server, verdict, true)
+	assert.Contains(t, out, "counts as **_healthy_**")
+	assert.Contains(t, out, "1 of 3 nodes see it as unhealthy, 2 required.")
+	assert.Contains(t, out, "* `a` *(this node)*: **unhealthy**")
+	assert.Contains(t, out, "* `b`: **healthy**")
+
+	out = RenderVerdict(cfgmodel.GroupConfig{ID: "lb"}, server, verdict, false)
+	assert.NotContains(t, out, "counts as")
+
+	verdict = consensus.Decide([]consensus.Vote{{NodeID: "a", Status: resource.StatusUnhealthy, Self: true}}, 2)
+	verdict.Status = resource.StatusHealthy
+	out = RenderVerdict(cfgmodel.GroupConfig{ID: "lb"}, server, verdict, true)
+	assert.Contains(t, out, "1 of 1 nodes see it as unhealthy, 2 required. Too few nodes have a recent view")
+}
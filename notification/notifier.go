@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"sync"
 
 	"github.com/gzuidhof/flipper/config/cfgmodel"
 	"github.com/gzuidhof/flipper/notification/mattermost"
@@ -22,6 +23,35 @@ func (n *NoopNotifier) Notify(_ context.Context, _ string) error {
 	return nil
 }
 
+// Swappable is a Notifier that sends through another notifier, which can be swapped while it is in use.
+// This is used to change the notification targets when the configuration is reloaded.
+type Swappable struct {
+	mu       sync.RWMutex
+	notifier Notifier
+}
+
+// NewSwappable creates a new Swappable that sends through the given notifier.
+func NewSwappable(notifier Notifier) *Swappable {
+	return &Swappable{notifier: notifier}
+}
+
+// Swap replaces the notifier that is sent through.
+func (s *Swappable) Swap(notifier Notifier) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notifier = notifier
+}
+
+// Notify sends the message through the current notifier.
+func (s *Swappable) Notify(ctx context.Context, message string) error {
+	s.mu.RLock()
+	notifier := s.notifier
+	s.mu.RUnlock()
+
+	//nolint:wrapcheck // The swappable notifier is transparent.
+	return notifier.Notify(ctx, message)
+}
+
 // NewNotifierFromConfig creates a new Notifier from the given NotificationsConfig.
 //
 //nolint:ireturn // This is a factory function, it's okay to return an interface.
@@ -0,0 +1,103 @@
+package plan
+
+import (
+	"github.com/google/uuid"
+)
+
+// ActionStatus is the outcome of executing a single action of a plan.
+type ActionStatus string
+
+const (
+	// ActionSucceeded means the floating IP was reassigned.
+	ActionSucceeded ActionStatus = "succeeded"
+	// ActionFailed means the floating IP could not be reassigned.
+	ActionFailed ActionStatus = "failed"
+	// ActionSkipped means the action was not attempted because the execution was aborted or timed out, or that it
+	// was cancelled while in flight because another action failed.
+	ActionSkipped ActionStatus = "skipped"
+	// ActionRolledBack means the floating IP was reassigned, and then moved back to its previous server.
+	ActionRolledBack ActionStatus = "rolled_back"
+	// ActionRollbackFailed means the floating IP was reassigned, but could not be moved back to its previous server.
+	ActionRollbackFailed ActionStatus = "rollback_failed"
+)
+
+// ActionResult is the outcome of executing a single action of a plan.
+type ActionResult struct {
+	Action ReassignFloatingIPAction `json:"action"`
+	Status ActionStatus             `json:"status"`
+
+	// Attempts is the number of times the reassignment was attempted.
+	Attempts int `json:"attempts"`
+
+	// Error is the last error, empty if there was none.
+	Error string `json:"error,omitempty"`
+}
+
+// ExecutionReport describes the outcome of executing a plan, per action.
+type ExecutionReport struct {
+	PlanID uuid.UUID `json:"plan_id"`
+
+	// OnFailure is the failure policy the plan was executed with, i.e. "continue", "abort" or "rollback".
+	OnFailure string `json:"on_failure"`
+
+	// Results are the outcomes of the actions, in the same order as the actions of the plan.
+	Results []ActionResult `json:"results"`
+}
+
+// Expected returns the server each floating IP should target after the execution, by floating IP ID.
+// Only floating IPs that were moved (or moved back) are included, before is the state the plan was created from.
+func (r ExecutionReport) Expected(before State) map[string]string {
+	expected := map[string]string{}
+	for _, result := range r.Results {
+		switch result.Status {
+		case ActionSucceeded:
+			expected[result.Action.FloatingIPID] = result.Action.ServerID
+		case ActionRolledBack:
+			expected[result.Action.FloatingIPID] = before.FloatingIPs[result.Action.FloatingIPID].CurrentTarget
+		case ActionFailed, ActionSkipped, ActionRollbackFailed:
+		}
+	}
+	return expected
+}
+
+// Divergence is a floating IP that does not target the server it should target after executing a plan.
+type Divergence struct {
+	FloatingIPID string `json:"floating_ip_id"`
+
+	// ExpectedServerID is the server the floating IP should target.
+	ExpectedServerID string `json:"expected_server_id"`
+	// ActualServerID is the server the floating IP targets according to the provider, empty if none.
+	ActualServerID string `json:"actual_server_id"`
+}
+
+// Failed returns true if any of the actions did not succeed.
+func (r ExecutionReport) Failed() bool {
+	for _, result := range r.Results {
+		if result.Status != ActionSucceeded {
+			return true
+		}
+	}
+	return false
+}
+
+// Count returns the number of actions with the given status.
+func (r ExecutionReport) Count(status ActionStatus) int {
+	count := 0
+	for _, result := range r.Results {
+		if result.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// Succeeded returns the actions that succeeded.
+func (r ExecutionReport) Succeeded() []ReassignFloatingIPAction {
+	var actions []ReassignFloatingIPAction
+	for _, result := range r.Results {
+		if result.Status == ActionSucceeded {
+			actions = append(actions, result.Action)
+		}
+	}
+	return actions
+}
@@ -0,0 +1,49 @@
+package plan
+
+import (
+	"slices"
+
+	"github.com/gzuidhof/flipper/resource"
+)
+
+// locationTiers returns the candidate servers for the floating IP grouped by location, in order of preference:
+// the home location, then the fallback locations in the configured order, and finally the other locations in the
+// same network zone. If the floating IP may not leave its home location only the home location is returned.
+// Locations without candidates are left out.
+func (o options) locationTiers(
+	flip resource.FloatingIP,
+	fc familyCandidates,
+) [][]*resource.WithStatus[resource.Server] {
+	var tiers [][]*resource.WithStatus[resource.Server]
+	if home, ok := fc.perLocation[flip.Location]; ok {
+		tiers = append(tiers, home)
+	}
+	if o.policy.HomeLocationOnlyFor(flip.Name()) {
+		return tiers
+	}
+
+	fallbacks := o.policy.LocationFallbacksFor(flip)
+	for _, location := range fallbacks {
+		if location == flip.Location {
+			continue
+		}
+		if candidates, ok := fc.perLocation[location]; ok {
+			tiers = append(tiers, candidates)
+		}
+	}
+
+	var others []*resource.WithStatus[resource.Server]
+	for _, server := range fc.all {
+		location := server.Resource.Location
+		if location == flip.Location || slices.Contains(fallbacks, location) ||
+			server.Resource.NetworkZone != flip.NetworkZone {
+			continue
+		}
+		others = append(others, server)
+	}
+	if len(others) > 0 {
+		tiers = append(tiers, others)
+	}
+
+	return tiers
+}
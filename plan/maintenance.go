@@ -0,0 +1,48 @@
+package plan
+
+import (
+	"github.com/gzuidhof/flipper/maintenance"
+)
+
+// IsDrained returns true if the server is in an active maintenance window that drains it.
+func (s State) IsDrained(serverID string) bool {
+	window, ok := s.Maintenance[serverID]
+	return ok && window.ModeOrDefault() == maintenance.ModeDrain
+}
+
+// IsSuppressed returns true if the server is in an active maintenance window that suppresses failover.
+func (s State) IsSuppressed(serverID string) bool {
+	window, ok := s.Maintenance[serverID]
+	return ok && window.ModeOrDefault() == maintenance.ModeSuppress
+}
+
+// placeSuppressed keeps the units that are not placed yet on their current server if it is in a maintenance window
+// that suppresses failover, even if the server is unhealthy. Units whose floating IPs target different servers are
+// left to the strategy.
+func (p *placement) placeSuppressed() {
+	for _, unit := range p.units {
+		primary := unit.primary()
+		if _, assigned := p.proposal[primary.ID()]; assigned || !p.state.IsSuppressed(primary.CurrentTarget) {
+			continue
+		}
+		server := p.state.Servers[primary.CurrentTarget]
+		sameTarget := server != nil
+		for _, flip := range unit.floatingIPs {
+			sameTarget = sameTarget && flip.CurrentTarget == primary.CurrentTarget
+		}
+		if !sameTarget {
+			continue
+		}
+
+		window := p.state.Maintenance[primary.CurrentTarget]
+		p.assign(unit, primary.CurrentTarget, explanation{
+			reasons: []Reason{{
+				Code:    ReasonMaintenance,
+				Message: "kept on " + server.Resource.Name() + " during " + window.Describe(),
+			}},
+		})
+		for _, member := range unit.floatingIPs {
+			p.pinned[member.ID()] = true
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package plan
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/gzuidhof/flipper/resource"
+)
+
+// familyNameSuffix matches the IP family suffix of a floating IP name, e.g. "-v4" or "_ipv6".
+var familyNameSuffix = regexp.MustCompile(`(?i)^(.+)[-_.](?:ip)?v[46]$`)
+
+// placementUnit is a set of floating IPs that is placed on a server as a whole:
+// either a single floating IP, or an IPv4/IPv6 pair.
+type placementUnit struct {
+	// floatingIPs are the floating IPs in the unit, the first one is used for the location and resource index.
+	floatingIPs []resource.FloatingIP
+}
+
+// primary returns the floating IP that determines where the unit is placed.
+func (u placementUnit) primary() resource.FloatingIP {
+	return u.floatingIPs[0]
+}
+
+// families returns the IP families of the floating IPs in the unit.
+func (u placementUnit) families() []resource.IPFamily {
+	families := make([]resource.IPFamily, 0, len(u.floatingIPs))
+	for _, flip := range u.floatingIPs {
+		families = append(families, flip.Family())
+	}
+	return families
+}
+
+// candidatesKey identifies the set of candidate servers of the unit, units with the same IP families share them.
+func (u placementUnit) candidatesKey() string {
+	families := u.families()
+	keys := make([]string, 0, len(families))
+	for _, family := range families {
+		keys = append(keys, string(family))
+	}
+	return strings.Join(keys, "+")
+}
+
+// pairKey returns the key that pairs the floating IP with another one, or an empty string if it isn't paired.
+// A pair key from a label takes precedence over the name convention.
+func (o options) pairKey(flip resource.FloatingIP) string {
+	if flip.PairKey != "" {
+		return "label:" + flip.PairKey
+	}
+	if !o.policy.PairByName {
+		return ""
+	}
+	match := familyNameSuffix.FindStringSubmatch(flip.Name())
+	if match == nil {
+		return ""
+	}
+	return "name:" + match[1]
+}
+
+// placementUnits groups the floating IPs into placement units. Floating IPs that should be paired but can not be
+// kept together are placed on their own, with a warning.
+//
+// The units are in the order of the given floating IPs (by their first floating IP).
+func (o options) placementUnits(s State, flips []resource.FloatingIP) ([]placementUnit, []Warning) {
+	byKey := map[string][]resource.FloatingIP{}
+	for _, flip := range flips {
+		if key := o.pairKey(flip); key != "" {
+			byKey[key] = append(byKey[key], flip)
+		}
+	}
+
+	var units []placementUnit
+	var warnings []Warning
+	done := map[string]bool{}
+	for _, flip := range flips {
+		if done[flip.ID()] {
+			continue
+		}
+
+		key := o.pairKey(flip)
+		if key == "" {
+			units = append(units, placementUnit{floatingIPs: []resource.FloatingIP{flip}})
+			continue
+		}
+
+		members := byKey[key]
+		for _, member := range members {
+			done[member.ID()] = true
+		}
+
+		pair, err := pairOf(members)
+		if err != nil {
+			for _, member := range members {
+				warnings = append(warnings, Warning{
+					FloatingIPID: member.ID(),
+					Message:      "not paired: " + err.Error(),
+				})
+				units = append(units, placementUnit{floatingIPs: []resource.FloatingIP{member}})
+			}
+			continue
+		}
+
+		unit := placementUnit{floatingIPs: pair.FloatingIPs()}
+		// A pair is only split up if there is no server that can serve both IP families while each IP family
+		// on its own does have healthy servers. If no server is healthy at all, the pair is kept together.
+		if !s.HasHealthyServer(unit.families()...) &&
+			s.HasHealthyServer(resource.IPFamilyIPv4) && s.HasHealthyServer(resource.IPFamilyIPv6) {
+			slog.Warn("no server is healthy for both floating IPs of pair, placing them separately",
+				slog.String("floating_ip_id_v4", pair.V4().ID()),
+				slog.String("floating_ip_id_v6", pair.V6().ID()),
+			)
+			for _, member := range unit.floatingIPs {
+				warnings = append(warnings, Warning{
+					FloatingIPID: member.ID(),
+					Message:      "pair split: no server is healthy for both IPv4 and IPv6",
+				})
+				units = append(units, placementUnit{floatingIPs: []resource.FloatingIP{member}})
+			}
+			continue
+		}
+		units = append(units, unit)
+	}
+
+	return units, warnings
+}
+
+// pairOf creates a floating IP pair from the floating IPs that share a pair key.
+func pairOf(members []resource.FloatingIP) (resource.FloatingIPPair, error) {
+	if len(members) != 2 {
+		return resource.FloatingIPPair{}, fmt.Errorf(
+			"%d floating IPs share the pair key, expected one IPv4 and one IPv6 floating IP", len(members))
+	}
+
+	v4, v6 := members[0], members[1]
+	if v4.Family() == resource.IPFamilyIPv6 {
+		v4, v6 = v6, v4
+	}
+	return resource.NewFloatingIPPair(v4, v6)
+}
@@ -0,0 +1,112 @@
+package plan
+
+import (
+	"time"
+)
+
+// Pin keeps a floating IP on a specific server regardless of the plan, as long as the server is healthy.
+type Pin struct {
+	// ServerID is the ID of the server the floating IP is pinned to.
+	ServerID string `json:"server_id"`
+
+	// Until is when the pin expires, the zero time means it doesn't expire.
+	Until time.Time `json:"until,omitempty"`
+
+	// Reason describes why the floating IP is pinned, e.g. "adopted manual change".
+	Reason string `json:"reason,omitempty"`
+
+	// Force keeps the floating IP on the server even if the server is unhealthy, e.g. for debugging.
+	Force bool `json:"force,omitempty"`
+}
+
+// Active returns true if the pin has not expired at the given time.
+func (p Pin) Active(now time.Time) bool {
+	return p.Until.IsZero() || now.Before(p.Until)
+}
+
+// Equal returns true if the pins are the same.
+func (p Pin) Equal(other Pin) bool {
+	return p.ServerID == other.ServerID && p.Until.Equal(other.Until) && p.Reason == other.Reason &&
+		p.Force == other.Force
+}
+
+// String returns a string representation of the pin.
+func (p Pin) String() string {
+	return p.Describe(p.ServerID)
+}
+
+// Describe describes the pin, using the given name for the server.
+func (p Pin) Describe(serverName string) string {
+	s := "pinned to " + serverName
+	if p.Force {
+		s += " (forced)"
+	}
+	if p.Reason != "" {
+		s += " (" + p.Reason + ")"
+	}
+	if !p.Until.IsZero() {
+		s += " until " + p.Until.UTC().Format(time.RFC3339)
+	}
+	return s
+}
+
+// ActivePins returns the pins that are active at the given time, by floating IP ID. The pins of the state (set
+// through the admin API or by the drift policy) take precedence over the pins set on the floating IPs themselves.
+func (s State) ActivePins(now time.Time) map[string]Pin {
+	pins := map[string]Pin{}
+	for id, flip := range s.FloatingIPs {
+		if flip.Pin.ServerName == "" {
+			continue
+		}
+
+		// Servers that are not in the group keep their name as ID, so that the pin is reported as ignored.
+		pin := Pin{ServerID: flip.Pin.ServerName, Until: flip.Pin.Until, Force: flip.Pin.Force, Reason: "label"}
+		for _, server := range s.Servers {
+			if server.Resource.Name() == flip.Pin.ServerName {
+				pin.ServerID = server.Resource.ID()
+				break
+			}
+		}
+		if pin.Active(now) {
+			pins[id] = pin
+		}
+	}
+	for id, pin := range s.Pins {
+		if pin.Active(now) {
+			pins[id] = pin
+		}
+	}
+	return pins
+}
+
+// placePinned assigns the units with an active pin to their pinned server, if that server is healthy for them or
+// the pin is forced. Pins that can't be honored are reported as warnings, those units are placed as usual.
+func (p *placement) placePinned() {
+	pins := p.state.ActivePins(p.o.now)
+	for _, unit := range p.units {
+		for _, flip := range unit.floatingIPs {
+			pin, ok := pins[flip.ID()]
+			if !ok {
+				continue
+			}
+
+			server, ok := p.state.Servers[pin.ServerID]
+			if !ok {
+				p.warn(flip, "pin ignored: server "+pin.ServerID+" is not in the group")
+				break
+			}
+			if !pin.Force && !isHealthyForAll(server, unit.families()) {
+				p.warn(flip, "pin ignored: server "+server.Resource.Name()+" is not healthy")
+				break
+			}
+
+			p.assign(unit, pin.ServerID, explanation{
+				reasons: []Reason{{Code: ReasonPinned, Message: pin.Describe(server.Resource.Name())}},
+			})
+			for _, member := range unit.floatingIPs {
+				p.pinned[member.ID()] = true
+			}
+			break
+		}
+	}
+}
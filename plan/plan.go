@@ -1,7 +1,6 @@
 package plan
 
 import (
-	"log/slog"
 	"slices"
 	"strings"
 
@@ -12,10 +11,16 @@ import (
 // ReassignFloatingIPAction is a plan to reassign a floating IP to a different server.
 type ReassignFloatingIPAction struct {
 	// FloatingIPID is the ID of the floating IP to be reassigned.
-	FloatingIPID string
+	FloatingIPID string `json:"floating_ip_id"`
 
 	// ServerID string is the ID of the server to which the floating IP should be reassigned.
-	ServerID string
+	ServerID string `json:"server_id"`
+
+	// Reasons explain why the floating IP is reassigned, and why to this server.
+	Reasons []Reason `json:"reasons,omitempty"`
+
+	// Alternatives are the IDs of the other servers that were considered, in order of preference.
+	Alternatives []string `json:"alternatives,omitempty"`
 }
 
 // String returns a string representation of the action.
@@ -23,11 +28,41 @@ func (a ReassignFloatingIPAction) String() string {
 	return a.FloatingIPID + " -> " + a.ServerID
 }
 
+// ReasonMessages returns the messages of the reasons of the action.
+func (a ReassignFloatingIPAction) ReasonMessages() []string {
+	messages := make([]string, 0, len(a.Reasons))
+	for _, reason := range a.Reasons {
+		messages = append(messages, reason.Message)
+	}
+	return messages
+}
+
 // Plan describes the changeset to be applied to the cloud resources.
 type Plan struct {
 	// ID is a random UUID to identify the plan.
-	ID      uuid.UUID
-	Actions []ReassignFloatingIPAction
+	ID      uuid.UUID                  `json:"id"`
+	Actions []ReassignFloatingIPAction `json:"actions"`
+
+	// PendingFailbacks are the moves back to a preferred (healthy) server that are held back by the failback policy.
+	// They are not executed as part of this plan.
+	PendingFailbacks []ReassignFloatingIPAction `json:"pending_failbacks,omitempty"`
+
+	// Warnings are problems found while creating the plan, e.g. floating IPs that could not be assigned.
+	Warnings []Warning `json:"warnings,omitempty"`
+}
+
+// Warning is a problem with a floating IP that was found while creating a plan.
+type Warning struct {
+	// FloatingIPID is the ID of the floating IP the warning is about.
+	FloatingIPID string `json:"floating_ip_id"`
+
+	// Message describes the problem.
+	Message string `json:"message"`
+}
+
+// String returns a string representation of the warning.
+func (w Warning) String() string {
+	return w.FloatingIPID + ": " + w.Message
 }
 
 // AddAction adds an action to the plan.
@@ -36,98 +71,168 @@ func (p *Plan) AddAction(action ReassignFloatingIPAction) {
 }
 
 // New takes the current state of the cloud resources and returns a plan for reassigning floating IPs.
-func New(s State) Plan {
-	// First, we find all candidate servers.
-	allCandidates := s.CandidateServers()
-	todo := s.CandidateFloatingIPs()
-
-	// Floating IP ID to server ID
-	proposal := map[string]string{}
-	// Server ID to floating IP count
-	assignCount := map[string]int{}
-
-	// Locations that contain at least one candidate server.
-	candidatesPerLocation := map[string][]*resource.WithStatus[resource.Server]{}
-	// Network zones that contain at least one candidate server.
-	availableRegions := map[string]bool{}
-	for _, server := range allCandidates {
-		loc := server.Resource.Location
-		candidatesPerLocation[loc] = append(candidatesPerLocation[loc], server)
-		availableRegions[server.Resource.NetworkZone] = true
-	}
+func New(s State, opts ...Option) Plan {
+	o := newOptions(opts...)
+	// Paired floating IPs are placed together as a single unit.
+	units, warnings := o.placementUnits(s, s.CandidateFloatingIPs())
 
-	// Floating IPs that are unassignable.
-	unassignable := map[string]bool{}
+	p := newPlacement(o, s, units)
+	p.placePinned()
+	p.placeSuppressed()
+	placerFor(o.policy.Strategy).place(p)
+	proposal := p.proposal
+	warnings = append(warnings, p.warnings...)
 
-	// First we try to assign floating IPs to servers in the same location with the same index.
-	for _, flip := range todo {
-		if !availableRegions[flip.NetworkZone] {
-			unassignable[flip.ID()] = true
-			slog.Warn("no candidate servers in network zone for floating IP",
-				slog.String("network_zone", flip.NetworkZone),
-				slog.String("floating_ip_id", flip.ID()),
-			)
+	// Now we remove any assignments that are already in place.
+	for _, flip := range s.FloatingIPs {
+		if flip.CurrentTarget == "" {
 			continue
 		}
+		if flip.CurrentTarget == proposal[flip.ID()] {
+			delete(proposal, flip.ID())
+		}
+	}
 
-		candidates, sameLocationPossible := candidatesPerLocation[flip.Location]
-		if !sameLocationPossible {
+	// Moves away from a server that is still a candidate are failbacks (or rebalancing), which are subject to
+	// the failback policy. Failover away from unhealthy servers is never held back.
+	// The floating IPs of a pair are held back together, so that they keep moving as one.
+	pendingFailbacks := map[string]string{}
+	for _, unit := range units {
+		if p.pinned[unit.primary().ID()] {
+			continue // Pins are explicit, they are not held back.
+		}
+		unitCandidates := p.candidatesFor(unit)
+		heldBack := false
+		for _, flip := range unit.floatingIPs {
+			serverID, ok := proposal[flip.ID()]
+			if !ok || flip.CurrentTarget == "" || !unitCandidates.contains(flip.CurrentTarget) {
+				continue
+			}
+			if !o.failbackAllowed(flip, s.Servers[serverID]) {
+				heldBack = true
+			}
+		}
+		if !heldBack {
 			continue
 		}
-
-		// The first choice is the server that is in the same location and shares the index with the floating IP.
-		for _, server := range candidates {
-			if server.Resource.ResourceIndex == flip.ResourceIndex {
-				proposal[flip.ID()] = server.Resource.ID()
-				assignCount[server.Resource.ID()]++
-				break
+		for _, flip := range unit.floatingIPs {
+			if serverID, ok := proposal[flip.ID()]; ok {
+				pendingFailbacks[flip.ID()] = serverID
+				delete(proposal, flip.ID())
 			}
 		}
 	}
 
-	// Then we assign the remaining floating IPs.
-	for _, flip := range todo {
-		if _, ok := proposal[flip.ID()]; ok { // Already assigned.. O(2nm) is fine
-			continue
+	// Every move is explained by why the floating IP leaves its current target, followed by why it goes where it goes.
+	explanations := make(map[string]explanation, len(p.explanations))
+	for _, unit := range units {
+		for _, flip := range unit.floatingIPs {
+			exp, ok := p.explanations[flip.ID()]
+			if !ok {
+				continue
+			}
+			exp.reasons = append([]Reason{moveReason(s, flip, p.candidatesFor(unit))}, exp.reasons...)
+			explanations[flip.ID()] = exp
 		}
-		if unassignable[flip.ID()] {
+	}
+
+	plan := planFromProposal(proposal, explanations)
+	if len(pendingFailbacks) > 0 {
+		plan.PendingFailbacks = planFromProposal(pendingFailbacks, explanations).Actions
+	}
+	plan.Warnings = warnings
+	return plan
+}
+
+// hasCapacity returns true if the server can take n more floating IPs.
+func (o options) hasCapacity(server resource.Server, totalCount map[string]int, n int) bool {
+	maxFloatingIPs := o.policy.MaxFloatingIPsOf(server)
+	return maxFloatingIPs <= 0 || totalCount[server.ID()]+n <= maxFloatingIPs
+}
+
+// leastLoaded returns the ID of the candidate server that would have the lowest load relative to its weight after
+// assigning one more floating IP to it. Servers without capacity left for n floating IPs are skipped, if there are none
+// left it returns an empty string. Ties are broken by the order of the candidates.
+func (o options) leastLoaded(
+	candidates []*resource.WithStatus[resource.Server],
+	count map[string]int,
+	totalCount map[string]int,
+	n int,
+) string {
+	var bestServerID string
+	var bestCount, bestWeight int
+	for _, server := range candidates {
+		if !o.hasCapacity(server.Resource, totalCount, n) {
 			continue
 		}
 
-		candidates, sameLocationPossible := candidatesPerLocation[flip.Location]
-		if !sameLocationPossible {
-			candidates = allCandidates
+		serverCount := count[server.Resource.ID()]
+		weight := o.policy.WeightOf(server.Resource)
+		// (count+1)/weight < (bestCount+1)/bestWeight, multiplied out to stay in integers.
+		if bestServerID == "" || (serverCount+1)*bestWeight < (bestCount+1)*weight {
+			bestServerID = server.Resource.ID()
+			bestCount = serverCount
+			bestWeight = weight
 		}
+	}
+	return bestServerID
+}
 
-		// The second choice is the server that has the least floating IPs assigned to it.
-		var minCount int
-		var minServerID string
-		for _, server := range candidates {
-			if count := assignCount[server.Resource.ID()]; count < minCount || minServerID == "" {
-				minCount = count
-				minServerID = server.Resource.ID()
-			}
-		}
-		proposal[flip.ID()] = minServerID
-		assignCount[minServerID]++
+// failbackAllowed returns true if the floating IP may fail back to the given server now.
+func (o options) failbackAllowed(flip resource.FloatingIP, target *resource.WithStatus[resource.Server]) bool {
+	if o.forceFailback {
+		return true
 	}
 
-	// Now we remove any assignments that are already in place.
-	for _, flip := range s.FloatingIPs {
-		if flip.CurrentTarget == "" {
-			continue
-		}
-		if flip.CurrentTarget == proposal[flip.ID()] {
-			delete(proposal, flip.ID())
+	failback := o.policy.FailbackFor(flip.Name())
+	switch failback.Policy {
+	case FailbackManual:
+		return false
+	case FailbackDelayed:
+		state := target.State()
+		return state.Status == resource.StatusHealthy && o.now.Sub(state.LastUpdated) >= failback.Delay
+	case FailbackImmediate:
+		return true
+	}
+	return true
+}
+
+// familyCandidates are the candidate servers for floating IPs of a single IP family.
+type familyCandidates struct {
+	all []*resource.WithStatus[resource.Server]
+	// perLocation contains the candidates by location, only locations with at least one candidate are present.
+	perLocation map[string][]*resource.WithStatus[resource.Server]
+	// networkZones contains the network zones that contain at least one candidate server.
+	networkZones map[string]bool
+}
+
+// contains returns true if the server with the given ID is a candidate.
+func (fc familyCandidates) contains(serverID string) bool {
+	for _, server := range fc.all {
+		if server.Resource.ID() == serverID {
+			return true
 		}
 	}
+	return false
+}
 
-	return planFromProposal(proposal)
+func newFamilyCandidates(candidates []*resource.WithStatus[resource.Server]) familyCandidates {
+	fc := familyCandidates{
+		all:          candidates,
+		perLocation:  map[string][]*resource.WithStatus[resource.Server]{},
+		networkZones: map[string]bool{},
+	}
+	for _, server := range candidates {
+		loc := server.Resource.Location
+		fc.perLocation[loc] = append(fc.perLocation[loc], server)
+		fc.networkZones[server.Resource.NetworkZone] = true
+	}
+	return fc
 }
 
 // planFromProposal creates a plan from a proposal.
-// The proposal is a map of floating IP IDs to server IDs.
-func planFromProposal(proposal map[string]string) Plan {
+// The proposal is a map of floating IP IDs to server IDs, the explanations are attached to the actions.
+func planFromProposal(proposal map[string]string, explanations map[string]explanation) Plan {
 	plan := Plan{
 		ID:      uuid.New(),
 		Actions: make([]ReassignFloatingIPAction, 0, len(proposal)),
@@ -144,6 +249,8 @@ func planFromProposal(proposal map[string]string) Plan {
 		plan.AddAction(ReassignFloatingIPAction{
 			ServerID:     proposal[k],
 			FloatingIPID: k,
+			Reasons:      explanations[k].reasons,
+			Alternatives: explanations[k].alternatives,
 		})
 	}
 
@@ -164,7 +271,7 @@ func (p Plan) String() string {
 	return res
 }
 
-// Empty returns true if the plan is empty.
+// Empty returns true if the plan has no actions. It may still have pending failbacks and warnings.
 func (p Plan) Empty() bool {
 	return len(p.Actions) == 0
 }
@@ -2,9 +2,13 @@ package plan
 
 import (
 	"fmt"
+	"net/netip"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/gzuidhof/flipper/maintenance"
 	"github.com/gzuidhof/flipper/resource"
 	"github.com/stretchr/testify/assert"
 )
@@ -28,6 +32,18 @@ func servers(state resource.Status, location, networkZone string, ids ...int64)
 	return servers
 }
 
+// withoutExplanations clears the random ID and the reasons of the actions, so that tests can focus on the moves.
+func withoutExplanations(p Plan) Plan {
+	p.ID = uuid.UUID{}
+	for _, actions := range [][]ReassignFloatingIPAction{p.Actions, p.PendingFailbacks} {
+		for i := range actions {
+			actions[i].Reasons = nil
+			actions[i].Alternatives = nil
+		}
+	}
+	return p
+}
+
 func TestPlan(t *testing.T) {
 	t.Parallel()
 
@@ -121,6 +137,9 @@ func TestPlan(t *testing.T) {
 			},
 			expectedPlan: Plan{
 				Actions: []ReassignFloatingIPAction{},
+				Warnings: []Warning{
+					{FloatingIPID: "1", Message: "unassignable: no candidate servers in network zone eu-central"},
+				},
 			},
 		},
 		{
@@ -161,8 +180,743 @@ func TestPlan(t *testing.T) {
 
 			plan := New(state)
 			// The plan ID is random, so we can't compare it.
-			plan.ID = uuid.UUID{}
+			plan = withoutExplanations(plan)
 			assert.Equal(t, tc.expectedPlan, plan)
 		})
 	}
 }
+
+func TestPlanPerIPFamily(t *testing.T) {
+	t.Parallel()
+
+	srvs := servers(resource.StatusHealthy, "nbg1", "eu-central", 1, 2)
+	// Server 1 is only unhealthy over IPv6.
+	srvs[0].SetState(resource.State{
+		Status: resource.StatusUnhealthy,
+		IPv4:   resource.StatusHealthy,
+		IPv6:   resource.StatusUnhealthy,
+	})
+
+	floatingIPs := []resource.FloatingIP{
+		{HetznerID: 1, Location: "nbg1", NetworkZone: "eu-central", CurrentTarget: "1", IP: netip.MustParseAddr("192.0.2.1")},
+		{HetznerID: 2, Location: "nbg1", NetworkZone: "eu-central", CurrentTarget: "1", IP: netip.MustParseAddr("2001:db8::1")},
+		{HetznerID: 3, Location: "nbg1", NetworkZone: "eu-central", CurrentTarget: "2", IP: netip.MustParseAddr("192.0.2.2")},
+		{HetznerID: 4, Location: "nbg1", NetworkZone: "eu-central", CurrentTarget: "2", IP: netip.MustParseAddr("2001:db8::2")},
+	}
+
+	plan := New(NewState(floatingIPs, srvs))
+	plan = withoutExplanations(plan)
+
+	// Only the IPv6 floating IP on server 1 should be moved.
+	assert.Equal(t, Plan{
+		Actions: []ReassignFloatingIPAction{
+			{ServerID: "2", FloatingIPID: "2"},
+		},
+	}, plan)
+}
+
+func TestPlanFlapping(t *testing.T) {
+	t.Parallel()
+
+	srvs := servers(resource.StatusHealthy, "nbg1", "eu-central", 1, 2)
+	// Server 1 is healthy, but flapping.
+	srvs[0].SetState(resource.State{Status: resource.StatusHealthy, Flapping: true})
+
+	floatingIPs := []resource.FloatingIP{
+		{HetznerID: 1, Location: "nbg1", NetworkZone: "eu-central", CurrentTarget: "", ResourceIndex: 1},
+		{HetznerID: 2, Location: "nbg1", NetworkZone: "eu-central", CurrentTarget: "2", ResourceIndex: 2},
+	}
+
+	plan := New(NewState(floatingIPs, srvs))
+	plan = withoutExplanations(plan)
+
+	assert.Equal(t, Plan{
+		Actions: []ReassignFloatingIPAction{
+			{ServerID: "2", FloatingIPID: "1"},
+		},
+	}, plan)
+}
+
+func TestPlanFailback(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// Server 1 was unhealthy, the floating IP failed over to server 2. Server 1 became healthy 10 minutes ago.
+	newState := func() State {
+		srvs := servers(resource.StatusHealthy, "nbg1", "eu-central", 1, 2)
+		srvs[0].SetState(resource.State{Status: resource.StatusHealthy, LastUpdated: now.Add(-10 * time.Minute)})
+		return NewState([]resource.FloatingIP{
+			{HetznerID: 1, Location: "nbg1", NetworkZone: "eu-central", CurrentTarget: "2", ResourceIndex: 1,
+				FloatingIPName: "floating-ip-1"},
+		}, srvs)
+	}
+	failback := []ReassignFloatingIPAction{{ServerID: "1", FloatingIPID: "1"}}
+
+	for _, tc := range []struct {
+		name         string
+		opts         []Option
+		expectedPlan Plan
+	}{
+		{
+			name:         "default_immediate",
+			expectedPlan: Plan{Actions: failback},
+		},
+		{
+			name:         "manual",
+			opts:         []Option{WithPolicy(Policy{Failback: Failback{Policy: FailbackManual}})},
+			expectedPlan: Plan{Actions: []ReassignFloatingIPAction{}, PendingFailbacks: failback},
+		},
+		{
+			name: "manual_forced",
+			opts: []Option{
+				WithPolicy(Policy{Failback: Failback{Policy: FailbackManual}}),
+				WithForcedFailback(),
+			},
+			expectedPlan: Plan{Actions: failback},
+		},
+		{
+			name:         "delayed_not_yet",
+			opts:         []Option{WithPolicy(Policy{Failback: Failback{Policy: FailbackDelayed, Delay: 15 * time.Minute}})},
+			expectedPlan: Plan{Actions: []ReassignFloatingIPAction{}, PendingFailbacks: failback},
+		},
+		{
+			name:         "delayed_passed",
+			opts:         []Option{WithPolicy(Policy{Failback: Failback{Policy: FailbackDelayed, Delay: 5 * time.Minute}})},
+			expectedPlan: Plan{Actions: failback},
+		},
+		{
+			name: "floating_ip_override",
+			opts: []Option{WithPolicy(Policy{
+				Failback: Failback{Policy: FailbackImmediate},
+				FloatingIPs: map[string]FloatingIPPolicy{
+					"floating-ip-1": {Failback: &Failback{Policy: FailbackManual}},
+				},
+			})},
+			expectedPlan: Plan{Actions: []ReassignFloatingIPAction{}, PendingFailbacks: failback},
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			plan := New(newState(), append(tc.opts, WithNow(now))...)
+			plan = withoutExplanations(plan)
+			assert.Equal(t, tc.expectedPlan, plan)
+		})
+	}
+
+	t.Run("failover_is_immediate", func(t *testing.T) {
+		t.Parallel()
+
+		state := newState()
+		state.Servers["2"].SetState(resource.State{Status: resource.StatusUnhealthy})
+
+		plan := New(state, WithNow(now), WithPolicy(Policy{Failback: Failback{Policy: FailbackManual}}))
+		plan = withoutExplanations(plan)
+		assert.Equal(t, Plan{Actions: failback}, plan)
+	})
+}
+
+func TestPlanWeights(t *testing.T) {
+	t.Parallel()
+
+	unassigned := func(n int) []resource.FloatingIP {
+		flips := make([]resource.FloatingIP, n)
+		for i := range flips {
+			flips[i] = resource.FloatingIP{
+				HetznerID: int64(i + 1), Location: "nbg1", NetworkZone: "eu-central", ResourceIndex: -1,
+			}
+		}
+		return flips
+	}
+
+	t.Run("proportional", func(t *testing.T) {
+		t.Parallel()
+
+		srvs := servers(resource.StatusHealthy, "nbg1", "eu-central", 1, 2)
+		srvs[1].Resource.Weight = 2
+
+		plan := New(NewState(unassigned(6), srvs))
+		plan = withoutExplanations(plan)
+
+		count := map[string]int{}
+		for _, action := range plan.Actions {
+			count[action.ServerID]++
+		}
+		assert.Equal(t, map[string]int{"1": 2, "2": 4}, count)
+	})
+
+	t.Run("policy_overrides_label", func(t *testing.T) {
+		t.Parallel()
+
+		srvs := servers(resource.StatusHealthy, "nbg1", "eu-central", 1, 2)
+		srvs[1].Resource.Weight = 2
+
+		plan := New(NewState(unassigned(4), srvs), WithPolicy(Policy{
+			Servers: map[string]ServerPolicy{"mock-server-1": {Weight: 3}},
+		}))
+
+		count := map[string]int{}
+		for _, action := range plan.Actions {
+			count[action.ServerID]++
+		}
+		assert.Equal(t, map[string]int{"1": 3, "2": 1}, count)
+	})
+
+	t.Run("max_floating_ips", func(t *testing.T) {
+		t.Parallel()
+
+		srvs := servers(resource.StatusHealthy, "nbg1", "eu-central", 1, 2)
+		srvs[0].Resource.MaxFloatingIPs = 1
+		srvs[1].Resource.MaxFloatingIPs = 1
+
+		plan := New(NewState(unassigned(3), srvs))
+		plan = withoutExplanations(plan)
+
+		assert.Equal(t, Plan{
+			Actions: []ReassignFloatingIPAction{
+				{ServerID: "1", FloatingIPID: "1"},
+				{ServerID: "2", FloatingIPID: "2"},
+			},
+			Warnings: []Warning{
+				{FloatingIPID: "3", Message: "unassignable: all candidate servers are at their maximum number of floating IPs"},
+			},
+		}, plan)
+	})
+}
+
+func TestPlanPairs(t *testing.T) {
+	t.Parallel()
+
+	pair := func(key string, target string) []resource.FloatingIP {
+		return []resource.FloatingIP{
+			{
+				HetznerID: 1, FloatingIPName: key + "-v4", Location: "nbg1", NetworkZone: "eu-central",
+				CurrentTarget: target, ResourceIndex: -1, IP: netip.MustParseAddr("192.0.2.1"),
+			},
+			{
+				HetznerID: 2, FloatingIPName: key + "-v6", Location: "nbg1", NetworkZone: "eu-central",
+				CurrentTarget: target, ResourceIndex: -1, IP: netip.MustParseAddr("2001:db8::1"),
+			},
+		}
+	}
+
+	t.Run("move_together", func(t *testing.T) {
+		t.Parallel()
+
+		srvs := servers(resource.StatusHealthy, "nbg1", "eu-central", 1, 2)
+		// Server 1 is only unhealthy over IPv6, without pairing only the IPv6 floating IP would move.
+		srvs[0].SetState(resource.State{
+			Status: resource.StatusUnhealthy,
+			IPv4:   resource.StatusHealthy,
+			IPv6:   resource.StatusUnhealthy,
+		})
+
+		flips := pair("web", "1")
+		flips[0].PairKey = "web"
+		flips[1].PairKey = "web"
+
+		plan := New(NewState(flips, srvs))
+		plan = withoutExplanations(plan)
+
+		assert.Equal(t, Plan{
+			Actions: []ReassignFloatingIPAction{
+				{ServerID: "2", FloatingIPID: "1"},
+				{ServerID: "2", FloatingIPID: "2"},
+			},
+		}, plan)
+	})
+
+	t.Run("by_name", func(t *testing.T) {
+		t.Parallel()
+
+		srvs := servers(resource.StatusHealthy, "nbg1", "eu-central", 1, 2)
+		flips := append(pair("web", ""), resource.FloatingIP{
+			HetznerID: 3, FloatingIPName: "other", Location: "nbg1", NetworkZone: "eu-central", ResourceIndex: -1,
+			IP: netip.MustParseAddr("2001:db8::2"),
+		})
+
+		plan := New(NewState(flips, srvs), WithPolicy(Policy{PairByName: true}))
+		plan = withoutExplanations(plan)
+
+		assert.Equal(t, Plan{
+			Actions: []ReassignFloatingIPAction{
+				{ServerID: "1", FloatingIPID: "1"},
+				{ServerID: "1", FloatingIPID: "2"},
+				{ServerID: "2", FloatingIPID: "3"},
+			},
+		}, plan)
+	})
+
+	t.Run("split", func(t *testing.T) {
+		t.Parallel()
+
+		srvs := servers(resource.StatusHealthy, "nbg1", "eu-central", 1, 2)
+		// No server is healthy for both IP families.
+		srvs[0].SetState(resource.State{
+			Status: resource.StatusUnhealthy,
+			IPv4:   resource.StatusHealthy,
+			IPv6:   resource.StatusUnhealthy,
+		})
+		srvs[1].SetState(resource.State{
+			Status: resource.StatusUnhealthy,
+			IPv4:   resource.StatusUnhealthy,
+			IPv6:   resource.StatusHealthy,
+		})
+
+		plan := New(NewState(pair("web", ""), srvs), WithPolicy(Policy{PairByName: true}))
+		plan = withoutExplanations(plan)
+
+		assert.Equal(t, Plan{
+			Actions: []ReassignFloatingIPAction{
+				{ServerID: "1", FloatingIPID: "1"},
+				{ServerID: "2", FloatingIPID: "2"},
+			},
+			Warnings: []Warning{
+				{FloatingIPID: "1", Message: "pair split: no server is healthy for both IPv4 and IPv6"},
+				{FloatingIPID: "2", Message: "pair split: no server is healthy for both IPv4 and IPv6"},
+			},
+		}, plan)
+	})
+
+	t.Run("not_a_pair", func(t *testing.T) {
+		t.Parallel()
+
+		srvs := servers(resource.StatusHealthy, "nbg1", "eu-central", 1, 2)
+		flips := pair("web", "")
+		flips[1].IP = netip.MustParseAddr("192.0.2.2")
+
+		plan := New(NewState(flips, srvs), WithPolicy(Policy{PairByName: true}))
+
+		assert.Len(t, plan.Actions, 2)
+		assert.Equal(t, []Warning{
+			{FloatingIPID: "1", Message: "not paired: v6 is not an IPv6 address"},
+			{FloatingIPID: "2", Message: "not paired: v6 is not an IPv6 address"},
+		}, plan.Warnings)
+	})
+}
+
+func TestPlanPriority(t *testing.T) {
+	t.Parallel()
+
+	floatingIPs := func() []resource.FloatingIP {
+		return []resource.FloatingIP{
+			{HetznerID: 1, FloatingIPName: "a", Location: "nbg1", NetworkZone: "eu-central", CurrentTarget: "1", ResourceIndex: 1},
+			{HetznerID: 2, FloatingIPName: "b", Location: "nbg1", NetworkZone: "eu-central", CurrentTarget: "", ResourceIndex: 2},
+		}
+	}
+	prioritized := func() []*resource.WithStatus[resource.Server] {
+		srvs := servers(resource.StatusHealthy, "nbg1", "eu-central", 1, 2, 3)
+		srvs[0].Resource.Priority = 50
+		srvs[1].Resource.Priority = 100
+		srvs[2].Resource.Priority = 10
+		return srvs
+	}
+
+	for _, tc := range []struct {
+		name         string
+		servers      func() []*resource.WithStatus[resource.Server]
+		policy       Policy
+		expectedPlan Plan
+	}{
+		{
+			name:    "highest_priority",
+			servers: prioritized,
+			policy:  Policy{Strategy: StrategyPriority},
+			expectedPlan: Plan{
+				Actions: []ReassignFloatingIPAction{
+					{ServerID: "2", FloatingIPID: "1"},
+					{ServerID: "2", FloatingIPID: "2"},
+				},
+			},
+		},
+		{
+			name: "primary_unhealthy",
+			servers: func() []*resource.WithStatus[resource.Server] {
+				srvs := prioritized()
+				srvs[1].SetState(resource.State{Status: resource.StatusUnhealthy})
+				return srvs
+			},
+			policy: Policy{Strategy: StrategyPriority},
+			expectedPlan: Plan{
+				Actions: []ReassignFloatingIPAction{
+					{ServerID: "1", FloatingIPID: "2"},
+				},
+			},
+		},
+		{
+			name:    "server_policy",
+			servers: prioritized,
+			policy: Policy{
+				Strategy: StrategyPriority,
+				Servers:  map[string]ServerPolicy{"mock-server-3": {Priority: 200}},
+			},
+			expectedPlan: Plan{
+				Actions: []ReassignFloatingIPAction{
+					{ServerID: "3", FloatingIPID: "1"},
+					{ServerID: "3", FloatingIPID: "2"},
+				},
+			},
+		},
+		{
+			name:    "floating_ip_priority_list",
+			servers: prioritized,
+			policy: Policy{
+				Strategy:    StrategyPriority,
+				FloatingIPs: map[string]FloatingIPPolicy{"b": {Priority: []string{"mock-server-3", "mock-server-1"}}},
+			},
+			expectedPlan: Plan{
+				Actions: []ReassignFloatingIPAction{
+					{ServerID: "2", FloatingIPID: "1"},
+					{ServerID: "3", FloatingIPID: "2"},
+				},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			plan := New(NewState(floatingIPs(), tc.servers()), WithPolicy(tc.policy))
+			plan = withoutExplanations(plan)
+
+			assert.Equal(t, tc.expectedPlan, plan)
+		})
+	}
+}
+
+func TestPlanLocationFallbacks(t *testing.T) {
+	t.Parallel()
+
+	yes := true
+	floatingIPs := []resource.FloatingIP{
+		{HetznerID: 1, FloatingIPName: "a", Location: "fsn1", NetworkZone: "eu-central", ResourceIndex: -1},
+	}
+	srvs := func() []*resource.WithStatus[resource.Server] {
+		srvs := servers(resource.StatusHealthy, "fsn1", "eu-central", 1)
+		srvs = append(srvs, servers(resource.StatusHealthy, "nbg1", "eu-central", 2)...)
+		srvs = append(srvs, servers(resource.StatusHealthy, "hel1", "eu-central", 3)...)
+		// The server in the home location is down.
+		srvs[0].SetState(resource.State{Status: resource.StatusUnhealthy})
+		return srvs
+	}
+
+	for _, tc := range []struct {
+		name         string
+		policy       Policy
+		expectedPlan Plan
+	}{
+		{
+			name:   "any_location",
+			policy: Policy{},
+			expectedPlan: Plan{
+				Actions: []ReassignFloatingIPAction{{ServerID: "3", FloatingIPID: "1"}},
+			},
+		},
+		{
+			name:   "group_fallbacks",
+			policy: Policy{LocationFallbacks: map[string][]string{"fsn1": {"nbg1", "hel1"}}},
+			expectedPlan: Plan{
+				Actions: []ReassignFloatingIPAction{{ServerID: "2", FloatingIPID: "1"}},
+			},
+		},
+		{
+			name: "floating_ip_fallbacks",
+			policy: Policy{
+				LocationFallbacks: map[string][]string{"fsn1": {"nbg1"}},
+				FloatingIPs:       map[string]FloatingIPPolicy{"a": {LocationFallbacks: []string{"hel1"}}},
+			},
+			expectedPlan: Plan{
+				Actions: []ReassignFloatingIPAction{{ServerID: "3", FloatingIPID: "1"}},
+			},
+		},
+		{
+			name:   "home_location_only",
+			policy: Policy{FloatingIPs: map[string]FloatingIPPolicy{"a": {HomeLocationOnly: &yes}}},
+			expectedPlan: Plan{
+				Actions: []ReassignFloatingIPAction{},
+				Warnings: []Warning{{
+					FloatingIPID: "1",
+					Message:      "unassignable: no candidate servers in home location fsn1 and cross-location moves are not allowed",
+				}},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			plan := New(NewState(floatingIPs, srvs()), WithPolicy(tc.policy))
+			plan = withoutExplanations(plan)
+
+			assert.Equal(t, tc.expectedPlan, plan)
+		})
+	}
+}
+
+func TestPlanPins(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	// The floating IP is on server 2, but server 1 has the same resource index.
+	floatingIPs := []resource.FloatingIP{
+		{HetznerID: 10, FloatingIPName: "a", Location: "fsn1", NetworkZone: "eu-central", ResourceIndex: 1, CurrentTarget: "2"},
+	}
+	srvs := func(unhealthy ...int) []*resource.WithStatus[resource.Server] {
+		srvs := servers(resource.StatusHealthy, "fsn1", "eu-central", 1, 2)
+		for _, i := range unhealthy {
+			srvs[i].SetState(resource.State{Status: resource.StatusUnhealthy})
+		}
+		return srvs
+	}
+
+	for _, tc := range []struct {
+		name         string
+		servers      []*resource.WithStatus[resource.Server]
+		labelPin     resource.Pin
+		pins         map[string]Pin
+		expectedPlan Plan
+	}{
+		{
+			name:    "no_pin",
+			servers: srvs(),
+			expectedPlan: Plan{
+				Actions: []ReassignFloatingIPAction{{ServerID: "1", FloatingIPID: "10"}},
+			},
+		},
+		{
+			name:         "pinned",
+			servers:      srvs(),
+			pins:         map[string]Pin{"10": {ServerID: "2", Until: now.Add(time.Minute)}},
+			expectedPlan: Plan{Actions: []ReassignFloatingIPAction{}},
+		},
+		{
+			name:    "expired",
+			servers: srvs(),
+			pins:    map[string]Pin{"10": {ServerID: "2", Until: now.Add(-time.Minute)}},
+			expectedPlan: Plan{
+				Actions: []ReassignFloatingIPAction{{ServerID: "1", FloatingIPID: "10"}},
+			},
+		},
+		{
+			name:    "unhealthy",
+			servers: srvs(1),
+			pins:    map[string]Pin{"10": {ServerID: "2"}},
+			expectedPlan: Plan{
+				Actions:  []ReassignFloatingIPAction{{ServerID: "1", FloatingIPID: "10"}},
+				Warnings: []Warning{{FloatingIPID: "10", Message: "pin ignored: server mock-server-2 is not healthy"}},
+			},
+		},
+		{
+			name:         "forced",
+			servers:      srvs(1),
+			pins:         map[string]Pin{"10": {ServerID: "2", Force: true}},
+			expectedPlan: Plan{Actions: []ReassignFloatingIPAction{}},
+		},
+		{
+			name:    "not_in_group",
+			servers: srvs(),
+			pins:    map[string]Pin{"10": {ServerID: "3"}},
+			expectedPlan: Plan{
+				Actions:  []ReassignFloatingIPAction{{ServerID: "1", FloatingIPID: "10"}},
+				Warnings: []Warning{{FloatingIPID: "10", Message: "pin ignored: server 3 is not in the group"}},
+			},
+		},
+		{
+			name:         "label",
+			servers:      srvs(),
+			labelPin:     resource.Pin{ServerName: "mock-server-2", Until: now.Add(time.Minute)},
+			expectedPlan: Plan{Actions: []ReassignFloatingIPAction{}},
+		},
+		{
+			name:     "label_expired",
+			servers:  srvs(),
+			labelPin: resource.Pin{ServerName: "mock-server-2", Until: now.Add(-time.Minute)},
+			expectedPlan: Plan{
+				Actions: []ReassignFloatingIPAction{{ServerID: "1", FloatingIPID: "10"}},
+			},
+		},
+		{
+			name:     "pin_overrides_label",
+			servers:  srvs(),
+			labelPin: resource.Pin{ServerName: "mock-server-2"},
+			pins:     map[string]Pin{"10": {ServerID: "1"}},
+			expectedPlan: Plan{
+				Actions: []ReassignFloatingIPAction{{ServerID: "1", FloatingIPID: "10"}},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			flips := slices.Clone(floatingIPs)
+			flips[0].Pin = tc.labelPin
+			state := NewState(flips, tc.servers)
+			state.Pins = tc.pins
+
+			plan := New(state, WithNow(now))
+			plan = withoutExplanations(plan)
+
+			assert.Equal(t, tc.expectedPlan, plan)
+		})
+	}
+}
+
+func TestPlanUnavailableLocations(t *testing.T) {
+	t.Parallel()
+
+	floatingIPs := []resource.FloatingIP{
+		{HetznerID: 10, Location: "fsn1", NetworkZone: "eu-central", ResourceIndex: 1, CurrentTarget: "1"},
+	}
+	srvs := servers(resource.StatusHealthy, "fsn1", "eu-central", 1)
+	srvs = append(srvs, servers(resource.StatusHealthy, "nbg1", "eu-central", 2)...)
+
+	for _, tc := range []struct {
+		name                 string
+		unavailableLocations map[string]string
+		expectedActions      []ReassignFloatingIPAction
+	}{
+		{
+			name:            "available",
+			expectedActions: []ReassignFloatingIPAction{},
+		},
+		{
+			name:                 "unavailable",
+			unavailableLocations: map[string]string{"fsn1": "api is down"},
+			expectedActions: []ReassignFloatingIPAction{{
+				FloatingIPID: "10",
+				ServerID:     "2",
+				Reasons: []Reason{
+					{
+						Code:    ReasonLocationUnavailable,
+						Message: "current target mock-server-1 is in unavailable location fsn1: api is down",
+					},
+					{Code: ReasonLocationFallback, Message: "no candidate in home location fsn1, falling back to nbg1"},
+					{Code: ReasonLeastLoaded, Message: "least-loaded candidate in nbg1"},
+				},
+			}},
+		},
+		{
+			// Unavailable locations are only avoided while there are healthy servers elsewhere.
+			name:                 "all_unavailable",
+			unavailableLocations: map[string]string{"fsn1": "api is down", "nbg1": "api is down"},
+			expectedActions:      []ReassignFloatingIPAction{},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			state := NewState(floatingIPs, srvs)
+			state.UnavailableLocations = tc.unavailableLocations
+
+			plan := New(state)
+			assert.Equal(t, tc.expectedActions, plan.Actions)
+		})
+	}
+}
+
+func TestPlanMaintenance(t *testing.T) {
+	t.Parallel()
+
+	floatingIPs := []resource.FloatingIP{
+		{HetznerID: 10, Location: "fsn1", NetworkZone: "eu-central", ResourceIndex: 1, CurrentTarget: "1"},
+	}
+	suppress := maintenance.Window{ID: "reboots", Mode: maintenance.ModeSuppress}
+	drain := maintenance.Window{ID: "updates", Mode: maintenance.ModeDrain, Reason: "kernel updates"}
+
+	for _, tc := range []struct {
+		name            string
+		status          resource.Status
+		maintenance     map[string]maintenance.Window
+		expectedActions []ReassignFloatingIPAction
+	}{
+		{
+			name:   "unhealthy",
+			status: resource.StatusUnhealthy,
+			expectedActions: []ReassignFloatingIPAction{{
+				FloatingIPID: "10",
+				ServerID:     "2",
+				Reasons: []Reason{
+					{Code: ReasonTargetUnhealthy, Message: "current target mock-server-1 is unhealthy"},
+					{Code: ReasonLeastLoaded, Message: "least-loaded candidate in fsn1"},
+				},
+			}},
+		},
+		{
+			name:            "suppressed",
+			status:          resource.StatusUnhealthy,
+			maintenance:     map[string]maintenance.Window{"1": suppress},
+			expectedActions: []ReassignFloatingIPAction{},
+		},
+		{
+			name:        "drained",
+			status:      resource.StatusHealthy,
+			maintenance: map[string]maintenance.Window{"1": drain},
+			expectedActions: []ReassignFloatingIPAction{{
+				FloatingIPID: "10",
+				ServerID:     "2",
+				Reasons: []Reason{
+					{
+						Code:    ReasonTargetDrained,
+						Message: "current target mock-server-1 is drained for maintenance window `updates` (kernel updates)",
+					},
+					{Code: ReasonLeastLoaded, Message: "least-loaded candidate in fsn1"},
+				},
+			}},
+		},
+		{
+			// Drained servers are only avoided while there are other healthy servers.
+			name:            "all drained",
+			status:          resource.StatusHealthy,
+			maintenance:     map[string]maintenance.Window{"1": drain, "2": drain},
+			expectedActions: []ReassignFloatingIPAction{},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			srvs := servers(tc.status, "fsn1", "eu-central", 1)
+			srvs = append(srvs, servers(resource.StatusHealthy, "fsn1", "eu-central", 2)...)
+			state := NewState(floatingIPs, srvs)
+			state.Maintenance = tc.maintenance
+
+			plan := New(state)
+			assert.Equal(t, tc.expectedActions, plan.Actions)
+		})
+	}
+}
+
+func TestPlanReasons(t *testing.T) {
+	t.Parallel()
+
+	srvs := servers(resource.StatusHealthy, "fsn1", "eu-central", 1, 2)
+	srvs = append(srvs, servers(resource.StatusHealthy, "nbg1", "eu-central", 3)...)
+	srvs[0].SetState(resource.State{Status: resource.StatusUnhealthy})
+
+	floatingIPs := []resource.FloatingIP{
+		{HetznerID: 1, Location: "fsn1", NetworkZone: "eu-central", CurrentTarget: "1", ResourceIndex: 2},
+		{HetznerID: 2, Location: "hel1", NetworkZone: "eu-central", CurrentTarget: "", ResourceIndex: -1},
+	}
+
+	plan := New(NewState(floatingIPs, srvs))
+
+	assert.Equal(t, []ReassignFloatingIPAction{
+		{
+			FloatingIPID: "1",
+			ServerID:     "2",
+			Reasons: []Reason{
+				{Code: ReasonTargetUnhealthy, Message: "current target mock-server-1 is unhealthy"},
+				{Code: ReasonResourceIndexMatch, Message: "resource_index 2 match in fsn1"},
+			},
+		},
+		{
+			FloatingIPID: "2",
+			ServerID:     "3",
+			Reasons: []Reason{
+				{Code: ReasonUnassigned, Message: "not assigned to a server"},
+				{Code: ReasonLocationFallback, Message: "no candidate in home location hel1, falling back to nbg1"},
+				{Code: ReasonLeastLoaded, Message: "least-loaded candidate in nbg1"},
+			},
+			Alternatives: []string{"2"},
+		},
+	}, plan.Actions)
+}
@@ -0,0 +1,176 @@
+package plan
+
+import (
+	"time"
+
+	"github.com/gzuidhof/flipper/resource"
+)
+
+// FailbackPolicy determines when floating IPs are moved back to their preferred server after a failover.
+type FailbackPolicy string
+
+const (
+	// FailbackImmediate moves floating IPs back as soon as the preferred server is healthy again.
+	FailbackImmediate FailbackPolicy = "immediate"
+	// FailbackDelayed moves floating IPs back once the preferred server has been healthy for some time.
+	FailbackDelayed FailbackPolicy = "delayed"
+	// FailbackManual only moves floating IPs back when explicitly requested.
+	FailbackManual FailbackPolicy = "manual"
+)
+
+// Failback describes the failback behavior for a floating IP.
+type Failback struct {
+	Policy FailbackPolicy
+
+	// Delay is the time the target server must have been healthy for, only used for the delayed policy.
+	Delay time.Duration
+}
+
+// FloatingIPPolicy overrides the group policy for a single floating IP.
+// Fields that are not set are inherited from the group policy.
+type FloatingIPPolicy struct {
+	Failback *Failback
+
+	// Priority lists server names from the highest to the lowest priority, for the priority strategy.
+	Priority []string
+
+	// LocationFallbacks are the locations to use in order of preference when the home location has no candidates.
+	LocationFallbacks []string
+	// HomeLocationOnly forbids targeting the floating IP at servers outside of its home location.
+	HomeLocationOnly *bool
+}
+
+// ServerPolicy overrides the properties of a single server.
+// Fields that are zero are taken from the server resource itself (i.e. its labels).
+type ServerPolicy struct {
+	// Weight is the relative capacity of the server.
+	Weight int
+	// MaxFloatingIPs is the maximum number of floating IPs that may target the server.
+	MaxFloatingIPs int
+	// Priority is the priority of the server for the priority strategy.
+	Priority int
+}
+
+// Policy describes how a plan should be created, beyond the state of the resources.
+// The zero value is the default behavior.
+type Policy struct {
+	// Strategy determines how floating IPs are distributed over the candidate servers, it defaults to spread.
+	Strategy Strategy
+
+	// Failback is the failback behavior for the floating IPs in the group.
+	// If the policy is empty, floating IPs fail back immediately.
+	Failback Failback
+
+	// FloatingIPs contains overrides for specific floating IPs, by floating IP name.
+	FloatingIPs map[string]FloatingIPPolicy
+
+	// Servers contains overrides for specific servers, by server name.
+	Servers map[string]ServerPolicy
+
+	// LocationFallbacks are the locations to use in order of preference, by home location, when the home location
+	// of a floating IP has no candidates. Locations that are not listed are used last.
+	LocationFallbacks map[string][]string
+
+	// HomeLocationOnly forbids targeting floating IPs at servers outside of their home location.
+	HomeLocationOnly bool
+
+	// PairByName pairs IPv4 and IPv6 floating IPs whose names only differ in their IP family suffix.
+	// Floating IPs with a pair key (from a label) are always paired by that key instead.
+	PairByName bool
+}
+
+// WeightOf returns the weight of the given server.
+func (p Policy) WeightOf(server resource.Server) int {
+	if override, ok := p.Servers[server.Name()]; ok && override.Weight > 0 {
+		return override.Weight
+	}
+	return server.WeightOrDefault()
+}
+
+// MaxFloatingIPsOf returns the maximum number of floating IPs for the given server, zero means unlimited.
+func (p Policy) MaxFloatingIPsOf(server resource.Server) int {
+	if override, ok := p.Servers[server.Name()]; ok && override.MaxFloatingIPs > 0 {
+		return override.MaxFloatingIPs
+	}
+	return server.MaxFloatingIPs
+}
+
+// PriorityOf returns the priority of the given server, higher is preferred.
+func (p Policy) PriorityOf(server resource.Server) int {
+	if override, ok := p.Servers[server.Name()]; ok && override.Priority != 0 {
+		return override.Priority
+	}
+	return server.Priority
+}
+
+// PriorityListFor returns the explicit server priority list of the given floating IP, if any.
+func (p Policy) PriorityListFor(floatingIPName string) []string {
+	return p.FloatingIPs[floatingIPName].Priority
+}
+
+// LocationFallbacksFor returns the fallback locations of the given floating IP in order of preference.
+func (p Policy) LocationFallbacksFor(flip resource.FloatingIP) []string {
+	if override, ok := p.FloatingIPs[flip.Name()]; ok && len(override.LocationFallbacks) > 0 {
+		return override.LocationFallbacks
+	}
+	return p.LocationFallbacks[flip.Location]
+}
+
+// HomeLocationOnlyFor returns true if the given floating IP may only target servers in its home location.
+func (p Policy) HomeLocationOnlyFor(floatingIPName string) bool {
+	if override, ok := p.FloatingIPs[floatingIPName]; ok && override.HomeLocationOnly != nil {
+		return *override.HomeLocationOnly
+	}
+	return p.HomeLocationOnly
+}
+
+// FailbackFor returns the failback behavior for the given floating IP.
+func (p Policy) FailbackFor(floatingIPName string) Failback {
+	if override, ok := p.FloatingIPs[floatingIPName]; ok && override.Failback != nil {
+		return *override.Failback
+	}
+	return p.Failback
+}
+
+// Option configures how a plan is created.
+type Option func(o *options)
+
+type options struct {
+	policy Policy
+
+	// now is the time at which the plan is created.
+	now time.Time
+
+	// forceFailback ignores the failback policy, all floating IPs are moved back right away.
+	forceFailback bool
+}
+
+func newOptions(opts ...Option) options {
+	o := options{now: time.Now()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithPolicy sets the policy to create the plan with.
+func WithPolicy(policy Policy) Option {
+	return func(o *options) {
+		o.policy = policy
+	}
+}
+
+// WithNow sets the time at which the plan is created, it defaults to the current time.
+func WithNow(now time.Time) Option {
+	return func(o *options) {
+		o.now = now
+	}
+}
+
+// WithForcedFailback ignores the failback policy and moves floating IPs back to their preferred server right away.
+// This is used to fail back by hand when the policy is manual (or delayed).
+func WithForcedFailback() Option {
+	return func(o *options) {
+		o.forceFailback = true
+	}
+}
@@ -0,0 +1,125 @@
+package plan
+
+import (
+	"fmt"
+
+	"github.com/gzuidhof/flipper/resource"
+)
+
+// ReasonCode identifies why a floating IP is moved, or why it is moved to a specific server.
+type ReasonCode string
+
+// Reasons for moving a floating IP away from its current target.
+const (
+	// ReasonUnassigned means the floating IP does not target any server.
+	ReasonUnassigned ReasonCode = "unassigned"
+	// ReasonTargetUnhealthy means the current target is unhealthy (for the IP family of the floating IP).
+	ReasonTargetUnhealthy ReasonCode = "current_target_unhealthy"
+	// ReasonTargetFlapping means the current target is flapping.
+	ReasonTargetFlapping ReasonCode = "current_target_flapping"
+	// ReasonTargetNotCandidate means the current target is not a candidate for another reason, e.g. because it is
+	// not healthy for both IP families of a pair.
+	ReasonTargetNotCandidate ReasonCode = "current_target_not_candidate"
+	// ReasonLocationUnavailable means the current target is in a location that is unavailable because of a group
+	// this group depends on.
+	ReasonLocationUnavailable ReasonCode = "current_target_location_unavailable"
+	// ReasonTargetDrained means the current target is drained by a maintenance window.
+	ReasonTargetDrained ReasonCode = "current_target_drained"
+	// ReasonRebalance means the current target is fine, but another server is preferred (e.g. failback).
+	ReasonRebalance ReasonCode = "rebalance"
+)
+
+// Reasons for choosing the new target of a floating IP.
+const (
+	// ReasonResourceIndexMatch means the server is in the home location and has the same resource index.
+	ReasonResourceIndexMatch ReasonCode = "resource_index_match"
+	// ReasonLeastLoaded means the server has the least floating IPs relative to its weight.
+	ReasonLeastLoaded ReasonCode = "least_loaded"
+	// ReasonLocationFallback means there was no candidate with capacity in the home location.
+	ReasonLocationFallback ReasonCode = "location_fallback"
+	// ReasonHighestPriority means the server is the candidate with the highest priority.
+	ReasonHighestPriority ReasonCode = "highest_priority"
+	// ReasonPaired means the floating IP follows the other floating IP of its pair.
+	ReasonPaired ReasonCode = "paired"
+	// ReasonPinned means the floating IP is pinned to the server.
+	ReasonPinned ReasonCode = "pinned"
+	// ReasonMaintenance means the floating IP is kept on its server during a maintenance window that suppresses
+	// failover.
+	ReasonMaintenance ReasonCode = "maintenance"
+)
+
+// Reason explains a part of the decision to reassign a floating IP.
+type Reason struct {
+	Code    ReasonCode `json:"code"`
+	Message string     `json:"message"`
+}
+
+// String returns a string representation of the reason.
+func (r Reason) String() string {
+	return r.Message
+}
+
+// explanation is why a floating IP was proposed to target a server, and which other servers were considered.
+type explanation struct {
+	reasons      []Reason
+	alternatives []string
+}
+
+// moveReason returns why the floating IP should leave its current target.
+func moveReason(s State, flip resource.FloatingIP, candidates familyCandidates) Reason {
+	if flip.CurrentTarget == "" {
+		return Reason{Code: ReasonUnassigned, Message: "not assigned to a server"}
+	}
+
+	target := s.Servers[flip.CurrentTarget]
+	switch {
+	case target.IsUnhealthyFor(flip.Family()):
+		if flip.Family() != resource.IPFamilyUnknown && target.State().StatusFor(flip.Family()) != target.Status() {
+			return Reason{
+				Code:    ReasonTargetUnhealthy,
+				Message: fmt.Sprintf("current target %s is unhealthy over %s", target.Resource.Name(), flip.Family()),
+			}
+		}
+		return Reason{
+			Code:    ReasonTargetUnhealthy,
+			Message: "current target " + target.Resource.Name() + " is unhealthy",
+		}
+	case target.IsFlapping():
+		return Reason{
+			Code:    ReasonTargetFlapping,
+			Message: "current target " + target.Resource.Name() + " is flapping",
+		}
+	case !candidates.contains(flip.CurrentTarget) && s.IsDrained(flip.CurrentTarget):
+		return Reason{
+			Code: ReasonTargetDrained,
+			Message: "current target " + target.Resource.Name() + " is drained for " +
+				s.Maintenance[flip.CurrentTarget].Describe(),
+		}
+	case !candidates.contains(flip.CurrentTarget) && s.UnavailableLocations[target.Resource.Location] != "":
+		return Reason{
+			Code: ReasonLocationUnavailable,
+			Message: "current target " + target.Resource.Name() + " is in unavailable location " +
+				target.Resource.Location + ": " + s.UnavailableLocations[target.Resource.Location],
+		}
+	case !candidates.contains(flip.CurrentTarget):
+		return Reason{
+			Code:    ReasonTargetNotCandidate,
+			Message: "current target " + target.Resource.Name() + " is not a candidate",
+		}
+	}
+	return Reason{
+		Code:    ReasonRebalance,
+		Message: "current target " + target.Resource.Name() + " is healthy, but another server is preferred",
+	}
+}
+
+// serverIDs returns the IDs of the servers, leaving out the one with the given ID.
+func serverIDs(servers []*resource.WithStatus[resource.Server], except string) []string {
+	var ids []string
+	for _, server := range servers {
+		if server.Resource.ID() != except {
+			ids = append(ids, server.Resource.ID())
+		}
+	}
+	return ids
+}
@@ -5,6 +5,7 @@ import (
 	"slices"
 	"strings"
 
+	"github.com/gzuidhof/flipper/maintenance"
 	"github.com/gzuidhof/flipper/resource"
 )
 
@@ -12,6 +13,17 @@ import (
 type State struct {
 	FloatingIPs map[string]resource.FloatingIP
 	Servers     map[string]*resource.WithStatus[resource.Server]
+
+	// Pins are the pins set through the admin API or by the drift policy, by floating IP ID.
+	// Pins set on the floating IPs themselves are part of the floating IPs.
+	Pins map[string]Pin
+
+	// UnavailableLocations are the locations that should be avoided because a group this group depends on is not
+	// healthy enough there, with the reason by location.
+	UnavailableLocations map[string]string
+
+	// Maintenance are the active maintenance windows, by the ID of the server they apply to.
+	Maintenance map[string]maintenance.Window
 }
 
 // NewState creates a new state.
@@ -45,20 +57,45 @@ func NewStateFromGroup(group resource.Group) State {
 	return NewState(group.FloatingIPs, statefulServers)
 }
 
-// CandidateServers returns all healthy servers, or all servers if there are no healthy ones.
-// It returns them in a fixed order.
-func (s State) CandidateServers() []*resource.WithStatus[resource.Server] {
-	var candidates []*resource.WithStatus[resource.Server]
-	for _, server := range s.Servers {
-		if server.IsHealthy() {
-			candidates = append(candidates, server)
-		}
+// CandidateServers returns all servers that are healthy for all the given IP families, not flapping, not in an
+// unavailable location and not drained for maintenance. If there are none, servers in unavailable locations are
+// used, then flapping servers that are healthy, then drained servers that are healthy, and if there are none of
+// those either all servers are returned. It returns them in a fixed order.
+//
+// An unknown IP family considers the overall status of the servers.
+func (s State) CandidateServers(families ...resource.IPFamily) []*resource.WithStatus[resource.Server] {
+	tiers := []func(server *resource.WithStatus[resource.Server]) bool{
+		func(server *resource.WithStatus[resource.Server]) bool {
+			return isHealthyForAll(server, families) && !server.IsFlapping() &&
+				s.UnavailableLocations[server.Resource.Location] == "" && !s.IsDrained(server.Resource.ID())
+		},
+		// Healthy servers in unavailable locations are still better than flapping servers.
+		func(server *resource.WithStatus[resource.Server]) bool {
+			return isHealthyForAll(server, families) && !server.IsFlapping() && !s.IsDrained(server.Resource.ID())
+		},
+		// Flapping servers that are currently healthy are still better than unhealthy servers.
+		func(server *resource.WithStatus[resource.Server]) bool {
+			return isHealthyForAll(server, families) && !s.IsDrained(server.Resource.ID())
+		},
+		// Drained servers are only used if there is no other healthy server left.
+		func(server *resource.WithStatus[resource.Server]) bool {
+			return isHealthyForAll(server, families)
+		},
+		// We use all servers as candidates if there are no healthy ones.
+		func(*resource.WithStatus[resource.Server]) bool {
+			return true
+		},
 	}
 
-	if len(candidates) == 0 {
-		// We use all servers as candidates if there are no healthy ones.
+	var candidates []*resource.WithStatus[resource.Server]
+	for _, inTier := range tiers {
 		for _, server := range s.Servers {
-			candidates = append(candidates, server)
+			if inTier(server) {
+				candidates = append(candidates, server)
+			}
+		}
+		if len(candidates) > 0 {
+			break
 		}
 	}
 
@@ -87,6 +124,25 @@ func (s State) CandidateServers() []*resource.WithStatus[resource.Server] {
 	return candidates
 }
 
+// HasHealthyServer returns true if there is at least one server that is healthy for all the given IP families.
+func (s State) HasHealthyServer(families ...resource.IPFamily) bool {
+	for _, server := range s.Servers {
+		if isHealthyForAll(server, families) {
+			return true
+		}
+	}
+	return false
+}
+
+func isHealthyForAll(server *resource.WithStatus[resource.Server], families []resource.IPFamily) bool {
+	for _, family := range families {
+		if !server.IsHealthyFor(family) {
+			return false
+		}
+	}
+	return true
+}
+
 // CandidateFloatingIPs returns all floating IPs that we should me managing the targets of.
 func (s State) CandidateFloatingIPs() []resource.FloatingIP {
 	flips := make([]resource.FloatingIP, 0, len(s.FloatingIPs))
@@ -125,7 +181,8 @@ func (s State) UnassignedFloatingIPs() []resource.FloatingIP {
 	return unassigned
 }
 
-// UnhealthyFloatingIPs returns all floating IPs that are assigned to an unhealthy server.
+// UnhealthyFloatingIPs returns all floating IPs that are assigned to a server that is unhealthy for the
+// floating IP's IP family.
 func (s State) UnhealthyFloatingIPs() []resource.FloatingIP {
 	var unhealthy []resource.FloatingIP
 	for _, flip := range s.FloatingIPs {
@@ -141,7 +198,7 @@ func (s State) UnhealthyFloatingIPs() []resource.FloatingIP {
 			)
 			continue
 		}
-		if serverTarget.IsUnhealthy() {
+		if serverTarget.IsUnhealthyFor(flip.Family()) {
 			unhealthy = append(unhealthy, flip)
 		}
 	}
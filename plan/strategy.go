@@ -0,0 +1,317 @@
+package plan
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/gzuidhof/flipper/resource"
+)
+
+// Strategy determines how floating IPs are distributed over the candidate servers.
+type Strategy string
+
+const (
+	// StrategySpread distributes the floating IPs evenly over the candidate servers, relative to their weight.
+	// This is the default.
+	StrategySpread Strategy = "spread"
+	// StrategyPriority targets each floating IP at the candidate server with the highest priority (active/passive).
+	StrategyPriority Strategy = "priority"
+)
+
+// placer assigns placement units to servers according to a strategy.
+type placer interface {
+	place(p *placement)
+}
+
+// placerFor returns the placer for the given strategy.
+func placerFor(strategy Strategy) placer {
+	switch strategy {
+	case StrategyPriority:
+		return priorityPlacer{}
+	case StrategySpread, "":
+		return spreadPlacer{}
+	}
+	slog.Warn("unknown plan strategy, using spread", slog.String("strategy", string(strategy)))
+	return spreadPlacer{}
+}
+
+// placement is the assignment of floating IPs to servers while a plan is being created.
+type placement struct {
+	o     options
+	state State
+
+	units []placementUnit
+	// candidates are the candidate servers by the candidates key of the placement units.
+	candidates map[string]familyCandidates
+
+	// Floating IP ID to server ID
+	proposal map[string]string
+	// IP family to server ID to floating IP count. The floating IPs of each IP family are spread separately.
+	assignCount map[resource.IPFamily]map[string]int
+	// Server ID to floating IP count over all IP families, used for the maximum number of floating IPs.
+	totalCount map[string]int
+	// Floating IP ID to why it was proposed to target the server.
+	explanations map[string]explanation
+
+	// pinned contains the floating IPs that were placed because of a pin, or kept on their server because of a
+	// maintenance window.
+	pinned map[string]bool
+
+	// unassignable contains the placement units (by their primary floating IP) that can not be assigned.
+	unassignable map[string]bool
+	warnings     []Warning
+}
+
+func newPlacement(o options, s State, units []placementUnit) *placement {
+	p := &placement{
+		o:            o,
+		state:        s,
+		units:        units,
+		candidates:   map[string]familyCandidates{},
+		proposal:     map[string]string{},
+		assignCount:  map[resource.IPFamily]map[string]int{},
+		totalCount:   map[string]int{},
+		explanations: map[string]explanation{},
+		pinned:       map[string]bool{},
+		unassignable: map[string]bool{},
+	}
+
+	// Servers are only candidates for the IP families they are healthy for, so that a server which is
+	// only unhealthy over IPv6 keeps its IPv4 floating IPs. Pairs need servers that are healthy for both.
+	for _, unit := range units {
+		if _, ok := p.candidates[unit.candidatesKey()]; !ok {
+			p.candidates[unit.candidatesKey()] = newFamilyCandidates(s.CandidateServers(unit.families()...))
+		}
+		for _, family := range unit.families() {
+			if _, ok := p.assignCount[family]; !ok {
+				p.assignCount[family] = map[string]int{}
+			}
+		}
+	}
+
+	// Units in a network zone without candidates can not be assigned by any strategy.
+	for _, unit := range units {
+		if p.candidatesFor(unit).networkZones[unit.primary().NetworkZone] {
+			continue
+		}
+		p.unassignable[unit.primary().ID()] = true
+		for _, member := range unit.floatingIPs {
+			slog.Warn("no candidate servers in network zone for floating IP",
+				slog.String("network_zone", member.NetworkZone),
+				slog.String("floating_ip_id", member.ID()),
+			)
+			p.warn(member, "unassignable: no candidate servers in network zone "+member.NetworkZone)
+		}
+	}
+
+	return p
+}
+
+// candidatesFor returns the candidate servers of the unit.
+func (p *placement) candidatesFor(unit placementUnit) familyCandidates {
+	return p.candidates[unit.candidatesKey()]
+}
+
+// assign assigns all floating IPs in the unit to the server, the explanation says why.
+func (p *placement) assign(unit placementUnit, serverID string, exp explanation) {
+	for _, flip := range unit.floatingIPs {
+		p.proposal[flip.ID()] = serverID
+		p.assignCount[flip.Family()][serverID]++
+		p.totalCount[serverID]++
+
+		flipExp := explanation{reasons: slices.Clone(exp.reasons), alternatives: exp.alternatives}
+		for _, other := range unit.floatingIPs {
+			if other.ID() != flip.ID() {
+				flipExp.reasons = append(flipExp.reasons, Reason{Code: ReasonPaired, Message: "paired with " + other.Name()})
+			}
+		}
+		p.explanations[flip.ID()] = flipExp
+	}
+}
+
+// pending returns true if the unit still has to be assigned.
+func (p *placement) pending(unit placementUnit) bool {
+	_, assigned := p.proposal[unit.primary().ID()]
+	return !assigned && !p.unassignable[unit.primary().ID()]
+}
+
+// warn adds a warning about the floating IP.
+func (p *placement) warn(flip resource.FloatingIP, message string) {
+	p.warnings = append(p.warnings, Warning{FloatingIPID: flip.ID(), Message: message})
+}
+
+// warnFull adds a warning for each floating IP in a unit that can not be assigned because every candidate is full.
+func (p *placement) warnFull(unit placementUnit) {
+	for _, member := range unit.floatingIPs {
+		slog.Warn("all candidate servers for floating IP are at their maximum number of floating IPs",
+			slog.String("floating_ip_id", member.ID()),
+		)
+		p.warn(member, "unassignable: all candidate servers are at their maximum number of floating IPs")
+	}
+}
+
+// warnHomeLocationOnly adds a warning for each floating IP in a unit that can not be assigned because there are no
+// candidates in its home location, and it may not be targeted at servers in other locations.
+func (p *placement) warnHomeLocationOnly(unit placementUnit) {
+	for _, member := range unit.floatingIPs {
+		slog.Warn("no candidate servers in home location of floating IP, and cross-location moves are not allowed",
+			slog.String("location", member.Location),
+			slog.String("floating_ip_id", member.ID()),
+		)
+		p.warn(member, "unassignable: no candidate servers in home location "+member.Location+
+			" and cross-location moves are not allowed")
+	}
+}
+
+// spreadPlacer distributes floating IPs evenly, preferring the server in the same location with the same
+// resource index.
+type spreadPlacer struct{}
+
+func (spreadPlacer) place(p *placement) {
+	o := p.o
+
+	// First we try to assign floating IPs to servers in the same location with the same index.
+	for _, unit := range p.units {
+		if !p.pending(unit) {
+			continue
+		}
+		flip := unit.primary()
+		candidates, sameLocationPossible := p.candidatesFor(unit).perLocation[flip.Location]
+		if !sameLocationPossible {
+			continue
+		}
+
+		// The first choice is the server that is in the same location and shares the index with the floating IP.
+		for _, server := range candidates {
+			if server.Resource.ResourceIndex == flip.ResourceIndex &&
+				o.hasCapacity(server.Resource, p.totalCount, len(unit.floatingIPs)) {
+				p.assign(unit, server.Resource.ID(), explanation{
+					reasons: []Reason{{
+						Code:    ReasonResourceIndexMatch,
+						Message: fmt.Sprintf("resource_index %d match in %s", flip.ResourceIndex, flip.Location),
+					}},
+					alternatives: serverIDs(candidates, server.Resource.ID()),
+				})
+				break
+			}
+		}
+	}
+
+	// Then we assign the remaining floating IPs.
+	for _, unit := range p.units {
+		if !p.pending(unit) { // Already assigned.. O(2nm) is fine
+			continue
+		}
+
+		flip := unit.primary()
+		tiers := o.locationTiers(flip, p.candidatesFor(unit))
+		if len(tiers) == 0 {
+			p.warnHomeLocationOnly(unit)
+			continue
+		}
+
+		// The second choice is the server that has the least floating IPs assigned to it relative to its weight,
+		// in the most preferred location that has a server with capacity left.
+		// For pairs the floating IPs of the first IP family (IPv4) are used to spread them.
+		familyCount := p.assignCount[flip.Family()]
+		var serverID string
+		var tier []*resource.WithStatus[resource.Server]
+		for _, candidates := range tiers {
+			serverID = o.leastLoaded(candidates, familyCount, p.totalCount, len(unit.floatingIPs))
+			if serverID != "" {
+				tier = candidates
+				break
+			}
+		}
+		if serverID == "" {
+			p.warnFull(unit)
+			continue
+		}
+
+		location := p.state.Servers[serverID].Resource.Location
+		exp := explanation{alternatives: serverIDs(tier, serverID)}
+		if location != flip.Location {
+			exp.reasons = append(exp.reasons, Reason{
+				Code:    ReasonLocationFallback,
+				Message: "no candidate in home location " + flip.Location + ", falling back to " + location,
+			})
+		}
+		exp.reasons = append(exp.reasons, Reason{
+			Code:    ReasonLeastLoaded,
+			Message: "least-loaded candidate in " + location,
+		})
+		p.assign(unit, serverID, exp)
+	}
+}
+
+// priorityPlacer targets every floating IP at the candidate server with the highest priority that has capacity left.
+// The priority comes from the priority list of the floating IP if it has one, and otherwise from the servers.
+type priorityPlacer struct{}
+
+func (priorityPlacer) place(p *placement) {
+	for _, unit := range p.units {
+		if !p.pending(unit) {
+			continue
+		}
+
+		flip := unit.primary()
+		tiers := p.o.locationTiers(flip, p.candidatesFor(unit))
+		if len(tiers) == 0 {
+			p.warnHomeLocationOnly(unit)
+			continue
+		}
+
+		// The location preference only limits where the floating IP may go, the priority decides.
+		candidates := p.o.byPriority(flip, slices.Concat(tiers...))
+		assigned := false
+		for i, server := range candidates {
+			if !p.o.hasCapacity(server.Resource, p.totalCount, len(unit.floatingIPs)) {
+				continue
+			}
+
+			message := fmt.Sprintf("highest priority candidate (priority %d)", p.o.policy.PriorityOf(server.Resource))
+			if slices.Contains(p.o.policy.PriorityListFor(flip.Name()), server.Resource.Name()) {
+				message = "highest candidate in the priority list of the floating IP"
+			}
+			p.assign(unit, server.Resource.ID(), explanation{
+				reasons:      []Reason{{Code: ReasonHighestPriority, Message: message}},
+				alternatives: serverIDs(candidates[i+1:], ""),
+			})
+			assigned = true
+			break
+		}
+		if !assigned {
+			p.warnFull(unit)
+		}
+	}
+}
+
+// byPriority returns the servers ordered from the highest to the lowest priority for the floating IP.
+// Servers in the priority list of the floating IP come first in that order, then the others by their own priority
+// (highest first) and finally by name.
+func (o options) byPriority(
+	flip resource.FloatingIP,
+	servers []*resource.WithStatus[resource.Server],
+) []*resource.WithStatus[resource.Server] {
+	list := o.policy.PriorityListFor(flip.Name())
+	rank := func(server *resource.WithStatus[resource.Server]) int {
+		if i := slices.Index(list, server.Resource.Name()); i >= 0 {
+			return i
+		}
+		return len(list)
+	}
+
+	sorted := slices.Clone(servers)
+	slices.SortStableFunc(sorted, func(i, j *resource.WithStatus[resource.Server]) int {
+		if ri, rj := rank(i), rank(j); ri != rj {
+			return ri - rj
+		}
+		if pi, pj := o.policy.PriorityOf(i.Resource), o.policy.PriorityOf(j.Resource); pi != pj {
+			return pj - pi
+		}
+		return strings.Compare(i.Resource.Name(), j.Resource.Name())
+	})
+	return sorted
+}
@@ -0,0 +1,87 @@
+package hetzner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gzuidhof/flipper/resource"
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+	"golang.org/x/sync/errgroup"
+)
+
+var _ resource.ChangeWatcher = Provider{}
+
+// actionCursor is the most recent action of the floating IPs and of the servers that was seen.
+type actionCursor struct {
+	mu sync.Mutex
+
+	// started is false until the first actions were seen.
+	started    bool
+	floatingIP int64
+	server     int64
+}
+
+// advance moves the cursor to the given actions, and returns true if they are newer than those seen before.
+func (c *actionCursor) advance(floatingIP, server int64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	changed := c.started && (floatingIP != c.floatingIP || server != c.server)
+	c.started, c.floatingIP, c.server = true, floatingIP, server
+	return changed
+}
+
+// WatchInterval returns how often the actions are checked, zero if watching them is disabled.
+func (c Provider) WatchInterval() time.Duration {
+	if !c.cfg.Hetzner.WatchActions.Enabled {
+		return 0
+	}
+	return c.cfg.Hetzner.WatchActions.IntervalOrDefault()
+}
+
+// Changed returns true if there are new floating IP or server actions in the project since the last call, e.g.
+// a floating IP was assigned or a server was created. Only the most recent action of each is fetched, which is a
+// lot cheaper than polling all resources. Changing labels is not an action, so label changes are not seen.
+func (c Provider) Changed(ctx context.Context) (bool, error) {
+	var floatingIP, server int64
+
+	errgp, ctx := errgroup.WithContext(ctx)
+	errgp.Go(func() error {
+		id, err := latestActionID(ctx, c.hc.FloatingIP.Action)
+		if err != nil {
+			return fmt.Errorf("failed to list floating IP actions: %w", err)
+		}
+		floatingIP = id
+		return nil
+	})
+	errgp.Go(func() error {
+		id, err := latestActionID(ctx, c.hc.Server.Action)
+		if err != nil {
+			return fmt.Errorf("failed to list server actions: %w", err)
+		}
+		server = id
+		return nil
+	})
+	if err := errgp.Wait(); err != nil {
+		return false, fmt.Errorf("failed to watch Hetzner actions: %w", err)
+	}
+
+	return c.actions.advance(floatingIP, server), nil
+}
+
+// latestActionID returns the ID of the most recent action, zero if there are none.
+func latestActionID(ctx context.Context, client *hcloud.ResourceActionClient) (int64, error) {
+	actions, _, err := client.List(ctx, hcloud.ActionListOpts{
+		ListOpts: hcloud.ListOpts{PerPage: 1},
+		Sort:     []string{"id:desc"},
+	})
+	if err != nil {
+		return 0, err //nolint:wrapcheck // Wrapped by the caller.
+	}
+	if len(actions) == 0 {
+		return 0, nil
+	}
+	return actions[0].ID, nil
+}
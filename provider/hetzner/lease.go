@@ -0,0 +1,159 @@
+package hetzner
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strconv"
+	"time"
+
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+	"github.com/gzuidhof/flipper/leader"
+	"github.com/gzuidhof/flipper/maintenance"
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+)
+
+// The labels of the floating IP that hold the lease.
+const (
+	leaseHolderLabel = "flipper_leader"
+	leaseUntilLabel  = "flipper_leader_until"
+)
+
+// leaseSettleDelay is how long to wait before checking that a newly acquired lease was not overwritten.
+const leaseSettleDelay = 2 * time.Second
+
+var _ leader.Backend = (*LeaseBackend)(nil)
+
+// LeaseBackend keeps the leader lease in the labels of a floating IP.
+//
+// Hetzner can not update labels conditionally, so two instances that acquire a free lease at the same time
+// could both succeed. To detect that, a newly acquired lease is read back after a short delay: the instance
+// whose write came last keeps it.
+//
+// The labels are updated as a whole, which could undo a concurrent change of the other labels. The floating IP
+// must therefore not be one that a group manages; the lease is not acquired if a group selects it.
+type LeaseBackend struct {
+	hc           *hcloud.Client
+	floatingIPID int64
+	groups       []groupSelector
+}
+
+// groupSelector is the floating IP label selector of a group.
+type groupSelector struct {
+	id       string
+	selector maintenance.Selector
+}
+
+// NewLeaseBackend creates a lease backend that uses the labels of the configured floating IP. The floating IP
+// selectors of the groups are used to check that none of them manages it.
+func NewLeaseBackend(cfg cfgmodel.LeaseHetznerConfig, groups []cfgmodel.GroupConfig) *LeaseBackend {
+	b := &LeaseBackend{
+		hc:           hcloud.NewClient(hcloud.WithToken(cfg.APIToken)),
+		floatingIPID: cfg.FloatingIPID,
+	}
+	for _, group := range groups {
+		if group.Provider != "hetzner" {
+			continue
+		}
+		selector, err := maintenance.ParseSelector(group.Hetzner.FloatingIPs.LabelSelector)
+		if err != nil {
+			// Hetzner rejects the selector when the group polls, so it can not select the floating IP either.
+			continue
+		}
+		b.groups = append(b.groups, groupSelector{id: group.ID, selector: selector})
+	}
+	return b
+}
+
+func (b *LeaseBackend) get(ctx context.Context) (*hcloud.FloatingIP, leader.Lease, error) {
+	flip, _, err := b.hc.FloatingIP.GetByID(ctx, b.floatingIPID)
+	if err != nil {
+		return nil, leader.Lease{}, fmt.Errorf("failed to get floating IP: %w", err)
+	}
+	if flip == nil {
+		return nil, leader.Lease{}, fmt.Errorf("floating IP %d not found", b.floatingIPID)
+	}
+	for _, group := range b.groups {
+		if group.selector.Matches(flip.Labels) {
+			return nil, leader.Lease{}, fmt.Errorf(
+				"floating IP %d holds the leader lease but is managed by group %s", b.floatingIPID, group.id,
+			)
+		}
+	}
+
+	lease := leader.Lease{Holder: flip.Labels[leaseHolderLabel]}
+	if until, err := strconv.ParseInt(flip.Labels[leaseUntilLabel], 10, 64); err == nil {
+		lease.Until = time.Unix(until, 0)
+	}
+	return flip, lease, nil
+}
+
+func (b *LeaseBackend) set(ctx context.Context, flip *hcloud.FloatingIP, lease leader.Lease) error {
+	// The labels are replaced as a whole, so the other labels are kept as they are.
+	labels := maps.Clone(flip.Labels)
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	if lease.Holder == "" {
+		delete(labels, leaseHolderLabel)
+		delete(labels, leaseUntilLabel)
+	} else {
+		labels[leaseHolderLabel] = lease.Holder
+		labels[leaseUntilLabel] = strconv.FormatInt(lease.Until.Unix(), 10)
+	}
+
+	if _, _, err := b.hc.FloatingIP.Update(ctx, flip, hcloud.FloatingIPUpdateOpts{Labels: labels}); err != nil {
+		return fmt.Errorf("failed to update floating IP labels: %w", err)
+	}
+	return nil
+}
+
+// Acquire acquires the lease for the given identity if it is free or already held by it.
+func (b *LeaseBackend) Acquire(
+	ctx context.Context,
+	identity string,
+	now time.Time,
+	duration time.Duration,
+) (leader.Lease, error) {
+	flip, current, err := b.get(ctx)
+	if err != nil {
+		return leader.Lease{}, err
+	}
+
+	renew := current.HeldBy(identity, now)
+	if !renew && !current.Free(now) {
+		return current, nil
+	}
+
+	// Labels only hold whole seconds, the lease is rounded down so that it does not last longer than intended.
+	next := leader.Lease{Holder: identity, Until: now.Add(duration).Truncate(time.Second)}
+	if err := b.set(ctx, flip, next); err != nil {
+		return leader.Lease{}, err
+	}
+	if renew {
+		return next, nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return leader.Lease{}, fmt.Errorf("failed to confirm lease: %w", ctx.Err())
+	case <-time.After(leaseSettleDelay):
+	}
+	_, confirmed, err := b.get(ctx)
+	if err != nil {
+		return leader.Lease{}, err
+	}
+	return confirmed, nil
+}
+
+// Release gives up the lease if it is held by the given identity.
+func (b *LeaseBackend) Release(ctx context.Context, identity string) error {
+	flip, current, err := b.get(ctx)
+	if err != nil {
+		return err
+	}
+	if current.Holder != identity {
+		return nil
+	}
+	return b.set(ctx, flip, leader.Lease{})
+}
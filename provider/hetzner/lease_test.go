@@ -0,0 +1,74 @@
+package hetzner
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLeaseBackendManagedFloatingIP(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	group := func(id, selector string) cfgmodel.GroupConfig {
+		return cfgmodel.GroupConfig{
+			ID:       id,
+			Provider: "hetzner",
+			Hetzner:  cfgmodel.HetznerProviderConfig{FloatingIPs: cfgmodel.HetznerSelector{LabelSelector: selector}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		groups  []cfgmodel.GroupConfig
+		wantErr string
+	}{
+		{"not selected", []cfgmodel.GroupConfig{group("lb", "role=lb")}, ""},
+		{"selected", []cfgmodel.GroupConfig{group("lb", "role=lb"), group("all", "env=prod")}, "managed by group all"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			labels := map[string]string{
+				"env":            "prod",
+				leaseHolderLabel: "a",
+				leaseUntilLabel:  strconv.FormatInt(now.Add(time.Minute).Unix(), 10),
+			}
+			var updates int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodPut {
+					updates++
+				}
+				w.Header().Set("Content-Type", "application/json")
+				require.NoError(t, json.NewEncoder(w).Encode(schema.FloatingIPGetResponse{
+					FloatingIP: schema.FloatingIP{ID: 1, Type: "ipv4", Labels: labels},
+				}))
+			}))
+			t.Cleanup(server.Close)
+
+			b := NewLeaseBackend(cfgmodel.LeaseHetznerConfig{FloatingIPID: 1}, tt.groups)
+			b.hc = hcloud.NewClient(hcloud.WithEndpoint(server.URL), hcloud.WithToken("token"))
+
+			// Renewing a held lease does not wait for it to settle.
+			_, err := b.Acquire(context.Background(), "a", now, time.Minute)
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				assert.Zero(t, updates)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, 1, updates)
+		})
+	}
+}
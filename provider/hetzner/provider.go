@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/netip"
+	"time"
 
 	"github.com/gzuidhof/flipper/config/cfgmodel"
 	"github.com/gzuidhof/flipper/resource"
@@ -21,6 +22,9 @@ type Provider struct {
 
 	// locations is a map from location name (e.g. "nbg1") to the location object.
 	locations map[string]*hcloud.Location
+
+	// actions is the most recent action that was seen when watching the actions.
+	actions *actionCursor
 }
 
 func hetznerIDToResourceID(hetznerID int64) string {
@@ -50,6 +54,7 @@ func NewProvider(ctx context.Context, cfg cfgmodel.GroupConfig) (*Provider, erro
 		hc:        hc,
 		cfg:       cfg,
 		locations: locationMap,
+		actions:   &actionCursor{},
 	}, nil
 }
 
@@ -105,6 +110,8 @@ func (c Provider) Poll(ctx context.Context) (resource.Group, error) {
 				IP:             ipParsed,
 				CurrentTarget:  currentTarget,
 				ResourceIndex:  resourceIndexFromLabel(flip.Labels),
+				PairKey:        pairKeyFromLabel(flip.Labels, c.cfg.Pairing.Label),
+				Pin:            pinFromLabels(flip.Labels),
 				URL:            url,
 			})
 		}
@@ -127,15 +134,19 @@ func (c Provider) Poll(ctx context.Context) (resource.Group, error) {
 				c.cfg.Hetzner.ProjectID, srv.ID)
 
 			servers = append(servers, resource.Server{
-				Provider:      c.Name(),
-				HetznerID:     srv.ID,
-				ServerName:    srv.Name,
-				Location:      srv.Datacenter.Location.Name,
-				NetworkZone:   string(srv.Datacenter.Location.NetworkZone),
-				PublicIPv4:    ipv4Target,
-				PublicIPv6:    ipv6Target,
-				ResourceIndex: resourceIndexFromLabel(srv.Labels),
-				URL:           url,
+				Provider:       c.Name(),
+				HetznerID:      srv.ID,
+				ServerName:     srv.Name,
+				Location:       srv.Datacenter.Location.Name,
+				NetworkZone:    string(srv.Datacenter.Location.NetworkZone),
+				PublicIPv4:     ipv4Target,
+				PublicIPv6:     ipv6Target,
+				ResourceIndex:  resourceIndexFromLabel(srv.Labels),
+				Weight:         intFromLabel(srv.Labels, "weight", 1),
+				MaxFloatingIPs: intFromLabel(srv.Labels, "max_floating_ips", 0),
+				Priority:       intFromLabel(srv.Labels, "priority", 0),
+				URL:            url,
+				Labels:         srv.Labels,
 			})
 		}
 		return nil
@@ -177,3 +188,53 @@ func (c Provider) AssignFloatingIP(ctx context.Context, flip resource.FloatingIP
 
 	return nil
 }
+
+// LastAssignment returns the last assignment of a floating IP since a point in time, from the floating IP actions
+// of the project. The actions are paged through newest first, until a match is found or the actions started before
+// since. With the zero since only the first page is searched. Hetzner does not expose who performed an action, so
+// only the time is known.
+func (c Provider) LastAssignment(
+	ctx context.Context,
+	flip resource.FloatingIP,
+	since time.Time,
+) (resource.Assignment, error) {
+	opts := hcloud.ActionListOpts{
+		ListOpts: hcloud.ListOpts{Page: 1, PerPage: 50},
+		Sort:     []string{"id:desc"},
+	}
+	for {
+		actions, resp, err := c.hc.FloatingIP.Action.List(ctx, opts)
+		if err != nil {
+			return resource.Assignment{}, fmt.Errorf("failed to list floating IP actions in hetzner: %w", err)
+		}
+
+		for _, action := range actions {
+			if action.Started.Before(since) {
+				return resource.Assignment{}, fmt.Errorf("no assignment of floating IP %s since %s",
+					flip.ID(), since.UTC().Format(time.RFC3339),
+				)
+			}
+			if isAssignment(action, flip) {
+				return resource.Assignment{At: action.Started}, nil
+			}
+		}
+
+		if since.IsZero() || resp.Meta.Pagination == nil || resp.Meta.Pagination.NextPage == 0 {
+			return resource.Assignment{}, fmt.Errorf("no recent assignment found for floating IP %s", flip.ID())
+		}
+		opts.Page = resp.Meta.Pagination.NextPage
+	}
+}
+
+// isAssignment returns true if the action (un)assigned the floating IP.
+func isAssignment(action *hcloud.Action, flip resource.FloatingIP) bool {
+	if action.Command != "assign_floating_ip" && action.Command != "unassign_floating_ip" {
+		return false
+	}
+	for _, res := range action.Resources {
+		if res.Type == hcloud.ActionResourceTypeFloatingIP && res.ID == flip.HetznerID {
+			return true
+		}
+	}
+	return false
+}
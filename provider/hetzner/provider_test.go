@@ -0,0 +1,95 @@
+package hetzner
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gzuidhof/flipper/resource"
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// actionsServer serves the floating IP actions in pages of two, newest first, and counts the requested pages.
+func actionsServer(t *testing.T, actions []schema.Action, pages *int) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*pages++
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		start, end := (page-1)*2, min(page*2, len(actions))
+		resp := schema.ActionListResponse{Actions: actions[start:end]}
+
+		pagination := schema.MetaPagination{Page: page, PerPage: 2, LastPage: (len(actions) + 1) / 2}
+		if end < len(actions) {
+			pagination.NextPage = page + 1
+		}
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(struct {
+			schema.ActionListResponse
+			Meta schema.Meta `json:"meta"`
+		}{resp, schema.Meta{Pagination: &pagination}}))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestLastAssignment(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	action := func(id int64, command string, flipID int64, started time.Time) schema.Action {
+		return schema.Action{
+			ID:        id,
+			Command:   command,
+			Status:    "success",
+			Started:   started,
+			Resources: []schema.ActionResourceReference{{ID: flipID, Type: "floating_ip"}},
+		}
+	}
+	actions := []schema.Action{
+		action(5, "assign_floating_ip", 12, now.Add(-1*time.Minute)),
+		action(4, "update_floating_ip", 11, now.Add(-2*time.Minute)),
+		action(3, "change_protection", 11, now.Add(-3*time.Minute)),
+		action(2, "assign_floating_ip", 11, now.Add(-4*time.Minute)),
+		action(1, "assign_floating_ip", 13, now.Add(-5*time.Minute)),
+	}
+
+	tests := []struct {
+		name      string
+		flipID    int64
+		since     time.Time
+		wantAt    time.Time
+		wantErr   bool
+		wantPages int
+	}{
+		{"found on a later page", 11, now.Add(-time.Hour), now.Add(-4 * time.Minute), false, 2},
+		{"older than since", 11, now.Add(-150 * time.Second), time.Time{}, true, 2},
+		{"no more pages", 14, now.Add(-time.Hour), time.Time{}, true, 3},
+		{"only the first page without since", 11, time.Time{}, time.Time{}, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var pages int
+			server := actionsServer(t, actions, &pages)
+			p := Provider{hc: hcloud.NewClient(hcloud.WithEndpoint(server.URL), hcloud.WithToken("token"))}
+
+			assignment, err := p.LastAssignment(context.Background(), resource.FloatingIP{HetznerID: tt.flipID}, tt.since)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantAt, assignment.At.UTC())
+			}
+			assert.Equal(t, tt.wantPages, pages)
+		})
+	}
+}
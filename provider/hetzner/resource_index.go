@@ -1,6 +1,11 @@
 package hetzner
 
-import "strconv"
+import (
+	"strconv"
+	"time"
+
+	"github.com/gzuidhof/flipper/resource"
+)
 
 func resourceIndexFromLabel(labels map[string]string) int {
 	if labels == nil {
@@ -17,3 +22,41 @@ func resourceIndexFromLabel(labels map[string]string) int {
 
 	return -1
 }
+
+// intFromLabel returns the integer value of a label, or the fallback if the label is not set or not a valid integer.
+func intFromLabel(labels map[string]string, key string, fallback int) int {
+	value, ok := labels[key]
+	if !ok {
+		return fallback
+	}
+	v, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return fallback
+	}
+	return int(v)
+}
+
+// pairKeyFromLabel returns the pair key of a floating IP, which is the value of the given label.
+// It returns an empty string if pairing by label is disabled (i.e. the key is empty) or the label is not set.
+func pairKeyFromLabel(labels map[string]string, key string) string {
+	if key == "" {
+		return ""
+	}
+	return labels[key]
+}
+
+// pinFromLabels returns the pin of a floating IP from its labels: `pin` is the name of the server, `pin_until` the
+// unix timestamp the pin expires at (optional) and `pin_force` set to "true" keeps the pin even if the server is
+// unhealthy. It returns the zero pin if the floating IP is not pinned.
+func pinFromLabels(labels map[string]string) resource.Pin {
+	serverName, ok := labels["pin"]
+	if !ok || serverName == "" {
+		return resource.Pin{}
+	}
+
+	pin := resource.Pin{ServerName: serverName, Force: labels["pin_force"] == "true"}
+	if until := intFromLabel(labels, "pin_until", 0); until > 0 {
+		pin.Until = time.Unix(int64(until), 0)
+	}
+	return pin
+}
@@ -2,6 +2,7 @@ package mock
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/gzuidhof/flipper/resource"
@@ -11,6 +12,8 @@ var _ resource.Provider = (*Provider)(nil)
 
 // Provider is a mock provider for testing.
 type Provider struct {
+	mu sync.Mutex
+
 	FloatingIPs []resource.FloatingIP
 	Servers     []resource.Server
 
@@ -19,6 +22,9 @@ type Provider struct {
 
 	PollError             error
 	AssignFloatingIPError error
+
+	// AssignFloatingIPErrorFunc is called for every assignment if set, an error it returns fails the assignment.
+	AssignFloatingIPErrorFunc func(fip resource.FloatingIP, server resource.Server) error
 }
 
 // NewProvider creates a new mock provider.
@@ -43,6 +49,9 @@ func (p *Provider) Poll(_ context.Context) (resource.Group, error) {
 		return resource.Group{}, p.PollError
 	}
 
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	result := resource.Group{}
 
 	result.FloatingIPs = append(result.FloatingIPs, p.FloatingIPs...)
@@ -60,6 +69,15 @@ func (p *Provider) AssignFloatingIP(_ context.Context, fip resource.FloatingIP,
 		return p.AssignFloatingIPError
 	}
 
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.AssignFloatingIPErrorFunc != nil {
+		if err := p.AssignFloatingIPErrorFunc(fip, server); err != nil {
+			return err
+		}
+	}
+
 	for i, f := range p.FloatingIPs {
 		if f.ID() == fip.ID() {
 			p.FloatingIPs[i].CurrentTarget = server.ID()
@@ -0,0 +1,39 @@
+package resource
+
+import (
+	"context"
+	"time"
+)
+
+// Drift is a change of the target of a floating IP that was not made by flipper, e.g. a manual change in the
+// cloud provider's web interface.
+type Drift struct {
+	FloatingIP FloatingIP
+
+	// From is the ID of the server the floating IP targeted before, empty if it was unassigned.
+	From string
+	// To is the ID of the server the floating IP targets now, empty if it is unassigned.
+	To string
+
+	// DetectedAt is when flipper noticed the change.
+	DetectedAt time.Time
+
+	// Assignment is the change according to the provider, nil if the provider can't tell.
+	Assignment *Assignment
+}
+
+// Assignment describes when and by whom a floating IP was last (re)assigned.
+type Assignment struct {
+	// At is when the assignment started.
+	At time.Time
+	// By is who made the assignment, empty if the provider doesn't expose it.
+	By string
+}
+
+// AssignmentHistoryProvider is implemented by providers that can tell when (and possibly by whom) a floating IP
+// was last assigned.
+type AssignmentHistoryProvider interface {
+	// LastAssignment returns the last assignment of the floating IP that started after since. It returns an error if
+	// there is none, the zero since looks at the recent assignments only.
+	LastAssignment(ctx context.Context, flip FloatingIP, since time.Time) (Assignment, error)
+}
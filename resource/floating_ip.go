@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/netip"
 	"sort"
+	"time"
 )
 
 // FloatingIPs is a list of floating IPs.
@@ -44,6 +45,13 @@ type FloatingIP struct {
 	// This should be `-1` if unknown (or invalid).
 	ResourceIndex int
 
+	// PairKey identifies the IPv4/IPv6 pair the floating IP belongs to, empty if it is not explicitly paired.
+	// Floating IPs with the same pair key are kept on the same server. It is generally set from a label.
+	PairKey string
+
+	// Pin is the pin set on the floating IP itself (e.g. through labels), the zero pin if it is not pinned.
+	Pin Pin
+
 	// URL is the URL of the floating IP in the cloud provider's web interface.
 	URL string
 }
@@ -53,6 +61,11 @@ func (f FloatingIP) ID() string {
 	return fmt.Sprint(f.HetznerID)
 }
 
+// Family returns the IP family of the floating IP.
+func (f FloatingIP) Family() IPFamily {
+	return IPFamilyOf(f.IP)
+}
+
 // Name returns the name of the floating IP.
 func (f FloatingIP) Name() string {
 	return f.FloatingIPName
@@ -72,14 +85,33 @@ func (f FloatingIP) Equal(other Resource) bool {
 		f.NetworkZone == otherFloatingIP.NetworkZone &&
 		f.IP == otherFloatingIP.IP &&
 		f.ResourceIndex == otherFloatingIP.ResourceIndex &&
+		f.PairKey == otherFloatingIP.PairKey &&
+		f.Pin.Equal(otherFloatingIP.Pin) &&
 		f.CurrentTarget == otherFloatingIP.CurrentTarget
 }
 
 // String returns a string representation of the floating IP.
 func (f FloatingIP) String() string {
 	//nolint:lll // Splitting this line would make it less readable.
-	return fmt.Sprintf("FloatingIP{Provider: %s, ID: %s, Name: %s, Location: %s, NetworkZone: %s, IP: %s, CurrentTarget: %s, ResourceIndex: %d}",
-		f.Provider, f.ID(), f.FloatingIPName, f.Location, f.NetworkZone, f.IP.String(), f.CurrentTarget, f.ResourceIndex)
+	return fmt.Sprintf("FloatingIP{Provider: %s, ID: %s, Name: %s, Location: %s, NetworkZone: %s, IP: %s, CurrentTarget: %s, ResourceIndex: %d, PairKey: %s}",
+		f.Provider, f.ID(), f.FloatingIPName, f.Location, f.NetworkZone, f.IP.String(), f.CurrentTarget, f.ResourceIndex, f.PairKey)
+}
+
+// Pin asks to keep a floating IP on a specific server regardless of the plan.
+type Pin struct {
+	// ServerName is the name of the server the floating IP is pinned to.
+	ServerName string
+
+	// Until is when the pin expires, the zero time means it doesn't expire.
+	Until time.Time
+
+	// Force keeps the floating IP on the server even if the server is unhealthy.
+	Force bool
+}
+
+// Equal returns true if the two pins are equal.
+func (p Pin) Equal(other Pin) bool {
+	return p.ServerName == other.ServerName && p.Until.Equal(other.Until) && p.Force == other.Force
 }
 
 // SortByName sorts the floating IPs by name.
@@ -0,0 +1,38 @@
+package resource
+
+import "net/netip"
+
+// IPFamily is the IP address family (IPv4 or IPv6) of an address.
+type IPFamily string
+
+const (
+	// IPFamilyIPv4 is the IPv4 address family.
+	IPFamilyIPv4 IPFamily = "ipv4"
+	// IPFamilyIPv6 is the IPv6 address family.
+	IPFamilyIPv6 IPFamily = "ipv6"
+	// IPFamilyUnknown is used for addresses that are not valid (e.g. not set).
+	IPFamilyUnknown IPFamily = ""
+)
+
+// IPFamilies is the list of all known IP families.
+//
+//nolint:gochecknoglobals // Static list.
+var IPFamilies = []IPFamily{IPFamilyIPv4, IPFamilyIPv6}
+
+// String returns the string representation of the IP family.
+func (f IPFamily) String() string {
+	return string(f)
+}
+
+// IPFamilyOf returns the IP family of the given address.
+// It returns IPFamilyUnknown if the address is not valid.
+func IPFamilyOf(addr netip.Addr) IPFamily {
+	switch {
+	case !addr.IsValid():
+		return IPFamilyUnknown
+	case addr.Is4() || addr.Is4In6():
+		return IPFamilyIPv4
+	default:
+		return IPFamilyIPv6
+	}
+}
@@ -23,3 +23,18 @@ func NewFloatingIPPair(v4, v6 FloatingIP) (FloatingIPPair, error) {
 
 	return FloatingIPPair{v4: v4, v6: v6}, nil
 }
+
+// V4 returns the IPv4 floating IP of the pair.
+func (p FloatingIPPair) V4() FloatingIP {
+	return p.v4
+}
+
+// V6 returns the IPv6 floating IP of the pair.
+func (p FloatingIPPair) V6() FloatingIP {
+	return p.v6
+}
+
+// FloatingIPs returns both floating IPs of the pair, IPv4 first.
+func (p FloatingIPPair) FloatingIPs() []FloatingIP {
+	return []FloatingIP{p.v4, p.v6}
+}
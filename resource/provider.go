@@ -1,6 +1,9 @@
 package resource
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // ProviderName is the name of a cloud provider.
 type ProviderName string
@@ -24,3 +27,14 @@ type Provider interface {
 	// AssignFloatingIP targets a floating IP at a server.
 	AssignFloatingIP(ctx context.Context, flip FloatingIP, srv Server) error
 }
+
+// ChangeWatcher is implemented by providers that can tell cheaply whether the resources may have changed, e.g.
+// from their log of actions, so that changes are picked up between polls.
+type ChangeWatcher interface {
+	// WatchInterval returns how often to check for changes, zero if watching is disabled.
+	WatchInterval() time.Duration
+
+	// Changed returns true if the resources may have changed since the last call. The first call only remembers
+	// where to start from.
+	Changed(ctx context.Context) (bool, error)
+}
@@ -2,6 +2,7 @@ package resource
 
 import (
 	"fmt"
+	"maps"
 	"net/netip"
 	"sort"
 )
@@ -38,6 +39,16 @@ type Server struct {
 	// This should be `-1` if unknown (or invalid).
 	ResourceIndex int
 
+	// Weight is the relative capacity of the server, floating IPs are distributed in proportion to it.
+	// A weight of zero (or lower) is treated as the default weight of 1.
+	Weight int
+
+	// MaxFloatingIPs is the maximum number of floating IPs that may target the server. Zero means unlimited.
+	MaxFloatingIPs int
+
+	// Priority of the server for the priority strategy, servers with a higher priority are preferred.
+	Priority int
+
 	// PublicIPv4 is the public IPv4 address of the server.
 	PublicIPv4 netip.Addr
 
@@ -46,6 +57,9 @@ type Server struct {
 
 	// URL is the URL to the server in the Cloud Provider's console.
 	URL string
+
+	// Labels are the labels of the server, e.g. to select the servers of a maintenance window.
+	Labels map[string]string
 }
 
 // ID returns the unique identifier of the server.
@@ -71,15 +85,27 @@ func (s Server) Equal(other Resource) bool {
 		s.Location == otherServer.Location &&
 		s.NetworkZone == otherServer.NetworkZone &&
 		s.ResourceIndex == otherServer.ResourceIndex &&
+		s.Weight == otherServer.Weight &&
+		s.MaxFloatingIPs == otherServer.MaxFloatingIPs &&
+		s.Priority == otherServer.Priority &&
 		s.PublicIPv4 == otherServer.PublicIPv4 &&
-		s.PublicIPv6 == otherServer.PublicIPv6
+		s.PublicIPv6 == otherServer.PublicIPv6 &&
+		maps.Equal(s.Labels, otherServer.Labels)
 }
 
 // String returns a string representation of the server.
 func (s Server) String() string {
 	//nolint:lll // Splitting it doesn't make it more readable.
-	return fmt.Sprintf("Server{Provider: %s, ID: %s, Name: %s, Location: %s, NetworkZone: %s, ResourceIndex: %d, IPv4: %s, IPv6: %s}",
-		s.Provider, s.ID(), s.ServerName, s.Location, s.NetworkZone, s.ResourceIndex, s.PublicIPv4, s.PublicIPv6)
+	return fmt.Sprintf("Server{Provider: %s, ID: %s, Name: %s, Location: %s, NetworkZone: %s, ResourceIndex: %d, Weight: %d, MaxFloatingIPs: %d, Priority: %d, IPv4: %s, IPv6: %s}",
+		s.Provider, s.ID(), s.ServerName, s.Location, s.NetworkZone, s.ResourceIndex, s.Weight, s.MaxFloatingIPs, s.Priority, s.PublicIPv4, s.PublicIPv6)
+}
+
+// WeightOrDefault returns the weight of the server, or the default weight of 1 if not set.
+func (s Server) WeightOrDefault() int {
+	if s.Weight <= 0 {
+		return 1
+	}
+	return s.Weight
 }
 
 // SortByName sorts the servers by name.
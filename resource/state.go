@@ -12,7 +12,56 @@ type State struct {
 	LastUpdated time.Time
 
 	// Status is the status code of the resource, e.g. healthy, unhealthy, unknown.
+	// For servers this is the worst status of its IP families.
 	Status Status
+
+	// IPv4 is the status of the resource when reached over IPv4.
+	// If empty, the overall Status applies.
+	IPv4 Status
+
+	// IPv6 is the status of the resource when reached over IPv6.
+	// If empty, the overall Status applies.
+	IPv6 Status
+
+	// Flapping is true if the resource keeps flipping between healthy and unhealthy. A flapping resource is held
+	// down: it is not targeted even if it is healthy.
+	Flapping bool
+
+	// HoldDownUntil is the time until which a flapping resource is expected to be held down.
+	HoldDownUntil time.Time
+}
+
+// StatusFor returns the status of the resource for the given IP family.
+// It falls back to the overall status if the status for the IP family is not known separately.
+func (s State) StatusFor(family IPFamily) Status {
+	var status Status
+	switch family {
+	case IPFamilyIPv4:
+		status = s.IPv4
+	case IPFamilyIPv6:
+		status = s.IPv6
+	case IPFamilyUnknown:
+	}
+
+	if status == "" {
+		return s.Status
+	}
+	return status
+}
+
+// DegradedFamilies returns the IP families for which the resource is unhealthy, but only if it is
+// not unhealthy for all of them. In other words, it is only non-empty when the resource is partially unhealthy.
+func (s State) DegradedFamilies() []IPFamily {
+	var unhealthy []IPFamily
+	for _, family := range IPFamilies {
+		if s.StatusFor(family) == StatusUnhealthy {
+			unhealthy = append(unhealthy, family)
+		}
+	}
+	if len(unhealthy) == len(IPFamilies) {
+		return nil
+	}
+	return unhealthy
 }
 
 // Status is the status code of a resource.
@@ -84,6 +133,21 @@ func (s *WithStatus[R]) IsHealthy() bool {
 	return s.Status() == StatusHealthy
 }
 
+// IsUnhealthyFor returns true if the resource is known to be unhealthy for the given IP family.
+func (s *WithStatus[R]) IsUnhealthyFor(family IPFamily) bool {
+	return s.State().StatusFor(family) == StatusUnhealthy
+}
+
+// IsHealthyFor returns true if the resource is known to be healthy for the given IP family.
+func (s *WithStatus[R]) IsHealthyFor(family IPFamily) bool {
+	return s.State().StatusFor(family) == StatusHealthy
+}
+
+// IsFlapping returns true if the resource is held down because it is flapping.
+func (s *WithStatus[R]) IsFlapping() bool {
+	return s.State().Flapping
+}
+
 // SortByName sorts the resources by name.
 func (servs WithStatusSlice[R]) SortByName() {
 	sort.Slice(servs, func(i, j int) bool {
@@ -1,8 +1,11 @@
 package server
 
 import (
+	"errors"
 	"log/slog"
 	"net/http"
+
+	"github.com/gzuidhof/flipper/monitor"
 )
 
 func (s *Server) writeInternalError(w http.ResponseWriter, err error) {
@@ -14,3 +17,35 @@ func (s *Server) writeInternalError(w http.ResponseWriter, err error) {
 
 	_, _ = w.Write([]byte("internal server error: " + err.Error()))
 }
+
+// writeMonitorError writes an error returned by the monitor, using a 404 status for resources that don't exist and
+// 4xx statuses for other errors caused by the request.
+func (s *Server) writeMonitorError(w http.ResponseWriter, err error) {
+	if errors.Is(err, monitor.ErrInvalidApprovalToken) {
+		w.Header().Set("Content-Type", "plain/text")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+	if errors.Is(err, monitor.ErrPlanStale) || errors.Is(err, monitor.ErrMaintenanceWindowInConfig) {
+		w.Header().Set("Content-Type", "plain/text")
+		w.WriteHeader(http.StatusConflict)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+	if errors.Is(err, monitor.ErrGroupNotFound) || errors.Is(err, monitor.ErrServerNotFound) ||
+		errors.Is(err, monitor.ErrFloatingIPNotFound) || errors.Is(err, monitor.ErrPlanNotFound) ||
+		errors.Is(err, monitor.ErrConsensusDisabled) || errors.Is(err, monitor.ErrMaintenanceWindowNotFound) {
+		w.Header().Set("Content-Type", "plain/text")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+	s.writeInternalError(w, err)
+}
+
+func (s *Server) writeBadRequest(w http.ResponseWriter, message string) {
+	w.Header().Set("Content-Type", "plain/text")
+	w.WriteHeader(http.StatusBadRequest)
+	_, _ = w.Write([]byte(message))
+}
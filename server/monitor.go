@@ -0,0 +1,39 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gzuidhof/flipper/consensus"
+	"github.com/gzuidhof/flipper/event"
+	"github.com/gzuidhof/flipper/maintenance"
+	"github.com/gzuidhof/flipper/monitor"
+	"github.com/gzuidhof/flipper/plan"
+)
+
+var _ Monitor = (*monitor.Monitor)(nil)
+
+// Monitor is what the admin and group endpoints use of the monitor, it is implemented by *monitor.Monitor.
+//
+//nolint:interfacebloat // Every endpoint uses a different part of the monitor.
+type Monitor interface {
+	ResetFlapping(groupID, serverID string) error
+	Failback(groupID string) error
+	OverrideSafety(ctx context.Context, groupID string) (time.Time, error)
+	Resync(groupID string) error
+	ResyncAll()
+
+	Pin(ctx context.Context, groupID, floatingIPID string, pin plan.Pin) error
+	Pins(groupID string) (map[string]plan.Pin, error)
+	SetMaintenance(ctx context.Context, groupID, windowID string, window *maintenance.Window) error
+	Maintenance(groupID string) ([]monitor.MaintenanceStatus, error)
+
+	LastPlan(groupID string) (plan.Plan, bool, error)
+	PendingPlan(groupID string) (monitor.PendingPlan, bool, error)
+	Decide(ctx context.Context, groupID string, planID uuid.UUID, approve bool, token string) error
+
+	RecentEvents() []event.Event
+	Leader() monitor.LeaderStatus
+	ConsensusView() (consensus.View, error)
+}
@@ -17,6 +17,11 @@ func (s *Server) registerRoutes() {
 		_, _ = w.Write([]byte(buildinfo.Version()))
 	})
 
+	if s.monitor != nil {
+		s.registerAdminRoutes()
+		s.registerGroupRoutes()
+	}
+
 	staticHandler := http.FileServerFS(s.staticFS)
 
 	s.mux.Handle("GET /static/{path...}", http.StripPrefix("/static/", staticHandler))
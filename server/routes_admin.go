@@ -0,0 +1,374 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gzuidhof/flipper/maintenance"
+	"github.com/gzuidhof/flipper/plan"
+	"github.com/gzuidhof/flipper/view/template"
+)
+
+// maxPinRequestSize is the maximum size of the body of a pin request.
+const maxPinRequestSize = 4096
+
+// pinRequest is the body of the pin endpoint.
+type pinRequest struct {
+	ServerID string `json:"server_id"`
+	// Duration is how long the floating IP stays pinned, e.g. "2h".
+	Duration string `json:"duration"`
+	Reason   string `json:"reason"`
+	// Force keeps the floating IP on the server even if it is unhealthy.
+	Force bool `json:"force"`
+}
+
+// toPin validates the request and turns it into a pin that expires relative to now.
+func (r pinRequest) toPin(now time.Time) (plan.Pin, error) {
+	if r.ServerID == "" {
+		return plan.Pin{}, errors.New("server_id is required")
+	}
+	duration, err := time.ParseDuration(r.Duration)
+	if err != nil || duration <= 0 {
+		return plan.Pin{}, errors.New("duration must be a positive duration, e.g. \"2h\"")
+	}
+	return plan.Pin{ServerID: r.ServerID, Until: now.Add(duration), Reason: r.Reason, Force: r.Force}, nil
+}
+
+// maxMaintenanceRequestSize is the maximum size of the body of a maintenance window request.
+const maxMaintenanceRequestSize = 4096
+
+// maintenanceRequest is the body of the maintenance window endpoint. A window is either recurring, with a schedule
+// and a duration, or a one-off window from its start (now if not set) to its end or for its duration.
+type maintenanceRequest struct {
+	Mode          string     `json:"mode"`
+	Notifications string     `json:"notifications"`
+	Schedule      string     `json:"schedule"`
+	Start         *time.Time `json:"start"`
+	End           *time.Time `json:"end"`
+	// Duration is how long the window lasts, e.g. "2h".
+	Duration      string   `json:"duration"`
+	Servers       []string `json:"servers"`
+	LabelSelector string   `json:"label_selector"`
+	Reason        string   `json:"reason"`
+}
+
+// toWindow validates the request and turns it into a maintenance window.
+func (r maintenanceRequest) toWindow(id string, now time.Time) (maintenance.Window, error) {
+	window := maintenance.Window{
+		ID:            id,
+		Mode:          maintenance.Mode(r.Mode),
+		Notifications: maintenance.Notifications(r.Notifications),
+		Servers:       r.Servers,
+		Reason:        r.Reason,
+	}
+	if r.Duration != "" {
+		duration, err := time.ParseDuration(r.Duration)
+		if err != nil || duration <= 0 {
+			return maintenance.Window{}, errors.New("duration must be a positive duration, e.g. \"2h\"")
+		}
+		window.Duration = duration
+	}
+	if r.LabelSelector != "" {
+		selector, err := maintenance.ParseSelector(r.LabelSelector)
+		if err != nil {
+			return maintenance.Window{}, err //nolint:wrapcheck // The error describes the selector.
+		}
+		window.LabelSelector = selector
+	}
+
+	if r.Schedule != "" {
+		schedule, err := maintenance.ParseSchedule(r.Schedule)
+		if err != nil {
+			return maintenance.Window{}, err //nolint:wrapcheck // The error describes the schedule.
+		}
+		window.Schedule = schedule
+		if r.Start != nil || r.End != nil {
+			return maintenance.Window{}, errors.New("a recurring maintenance window has no start or end")
+		}
+		return window, window.Validate() //nolint:wrapcheck // The error describes the window.
+	}
+
+	window.Start = now
+	if r.Start != nil {
+		window.Start = *r.Start
+	}
+	switch {
+	case r.End != nil && window.Duration > 0:
+		return maintenance.Window{}, errors.New("a maintenance window has either an end or a duration, not both")
+	case r.End != nil:
+		window.End = *r.End
+	case window.Duration > 0:
+		window.End = window.Start.Add(window.Duration)
+	default:
+		return maintenance.Window{}, errors.New("an end or a duration is required")
+	}
+	window.Duration = 0
+	if !window.End.After(now) {
+		return maintenance.Window{}, errors.New("the maintenance window already ended")
+	}
+	return window, window.Validate() //nolint:wrapcheck // The error describes the window.
+}
+
+// requireAdmin wraps a handler to require the admin token, if one is configured.
+func (s *Server) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.isAdmin(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte("unauthorized"))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// isAdmin returns true if the request has the admin token, or if no admin token is configured.
+func (s *Server) isAdmin(r *http.Request) bool {
+	if s.adminToken == "" {
+		return true
+	}
+	expected := "Bearer " + s.adminToken
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte(expected)) == 1
+}
+
+// registerAdminRoutes registers the endpoints used to perform administrative actions on the monitor.
+func (s *Server) registerAdminRoutes() {
+	s.mux.HandleFunc("POST /v1/groups/{group}/servers/{server}/reset-flapping",
+		s.requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+			groupID, serverID := r.PathValue("group"), r.PathValue("server")
+
+			s.logger.InfoContext(r.Context(), "Admin request to reset flapping.",
+				slog.String("group_id", groupID),
+				slog.String("server_id", serverID),
+			)
+
+			if err := s.monitor.ResetFlapping(groupID, serverID); err != nil {
+				s.writeMonitorError(w, err)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("OK"))
+		}))
+
+	s.mux.HandleFunc("POST /v1/groups/{group}/failback",
+		s.requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+			groupID := r.PathValue("group")
+
+			s.logger.InfoContext(r.Context(), "Admin request to fail back.", slog.String("group_id", groupID))
+
+			if err := s.monitor.Failback(groupID); err != nil {
+				s.writeMonitorError(w, err)
+				return
+			}
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = w.Write([]byte("Accepted"))
+		}))
+
+	s.mux.HandleFunc("POST /v1/resync",
+		s.requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+			s.logger.InfoContext(r.Context(), "Admin request to resync all groups.")
+
+			s.monitor.ResyncAll()
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = w.Write([]byte("Accepted"))
+		}))
+
+	s.mux.HandleFunc("POST /v1/groups/{group}/resync",
+		s.requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+			groupID := r.PathValue("group")
+
+			s.logger.InfoContext(r.Context(), "Admin request to resync.", slog.String("group_id", groupID))
+
+			if err := s.monitor.Resync(groupID); err != nil {
+				s.writeMonitorError(w, err)
+				return
+			}
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = w.Write([]byte("Accepted"))
+		}))
+
+	s.mux.HandleFunc("POST /v1/groups/{group}/override-safety",
+		s.requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+			groupID := r.PathValue("group")
+
+			s.logger.WarnContext(r.Context(), "Admin request to override safety limits.", slog.String("group_id", groupID))
+
+			until, err := s.monitor.OverrideSafety(r.Context(), groupID)
+			if err != nil {
+				s.writeMonitorError(w, err)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("Overridden until " + until.UTC().Format(time.RFC3339)))
+		}))
+
+	s.mux.HandleFunc("PUT /v1/groups/{group}/floating-ips/{floating_ip}/pin",
+		s.requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+			groupID, floatingIPID := r.PathValue("group"), r.PathValue("floating_ip")
+
+			var req pinRequest
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPinRequestSize)).Decode(&req); err != nil {
+				s.writeBadRequest(w, "invalid request body: "+err.Error())
+				return
+			}
+			pin, err := req.toPin(time.Now())
+			if err != nil {
+				s.writeBadRequest(w, err.Error())
+				return
+			}
+
+			s.logger.WarnContext(r.Context(), "Admin request to pin floating IP.",
+				slog.String("group_id", groupID),
+				slog.String("floating_ip_id", floatingIPID),
+				slog.String("server_id", pin.ServerID),
+				slog.Time("until", pin.Until),
+				slog.Bool("force", pin.Force),
+			)
+
+			if err := s.monitor.Pin(r.Context(), groupID, floatingIPID, pin); err != nil {
+				s.writeMonitorError(w, err)
+				return
+			}
+			s.writeJSON(w, pin)
+		}))
+
+	s.mux.HandleFunc("DELETE /v1/groups/{group}/floating-ips/{floating_ip}/pin",
+		s.requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+			groupID, floatingIPID := r.PathValue("group"), r.PathValue("floating_ip")
+
+			s.logger.InfoContext(r.Context(), "Admin request to unpin floating IP.",
+				slog.String("group_id", groupID),
+				slog.String("floating_ip_id", floatingIPID),
+			)
+
+			if err := s.monitor.Pin(r.Context(), groupID, floatingIPID, plan.Pin{}); err != nil {
+				s.writeMonitorError(w, err)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("OK"))
+		}))
+
+	s.mux.HandleFunc("PUT /v1/groups/{group}/maintenance/{window}",
+		s.requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+			groupID, windowID := r.PathValue("group"), r.PathValue("window")
+
+			var req maintenanceRequest
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxMaintenanceRequestSize)).Decode(&req); err != nil {
+				s.writeBadRequest(w, "invalid request body: "+err.Error())
+				return
+			}
+			window, err := req.toWindow(windowID, time.Now())
+			if err != nil {
+				s.writeBadRequest(w, err.Error())
+				return
+			}
+
+			s.logger.WarnContext(r.Context(), "Admin request to set maintenance window.",
+				slog.String("group_id", groupID),
+				slog.String("window_id", windowID),
+				slog.String("mode", string(window.ModeOrDefault())),
+			)
+
+			if err := s.monitor.SetMaintenance(r.Context(), groupID, windowID, &window); err != nil {
+				s.writeMonitorError(w, err)
+				return
+			}
+			s.writeJSON(w, window)
+		}))
+
+	s.mux.HandleFunc("DELETE /v1/groups/{group}/maintenance/{window}",
+		s.requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+			groupID, windowID := r.PathValue("group"), r.PathValue("window")
+
+			s.logger.InfoContext(r.Context(), "Admin request to remove maintenance window.",
+				slog.String("group_id", groupID),
+				slog.String("window_id", windowID),
+			)
+
+			if err := s.monitor.SetMaintenance(r.Context(), groupID, windowID, nil); err != nil {
+				s.writeMonitorError(w, err)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("OK"))
+		}))
+
+	// Plans are approved or rejected with the admin token, or with the token of the plan from the links in its
+	// notification. Chat tools fetch the links to preview them, so opening a link only shows a page that asks to
+	// confirm the decision, which is then posted.
+	for _, decision := range []string{"approve", "reject"} {
+		path := "/v1/groups/{group}/plans/{plan}/" + decision
+		s.mux.HandleFunc("POST "+path, s.handleDecision(decision == "approve"))
+		s.mux.HandleFunc("GET "+path, s.handleDecisionPage(decision == "approve"))
+	}
+}
+
+// maxDecisionRequestSize is the maximum size of the body of a decision request.
+const maxDecisionRequestSize = 4096
+
+// handleDecisionPage returns a handler that renders the page to confirm the approval or rejection of a plan with
+// the token of the plan. It does not decide anything itself.
+func (s *Server) handleDecisionPage(approve bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("token")
+		if token == "" {
+			s.writeBadRequest(w, "token is required")
+			return
+		}
+
+		err := template.RenderTemplateHTML(s.template.DecisionPage(), w, template.DecisionPageData{
+			GroupID: r.PathValue("group"),
+			PlanID:  r.PathValue("plan"),
+			Approve: approve,
+			Action:  r.URL.Path,
+			Token:   token,
+		})
+		if err != nil {
+			s.writeInternalError(w, err)
+		}
+	}
+}
+
+// handleDecision returns a handler that approves or rejects a plan that waits for approval. The token of the plan
+// is taken from the form, or from the query.
+func (s *Server) handleDecision(approve bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID := r.PathValue("group")
+		r.Body = http.MaxBytesReader(w, r.Body, maxDecisionRequestSize)
+		token := r.FormValue("token")
+		if token == "" && !s.isAdmin(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte("unauthorized"))
+			return
+		}
+
+		planID, err := uuid.Parse(r.PathValue("plan"))
+		if err != nil {
+			s.writeBadRequest(w, "invalid plan ID: "+err.Error())
+			return
+		}
+
+		s.logger.WarnContext(r.Context(), "Request to decide on plan.",
+			slog.String("group_id", groupID),
+			slog.String("plan_id", planID.String()),
+			slog.Bool("approve", approve),
+			slog.Bool("with_token", token != ""),
+		)
+
+		if err := s.monitor.Decide(r.Context(), groupID, planID, approve, token); err != nil {
+			s.writeMonitorError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		if approve {
+			_, _ = w.Write([]byte("Approved"))
+			return
+		}
+		_, _ = w.Write([]byte("Rejected"))
+	}
+}
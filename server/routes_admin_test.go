@@ -0,0 +1,191 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gzuidhof/flipper/monitor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// decision is a decision on a plan that reached the monitor.
+type decision struct {
+	groupID string
+	planID  uuid.UUID
+	approve bool
+	token   string
+}
+
+// fakeMonitor records the decisions on plans, the other methods panic.
+type fakeMonitor struct {
+	Monitor
+
+	// token is the token of the pending plan.
+	token     string
+	decisions []decision
+	failbacks []string
+}
+
+func (m *fakeMonitor) Decide(_ context.Context, groupID string, planID uuid.UUID, approve bool, token string) error {
+	if token != "" && token != m.token {
+		return monitor.ErrInvalidApprovalToken
+	}
+	m.decisions = append(m.decisions, decision{groupID: groupID, planID: planID, approve: approve, token: token})
+	return nil
+}
+
+func (m *fakeMonitor) Failback(groupID string) error {
+	m.failbacks = append(m.failbacks, groupID)
+	return nil
+}
+
+func newTestServer(t *testing.T, adminToken string) (*Server, *fakeMonitor) {
+	t.Helper()
+
+	m := &fakeMonitor{token: "plan-token"}
+	s, err := New(WithMonitor(m), WithAdminToken(adminToken))
+	require.NoError(t, err)
+	return s, m
+}
+
+func TestDecision(t *testing.T) {
+	t.Parallel()
+
+	planID := uuid.New()
+	path := "/v1/groups/web/plans/" + planID.String()
+
+	for _, tc := range []struct {
+		name           string
+		method         string
+		path           string
+		form           url.Values
+		header         string
+		expectedStatus int
+		expected       []decision
+	}{
+		{
+			name: "admin token", method: http.MethodPost, path: path + "/approve", header: "Bearer admin-token",
+			expectedStatus: http.StatusOK,
+			expected:       []decision{{groupID: "web", planID: planID, approve: true}},
+		},
+		{
+			name: "plan token", method: http.MethodPost, path: path + "/reject", form: url.Values{"token": {"plan-token"}},
+			expectedStatus: http.StatusOK,
+			expected:       []decision{{groupID: "web", planID: planID, token: "plan-token"}},
+		},
+		{
+			name: "invalid plan token", method: http.MethodPost, path: path + "/approve",
+			form:           url.Values{"token": {"other-token"}},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name: "invalid admin token", method: http.MethodPost, path: path + "/approve", header: "Bearer other-token",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{name: "no token", method: http.MethodPost, path: path + "/approve", expectedStatus: http.StatusUnauthorized},
+		{
+			name: "invalid plan ID", method: http.MethodPost, path: "/v1/groups/web/plans/123/approve",
+			header: "Bearer admin-token", expectedStatus: http.StatusBadRequest,
+		},
+		{
+			// Opening a link, e.g. by a chat tool that previews it, does not decide anything.
+			name: "link", method: http.MethodGet, path: path + "/approve?token=plan-token",
+			expectedStatus: http.StatusOK,
+		},
+		{name: "link without token", method: http.MethodGet, path: path + "/approve", expectedStatus: http.StatusBadRequest},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s, m := newTestServer(t, "admin-token")
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.form.Encode()))
+			if tc.form != nil {
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code, rec.Body.String())
+			assert.Equal(t, tc.expected, m.decisions)
+		})
+	}
+
+	t.Run("confirm", func(t *testing.T) {
+		t.Parallel()
+
+		s, m := newTestServer(t, "admin-token")
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path+"/approve?token=plan-token", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `<form method="post" action="`+path+`/approve">`)
+		assert.Contains(t, rec.Body.String(), `value="plan-token"`)
+
+		// Submitting the form of the page approves the plan.
+		req := httptest.NewRequest(http.MethodPost, path+"/approve", strings.NewReader("token=plan-token"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec = httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, []decision{{groupID: "web", planID: planID, approve: true, token: "plan-token"}}, m.decisions)
+	})
+}
+
+func TestRequireAdmin(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name           string
+		adminToken     string
+		header         string
+		expectedStatus int
+	}{
+		{name: "valid token", adminToken: "admin-token", header: "Bearer admin-token", expectedStatus: http.StatusAccepted},
+		{name: "invalid token", adminToken: "admin-token", header: "Bearer other", expectedStatus: http.StatusUnauthorized},
+		{name: "no token", adminToken: "admin-token", expectedStatus: http.StatusUnauthorized},
+		{name: "no admin token configured", expectedStatus: http.StatusAccepted},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s, m := newTestServer(t, tc.adminToken)
+			req := httptest.NewRequest(http.MethodPost, "/v1/groups/web/failback", nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			rec := httptest.NewRecorder()
+			s.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+			if tc.expectedStatus == http.StatusAccepted {
+				assert.Equal(t, []string{"web"}, m.failbacks)
+			} else {
+				assert.Empty(t, m.failbacks)
+			}
+		})
+	}
+}
+
+func TestIsLoopbackAddr(t *testing.T) {
+	t.Parallel()
+
+	for addr, expected := range map[string]bool{
+		"localhost:8080": true,
+		"127.0.0.1:8080": true,
+		"[::1]:8080":     true,
+		":8080":          false,
+		"0.0.0.0:8080":   false,
+		"10.0.0.1:8080":  false,
+		"example.com:80": false,
+	} {
+		assert.Equal(t, expected, isLoopbackAddr(addr), addr)
+	}
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gzuidhof/flipper/consensus"
+	"github.com/gzuidhof/flipper/event"
+	"github.com/gzuidhof/flipper/monitor"
+	"github.com/gzuidhof/flipper/plan"
+)
+
+// planResponse is the response of the plan endpoint.
+type planResponse struct {
+	GroupID string `json:"group_id"`
+	// Plan is the most recent plan that required actions, null if there was none yet.
+	Plan *plan.Plan `json:"plan"`
+}
+
+// pinsResponse is the response of the pins endpoint.
+type pinsResponse struct {
+	GroupID string `json:"group_id"`
+	// Pins are the active pins by floating IP ID.
+	Pins map[string]plan.Pin `json:"pins"`
+}
+
+// maintenanceResponse is the response of the maintenance endpoint.
+type maintenanceResponse struct {
+	GroupID string `json:"group_id"`
+	// Windows are the maintenance windows, those of the config first.
+	Windows []monitor.MaintenanceStatus `json:"windows"`
+}
+
+// pendingPlanResponse is the response of the pending plan endpoint.
+type pendingPlanResponse struct {
+	GroupID string `json:"group_id"`
+	// Pending is the plan that waits for approval, null if there is none.
+	Pending *monitor.PendingPlan `json:"pending"`
+}
+
+// eventResponse is a single event in the response of the events endpoint.
+type eventResponse struct {
+	Kind  event.Kind  `json:"kind"`
+	Event event.Event `json:"event"`
+}
+
+// eventsResponse is the response of the events endpoint.
+type eventsResponse struct {
+	// Events are the most recent events, oldest first.
+	Events []eventResponse `json:"events"`
+}
+
+// registerGroupRoutes registers the endpoints that expose the state of the groups.
+func (s *Server) registerGroupRoutes() {
+	s.mux.HandleFunc("GET /v1/groups/{group}/plan",
+		s.requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+			groupID := r.PathValue("group")
+
+			p, ok, err := s.monitor.LastPlan(groupID)
+			if err != nil {
+				s.writeMonitorError(w, err)
+				return
+			}
+
+			resp := planResponse{GroupID: groupID}
+			if ok {
+				resp.Plan = &p
+			}
+			s.writeJSON(w, resp)
+		}))
+
+	s.mux.HandleFunc("GET /v1/groups/{group}/pins",
+		s.requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+			groupID := r.PathValue("group")
+
+			pins, err := s.monitor.Pins(groupID)
+			if err != nil {
+				s.writeMonitorError(w, err)
+				return
+			}
+			s.writeJSON(w, pinsResponse{GroupID: groupID, Pins: pins})
+		}))
+
+	s.mux.HandleFunc("GET /v1/groups/{group}/maintenance",
+		s.requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+			groupID := r.PathValue("group")
+
+			windows, err := s.monitor.Maintenance(groupID)
+			if err != nil {
+				s.writeMonitorError(w, err)
+				return
+			}
+			s.writeJSON(w, maintenanceResponse{GroupID: groupID, Windows: windows})
+		}))
+
+	s.mux.HandleFunc("GET /v1/events",
+		s.requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+			// The events can be filtered by group with the group query parameter.
+			groupID := r.URL.Query().Get("group")
+
+			resp := eventsResponse{Events: []eventResponse{}}
+			for _, e := range s.monitor.RecentEvents() {
+				if groupID != "" && e.Metadata().Group.ID != groupID {
+					continue
+				}
+				resp.Events = append(resp.Events, eventResponse{Kind: e.Kind(), Event: e})
+			}
+			s.writeJSON(w, resp)
+		}))
+
+	s.mux.HandleFunc("GET /v1/leader",
+		s.requireAdmin(func(w http.ResponseWriter, _ *http.Request) {
+			s.writeJSON(w, s.monitor.Leader())
+		}))
+
+	s.mux.HandleFunc("GET "+consensus.ViewPath,
+		s.requireAdmin(func(w http.ResponseWriter, _ *http.Request) {
+			view, err := s.monitor.ConsensusView()
+			if err != nil {
+				s.writeMonitorError(w, err)
+				return
+			}
+			s.writeJSON(w, view)
+		}))
+
+	s.mux.HandleFunc("GET /v1/groups/{group}/pending-plan",
+		s.requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+			groupID := r.PathValue("group")
+
+			p, ok, err := s.monitor.PendingPlan(groupID)
+			if err != nil {
+				s.writeMonitorError(w, err)
+				return
+			}
+
+			resp := pendingPlanResponse{GroupID: groupID}
+			if ok {
+				resp.Pending = &p
+			}
+			s.writeJSON(w, resp)
+		}))
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		s.writeInternalError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
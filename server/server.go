@@ -25,6 +25,11 @@ type Server struct {
 
 	staticFS fs.FS
 	template *template.Engine
+
+	// monitor is used for the admin endpoints, they are only available if it is set.
+	monitor Monitor
+	// adminToken is the bearer token required for the admin endpoints, if set.
+	adminToken string
 }
 
 // Option is a functional option for the server.
@@ -90,6 +95,22 @@ func WithTemplateEngine(t *template.Engine) Option {
 	}
 }
 
+// WithMonitor sets the monitor for the server, which enables the admin endpoints.
+func WithMonitor(m Monitor) Option {
+	return func(s *Server) error {
+		s.monitor = m
+		return nil
+	}
+}
+
+// WithAdminToken sets the bearer token that is required for the admin endpoints.
+func WithAdminToken(token string) Option {
+	return func(s *Server) error {
+		s.adminToken = token
+		return nil
+	}
+}
+
 // ServeHTTP implements the http.Handler interface.
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	s.mux.ServeHTTP(w, r)
@@ -119,6 +140,10 @@ func (s *Server) ListenAndServe(ctx context.Context) error {
 	}()
 
 	s.logger.InfoContext(ctx, "Server listening.", slog.String("addr", s.addr))
+	if s.monitor != nil && s.adminToken == "" && !isLoopbackAddr(s.addr) {
+		s.logger.WarnContext(ctx, "The admin endpoints are open to anyone that can reach the server, "+
+			"set an admin token or listen on localhost.", slog.String("addr", s.addr))
+	}
 
 	select {
 	case <-ctx.Done():
@@ -136,3 +161,17 @@ func (s *Server) ListenAndServe(ctx context.Context) error {
 		return err
 	}
 }
+
+// isLoopbackAddr returns true if the address only listens on the loopback interface. An empty host listens on all
+// interfaces.
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
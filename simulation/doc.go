@@ -0,0 +1,8 @@
+// Package simulation runs the monitor of a group through a scripted scenario with a virtual clock, so that the
+// plans and notifications it results in can be asserted on in tests without waiting for real time to pass.
+//
+// A Scenario describes the servers and floating IPs of a group, and the steps that happen to them over time: servers
+// that become unhealthy or healthy again, servers that are added or removed, floating IPs that are moved outside of
+// flipper and assignments that fail. Run simulates the full group loop against a fake provider and fake health
+// checks, and returns the events it published.
+package simulation
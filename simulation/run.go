@@ -0,0 +1,168 @@
+package simulation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/gzuidhof/flipper/clock"
+	"github.com/gzuidhof/flipper/event"
+	"github.com/gzuidhof/flipper/monitor"
+)
+
+// Epoch is the time at which simulations start.
+//
+//nolint:gochecknoglobals // Fixed start time.
+var Epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// settleRounds is the number of rounds without any activity of the monitor after which it is assumed to wait for
+// the clock. The rounds are a millisecond of real time each.
+const settleRounds = 5
+
+// Move is a floating IP that was assigned to a server by the monitor.
+type Move struct {
+	At         time.Time
+	FloatingIP string
+	Server     string
+}
+
+// Result is what the monitor did during a simulation.
+type Result struct {
+	// Events are the events the monitor published, in order.
+	Events []event.Event
+	// Moves are the assignments of floating IPs that succeeded, in order. Unlike the plans, of which some are
+	// dropped when they are stale, they are what actually happened.
+	Moves []Move
+	// Targets are the names of the servers the floating IPs target at the end, by floating IP name. Floating IPs
+	// that are unassigned target the empty string.
+	Targets map[string]string
+	// Notifications are the notifications the events would have sent, in order. They are formatted when the events
+	// are published, as the state the events refer to changes afterwards.
+	Notifications []string
+}
+
+// Plans returns the plans the monitor made, in order.
+func (r Result) Plans() []event.PlanCreated {
+	var plans []event.PlanCreated
+	for _, e := range r.Events {
+		if p, ok := e.(event.PlanCreated); ok {
+			plans = append(plans, p)
+		}
+	}
+	return plans
+}
+
+// Executions returns the reports of the plans the monitor executed, in order.
+func (r Result) Executions() []event.PlanExecuted {
+	var executions []event.PlanExecuted
+	for _, e := range r.Events {
+		if executed, ok := e.(event.PlanExecuted); ok {
+			executions = append(executions, executed)
+		}
+	}
+	return executions
+}
+
+// Run simulates the monitor of the group of a scenario. The clock is virtual, it jumps from one timer of the
+// monitor or step of the scenario to the next once the monitor is done with the previous one, so that a scenario
+// of hours runs in well under a second.
+func Run(ctx context.Context, sc Scenario) (Result, error) {
+	if err := sc.validate(); err != nil {
+		return Result{}, err
+	}
+	clk := clock.NewVirtual(Epoch)
+	w, err := newWorld(sc, clk)
+	if err != nil {
+		return Result{}, err
+	}
+
+	events := event.NewBus()
+	events.Subscribe(w.handle)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	group := monitor.NewGroup(sc.groupConfig(), w, logger, events,
+		monitor.WithClock(clk),
+		monitor.WithHTTPCheckFactory(w.newCheck),
+	)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- group.Start(ctx) }()
+
+	steps := slices.Clone(sc.Steps)
+	slices.SortStableFunc(steps, func(a, b Step) int { return int(a.At - b.At) })
+	end := Epoch.Add(sc.duration())
+
+	settle(clk, w)
+	for ctx.Err() == nil {
+		next, ok := clk.Next()
+		if len(steps) > 0 && (!ok || !Epoch.Add(steps[0].At).After(next)) {
+			next, ok = Epoch.Add(steps[0].At), true
+		}
+		if !ok || next.After(end) {
+			break
+		}
+		for len(steps) > 0 && !Epoch.Add(steps[0].At).After(next) {
+			if err := w.apply(steps[0].Actions); err != nil {
+				return Result{}, fmt.Errorf("step at %s: %w", steps[0].At, err)
+			}
+			steps = steps[1:]
+		}
+		clk.Set(next)
+		if w.takeApproval() {
+			// The group publishes events while it handles the decision, so the world must not be locked.
+			if err := approvePending(ctx, group); err != nil {
+				return Result{}, err
+			}
+		}
+		settle(clk, w)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		return Result{}, fmt.Errorf("group failed: %w", err)
+	}
+
+	w.mu.Lock()
+	result := Result{
+		Events:        slices.Clone(w.events),
+		Moves:         slices.Clone(w.moves),
+		Notifications: slices.Clone(w.notifications),
+	}
+	w.mu.Unlock()
+	result.Targets = w.targets()
+	return result, nil
+}
+
+// approvePending approves the plan of the group that waits for approval, if there is one.
+func approvePending(ctx context.Context, group *monitor.Group) error {
+	pending, ok := group.PendingPlan()
+	if !ok {
+		return nil
+	}
+	err := group.Decide(ctx, pending.Plan.ID, true, "")
+	if err != nil && !errors.Is(err, monitor.ErrPlanNotFound) {
+		return fmt.Errorf("failed to approve plan: %w", err)
+	}
+	return nil
+}
+
+// settle waits until the monitor received all ticks of the clock and did nothing for a number of rounds, so that
+// it is waiting for the clock to move on.
+func settle(clk *clock.Virtual, w *world) {
+	progress := func() uint64 { return w.progress() + uint64(clk.Waiters()) }
+	last, stable := progress(), 0
+	for stable < settleRounds {
+		time.Sleep(time.Millisecond)
+		current := progress()
+		if current == last && clk.Pending() == 0 {
+			stable++
+			continue
+		}
+		last, stable = current, 0
+	}
+}
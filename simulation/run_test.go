@@ -0,0 +1,345 @@
+package simulation
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+	"github.com/gzuidhof/flipper/event"
+	"github.com/gzuidhof/flipper/plan"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	servers := []Server{
+		{Name: "web-1", Location: "fsn1", ResourceIndex: 0},
+		{Name: "web-2", Location: "fsn1", ResourceIndex: 1},
+	}
+	floatingIPs := []FloatingIP{{Name: "public", Location: "fsn1", ResourceIndex: 0, Target: "web-1"}}
+
+	at := func(d time.Duration) time.Time { return Epoch.Add(d) }
+
+	tests := []struct {
+		name  string
+		steps []Step
+		// wantMoves are the moves of the floating IP, wantTarget is the server it targets at the end.
+		wantMoves      []Move
+		wantTarget     string
+		wantFailed     bool
+		wantNotifyLike string
+	}{
+		{
+			name:       "healthy",
+			wantTarget: "web-1",
+		},
+		{
+			name:           "failover",
+			steps:          []Step{At(time.Minute, Unhealthy("web-1"))},
+			wantMoves:      []Move{{At: at(time.Minute + 20*time.Second), FloatingIP: "public", Server: "web-2"}},
+			wantTarget:     "web-2",
+			wantNotifyLike: "became **_unhealthy_**",
+		},
+		{
+			name: "failover and back",
+			steps: []Step{
+				At(time.Minute, Unhealthy("web-1")),
+				At(5*time.Minute, Healthy("web-1")),
+			},
+			wantMoves: []Move{
+				{At: at(time.Minute + 20*time.Second), FloatingIP: "public", Server: "web-2"},
+				{At: at(5*time.Minute + 10*time.Second), FloatingIP: "public", Server: "web-1"},
+			},
+			wantTarget:     "web-1",
+			wantNotifyLike: "became **_healthy_** again",
+		},
+		{
+			name: "assignments fail",
+			steps: []Step{
+				At(0, FailAssignments()),
+				At(time.Minute, Unhealthy("web-1")),
+				At(3*time.Minute, RestoreAssignments()),
+			},
+			wantMoves:  []Move{{At: at(3 * time.Minute), FloatingIP: "public", Server: "web-2"}},
+			wantTarget: "web-2",
+			wantFailed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			result, err := Run(context.Background(), Scenario{
+				Group: cfgmodel.GroupConfig{
+					ID:           "lb",
+					PollInterval: 30 * time.Second,
+					Checks: []cfgmodel.HealthCheckConfig{
+						{ID: "http", Type: "http", Interval: 10 * time.Second, Fall: 3, Rise: 2},
+					},
+				},
+				Servers:     servers,
+				FloatingIPs: floatingIPs,
+				Steps:       tt.steps,
+				Duration:    10 * time.Minute,
+			})
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.wantMoves, result.Moves)
+			assert.Equal(t, tt.wantTarget, result.Targets["public"])
+			assert.Equal(t, len(tt.wantMoves) > 0 || tt.wantFailed, len(result.Plans()) > 0)
+
+			failed := false
+			for _, executed := range result.Executions() {
+				failed = failed || executed.Failed
+			}
+			assert.Equal(t, tt.wantFailed, failed)
+
+			if tt.wantNotifyLike != "" {
+				assert.True(t, containsAny(result.Notifications, tt.wantNotifyLike),
+					"a notification contains %q", tt.wantNotifyLike)
+			}
+		})
+	}
+}
+
+func TestRunMaintenance(t *testing.T) {
+	t.Parallel()
+
+	servers := []Server{
+		{Name: "web-1", Location: "fsn1", ResourceIndex: 0, Labels: map[string]string{"rack": "a"}},
+		{Name: "web-2", Location: "fsn1", ResourceIndex: 1, Labels: map[string]string{"rack": "b"}},
+	}
+	floatingIPs := []FloatingIP{{Name: "public", Location: "fsn1", ResourceIndex: 0, Target: "web-1"}}
+
+	at := func(d time.Duration) time.Time { return Epoch.Add(d) }
+
+	tests := []struct {
+		name   string
+		window cfgmodel.MaintenanceWindowConfig
+		steps  []Step
+		// wantMoves are the moves of the floating IP, wantNotifyLike a part of a notification.
+		wantMoves      []Move
+		wantNotifyLike []string
+		wantMuted      bool
+	}{
+		{
+			name: "suppress",
+			window: cfgmodel.MaintenanceWindowConfig{
+				ID: "reboots", Start: at(30 * time.Second), End: at(5 * time.Minute), Servers: []string{"web-1"},
+			},
+			steps: []Step{At(time.Minute, Unhealthy("web-1"))},
+			// The floating IP stays on the unhealthy server until the window ends.
+			wantMoves: []Move{{At: at(5 * time.Minute), FloatingIP: "public", Server: "web-2"}},
+			wantNotifyLike: []string{
+				"Maintenance window `reboots` in group **lb** (`lb`) **started**, failover is suppressed for `web-1`",
+				"*Expected during maintenance window `reboots`.*",
+				"Maintenance window `reboots` in group **lb** (`lb`) **ended**",
+			},
+		},
+		{
+			name: "drain",
+			window: cfgmodel.MaintenanceWindowConfig{
+				ID: "updates", Mode: "drain", Schedule: "0 * * * *", Duration: 3 * time.Minute,
+				LabelSelector: "rack=a", Reason: "kernel updates",
+			},
+			// The floating IP moves away once the health of the servers is known, and back at the end of the window.
+			wantMoves: []Move{
+				{At: at(10 * time.Second), FloatingIP: "public", Server: "web-2"},
+				{At: at(3 * time.Minute), FloatingIP: "public", Server: "web-1"},
+			},
+			wantNotifyLike: []string{"is drained for maintenance window `updates` (kernel updates)"},
+		},
+		{
+			name: "muted",
+			window: cfgmodel.MaintenanceWindowConfig{
+				ID: "reboots", Notifications: "mute", Start: at(0), End: at(10 * time.Minute),
+			},
+			steps:     []Step{At(time.Minute, Unhealthy("web-1")), At(3*time.Minute, Healthy("web-1"))},
+			wantMuted: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			result, err := Run(context.Background(), Scenario{
+				Group: cfgmodel.GroupConfig{
+					ID:           "lb",
+					DisplayName:  "lb",
+					PollInterval: 30 * time.Second,
+					Checks: []cfgmodel.HealthCheckConfig{
+						{ID: "http", Type: "http", Interval: 10 * time.Second, Fall: 3, Rise: 2},
+					},
+					Maintenance: []cfgmodel.MaintenanceWindowConfig{tt.window},
+				},
+				Servers:     servers,
+				FloatingIPs: floatingIPs,
+				Steps:       tt.steps,
+				Duration:    10 * time.Minute,
+			})
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.wantMoves, result.Moves)
+			for _, like := range tt.wantNotifyLike {
+				assert.True(t, containsAny(result.Notifications, like), "a notification contains %q", like)
+			}
+			for _, n := range result.Notifications {
+				if !strings.Contains(n, "became **_") {
+					continue
+				}
+				assert.False(t, tt.wantMuted, "the change of the health is muted: %s", n)
+				assert.True(t, strings.HasPrefix(n, ":construction: *Expected during"), "the change is expected: %s", n)
+			}
+		})
+	}
+}
+
+func TestRunInvalid(t *testing.T) {
+	t.Parallel()
+
+	_, err := Run(context.Background(), Scenario{
+		Servers: []Server{{Name: "web-1", Location: "fsn1"}},
+		Steps:   []Step{At(time.Minute, Unhealthy("web-3"))},
+	})
+	require.ErrorContains(t, err, `unknown server "web-3"`)
+
+	_, err = Run(context.Background(), Scenario{
+		Servers:     []Server{{Name: "web-1", Location: "fsn1"}},
+		FloatingIPs: []FloatingIP{{Name: "public", Location: "fsn1", Target: "web-2"}},
+	})
+	require.ErrorContains(t, err, `unknown server "web-2"`)
+}
+
+func containsAny(notifications []string, substr string) bool {
+	for _, n := range notifications {
+		if strings.Contains(n, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRunApproval(t *testing.T) {
+	t.Parallel()
+
+	servers := []Server{
+		{Name: "web-1", Location: "fsn1", ResourceIndex: 0},
+		{Name: "web-2", Location: "fsn1", ResourceIndex: 1},
+	}
+	floatingIPs := []FloatingIP{{Name: "public", Location: "fsn1", ResourceIndex: 0, Target: "web-1"}}
+
+	at := func(d time.Duration) time.Time { return Epoch.Add(d) }
+
+	tests := []struct {
+		name     string
+		approval cfgmodel.ApprovalConfig
+		steps    []Step
+		// wantMoves are the moves of the floating IP, wantDecisions what happened to the pending plans.
+		wantMoves     []Move
+		wantDecisions []string
+	}{
+		{
+			name:          "approved",
+			approval:      cfgmodel.ApprovalConfig{Required: true},
+			steps:         []Step{At(time.Minute, Unhealthy("web-1")), At(3*time.Minute, Approve())},
+			wantMoves:     []Move{{At: at(3 * time.Minute), FloatingIP: "public", Server: "web-2"}},
+			wantDecisions: []string{event.DecisionApproved},
+		},
+		{
+			name:     "recovered before approval",
+			approval: cfgmodel.ApprovalConfig{Required: true},
+			steps: []Step{
+				At(time.Minute, Unhealthy("web-1")),
+				At(3*time.Minute, Healthy("web-1")),
+				At(5*time.Minute, Approve()),
+			},
+			wantDecisions: []string{event.DecisionUnneeded},
+		},
+		{
+			name:     "recovered before auto-approval",
+			approval: cfgmodel.ApprovalConfig{Required: true, Timeout: 5 * time.Minute, AutoApprove: true},
+			steps: []Step{
+				At(time.Minute, Unhealthy("web-1")),
+				At(3*time.Minute, Healthy("web-1")),
+			},
+			wantDecisions: []string{event.DecisionUnneeded},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			result, err := Run(context.Background(), Scenario{
+				Group: cfgmodel.GroupConfig{
+					ID:           "lb",
+					PollInterval: 30 * time.Second,
+					Checks: []cfgmodel.HealthCheckConfig{
+						{ID: "http", Type: "http", Interval: 10 * time.Second, Fall: 3, Rise: 2},
+					},
+					Approval: tt.approval,
+				},
+				Servers:     servers,
+				FloatingIPs: floatingIPs,
+				Steps:       tt.steps,
+				Duration:    10 * time.Minute,
+			})
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.wantMoves, result.Moves)
+			var decisions []string
+			for _, e := range result.Events {
+				if decided, ok := e.(event.PlanDecided); ok {
+					decisions = append(decisions, decided.Decision)
+				}
+			}
+			assert.Equal(t, tt.wantDecisions, decisions)
+		})
+	}
+}
+
+func TestRunWarnings(t *testing.T) {
+	t.Parallel()
+
+	result, err := Run(context.Background(), Scenario{
+		Group: cfgmodel.GroupConfig{
+			ID:               "lb",
+			PollInterval:     30 * time.Second,
+			HomeLocationOnly: true,
+			Checks: []cfgmodel.HealthCheckConfig{
+				{ID: "http", Type: "http", Interval: 10 * time.Second, Fall: 3, Rise: 2},
+			},
+		},
+		Servers: []Server{
+			{Name: "web-1", Location: "fsn1", ResourceIndex: 0},
+			{Name: "web-2", Location: "nbg1", ResourceIndex: 1},
+		},
+		FloatingIPs: []FloatingIP{{Name: "public", Location: "nbg1", ResourceIndex: 1, Target: "web-2"}},
+		Steps: []Step{
+			At(time.Minute, Unhealthy("web-2")),
+			At(3*time.Minute, Healthy("web-2")),
+		},
+		Duration: 10 * time.Minute,
+	})
+	require.NoError(t, err)
+
+	// The floating IP can not be moved, so there is no plan to carry the warning. It is published once when it
+	// appears, even though it is found again on every check, and once more when it is resolved.
+	assert.Empty(t, result.Plans())
+	var warnings [][]plan.Warning
+	for _, e := range result.Events {
+		if changed, ok := e.(event.PlanWarningsChanged); ok {
+			warnings = append(warnings, changed.Warnings)
+		}
+	}
+	require.Len(t, warnings, 2)
+	require.Len(t, warnings[0], 1)
+	assert.Contains(t, warnings[0][0].Message, "no candidate servers in home location nbg1")
+	assert.Empty(t, warnings[1])
+}
@@ -0,0 +1,177 @@
+package simulation
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+	"github.com/gzuidhof/flipper/resource"
+)
+
+// Scenario describes the resources of a group and what happens to them over time.
+type Scenario struct {
+	// Group is the config of the simulated group. The provider is always the mock provider, and a single HTTP check
+	// every 10 seconds is used if no checks are configured.
+	Group cfgmodel.GroupConfig
+
+	Servers     []Server
+	FloatingIPs []FloatingIP
+
+	// Steps are applied at their time since the start of the simulation, steps at the same time in order.
+	Steps []Step
+
+	// Duration is how long the simulation runs, by default until 5 minutes after the last step.
+	Duration time.Duration
+}
+
+// Server is a server of a scenario, it is referred to by its name. Servers are healthy unless a step makes them
+// unhealthy.
+type Server struct {
+	Name          string
+	Location      string
+	ResourceIndex int
+	Labels        map[string]string
+}
+
+// FloatingIP is a floating IP of a scenario, it is referred to by its name.
+type FloatingIP struct {
+	Name          string
+	Location      string
+	ResourceIndex int
+	// Target is the name of the server the floating IP is assigned to at the start, empty if it is unassigned.
+	Target string
+}
+
+// Step is a set of actions that happen at the same time.
+type Step struct {
+	// At is the time since the start of the simulation.
+	At      time.Duration
+	Actions []Action
+}
+
+// At creates a step that applies the actions at the given time since the start of the simulation.
+func At(at time.Duration, actions ...Action) Step {
+	return Step{At: at, Actions: actions}
+}
+
+// Action changes the simulated world, e.g. the health of a server.
+type Action func(w *world) error
+
+// Healthy makes the health checks of the servers succeed.
+func Healthy(names ...string) Action {
+	return func(w *world) error {
+		for _, name := range names {
+			if _, err := w.server(name); err != nil {
+				return err
+			}
+			delete(w.unhealthy, name)
+		}
+		return nil
+	}
+}
+
+// Unhealthy makes the health checks of the servers fail.
+func Unhealthy(names ...string) Action {
+	return func(w *world) error {
+		for _, name := range names {
+			if _, err := w.server(name); err != nil {
+				return err
+			}
+			w.unhealthy[name] = true
+		}
+		return nil
+	}
+}
+
+// AddServer adds a server to the group, the monitor sees it on its next poll.
+func AddServer(server Server) Action {
+	return func(w *world) error {
+		return w.addServer(server)
+	}
+}
+
+// RemoveServer removes a server from the group, the monitor sees it on its next poll. Floating IPs that target
+// the server become unassigned.
+func RemoveServer(name string) Action {
+	return func(w *world) error {
+		return w.removeServer(name)
+	}
+}
+
+// MoveFloatingIP assigns a floating IP to a server outside of flipper, e.g. by hand in the console of the provider.
+func MoveFloatingIP(floatingIP, server string) Action {
+	return func(w *world) error {
+		fip, err := w.floatingIP(floatingIP)
+		if err != nil {
+			return err
+		}
+		srv, err := w.server(server)
+		if err != nil {
+			return err
+		}
+		fip.CurrentTarget = srv.ID()
+		return nil
+	}
+}
+
+// FailAssignments makes all assignments of floating IPs fail, until RestoreAssignments.
+func FailAssignments() Action {
+	return func(w *world) error {
+		w.failAssignments = true
+		return nil
+	}
+}
+
+// RestoreAssignments makes the assignments of floating IPs succeed again.
+func RestoreAssignments() Action {
+	return func(w *world) error {
+		w.failAssignments = false
+		return nil
+	}
+}
+
+// Approve approves the plan that waits for approval, if there is one, like a human would through the admin API.
+func Approve() Action {
+	return func(w *world) error {
+		w.approve = true
+		return nil
+	}
+}
+
+// duration returns how long the scenario runs.
+func (s Scenario) duration() time.Duration {
+	if s.Duration > 0 {
+		return s.Duration
+	}
+	var last time.Duration
+	for _, step := range s.Steps {
+		last = max(last, step.At)
+	}
+	return last + 5*time.Minute
+}
+
+// groupConfig returns the config of the simulated group.
+func (s Scenario) groupConfig() cfgmodel.GroupConfig {
+	cfg := s.Group
+	if cfg.ID == "" {
+		cfg.ID = "simulation"
+	}
+	cfg.Provider = string(resource.ProviderNameMock)
+	if len(cfg.Checks) == 0 {
+		cfg.Checks = []cfgmodel.HealthCheckConfig{{ID: "http", Type: "http", Interval: 10 * time.Second}}
+	}
+	return cfg
+}
+
+// validate checks that the steps are in the scenario's time range.
+func (s Scenario) validate() error {
+	for _, step := range s.Steps {
+		if step.At < 0 {
+			return fmt.Errorf("step at %s is before the start of the simulation", step.At)
+		}
+		if step.At > s.duration() {
+			return fmt.Errorf("step at %s is after the end of the simulation at %s", step.At, s.duration())
+		}
+	}
+	return nil
+}
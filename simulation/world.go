@@ -0,0 +1,285 @@
+package simulation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"slices"
+	"sync"
+
+	"github.com/gzuidhof/flipper/check"
+	"github.com/gzuidhof/flipper/checker"
+	"github.com/gzuidhof/flipper/clock"
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+	"github.com/gzuidhof/flipper/event"
+	"github.com/gzuidhof/flipper/notification"
+	"github.com/gzuidhof/flipper/resource"
+)
+
+var (
+	// errUnhealthy is the error of the health checks of unhealthy servers.
+	errUnhealthy = errors.New("server is unhealthy")
+	// errAssignmentFailed is the error of the assignments while they fail.
+	errAssignmentFailed = errors.New("assignment failed")
+)
+
+var _ resource.Provider = (*world)(nil)
+
+// world is the simulated world: the resources of the provider, the health of the servers and the events the monitor
+// published. It is the provider of the simulated group.
+type world struct {
+	mu    sync.Mutex
+	clock clock.Clock
+
+	resources       resource.Group
+	unhealthy       map[string]bool
+	failAssignments bool
+	// approve is set when the pending plan should be approved, which is done once the step is applied.
+	approve bool
+	// nextID is the ID of the next resource, the addresses of the resources are derived from it.
+	nextID int64
+
+	events        []event.Event
+	notifications []string
+	moves         []Move
+	// activity counts everything the monitor did, the simulation waits for it to settle before moving the clock.
+	activity uint64
+}
+
+// newWorld creates the world of a scenario.
+func newWorld(sc Scenario, clk clock.Clock) (*world, error) {
+	w := &world{clock: clk, unhealthy: make(map[string]bool), nextID: 1}
+	for _, server := range sc.Servers {
+		if err := w.addServer(server); err != nil {
+			return nil, err
+		}
+	}
+	for _, fip := range sc.FloatingIPs {
+		if err := w.addFloatingIP(fip); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// Name returns the name of the mock provider.
+func (w *world) Name() resource.ProviderName {
+	return resource.ProviderNameMock
+}
+
+// Poll returns the current resources.
+func (w *world) Poll(_ context.Context) (resource.Group, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.activity++
+	return resource.Group{
+		FloatingIPs: slices.Clone(w.resources.FloatingIPs),
+		Servers:     slices.Clone(w.resources.Servers),
+	}, nil
+}
+
+// AssignFloatingIP assigns a floating IP to a server, unless assignments fail.
+func (w *world) AssignFloatingIP(_ context.Context, flip resource.FloatingIP, srv resource.Server) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.activity++
+	if w.failAssignments {
+		return errAssignmentFailed
+	}
+	for i, fip := range w.resources.FloatingIPs {
+		if fip.ID() == flip.ID() {
+			w.resources.FloatingIPs[i].CurrentTarget = srv.ID()
+			w.moves = append(w.moves, Move{At: w.clock.Now(), FloatingIP: fip.Name(), Server: srv.Name()})
+			return nil
+		}
+	}
+	return fmt.Errorf("floating IP %s not found", flip.ID())
+}
+
+// apply applies the actions of a step.
+func (w *world) apply(actions []Action) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, action := range actions {
+		if err := action(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// takeApproval returns true if the pending plan should be approved, and resets the request.
+func (w *world) takeApproval() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	approve := w.approve
+	w.approve = false
+	return approve
+}
+
+// handle collects the events the monitor publishes, and the notifications they would have sent.
+func (w *world) handle(_ context.Context, e event.Event) {
+	notifications := notification.Messages(e)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.activity++
+	w.events = append(w.events, e)
+	w.notifications = append(w.notifications, notifications...)
+}
+
+// progress returns the activity so far.
+func (w *world) progress() uint64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.activity
+}
+
+// newCheck creates a health check that succeeds or fails depending on the scripted health of the server.
+func (w *world) newCheck(cfg cfgmodel.HealthCheckConfig, target string) checker.Check[check.HTTPCheckResult] {
+	return &scriptedCheck{world: w, cfg: cfg, target: target}
+}
+
+// addServer adds a server, its addresses are derived from its ID.
+func (w *world) addServer(server Server) error {
+	if _, err := w.server(server.Name); err == nil {
+		return fmt.Errorf("duplicate server %q", server.Name)
+	}
+	id := w.id()
+	w.resources.Servers = append(w.resources.Servers, resource.Server{
+		Provider:      resource.ProviderNameMock,
+		HetznerID:     id,
+		ServerName:    server.Name,
+		Location:      server.Location,
+		ResourceIndex: server.ResourceIndex,
+		PublicIPv4:    netip.AddrFrom4([4]byte{198, 51, byte(id >> 8), byte(id)}),
+		Labels:        server.Labels,
+	})
+	return nil
+}
+
+// removeServer removes a server, the floating IPs that target it become unassigned.
+func (w *world) removeServer(name string) error {
+	server, err := w.server(name)
+	if err != nil {
+		return err
+	}
+	id := server.ID()
+	w.resources.Servers = slices.DeleteFunc(w.resources.Servers, func(s resource.Server) bool {
+		return s.ID() == id
+	})
+	for i, fip := range w.resources.FloatingIPs {
+		if fip.CurrentTarget == id {
+			w.resources.FloatingIPs[i].CurrentTarget = ""
+		}
+	}
+	delete(w.unhealthy, name)
+	return nil
+}
+
+// addFloatingIP adds a floating IP, its address is derived from its ID.
+func (w *world) addFloatingIP(fip FloatingIP) error {
+	if _, err := w.floatingIP(fip.Name); err == nil {
+		return fmt.Errorf("duplicate floating IP %q", fip.Name)
+	}
+	var target string
+	if fip.Target != "" {
+		server, err := w.server(fip.Target)
+		if err != nil {
+			return fmt.Errorf("target of floating IP %q: %w", fip.Name, err)
+		}
+		target = server.ID()
+	}
+	id := w.id()
+	w.resources.FloatingIPs = append(w.resources.FloatingIPs, resource.FloatingIP{
+		Provider:       resource.ProviderNameMock,
+		HetznerID:      id,
+		FloatingIPName: fip.Name,
+		Location:       fip.Location,
+		ResourceIndex:  fip.ResourceIndex,
+		IP:             netip.AddrFrom4([4]byte{192, 0, byte(id >> 8), byte(id)}),
+		CurrentTarget:  target,
+	})
+	return nil
+}
+
+// server returns the server with the given name.
+func (w *world) server(name string) (*resource.Server, error) {
+	for i, server := range w.resources.Servers {
+		if server.Name() == name {
+			return &w.resources.Servers[i], nil
+		}
+	}
+	return nil, fmt.Errorf("unknown server %q", name)
+}
+
+// floatingIP returns the floating IP with the given name.
+func (w *world) floatingIP(name string) (*resource.FloatingIP, error) {
+	for i, fip := range w.resources.FloatingIPs {
+		if fip.Name() == name {
+			return &w.resources.FloatingIPs[i], nil
+		}
+	}
+	return nil, fmt.Errorf("unknown floating IP %q", name)
+}
+
+// targets returns the names of the servers the floating IPs target, by floating IP name.
+func (w *world) targets() map[string]string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	names := make(map[string]string)
+	for _, server := range w.resources.Servers {
+		names[server.ID()] = server.Name()
+	}
+	targets := make(map[string]string)
+	for _, fip := range w.resources.FloatingIPs {
+		targets[fip.Name()] = names[fip.CurrentTarget]
+	}
+	return targets
+}
+
+func (w *world) id() int64 {
+	id := w.nextID
+	w.nextID++
+	return id
+}
+
+// scriptedCheck is a health check of which the result is scripted by the scenario.
+type scriptedCheck struct {
+	world  *world
+	cfg    cfgmodel.HealthCheckConfig
+	target string
+}
+
+// Check returns the scripted health of the server with the target address. Checks of servers that were removed
+// fail.
+func (c *scriptedCheck) Check(_ context.Context) check.HTTPCheckResult {
+	w := c.world
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.activity++
+	for _, server := range w.resources.Servers {
+		if server.PublicIPv4.String() != c.target {
+			continue
+		}
+		if w.unhealthy[server.Name()] {
+			return check.HTTPCheckResult{Error: errUnhealthy}
+		}
+		return check.HTTPCheckResult{StatusCode: 200}
+	}
+	return check.HTTPCheckResult{Error: errUnhealthy}
+}
+
+// Config returns the config of the check.
+func (c *scriptedCheck) Config() cfgmodel.HealthCheckConfig {
+	return c.cfg
+}
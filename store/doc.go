@@ -0,0 +1,3 @@
+// Package store keeps the state of flipper across restarts, so that it can continue where it left off instead of
+// waiting for every health check to report again.
+package store
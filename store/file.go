@@ -0,0 +1,77 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// File stores the state of every group as a JSON file in a directory.
+type File struct {
+	dir string
+}
+
+// NewFile creates a file store that writes to the given directory, creating it if it does not exist.
+func NewFile(dir string) (*File, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create store directory: %w", err)
+	}
+	return &File{dir: dir}, nil
+}
+
+func (f *File) path(groupID string) string {
+	return filepath.Join(f.dir, groupID+".json")
+}
+
+// Load returns the stored state of a group, or ErrNotFound if there is none.
+func (f *File) Load(_ context.Context, groupID string) (GroupState, error) {
+	data, err := os.ReadFile(f.path(groupID))
+	if errors.Is(err, fs.ErrNotExist) {
+		return GroupState{}, fmt.Errorf("%w: %s", ErrNotFound, groupID)
+	}
+	if err != nil {
+		return GroupState{}, fmt.Errorf("failed to read state: %w", err)
+	}
+
+	var state GroupState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return GroupState{}, fmt.Errorf("failed to decode state: %w", err)
+	}
+	return state, nil
+}
+
+// Save replaces the stored state of a group. The file is replaced at once, so a crash halfway does not corrupt it,
+// and the directory is synced, so a crash right after does not undo it.
+func (f *File) Save(_ context.Context, groupID string, state GroupState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(f.dir, groupID+".json.*")
+	if err != nil {
+		return fmt.Errorf("failed to create state file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // it no longer exists after a successful rename.
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), f.path(groupID)); err != nil {
+		return fmt.Errorf("failed to replace state: %w", err)
+	}
+	// Without syncing the directory, a crash right after the rename could bring back the previous state.
+	return syncDir(f.dir)
+}
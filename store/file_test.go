@@ -0,0 +1,53 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gzuidhof/flipper/checker"
+	"github.com/gzuidhof/flipper/plan"
+	"github.com/gzuidhof/flipper/resource"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFile(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	f, err := NewFile(t.TempDir())
+	require.NoError(t, err)
+
+	_, err = f.Load(ctx, "lb")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	savedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	state := GroupState{
+		SavedAt: savedAt,
+		Servers: map[string]ServerState{
+			"1": {
+				State:  resource.State{Status: resource.StatusUnhealthy, LastUpdated: savedAt},
+				Checks: map[string]checker.CheckState{"http__ipv4": {State: checker.StateUnhealthy, Fall: 3}},
+			},
+		},
+		Pins: map[string]plan.Pin{"11": {ServerID: "2", Until: savedAt.Add(time.Hour)}},
+		Plans: []PlanRecord{{
+			ExecutedAt: savedAt,
+			Report: plan.ExecutionReport{Results: []plan.ActionResult{{
+				Action: plan.ReassignFloatingIPAction{FloatingIPID: "11", ServerID: "2"},
+				Status: plan.ActionSucceeded,
+			}}},
+		}},
+	}
+	require.NoError(t, f.Save(ctx, "lb", state))
+	// Saving again replaces the state.
+	require.NoError(t, f.Save(ctx, "lb", state))
+
+	loaded, err := f.Load(ctx, "lb")
+	require.NoError(t, err)
+	assert.Equal(t, state, loaded)
+
+	_, err = f.Load(ctx, "other")
+	require.ErrorIs(t, err, ErrNotFound)
+}
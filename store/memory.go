@@ -0,0 +1,49 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// Memory keeps the state of groups in memory. It does not survive restarts, it is meant for tests.
+type Memory struct {
+	mu     sync.Mutex
+	states map[string][]byte
+}
+
+// NewMemory creates an empty memory store.
+func NewMemory() *Memory {
+	return &Memory{states: make(map[string][]byte)}
+}
+
+// Load returns the stored state of a group, or ErrNotFound if there is none.
+func (m *Memory) Load(_ context.Context, groupID string) (GroupState, error) {
+	m.mu.Lock()
+	data, ok := m.states[groupID]
+	m.mu.Unlock()
+	if !ok {
+		return GroupState{}, fmt.Errorf("%w: %s", ErrNotFound, groupID)
+	}
+
+	var state GroupState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return GroupState{}, fmt.Errorf("failed to decode state: %w", err)
+	}
+	return state, nil
+}
+
+// Save replaces the stored state of a group. The state is encoded like it would be on disk, so that it is not
+// shared with the caller.
+func (m *Memory) Save(_ context.Context, groupID string, state GroupState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.states[groupID] = data
+	return nil
+}
@@ -0,0 +1,84 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gzuidhof/flipper/checker"
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+	"github.com/gzuidhof/flipper/maintenance"
+	"github.com/gzuidhof/flipper/plan"
+	"github.com/gzuidhof/flipper/resource"
+)
+
+// ErrNotFound is returned when there is no stored state for a group.
+var ErrNotFound = errors.New("no stored state")
+
+// Store stores the state of groups.
+type Store interface {
+	// Load returns the stored state of a group, or ErrNotFound if there is none.
+	Load(ctx context.Context, groupID string) (GroupState, error)
+	// Save replaces the stored state of a group.
+	Save(ctx context.Context, groupID string, state GroupState) error
+}
+
+// GroupState is the state of a group that is kept across restarts.
+type GroupState struct {
+	// SavedAt is the time the state was saved.
+	SavedAt time.Time `json:"saved_at"`
+
+	// Servers are the last known states of the servers, by server ID.
+	Servers map[string]ServerState `json:"servers,omitempty"`
+
+	// Pins are the pins set through the admin API or by the drift policy, by floating IP ID.
+	Pins map[string]plan.Pin `json:"pins,omitempty"`
+
+	// Maintenance are the maintenance windows set through the admin API, by window ID.
+	Maintenance map[string]maintenance.Window `json:"maintenance,omitempty"`
+
+	// Plans are the most recently executed plans, oldest first.
+	Plans []PlanRecord `json:"plans,omitempty"`
+}
+
+// ServerState is the last known state of a server.
+type ServerState struct {
+	State resource.State `json:"state"`
+
+	// Checks are the states of the health checks of the server, by check ID.
+	Checks map[string]checker.CheckState `json:"checks,omitempty"`
+}
+
+// PlanRecord is a plan that was executed.
+type PlanRecord struct {
+	ExecutedAt time.Time            `json:"executed_at"`
+	Report     plan.ExecutionReport `json:"report"`
+}
+
+// Noop is a store that does not store anything, it is used when no store is configured.
+type Noop struct{}
+
+// Load always returns ErrNotFound on the Noop store.
+func (Noop) Load(_ context.Context, groupID string) (GroupState, error) {
+	return GroupState{}, fmt.Errorf("%w: %s", ErrNotFound, groupID)
+}
+
+// Save does nothing on the Noop store.
+func (Noop) Save(_ context.Context, _ string, _ GroupState) error {
+	return nil
+}
+
+// New creates the store from the config.
+//
+//nolint:ireturn // This is a factory function, it's okay to return an interface.
+func New(cfg cfgmodel.StoreConfig) (Store, error) {
+	switch cfg.Type {
+	case "":
+		return Noop{}, nil
+	case cfgmodel.StoreTypeFile:
+		return NewFile(cfg.Path)
+	default:
+		return nil, fmt.Errorf("unsupported store type: %s", cfg.Type)
+	}
+}
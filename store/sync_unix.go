@@ -0,0 +1,24 @@
+//go:build !windows
+
+package store
+
+import (
+	"fmt"
+	"os"
+)
+
+// syncDir flushes the entries of a directory to disk, so that a file renamed into it survives a crash.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open directory: %w", err)
+	}
+	if err := d.Sync(); err != nil {
+		_ = d.Close()
+		return fmt.Errorf("failed to sync directory: %w", err)
+	}
+	if err := d.Close(); err != nil {
+		return fmt.Errorf("failed to close directory: %w", err)
+	}
+	return nil
+}
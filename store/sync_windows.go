@@ -0,0 +1,8 @@
+//go:build windows
+
+package store
+
+// syncDir does nothing: Windows can not open a directory to sync it, so the rename relies on the file system journal.
+func syncDir(string) error {
+	return nil
+}
@@ -0,0 +1,5 @@
+// Package trace records the inputs of the monitor: the resources polled from the provider and the results of the
+// health checks. A trace can be replayed to reproduce the decisions that were made from them, see monitor.Replay.
+//
+// A trace is a file of JSON lines, one Entry per line, in the order the monitor processed them.
+package trace
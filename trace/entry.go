@@ -0,0 +1,87 @@
+package trace
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gzuidhof/flipper/check"
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+	"github.com/gzuidhof/flipper/resource"
+)
+
+// Kind is the kind of an entry.
+type Kind string
+
+// Kinds of entries.
+const (
+	// KindStart is recorded when a group starts, with its config.
+	KindStart Kind = "start"
+	// KindReconfigure is recorded when the config of a running group changes, with its new config.
+	KindReconfigure Kind = "reconfigure"
+	// KindPoll is recorded for every poll of the resources of a group.
+	KindPoll Kind = "poll"
+	// KindCheck is recorded for every result of a health check of a server.
+	KindCheck Kind = "check"
+	// KindResetFlapping is recorded when the flap damping state of a server is reset.
+	KindResetFlapping Kind = "reset_flapping"
+)
+
+// Entry is a single input of the monitor.
+type Entry struct {
+	// Time is the time at which the monitor processed the input.
+	Time    time.Time `json:"time"`
+	Kind    Kind      `json:"kind"`
+	GroupID string    `json:"group_id"`
+
+	// Config is the config of the group, for start and reconfigure entries. The API token is left out.
+	Config *cfgmodel.GroupConfig `json:"config,omitempty"`
+	// Resources are the resources that were polled, for poll entries.
+	Resources *resource.Group `json:"resources,omitempty"`
+	// ServerID is the ID of the server, for check and reset_flapping entries.
+	ServerID string `json:"server_id,omitempty"`
+	// Check is the result of the health check, for check entries.
+	Check *CheckResult `json:"check,omitempty"`
+}
+
+// CheckResult is the result of a health check of a server.
+type CheckResult struct {
+	// ID is the ID of the check, including the IP family suffix.
+	ID         string        `json:"id"`
+	Duration   time.Duration `json:"duration"`
+	StatusCode int           `json:"status_code,omitempty"`
+	// Error is the error the check failed with, empty if it was healthy.
+	Error string `json:"error,omitempty"`
+}
+
+// NewCheckResult creates the check result of a HTTP check.
+func NewCheckResult(id string, duration time.Duration, result check.HTTPCheckResult) *CheckResult {
+	checkResult := &CheckResult{ID: id, Duration: duration, StatusCode: result.StatusCode}
+	if result.Error != nil {
+		checkResult.Error = result.Error.Error()
+	}
+	return checkResult
+}
+
+// HTTPCheckResult returns the HTTP check result that was recorded. Only its status code and error are recorded.
+func (r CheckResult) HTTPCheckResult() check.HTTPCheckResult {
+	result := check.HTTPCheckResult{StatusCode: r.StatusCode}
+	if r.Error != "" {
+		result.Error = errors.New(r.Error)
+	}
+	return result
+}
+
+// NewStart creates a start entry. The API token is removed from the config.
+func NewStart(now time.Time, cfg cfgmodel.GroupConfig) Entry {
+	return Entry{Time: now, Kind: KindStart, GroupID: cfg.ID, Config: redact(cfg)}
+}
+
+// NewReconfigure creates a reconfigure entry. The API token is removed from the config.
+func NewReconfigure(now time.Time, cfg cfgmodel.GroupConfig) Entry {
+	return Entry{Time: now, Kind: KindReconfigure, GroupID: cfg.ID, Config: redact(cfg)}
+}
+
+func redact(cfg cfgmodel.GroupConfig) *cfgmodel.GroupConfig {
+	cfg.Hetzner.APIToken = ""
+	return &cfg
+}
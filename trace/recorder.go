@@ -0,0 +1,89 @@
+package trace
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// maxLineSize is the size of the largest entry that can be read.
+const maxLineSize = 64 << 20
+
+// Recorder appends entries to a trace file. It is safe to use from multiple goroutines.
+type Recorder struct {
+	logger *slog.Logger
+
+	mu      sync.Mutex
+	file    *os.File
+	encoder *json.Encoder
+}
+
+// Open opens the trace file at the given path for recording, creating it and its directory if they do not exist.
+// Entries are appended, so a restart continues the trace.
+func Open(path string, logger *slog.Logger) (*Recorder, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create trace directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open trace: %w", err)
+	}
+	return &Recorder{logger: logger, file: file, encoder: json.NewEncoder(file)}, nil
+}
+
+// Record appends an entry to the trace. Failures are logged, they do not stop the monitor.
+func (r *Recorder) Record(entry Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return
+	}
+	if err := r.encoder.Encode(entry); err != nil {
+		r.logger.Error("Failed to record trace entry.",
+			slog.String("kind", string(entry.Kind)),
+			slog.String("group_id", entry.GroupID),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// Close closes the trace file, entries are no longer recorded.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// Read calls fn for every entry of a trace, in order. It stops at the first error that fn returns.
+func Read(r io.Reader, fn func(Entry) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return fmt.Errorf("failed to decode trace entry on line %d: %w", line, err)
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read trace: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package trace
+
+import (
+	"errors"
+	"log/slog"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gzuidhof/flipper/check"
+	"github.com/gzuidhof/flipper/config/cfgmodel"
+	"github.com/gzuidhof/flipper/resource"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "traces", "trace.jsonl")
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	cfg := cfgmodel.GroupConfig{ID: "lb", Hetzner: cfgmodel.HetznerProviderConfig{APIToken: "secret"}}
+	resources := resource.Group{
+		FloatingIPs: []resource.FloatingIP{{HetznerID: 11, IP: netip.MustParseAddr("192.0.2.1"), CurrentTarget: "1"}},
+		Servers:     []resource.Server{{HetznerID: 1, PublicIPv4: netip.MustParseAddr("198.51.100.1")}},
+	}
+	entries := []Entry{
+		NewStart(at, cfg),
+		{Time: at, Kind: KindPoll, GroupID: "lb", Resources: &resources},
+		{Time: at, Kind: KindCheck, GroupID: "lb", ServerID: "1", Check: NewCheckResult(
+			"http__ipv4", time.Millisecond, check.HTTPCheckResult{Error: errors.New("connection refused")},
+		)},
+	}
+
+	recorder, err := Open(path, slog.Default())
+	require.NoError(t, err)
+	recorder.Record(entries[0])
+	require.NoError(t, recorder.Close())
+	// Reopening continues the trace.
+	recorder, err = Open(path, slog.Default())
+	require.NoError(t, err)
+	recorder.Record(entries[1])
+	recorder.Record(entries[2])
+	require.NoError(t, recorder.Close())
+	recorder.Record(entries[0])
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var read []Entry
+	require.NoError(t, Read(file, func(entry Entry) error {
+		read = append(read, entry)
+		return nil
+	}))
+	require.Len(t, read, 3, "nothing is recorded after closing")
+	assert.Empty(t, read[0].Config.Hetzner.APIToken, "the API token is not recorded")
+	assert.Equal(t, "lb", read[0].Config.ID)
+	assert.Equal(t, entries[1], read[1])
+	assert.Equal(t, entries[2], read[2])
+	assert.Equal(t, "connection refused", read[2].Check.HTTPCheckResult().Error.Error())
+	assert.True(t, NewCheckResult("http__ipv4", 0, check.HTTPCheckResult{}).HTTPCheckResult().Healthy())
+}
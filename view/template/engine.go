@@ -55,3 +55,19 @@ type HomePageData struct{}
 func (tfs *Engine) HomePage() PageTemplate[HomePageData] {
 	return getPageTemplate[HomePageData](tfs, "home")
 }
+
+// DecisionPageData is the data needed to render the page that confirms the approval or rejection of a plan.
+type DecisionPageData struct {
+	GroupID string
+	PlanID  string
+	// Approve is true to approve the plan, false to reject it.
+	Approve bool
+	// Action is the URL the decision is posted to, with the token of the plan.
+	Action string
+	Token  string
+}
+
+// DecisionPage returns a handle to the template for the page that confirms a decision on a plan.
+func (tfs *Engine) DecisionPage() PageTemplate[DecisionPageData] {
+	return getPageTemplate[DecisionPageData](tfs, "decision")
+}
@@ -35,4 +35,11 @@ func TestEmbedTemplates(t *testing.T) {
 	// Test that the template can be executed without error
 	err := homePageTemplate.ExecuteTemplate(sink, HomePageData{})
 	assert.NoError(t, err)
+
+	sink.Reset()
+	err = embedT.DecisionPage().ExecuteTemplate(sink, DecisionPageData{
+		GroupID: "web", PlanID: "123", Approve: true, Action: "/v1/groups/web/plans/123/approve", Token: "<secret>",
+	})
+	assert.NoError(t, err)
+	assert.Contains(t, sink.String(), `value="&lt;secret&gt;"`)
 }